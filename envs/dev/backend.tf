@@ -24,11 +24,6 @@ terraform {
   }
 }
 
-# The AWS provider block is separate from the backend configuration
-provider "aws" {
-  region = "us-east-1" # This must match the region specified in the backend if you're deploying resources there
-}
-
 # Example resource to demonstrate it works
 resource "aws_s3_bucket" "example" {
   bucket = "cloudwalker-terraform-state-dev" # Replace with a unique bucket name
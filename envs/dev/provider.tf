@@ -6,13 +6,6 @@ terraform {
     region         = "us-west-2"
     dynamodb_table = "terraform-locks"
   }*/
-  required_providers {
-    aws = {
-      source  = "hashicorp/aws"
-      #version = "values(aws.version)"
-      version = "~> 5.0"
-    }
-  }
 }
 
 provider "aws" {
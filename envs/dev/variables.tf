@@ -1,7 +1,19 @@
 variable "vpc_id" {}
+variable "env" {
+  default = "dev"
+}
 variable "vpc_cidr_block" {
   default = "10.0.0.0/16"
 }
+variable "public_subnet_cidrs" {
+  type = list(string)
+}
+variable "private_subnet_cidrs" {
+  type = list(string)
+}
+variable "allowed_ips" {
+  type = list(string)
+}
 variable "public_subnet_ids" {
   type = list(string)
 }
@@ -17,7 +29,7 @@ variable "tags" {
   type = map(string)
   default = {
     Name        = "cloudwalker"
-    Environment = "${var.env}-main"
+    Environment = "dev-main"
     Project     = "cloudwalker"
   }
 }
\ No newline at end of file
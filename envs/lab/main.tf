@@ -2,18 +2,16 @@ module "ec2" {
   source             = "../../modules/ec2"
   providers          = { aws = aws }
 
-  vpc_id             = var.vpc_id
-  public_subnet_ids  = var.public_subnet_ids
+  name_prefix        = "cloudwalker-lab"
+  subnet_ids         = var.public_subnet_ids
 
   ami_id             = var.ami_id
   instance_type      = var.instance_type
   key_name           = var.key_name
 
-  domain_name        = var.domain_name
-  hosted_zone_id     = var.hosted_zone_id
-
+  # var.vpc_id, var.domain_name and var.hosted_zone_id are not passed on:
+  # modules/ec2 declares no VPC, DNS or health check path inputs
   desired_capacity   = 3
   min_size           = 3
   max_size           = 3
-  health_check_path  = "/"
 }
\ No newline at end of file
@@ -13,7 +13,7 @@ module "vpc" {
   vpc_cidr_block          = "10.0.0.0/16"
   public_subnet_cidrs     = ["10.0.1.0/24", "10.0.2.0/24"]
   private_subnet_cidrs    = ["10.0.10.0/24", "10.0.20.0/24"]
-  allowed_ips             = ["0.0.0.0/0"]
+  allowed_ips             = ["10.0.0.0/16"]
 
   tags = local.common_tags
 }
@@ -41,7 +41,7 @@ module "iam" {
                 "s3:GetObject",
                 "s3:PutObject"
               ]
-              Resource = "${module.storage.s3_bucket_arn}/*"
+              Resource = "${module.s3.bucket_arn}/*"
             }
           ]
         })
@@ -69,7 +69,7 @@ module "iam" {
                 "s3:GetObject",
                 "s3:PutObject"
               ]
-              Resource = "${module.storage.s3_bucket_arn}/*"
+              Resource = "${module.s3.bucket_arn}/*"
             }
           ]
         })
@@ -129,7 +129,7 @@ module "iam" {
             Action = [
               "s3:ListBucket"
             ]
-            Resource = module.storage.s3_bucket_arn
+            Resource = module.s3.bucket_arn
           },
           {
             Effect = "Allow"
@@ -138,7 +138,7 @@ module "iam" {
               "s3:PutObject",
               "s3:DeleteObject"
             ]
-            Resource = "${module.storage.s3_bucket_arn}/*"
+            Resource = "${module.s3.bucket_arn}/*"
           }
         ]
       })
@@ -149,21 +149,17 @@ module "iam" {
 }
 
 # Storage Resources
-module "storage" {
-  source = "../../modules/storage"
+module "s3" {
+  source = "../../modules/s3"
 
-  # S3 Configuration
-  create_s3_bucket      = true
-  s3_bucket_name        = "${var.name_prefix}-app-storage"
-  s3_versioning_enabled = true
-  s3_encryption_algorithm = "AES256"
+  bucket_name          = "${var.name_prefix}-app-storage"
+  versioning_enabled   = true
+  encryption_algorithm = "AES256"
 
-  # S3 Lifecycle Rules
-  s3_lifecycle_rules = [
+  lifecycle_rules = [
     {
       id     = "transition_to_ia"
       status = "Enabled"
-      expiration = null
       transitions = [
         {
           days          = 30
@@ -177,24 +173,75 @@ module "storage" {
     }
   ]
 
-  # EFS Configuration
-  create_efs              = true
-  efs_name                = "${var.name_prefix}-shared-storage"
-  efs_performance_mode    = "generalPurpose"
-  efs_throughput_mode     = "bursting"
-  efs_encrypted           = true
-
-  vpc_id                  = module.vpc.vpc_id
-  efs_subnet_ids          = module.vpc.private_subnet_ids
-  efs_allowed_cidr_blocks = [module.vpc.vpc_cidr_block]
-
-  # S3 Access Role
-  create_s3_access_role = true
-  s3_access_role_name   = "${var.name_prefix}-s3-access"
-  s3_access_principals = [
-    module.iam.role_arns["ec2-instance-role"],
-    module.iam.role_arns["ecs-task-role"]
-  ]
+  tags = local.common_tags
+}
+
+module "efs" {
+  source = "../../modules/efs"
+
+  name             = "${var.name_prefix}-shared-storage"
+  performance_mode = "generalPurpose"
+  throughput_mode  = "bursting"
+  encrypted        = true
+
+  vpc_id     = module.vpc.vpc_id
+  subnet_ids = module.vpc.private_subnet_ids
+
+  # Only the ECS service mounts the file system
+  allowed_security_group_ids = [module.ecs.security_group_id]
+
+  tags = local.common_tags
+}
+
+# S3 Access Role
+# Assumed by the EC2 instance and ECS task roles for access to the storage bucket.
+# A module call of its own, since its trust policy names roles of module.iam.
+module "s3_access" {
+  source = "../../modules/iam"
+
+  roles = {
+    "s3-access-role" = {
+      assume_role_policy = jsonencode({
+        Version = "2012-10-17"
+        Statement = [
+          {
+            Action = "sts:AssumeRole"
+            Effect = "Allow"
+            Principal = {
+              AWS = [
+                module.iam.role_arns["ec2-instance-role"],
+                module.iam.role_arns["ecs-task-role"]
+              ]
+            }
+          }
+        ]
+      })
+      inline_policies = {
+        "s3-access" = jsonencode({
+          Version = "2012-10-17"
+          Statement = [
+            {
+              Effect = "Allow"
+              Action = [
+                "s3:ListBucket"
+              ]
+              Resource = module.s3.bucket_arn
+            },
+            {
+              Effect = "Allow"
+              Action = [
+                "s3:GetObject",
+                "s3:PutObject",
+                "s3:DeleteObject"
+              ]
+              Resource = "${module.s3.bucket_arn}/*"
+            }
+          ]
+        })
+      }
+      description = "Role for access to the application storage bucket"
+    }
+  }
 
   tags = local.common_tags
 }
@@ -318,7 +365,7 @@ module "ecs" {
   environment_variables = [
     {
       name  = "S3_BUCKET"
-      value = module.storage.s3_bucket_id
+      value = module.s3.bucket_id
     },
     {
       name  = "EFS_MOUNT_POINT"
@@ -332,7 +379,8 @@ module "ecs" {
   assign_public_ip = false
 
   # Load Balancer Integration
-  target_group_arn = module.alb.target_group_arns["api-servers"]
+  target_group_arn           = module.alb.target_group_arns["api-servers"]
+  allowed_security_group_ids = [module.alb.security_group_id]
 
   tags = local.common_tags
 }
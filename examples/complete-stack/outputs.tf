@@ -30,25 +30,30 @@ output "ec2_instance_profile_name" {
   value       = module.iam.instance_profiles["ec2-instance-role"].name
 }
 
+output "s3_access_role_arn" {
+  description = "ARN of the role the EC2 instance and ECS task roles assume for bucket access"
+  value       = module.s3_access.role_arns["s3-access-role"]
+}
+
 # Storage Outputs
 output "s3_bucket_name" {
   description = "Name of the S3 bucket"
-  value       = module.storage.s3_bucket_id
+  value       = module.s3.bucket_id
 }
 
 output "s3_bucket_arn" {
   description = "ARN of the S3 bucket"
-  value       = module.storage.s3_bucket_arn
+  value       = module.s3.bucket_arn
 }
 
 output "efs_id" {
   description = "ID of the EFS file system"
-  value       = module.storage.efs_id
+  value       = module.efs.file_system_id
 }
 
 output "efs_dns_name" {
   description = "DNS name of the EFS file system"
-  value       = module.storage.efs_dns_name
+  value       = module.efs.dns_name
 }
 
 # Load Balancer Outputs
@@ -121,8 +126,8 @@ output "integration_summary" {
     ecs_task_role          = module.iam.role_arns["ecs-task-role"]
     eks_cluster_role       = module.iam.role_arns["eks-cluster-role"]
     eks_node_group_role    = module.iam.role_arns["eks-node-group-role"]
-    s3_bucket              = module.storage.s3_bucket_id
-    efs_file_system        = module.storage.efs_id
+    s3_bucket              = module.s3.bucket_id
+    efs_file_system        = module.efs.file_system_id
     load_balancer_dns      = module.alb.load_balancer_dns_name
   }
 }
\ No newline at end of file
@@ -0,0 +1,65 @@
+module "ec2" {
+  source = "../../modules/ec2"
+
+  name_prefix      = var.name_prefix
+  ami_id           = var.ami_id
+  instance_type    = var.instance_type
+  key_name         = var.key_name
+  subnet_ids       = var.subnet_ids
+  desired_capacity = var.desired_capacity
+  min_size         = var.min_size
+  max_size         = var.max_size
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name_prefix" {
+  description = "Prefix for resource names"
+  type        = string
+}
+
+variable "ami_id" {
+  description = "AMI ID for the instances"
+  type        = string
+}
+
+variable "instance_type" {
+  description = "Instance type"
+  type        = string
+}
+
+variable "key_name" {
+  description = "Key pair name"
+  type        = string
+}
+
+variable "subnet_ids" {
+  description = "Subnet IDs for the Auto Scaling group"
+  type        = list(string)
+}
+
+variable "desired_capacity" {
+  description = "Desired number of instances"
+  type        = number
+}
+
+variable "min_size" {
+  description = "Minimum number of instances"
+  type        = number
+}
+
+variable "max_size" {
+  description = "Maximum number of instances"
+  type        = number
+}
+
+output "launch_template_id" {
+  value = module.ec2.launch_template_id
+}
+
+output "autoscaling_group_name" {
+  value = module.ec2.autoscaling_group_name
+}
@@ -0,0 +1,54 @@
+# Instances with the role and instance profile created by the EC2 module
+module "ec2" {
+  source = "../../modules/ec2"
+
+  name_prefix                 = var.name_prefix
+  ami_id                      = var.ami_id
+  key_name                    = var.key_name
+  subnet_ids                  = var.subnet_ids
+  desired_capacity            = 1
+  min_size                    = 1
+  max_size                    = 1
+  create_iam_instance_profile = true
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name_prefix" {
+  description = "Prefix for resource names"
+  type        = string
+}
+
+variable "ami_id" {
+  description = "AMI ID for the instances"
+  type        = string
+}
+
+variable "key_name" {
+  description = "Key pair name"
+  type        = string
+}
+
+variable "subnet_ids" {
+  description = "Subnet IDs for the Auto Scaling group"
+  type        = list(string)
+}
+
+output "autoscaling_group_name" {
+  value = module.ec2.autoscaling_group_name
+}
+
+output "iam_role_arn" {
+  value = module.ec2.iam_role_arn
+}
+
+output "iam_role_name" {
+  value = module.ec2.iam_role_name
+}
+
+output "iam_instance_profile_name" {
+  value = module.ec2.iam_instance_profile_name
+}
@@ -0,0 +1,103 @@
+module "ec2" {
+  source = "../../modules/ec2"
+
+  name_prefix      = var.name_prefix
+  ami_id           = var.ami_id
+  instance_type    = var.instance_type
+  key_name         = var.key_name
+  subnet_ids       = var.subnet_ids
+  desired_capacity = var.desired_capacity
+  min_size         = var.min_size
+  max_size         = var.max_size
+
+  enable_scaling_policies = var.enable_scaling_policies
+  cpu_high_threshold      = var.cpu_high_threshold
+  cpu_low_threshold       = var.cpu_low_threshold
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name_prefix" {
+  description = "Prefix for resource names"
+  type        = string
+}
+
+variable "ami_id" {
+  description = "AMI ID for the instances"
+  type        = string
+}
+
+variable "instance_type" {
+  description = "Instance type"
+  type        = string
+}
+
+variable "key_name" {
+  description = "Key pair name"
+  type        = string
+}
+
+variable "subnet_ids" {
+  description = "Subnet IDs for the Auto Scaling group"
+  type        = list(string)
+}
+
+variable "desired_capacity" {
+  description = "Desired number of instances"
+  type        = number
+}
+
+variable "min_size" {
+  description = "Minimum number of instances"
+  type        = number
+}
+
+variable "max_size" {
+  description = "Maximum number of instances"
+  type        = number
+}
+
+variable "enable_scaling_policies" {
+  description = "Create the scaling policies and CPU alarms"
+  type        = bool
+  default     = true
+}
+
+variable "cpu_high_threshold" {
+  description = "CPU threshold for scaling up"
+  type        = number
+  default     = 80
+}
+
+variable "cpu_low_threshold" {
+  description = "CPU threshold for scaling down"
+  type        = number
+  default     = 20
+}
+
+output "launch_template_id" {
+  value = module.ec2.launch_template_id
+}
+
+output "autoscaling_group_name" {
+  value = module.ec2.autoscaling_group_name
+}
+
+output "scale_up_policy_arn" {
+  value = module.ec2.scale_up_policy_arn
+}
+
+output "scale_down_policy_arn" {
+  value = module.ec2.scale_down_policy_arn
+}
+
+output "cpu_high_alarm_arn" {
+  value = module.ec2.cpu_high_alarm_arn
+}
+
+output "cpu_low_alarm_arn" {
+  value = module.ec2.cpu_low_alarm_arn
+}
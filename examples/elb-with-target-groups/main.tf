@@ -0,0 +1,68 @@
+module "elb" {
+  source = "../../modules/elb"
+
+  name               = var.name
+  vpc_id             = var.vpc_id
+  subnet_ids         = var.subnet_ids
+  load_balancer_type = var.load_balancer_type
+  target_groups      = var.target_groups
+  listener_rules     = var.listener_rules
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name" {
+  description = "Name of the load balancer"
+  type        = string
+}
+
+variable "vpc_id" {
+  description = "VPC ID for the load balancer"
+  type        = string
+}
+
+variable "subnet_ids" {
+  description = "Subnet IDs for the load balancer"
+  type        = list(string)
+}
+
+variable "load_balancer_type" {
+  description = "Type of load balancer"
+  type        = string
+  default     = "application"
+}
+
+variable "target_groups" {
+  description = "Map of target groups to create"
+  type        = any
+  default     = {}
+}
+
+variable "listener_rules" {
+  description = "Map of listeners to create"
+  type        = any
+  default     = {}
+}
+
+output "load_balancer_arn" {
+  value = module.elb.load_balancer_arn
+}
+
+output "load_balancer_dns_name" {
+  value = module.elb.load_balancer_dns_name
+}
+
+output "security_group_id" {
+  value = module.elb.security_group_id
+}
+
+output "target_group_arns" {
+  value = module.elb.target_group_arns
+}
+
+output "listener_arns" {
+  value = module.elb.listener_arns
+}
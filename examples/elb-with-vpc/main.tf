@@ -0,0 +1,66 @@
+# Load balancer in the public subnets of examples/vpc-basic, forwarding HTTP to the
+# web-servers target group that examples/ec2-with-alb registers its instances with
+module "elb" {
+  source = "../../modules/elb"
+
+  name       = var.name
+  vpc_id     = var.vpc_id
+  subnet_ids = var.subnet_ids
+
+  target_groups = {
+    web-servers = {
+      port     = 80
+      protocol = "HTTP"
+      health_check = {
+        path = "/"
+      }
+    }
+  }
+
+  listener_rules = {
+    http = {
+      port     = 80
+      protocol = "HTTP"
+      default_action = {
+        type              = "forward"
+        target_group_name = "web-servers"
+      }
+    }
+  }
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name" {
+  description = "Name of the load balancer"
+  type        = string
+}
+
+variable "vpc_id" {
+  description = "VPC ID for the load balancer"
+  type        = string
+}
+
+variable "subnet_ids" {
+  description = "Subnet IDs for the load balancer"
+  type        = list(string)
+}
+
+output "load_balancer_arn" {
+  value = module.elb.load_balancer_arn
+}
+
+output "load_balancer_dns_name" {
+  value = module.elb.load_balancer_dns_name
+}
+
+output "security_group_id" {
+  value = module.elb.security_group_id
+}
+
+output "target_group_arns" {
+  value = module.elb.target_group_arns
+}
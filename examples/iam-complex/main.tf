@@ -0,0 +1,51 @@
+module "iam" {
+  source = "../../modules/iam"
+
+  users  = var.users
+  groups = var.groups
+  roles  = var.roles
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name_prefix" {
+  description = "Prefix for resource names"
+  type        = string
+}
+
+variable "users" {
+  description = "Map of users to create"
+  type        = any
+  default     = {}
+}
+
+variable "groups" {
+  description = "Map of groups to create"
+  type        = any
+  default     = {}
+}
+
+variable "roles" {
+  description = "Map of roles to create"
+  type        = any
+  default     = {}
+}
+
+output "users" {
+  value = module.iam.users
+}
+
+output "groups" {
+  value = module.iam.groups
+}
+
+output "roles" {
+  value = module.iam.roles
+}
+
+output "instance_profiles" {
+  value = module.iam.instance_profiles
+}
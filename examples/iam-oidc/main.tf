@@ -2,6 +2,7 @@ module "iam" {
   source = "../../modules/iam"
 
   oidc_providers = var.oidc_providers
+  roles          = var.roles
 
   tags = {
     Environment = "test"
@@ -20,6 +21,12 @@ variable "oidc_providers" {
   default     = {}
 }
 
+variable "roles" {
+  description = "Map of roles to create, e.g. roles trusting the OIDC providers"
+  type        = any
+  default     = {}
+}
+
 output "oidc_providers" {
   value = module.iam.oidc_providers
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+# Passes its users straight to the IAM module so the module's validation rules reject invalid names at plan time
+module "iam" {
+  source = "../../modules/iam"
+
+  users = var.users
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "users" {
+  description = "Map of users to create"
+  type        = any
+  default     = {}
+}
+
+output "users" {
+  value = module.iam.users
+}
@@ -0,0 +1,56 @@
+# The VPC module with caller-chosen CIDR blocks, deployed with three subnets of each type by TestVPCModuleWithCustomCIDR
+module "vpc" {
+  source = "../../modules/vpc"
+
+  name_prefix             = var.name_prefix
+  vpc_cidr_block          = var.vpc_cidr_block
+  public_subnet_cidrs     = var.public_subnet_cidrs
+  private_subnet_cidrs    = var.private_subnet_cidrs
+  allowed_ips             = var.allowed_ips
+
+  tags = {
+    Environment = "test"
+    Purpose     = "terratest"
+  }
+}
+
+variable "name_prefix" {
+  description = "Prefix for resource names"
+  type        = string
+}
+
+variable "vpc_cidr_block" {
+  description = "CIDR block for VPC"
+  type        = string
+}
+
+variable "public_subnet_cidrs" {
+  description = "Public subnet CIDR blocks"
+  type        = list(string)
+}
+
+variable "private_subnet_cidrs" {
+  description = "Private subnet CIDR blocks"
+  type        = list(string)
+}
+
+variable "allowed_ips" {
+  description = "Allowed IP addresses"
+  type        = list(string)
+}
+
+output "vpc_id" {
+  value = module.vpc.vpc_id
+}
+
+output "public_subnet_ids" {
+  value = module.vpc.public_subnet_ids
+}
+
+output "private_subnet_ids" {
+  value = module.vpc.private_subnet_ids
+}
+
+output "internet_gateway_id" {
+  value = module.vpc.internet_gateway_id
+}
\ No newline at end of file
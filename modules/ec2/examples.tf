@@ -308,8 +308,9 @@ module "compute_cluster" {
     InstanceClass = "compute-optimized"
   }
 }
-*/# Ex
-ample 7: Complete Integration with ELB, Route53, and VPC Modules
+*/
+
+# Example 7: Complete Integration with ELB, Route53, and VPC Modules
 /*
 # VPC Infrastructure
 module "vpc" {
@@ -36,9 +36,10 @@ module "web_app_ecs" {
   container_port  = 80
 
   # Networking
-  vpc_id           = "vpc-12345678"
-  subnet_ids       = ["subnet-12345678", "subnet-87654321"]
-  assign_public_ip = true
+  vpc_id              = "vpc-12345678"
+  subnet_ids          = ["subnet-12345678", "subnet-87654321"]
+  assign_public_ip    = true
+  allowed_cidr_blocks = ["0.0.0.0/0"]
 
   tags = {
     Environment = "production"
@@ -100,7 +101,8 @@ module "api_service" {
   assign_public_ip = false
 
   # Load Balancer Integration
-  target_group_arn = module.alb.target_group_arns["api-servers"]
+  target_group_arn           = module.alb.target_group_arns["api-servers"]
+  allowed_security_group_ids = [module.alb.security_group_id]
 
   # Extended log retention
   log_retention_in_days = 30
@@ -137,7 +139,8 @@ module "frontend_service" {
   vpc_id     = module.vpc.vpc_id
   subnet_ids = module.vpc.private_subnet_ids
 
-  target_group_arn = module.alb.target_group_arns["frontend"]
+  target_group_arn           = module.alb.target_group_arns["frontend"]
+  allowed_security_group_ids = [module.alb.security_group_id]
 
   tags = {
     Environment = "production"
@@ -178,7 +181,8 @@ module "backend_service" {
   vpc_id     = module.vpc.vpc_id
   subnet_ids = module.vpc.private_subnet_ids
 
-  target_group_arn = module.alb.target_group_arns["backend"]
+  target_group_arn           = module.alb.target_group_arns["backend"]
+  allowed_security_group_ids = [module.alb.security_group_id]
 
   tags = {
     Environment = "production"
@@ -228,9 +232,10 @@ module "dev_ecs" {
     }
   ]
 
-  vpc_id           = module.vpc.vpc_id
-  subnet_ids       = module.vpc.private_subnet_ids
-  assign_public_ip = true  # For development access
+  vpc_id              = module.vpc.vpc_id
+  subnet_ids          = module.vpc.private_subnet_ids
+  assign_public_ip    = true  # For development access
+  allowed_cidr_blocks = [module.vpc.vpc_cidr_block]
 
   # Short log retention for cost savings
   log_retention_in_days = 3
@@ -224,8 +224,8 @@ resource "aws_ecs_task_definition" "this" {
 
 # ECS Service Security Group
 # Controls network access to ECS tasks running in the service
-# Allows inbound traffic on the container port and all outbound traffic
-# Should be customized based on application security requirements
+# Allows inbound traffic on the container port from the configured sources
+# and all outbound traffic; with no sources the tasks accept no connections
 resource "aws_security_group" "ecs_service" {
   count = var.create_service ? 1 : 0
 
@@ -233,15 +233,29 @@ resource "aws_security_group" "ecs_service" {
   vpc_id      = var.vpc_id
   description = "Security group for ECS service ${var.service_name}"
 
-  # Ingress Rule - Container Port
-  # Allows inbound traffic on the container's exposed port
-  # Typically used by load balancers to reach the application
-  ingress {
-    description = "Container port access"
-    from_port   = var.container_port
-    to_port     = var.container_port
-    protocol    = "tcp"
-    cidr_blocks = ["0.0.0.0/0"]  # Consider restricting to load balancer security group
+  # Ingress Rule - Container Port from Security Groups
+  # Typically the load balancer's security group
+  dynamic "ingress" {
+    for_each = length(var.allowed_security_group_ids) > 0 ? [1] : []
+    content {
+      description     = "Container port access from security groups"
+      from_port       = var.container_port
+      to_port         = var.container_port
+      protocol        = "tcp"
+      security_groups = var.allowed_security_group_ids
+    }
+  }
+
+  # Ingress Rule - Container Port from CIDR Blocks
+  dynamic "ingress" {
+    for_each = length(var.allowed_cidr_blocks) > 0 ? [1] : []
+    content {
+      description = "Container port access from CIDR blocks"
+      from_port   = var.container_port
+      to_port     = var.container_port
+      protocol    = "tcp"
+      cidr_blocks = var.allowed_cidr_blocks
+    }
   }
 
   # Egress Rule - All Outbound Traffic
@@ -103,6 +103,24 @@ variable "assign_public_ip" {
   default     = false
 }
 
+variable "allowed_security_group_ids" {
+  description = "Security group IDs allowed to reach the container port, typically the load balancer's"
+  type        = list(string)
+  default     = []
+}
+
+variable "allowed_cidr_blocks" {
+  description = "CIDR blocks allowed to reach the container port"
+  type        = list(string)
+  default     = []
+  validation {
+    condition = alltrue([
+      for cidr in var.allowed_cidr_blocks : can(cidrhost(cidr, 0))
+    ])
+    error_message = "All CIDR blocks must be valid."
+  }
+}
+
 variable "target_group_arn" {
   description = "ARN of the load balancer target group"
   type        = string
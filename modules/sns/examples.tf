@@ -241,7 +241,7 @@ module "secure_notifications" {
         Action = "SNS:Publish"
         Resource = "*"
         Condition = {
-          ForAnyValue:StringEquals = {
+          "ForAnyValue:StringEquals" = {
             "aws:RequestedRegion" = ["us-west-2"]
           }
         }
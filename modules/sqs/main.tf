@@ -28,24 +28,16 @@ resource "aws_sqs_queue" "main" {
 
   # Enable server-side encryption if specified
   # Uses AWS managed keys (SSE-SQS) or customer managed keys (SSE-KMS)
-  dynamic "kms_master_key_id" {
-    for_each = var.kms_master_key_id != null ? [var.kms_master_key_id] : []
-    content {
-      kms_master_key_id = kms_master_key_id.value
-    }
-  }
+  # An argument, not a block: null leaves the queue on the default encryption
+  kms_master_key_id = var.kms_master_key_id
 
   # Configure dead letter queue for failed message handling
   # Messages that exceed max_receive_count are moved to DLQ
-  dynamic "redrive_policy" {
-    for_each = var.create_dlq ? [1] : []
-    content {
-      redrive_policy = jsonencode({
-        deadLetterTargetArn = aws_sqs_queue.dlq[0].arn
-        maxReceiveCount     = var.max_receive_count
-      })
-    }
-  }
+  # An argument, not a block: null leaves the queue without a redrive policy
+  redrive_policy = var.create_dlq ? jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.dlq[0].arn
+    maxReceiveCount     = var.max_receive_count
+  }) : null
 
   # Apply resource tags for organization and cost tracking
   tags = merge(
@@ -105,16 +97,11 @@ resource "aws_sqs_queue" "fifo" {
   receive_wait_time_seconds  = var.receive_wait_time_seconds
   visibility_timeout_seconds = var.visibility_timeout_seconds
 
-  # Configure DLQ for FIFO queue if enabled
-  dynamic "redrive_policy" {
-    for_each = var.create_fifo_dlq ? [1] : []
-    content {
-      redrive_policy = jsonencode({
-        deadLetterTargetArn = aws_sqs_queue.fifo_dlq[0].arn
-        maxReceiveCount     = var.max_receive_count
-      })
-    }
-  }
+  # Configure DLQ for FIFO queue if enabled (null when disabled)
+  redrive_policy = var.create_fifo_dlq ? jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.fifo_dlq[0].arn
+    maxReceiveCount     = var.max_receive_count
+  }) : null
 
   tags = merge(
     var.tags,
@@ -0,0 +1,24 @@
+# Instances must require IMDSv2, which protects instance credentials from
+# SSRF: IMDSv1 answers any GET request for them.
+package terraform.ec2
+
+import rego.v1
+
+import data.terraform.lib
+
+deny contains {"address": rc.address, "msg": msg} if {
+	some t in {"aws_instance", "aws_launch_template"}
+	some rc in lib.resources(t)
+	not requires_imdsv2(rc)
+	msg := sprintf("%s does not require IMDSv2: set metadata_options.http_tokens to \"required\"", [t])
+}
+
+requires_imdsv2(rc) if {
+	some options in rc.change.after.metadata_options
+	options.http_tokens == "required"
+}
+
+# http_tokens only known after apply is given the benefit of the doubt
+requires_imdsv2(rc) if {
+	not lib.known(rc, "metadata_options")
+}
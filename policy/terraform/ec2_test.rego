@@ -0,0 +1,40 @@
+package terraform.ec2_test
+
+import rego.v1
+
+import data.terraform.ec2
+
+plan(t, actions, after) := {"resource_changes": [{
+	"address": sprintf("%s.this", [t]),
+	"mode": "managed",
+	"type": t,
+	"change": {"actions": actions, "after": after, "after_unknown": {}},
+}]}
+
+test_launch_template_without_metadata_options_denied if {
+	p := plan("aws_launch_template", ["create"], {"metadata_options": []})
+	ec2.deny == {{
+		"address": "aws_launch_template.this",
+		"msg": "aws_launch_template does not require IMDSv2: set metadata_options.http_tokens to \"required\"",
+	}} with input as p
+}
+
+test_optional_tokens_denied if {
+	p := plan("aws_instance", ["update"], {"metadata_options": [{"http_tokens": "optional"}]})
+	count(ec2.deny) == 1 with input as p
+}
+
+test_required_tokens_allowed if {
+	p := plan("aws_instance", ["create"], {"metadata_options": [{"http_tokens": "required"}]})
+	count(ec2.deny) == 0 with input as p
+}
+
+test_deleted_instance_ignored if {
+	p := plan("aws_instance", ["delete"], null)
+	count(ec2.deny) == 0 with input as p
+}
+
+test_other_types_ignored if {
+	p := plan("aws_s3_bucket", ["create"], {})
+	count(ec2.deny) == 0 with input as p
+}
@@ -0,0 +1,13 @@
+# EFS file systems must be encrypted at rest. Encryption can only be chosen
+# when a file system is created, so fixing it later means a migration.
+package terraform.efs
+
+import rego.v1
+
+import data.terraform.lib
+
+deny contains {"address": rc.address, "msg": "EFS file system is not encrypted at rest: set encrypted = true", "severity": "HIGH"} if {
+	some rc in lib.resources("aws_efs_file_system")
+	lib.known(rc, "encrypted")
+	not rc.change.after.encrypted
+}
@@ -0,0 +1,34 @@
+package terraform.efs_test
+
+import rego.v1
+
+import data.terraform.efs
+
+plan(after, unknown) := {"resource_changes": [{
+	"address": "aws_efs_file_system.this",
+	"mode": "managed",
+	"type": "aws_efs_file_system",
+	"change": {"actions": ["create"], "after": after, "after_unknown": unknown},
+}]}
+
+test_unencrypted_denied if {
+	p := plan({"encrypted": false}, {})
+	denials := efs.deny with input as p
+	some denial in denials
+	denial.address == "aws_efs_file_system.this"
+}
+
+test_unset_denied if {
+	p := plan({}, {})
+	count(efs.deny) == 1 with input as p
+}
+
+test_encrypted_allowed if {
+	p := plan({"encrypted": true}, {})
+	count(efs.deny) == 0 with input as p
+}
+
+test_unknown_allowed if {
+	p := plan({}, {"encrypted": true})
+	count(efs.deny) == 0 with input as p
+}
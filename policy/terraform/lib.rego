@@ -0,0 +1,19 @@
+# Helpers shared by the policies. This package has no deny rules of its own.
+package terraform.lib
+
+import rego.v1
+
+# resources returns the managed resources of type t in the plan that will
+# exist after apply, skipping those being deleted and data sources.
+resources(t) := {rc |
+	some rc in input.resource_changes
+	rc.mode == "managed"
+	rc.type == t
+	rc.change.actions != ["delete"]
+}
+
+# known reports whether the planned value of attribute attr of rc is known
+# before apply.
+known(rc, attr) if {
+	not rc.change.after_unknown[attr]
+}
@@ -0,0 +1,170 @@
+// Package analysis turns the JSON reports of the static analysis tools run
+// over this repository (tfsec, tflint and checkov) into one findings model,
+// and decides which findings fail the build: those not suppressed by the
+// committed baseline and at or above the severity threshold of the module
+// they are in.
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Tool names, as recorded on findings and in the baseline.
+const (
+	ToolTFSec   = "tfsec"
+	ToolTFLint  = "tflint"
+	ToolCheckov = "checkov"
+)
+
+// Severity orders findings from informational to critical.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityUnknown:  "UNKNOWN",
+	SeverityLow:      "LOW",
+	SeverityMedium:   "MEDIUM",
+	SeverityHigh:     "HIGH",
+	SeverityCritical: "CRITICAL",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses a severity name, ignoring case. "INFO" is LOW.
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "LOW", "INFO":
+		return SeverityLow, nil
+	case "MEDIUM":
+		return SeverityMedium, nil
+	case "HIGH":
+		return SeverityHigh, nil
+	case "CRITICAL":
+		return SeverityCritical, nil
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	sev, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = sev
+	return nil
+}
+
+// Finding is one result reported by a static analysis tool.
+type Finding struct {
+	Tool     string
+	Rule     string
+	Severity Severity
+	// File is relative to the repository root, with forward slashes.
+	File    string
+	Line    int
+	EndLine int
+	// Module is the module, example or environment directory File is in,
+	// e.g. "modules/vpc", or "" for files outside them.
+	Module string
+	// Resource is the address of the resource the finding is about, when
+	// the tool reports one.
+	Resource string
+	Message  string
+}
+
+// String formats the finding as "file:line: [SEVERITY] message (tool rule)".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: [%s] %s (%s %s)", f.File, f.Line, f.Severity, f.Message, f.Tool, f.Rule)
+}
+
+// moduleDirs are the top-level directories whose subdirectories are the
+// configurations a finding can be attributed to.
+var moduleDirs = map[string]bool{"modules": true, "examples": true, "envs": true}
+
+// ModuleOf returns the module, example or environment directory containing
+// file, a path relative to the repository root.
+func ModuleOf(file string) string {
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	if len(parts) >= 3 && moduleDirs[parts[0]] {
+		return parts[0] + "/" + parts[1]
+	}
+	return ""
+}
+
+// relativeFile makes a path reported by a tool relative to root. Relative
+// paths are taken to be relative to dir, the directory the tool ran in.
+func relativeFile(root, dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Dedupe removes findings reported more than once, as happens when a tool
+// follows module sources from several configurations into the same module,
+// and sorts the rest by file and line.
+func Dedupe(findings []Finding) []Finding {
+	seen := map[Finding]bool{}
+	var out []Finding
+	for _, f := range findings {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	Sort(out)
+	return out
+}
+
+// Sort orders findings by file, line, tool and rule.
+func Sort(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Tool != b.Tool {
+			return a.Tool < b.Tool
+		}
+		return a.Rule < b.Rule
+	})
+}
@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/tagcheck"
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseTFSec(t *testing.T) {
+	findings, err := ParseTFSec(readTestdata(t, "tfsec.json"), "/repo", "/repo/examples/elb-with-vpc")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Finding{
+		{
+			Tool:     ToolTFSec,
+			Rule:     "aws-ec2-no-public-egress-sgr",
+			Severity: SeverityCritical,
+			File:     "modules/elb/main.tf",
+			Line:     48,
+			EndLine:  48,
+			Module:   "modules/elb",
+			Resource: "module.alb.aws_security_group.alb[0]",
+			Message:  "Security group rule allows egress to multiple public internet addresses.",
+		},
+		{
+			Tool:     ToolTFSec,
+			Rule:     "aws-ec2-require-vpc-flow-logs-for-all-vpcs",
+			Severity: SeverityMedium,
+			File:     "modules/vpc/main.tf",
+			Line:     10,
+			EndLine:  20,
+			Module:   "modules/vpc",
+			Resource: "aws_vpc.this",
+			Message:  "VPC Flow Logs is not enabled for VPC",
+		},
+	}, findings)
+
+	findings, err = ParseTFSec([]byte(`{"results": null}`), "/repo", "/repo/modules/sqs")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestParseTFLint(t *testing.T) {
+	findings, err := ParseTFLint(readTestdata(t, "tflint.json"), "/repo", "/repo/modules/ec2")
+	require.NoError(t, err)
+
+	require.Len(t, findings, 2)
+	assert.Equal(t, "modules/ec2/main.tf:3: [HIGH] \"t2.mirco\" is an invalid value as instance_type (tflint aws_instance_invalid_type)", findings[0].String())
+	assert.Equal(t, "modules/ec2", findings[1].Module)
+	assert.Equal(t, SeverityMedium, findings[1].Severity)
+	assert.Equal(t, "terraform_unused_declarations", findings[1].Rule)
+}
+
+func TestParseTFLintErrors(t *testing.T) {
+	_, err := ParseTFLint([]byte(`{
+		"issues": [],
+		"errors": [{"message": "Plugin \"aws\" not found. Did you run \"tflint --init\"?", "severity": "error"}]
+	}`), "/repo", "/repo/modules/ec2")
+	assert.ErrorContains(t, err, `tflint failed: Plugin "aws" not found`)
+}
+
+func TestParseCheckov(t *testing.T) {
+	findings, err := ParseCheckov(readTestdata(t, "checkov.json"), "/repo", "/repo/modules/s3")
+	require.NoError(t, err)
+
+	require.Len(t, findings, 2)
+	assert.Equal(t, Finding{
+		Tool:     ToolCheckov,
+		Rule:     "CKV_AWS_18",
+		Severity: SeverityMedium,
+		File:     "modules/s3/main.tf",
+		Line:     1,
+		EndLine:  9,
+		Module:   "modules/s3",
+		Resource: "aws_s3_bucket.this",
+		Message:  "Ensure the S3 bucket has access logging enabled",
+	}, findings[0])
+	assert.Equal(t, SeverityHigh, findings[1].Severity)
+	assert.Equal(t, "modules/s3/main.tf", findings[1].File, "file_path is relative to the scanned directory")
+
+	// A single framework is printed as an object rather than a list
+	findings, err = ParseCheckov([]byte(`{"check_type": "terraform", "results": {"failed_checks": []}}`), "/repo", "/repo/modules/s3")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestModuleOf(t *testing.T) {
+	assert.Equal(t, "modules/vpc", ModuleOf("modules/vpc/main.tf"))
+	assert.Equal(t, "envs/prod", ModuleOf("envs/prod/main.tf"))
+	assert.Equal(t, "", ModuleOf("versions.tf"))
+	assert.Equal(t, "", ModuleOf("modules/README.md"))
+}
+
+func TestDedupe(t *testing.T) {
+	a := Finding{Tool: ToolTFSec, Rule: "r", File: "modules/vpc/main.tf", Line: 2}
+	b := Finding{Tool: ToolTFSec, Rule: "r", File: "modules/vpc/main.tf", Line: 1}
+	assert.Equal(t, []Finding{b, a}, Dedupe([]Finding{a, b, a}))
+}
+
+func TestBaseline(t *testing.T) {
+	baseline, err := LoadBaseline(filepath.Join("testdata", "baseline.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, SeverityMedium, baseline.Threshold("modules/vpc"))
+	assert.Equal(t, SeverityHigh, baseline.Threshold("modules/ec2"))
+	assert.Equal(t, SeverityHigh, Baseline{}.Threshold("modules/ec2"))
+
+	var findings []Finding
+	for name, parse := range map[string]func([]byte, string, string) ([]Finding, error){
+		"tfsec.json":   ParseTFSec,
+		"checkov.json": ParseCheckov,
+	} {
+		parsed, err := parse(readTestdata(t, name), "/repo", "/repo/modules/s3")
+		require.NoError(t, err)
+		findings = append(findings, parsed...)
+	}
+	Sort(findings)
+
+	result := baseline.Evaluate(findings, time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+
+	// The ALB egress rule is suppressed
+	require.Len(t, result.Suppressed, 1)
+	assert.Equal(t, "aws-ec2-no-public-egress-sgr", result.Suppressed[0].Rule)
+
+	// The S3 logging suppression expired, but MEDIUM is below the default threshold.
+	// The subnet check is HIGH and the flow logs one MEDIUM in a module with a MEDIUM threshold
+	require.Len(t, result.Expired, 1)
+	assert.Equal(t, "CKV_AWS_18", result.Expired[0].Rule)
+	assert.Equal(t, []string{"CKV_AWS_18"}, rules(result.BelowThreshold))
+	assert.Equal(t, []string{"CKV_AWS_130", "aws-ec2-require-vpc-flow-logs-for-all-vpcs"}, rules(result.Failing))
+
+	require.Len(t, result.Unused, 1)
+	assert.Equal(t, "tflint terraform_required_version on modules/sqs/versions.tf (expires 2026-12-31)", result.Unused[0].String())
+
+	// A suppression is valid through its expiry date
+	assert.False(t, baseline.Suppressions[0].Expired(time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, baseline.Suppressions[0].Expired(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestBaselineValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"suppressions": [
+			{"tool": "tfsec", "rule": "aws-s3-enable-versioning", "file": "modules/s3/main.tf", "expires": "2026-12-31"},
+			{"tool": "trivy", "rule": "x", "file": "main.tf", "justification": "y", "expires": "2026-12-31"}
+		]
+	}`), 0o644))
+
+	_, err := LoadBaseline(path)
+	assert.ErrorContains(t, err, "suppression 0 (tfsec aws-s3-enable-versioning) has no justification")
+	assert.ErrorContains(t, err, `suppression 1 has unknown tool "trivy"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"thresholds": {"default": "SEVERE"}}`), 0o644))
+	_, err = LoadBaseline(path)
+	assert.ErrorContains(t, err, `unknown severity "SEVERE"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"suppressions": [{"tool": "tfsec", "rule": "r", "file": "f", "justification": "j", "expires": "31/12/2026"}]}`), 0o644))
+	_, err = LoadBaseline(path)
+	assert.ErrorContains(t, err, "want YYYY-MM-DD")
+}
+
+func rules(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Rule)
+	}
+	return out
+}
+
+func TestLocate(t *testing.T) {
+	f := Locate(Finding{Tool: "tagcheck", Resource: "module.vpc.aws_vpc.this"}, "../..", "../../examples/vpc-basic")
+	assert.Equal(t, "modules/vpc/main.tf", f.File)
+	assert.Equal(t, "modules/vpc", f.Module)
+	assert.NotZero(t, f.Line)
+
+	f = Locate(Finding{Tool: "tagcheck", Resource: "aws_vpc.missing"}, "../..", "../../modules/vpc")
+	assert.Empty(t, f.File)
+}
+
+func TestFromTFCheck(t *testing.T) {
+	findings := FromTFCheck([]tfcheck.Finding{{
+		Rule:    "variable-description",
+		Message: `variable "name" has no description`,
+		Range: hcl.Range{
+			Filename: "../modules/sqs/variables.tf",
+			Start:    hcl.Pos{Line: 4},
+			End:      hcl.Pos{Line: 7},
+		},
+	}, {
+		Rule:     "provider-unused",
+		Message:  `Provider "tls" is declared but no module uses it`,
+		Range:    hcl.Range{Filename: "../versions.tf", Start: hcl.Pos{Line: 38}, End: hcl.Pos{Line: 41}},
+		Resource: "tls",
+	}}, SeverityHigh, "..")
+
+	assert.Equal(t, []Finding{{
+		Tool:     ToolTFCheck,
+		Rule:     "variable-description",
+		Severity: SeverityHigh,
+		File:     "modules/sqs/variables.tf",
+		Line:     4,
+		EndLine:  7,
+		Module:   "modules/sqs",
+		Message:  `variable "name" has no description`,
+	}, {
+		Tool:     ToolTFCheck,
+		Rule:     "provider-unused",
+		Severity: SeverityHigh,
+		File:     "versions.tf",
+		Line:     38,
+		EndLine:  41,
+		Message:  `Provider "tls" is declared but no module uses it`,
+		Resource: "tls",
+	}}, findings)
+}
+
+func TestFromTagCheck(t *testing.T) {
+	findings := FromTagCheck([]tagcheck.Violation{{
+		Module:  "module.vpc",
+		Type:    "aws_subnet",
+		Address: "module.vpc.aws_subnet.public[0]",
+		Missing: []string{"Project"},
+	}}, SeverityMedium, "../..", "../../examples/vpc-basic")
+
+	require.Len(t, findings, 1)
+	f := findings[0]
+	assert.Equal(t, ToolTagCheck, f.Tool)
+	assert.Equal(t, RuleRequiredTags, f.Rule)
+	assert.Equal(t, "modules/vpc/main.tf", f.File)
+	assert.NotZero(t, f.Line)
+	assert.Equal(t, "missing required tags Project", f.Message)
+}
@@ -0,0 +1,228 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultThreshold is the lowest severity that fails the build in modules
+// without a threshold of their own.
+const DefaultThreshold = SeverityHigh
+
+// defaultThresholdKey is the key of the thresholds map applying to every
+// module not listed.
+const defaultThresholdKey = "default"
+
+// dateLayout is the format of suppression expiry dates.
+const dateLayout = "2006-01-02"
+
+// Date is a calendar date, written as 2006-01-02 in the baseline file.
+type Date struct {
+	time.Time
+}
+
+// MarshalJSON implements json.Marshaler, replacing the RFC 3339 encoding
+// Date would otherwise get from time.Time.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, want YYYY-MM-DD", s)
+	}
+	d.Time = t
+	return nil
+}
+
+// Suppression accepts a known finding until it expires. Tool, Rule and File
+// must match the finding; Resource and Line narrow the match when set. Prefer
+// Resource over Line, which goes stale as soon as the file is edited.
+type Suppression struct {
+	Tool          string `json:"tool"`
+	Rule          string `json:"rule"`
+	File          string `json:"file"`
+	Resource      string `json:"resource,omitempty"`
+	Line          int    `json:"line,omitempty"`
+	Justification string `json:"justification"`
+	Expires       Date   `json:"expires"`
+}
+
+// Matches reports whether s applies to f, regardless of expiry.
+func (s Suppression) Matches(f Finding) bool {
+	return s.Tool == f.Tool && s.Rule == f.Rule && s.File == f.File &&
+		(s.Resource == "" || s.Resource == f.Resource) &&
+		(s.Line == 0 || s.Line == f.Line)
+}
+
+// Suppression returns the current suppression matching f, if any.
+func (b Baseline) Suppression(f Finding, now time.Time) (Suppression, bool) {
+	for _, s := range b.Suppressions {
+		if s.Matches(f) && !s.Expired(now) {
+			return s, true
+		}
+	}
+	return Suppression{}, false
+}
+
+// Expired reports whether s no longer applies on the day of now. A
+// suppression is valid up to and including its expiry date.
+func (s Suppression) Expired(now time.Time) bool {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return today.After(s.Expires.Time)
+}
+
+func (s Suppression) String() string {
+	target := s.File
+	if s.Resource != "" {
+		target += " " + s.Resource
+	} else if s.Line != 0 {
+		target = fmt.Sprintf("%s:%d", s.File, s.Line)
+	}
+	return fmt.Sprintf("%s %s on %s (expires %s)", s.Tool, s.Rule, target, s.Expires.Format(dateLayout))
+}
+
+// Baseline is the committed record of accepted findings and of the severity
+// from which findings fail the build in each module.
+type Baseline struct {
+	// Thresholds maps a module, e.g. "modules/iam", to the lowest severity
+	// that fails the build in it. The "default" key applies to every other
+	// module; without it DefaultThreshold does.
+	Thresholds   map[string]Severity `json:"thresholds"`
+	Suppressions []Suppression       `json:"suppressions"`
+}
+
+// LoadBaseline reads and validates a baseline file.
+func LoadBaseline(path string) (Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Baseline{}, err
+	}
+
+	var b Baseline
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&b); err != nil {
+		return Baseline{}, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	if err := b.Validate(); err != nil {
+		return Baseline{}, fmt.Errorf("baseline %s: %w", path, err)
+	}
+	return b, nil
+}
+
+var knownTools = map[string]bool{ToolTFSec: true, ToolTFLint: true, ToolCheckov: true, ToolTFCheck: true}
+
+// Validate checks every suppression names a known tool, a rule and a file,
+// and carries a justification and an expiry date.
+func (b Baseline) Validate() error {
+	var problems []string
+	for i, s := range b.Suppressions {
+		var missing []string
+		if s.Rule == "" {
+			missing = append(missing, "rule")
+		}
+		if s.File == "" {
+			missing = append(missing, "file")
+		}
+		if strings.TrimSpace(s.Justification) == "" {
+			missing = append(missing, "justification")
+		}
+		if s.Expires.IsZero() {
+			missing = append(missing, "expires")
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("suppression %d (%s %s) has no %s", i, s.Tool, s.Rule, strings.Join(missing, ", ")))
+		}
+		if !knownTools[s.Tool] {
+			problems = append(problems, fmt.Sprintf("suppression %d has unknown tool %q", i, s.Tool))
+		}
+	}
+	for module, threshold := range b.Thresholds {
+		if threshold == SeverityUnknown {
+			problems = append(problems, fmt.Sprintf("threshold for %s has no severity", module))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Threshold returns the lowest severity that fails the build in module.
+func (b Baseline) Threshold(module string) Severity {
+	if threshold, ok := b.Thresholds[module]; ok {
+		return threshold
+	}
+	if threshold, ok := b.Thresholds[defaultThresholdKey]; ok {
+		return threshold
+	}
+	return DefaultThreshold
+}
+
+// Result sorts findings by what the baseline makes of them.
+type Result struct {
+	// Failing are new findings at or above their module's threshold.
+	Failing []Finding
+	// BelowThreshold are new findings under their module's threshold.
+	BelowThreshold []Finding
+	// Suppressed are findings matched by a current suppression.
+	Suppressed []Finding
+	// Expired are suppressions that matched a finding but have expired;
+	// the findings they matched are new again.
+	Expired []Suppression
+	// Unused are current suppressions that matched no finding. They may
+	// belong to a tool that did not run, or to a finding since fixed.
+	Unused []Suppression
+}
+
+// Evaluate applies the baseline to findings as of now.
+func (b Baseline) Evaluate(findings []Finding, now time.Time) Result {
+	var result Result
+	used := make([]bool, len(b.Suppressions))
+	expired := make([]bool, len(b.Suppressions))
+
+	for _, f := range findings {
+		suppressed := false
+		for i, s := range b.Suppressions {
+			if !s.Matches(f) {
+				continue
+			}
+			if s.Expired(now) {
+				expired[i] = true
+				continue
+			}
+			used[i] = true
+			suppressed = true
+		}
+
+		switch {
+		case suppressed:
+			result.Suppressed = append(result.Suppressed, f)
+		case f.Severity >= b.Threshold(f.Module):
+			result.Failing = append(result.Failing, f)
+		default:
+			result.BelowThreshold = append(result.BelowThreshold, f)
+		}
+	}
+
+	for i, s := range b.Suppressions {
+		switch {
+		case expired[i]:
+			result.Expired = append(result.Expired, s)
+		case !used[i] && !s.Expired(now):
+			result.Unused = append(result.Unused, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/tagcheck"
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
+)
+
+// Tool names of findings from this repository's own checks.
+const (
+	ToolTFCheck  = "tfcheck"
+	ToolTagCheck = "tagcheck"
+)
+
+// RuleRequiredTags is the rule of findings converted from tagcheck violations.
+const RuleRequiredTags = "required-tags"
+
+// FromTFCheck converts findings of the tfcheck source checks, whose file
+// names are relative to the working directory, to findings of the given
+// severity with files relative to root.
+func FromTFCheck(findings []tfcheck.Finding, severity Severity, root string) []Finding {
+	out := make([]Finding, 0, len(findings))
+	for _, f := range findings {
+		out = append(out, At(Finding{
+			Tool:     ToolTFCheck,
+			Rule:     f.Rule,
+			Severity: severity,
+			Message:  f.Message,
+			Resource: f.Resource,
+		}, root, f.Range))
+	}
+	return out
+}
+
+// At sets the file, lines and module of f to those of rng, a source range
+// whose file name is relative to the working directory, with the file made
+// relative to root.
+func At(f Finding, root string, rng hcl.Range) Finding {
+	f.File = relativeFile(root, ".", rng.Filename)
+	f.Line, f.EndLine = rng.Start.Line, rng.End.Line
+	f.Module = ModuleOf(f.File)
+	return f
+}
+
+// FromTagCheck converts tagcheck violations found in a plan of the
+// configuration in dir to findings of the given severity, located at the
+// blocks declaring the resources and with files relative to root.
+func FromTagCheck(violations []tagcheck.Violation, severity Severity, root, dir string) []Finding {
+	out := make([]Finding, 0, len(violations))
+	for _, v := range violations {
+		msg := "missing required tags " + strings.Join(v.Missing, ", ")
+		if v.Where != "" {
+			msg += " on " + v.Where
+		}
+		out = append(out, Locate(Finding{
+			Tool:     ToolTagCheck,
+			Rule:     RuleRequiredTags,
+			Severity: severity,
+			Resource: v.Address,
+			Message:  msg,
+		}, root, dir))
+	}
+	return out
+}
+
+// Locate sets the file, lines and module of f, a finding from a plan of the
+// configuration in dir about the resource at address f.Resource, to those of
+// the block declaring the resource. Plan JSON carries no source positions, so
+// this is what lets plan-based findings point at a line of a .tf file. f is
+// returned unchanged when the block cannot be found.
+func Locate(f Finding, root, dir string) Finding {
+	r, err := discovery.Locate(dir, f.Resource)
+	if err != nil {
+		return f
+	}
+	f.File = relativeFile(root, ".", r.DeclRange.Filename)
+	f.Line, f.EndLine = r.DeclRange.Start.Line, r.DeclRange.End.Line
+	f.Module = ModuleOf(f.File)
+	return f
+}
@@ -0,0 +1,209 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// tfsecReport is the output of `tfsec --format json`.
+type tfsecReport struct {
+	Results []struct {
+		RuleID      string `json:"rule_id"`
+		LongID      string `json:"long_id"`
+		Description string `json:"description"`
+		Severity    string `json:"severity"`
+		Resource    string `json:"resource"`
+		Location    struct {
+			Filename  string `json:"filename"`
+			StartLine int    `json:"start_line"`
+			EndLine   int    `json:"end_line"`
+		} `json:"location"`
+	} `json:"results"`
+}
+
+// ParseTFSec parses the output of `tfsec --format json` run in dir. File
+// paths are made relative to root, the repository root.
+func ParseTFSec(data []byte, root, dir string) ([]Finding, error) {
+	var report tfsecReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parsing tfsec output: %w", err)
+	}
+
+	var findings []Finding
+	for _, r := range report.Results {
+		severity, err := ParseSeverity(r.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("parsing tfsec output: %s: %w", r.LongID, err)
+		}
+		rule := r.LongID
+		if rule == "" {
+			rule = r.RuleID
+		}
+		file := relativeFile(root, dir, r.Location.Filename)
+		findings = append(findings, Finding{
+			Tool:     ToolTFSec,
+			Rule:     rule,
+			Severity: severity,
+			File:     file,
+			Line:     r.Location.StartLine,
+			EndLine:  r.Location.EndLine,
+			Module:   ModuleOf(file),
+			Resource: r.Resource,
+			Message:  r.Description,
+		})
+	}
+	Sort(findings)
+	return findings, nil
+}
+
+type tflintRange struct {
+	Filename string `json:"filename"`
+	Start    struct {
+		Line int `json:"line"`
+	} `json:"start"`
+	End struct {
+		Line int `json:"line"`
+	} `json:"end"`
+}
+
+// tflintReport is the output of `tflint --format json`.
+type tflintReport struct {
+	Issues []struct {
+		Rule struct {
+			Name     string `json:"name"`
+			Severity string `json:"severity"`
+		} `json:"rule"`
+		Message string      `json:"message"`
+		Range   tflintRange `json:"range"`
+	} `json:"issues"`
+	Errors []struct {
+		Message  string       `json:"message"`
+		Severity string       `json:"severity"`
+		Range    *tflintRange `json:"range"`
+	} `json:"errors"`
+}
+
+// tflintSeverities maps tflint's rule severities onto Severity.
+var tflintSeverities = map[string]Severity{
+	"error":   SeverityHigh,
+	"warning": SeverityMedium,
+	"notice":  SeverityLow,
+	"info":    SeverityLow,
+}
+
+// ParseTFLint parses the output of `tflint --format json` run in dir. File
+// paths are made relative to root, the repository root. Errors tflint
+// reports about the run itself, such as a plugin that is not installed, are
+// returned as an error rather than dropped: the run checked nothing.
+func ParseTFLint(data []byte, root, dir string) ([]Finding, error) {
+	var report tflintReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parsing tflint output: %w", err)
+	}
+
+	if len(report.Errors) > 0 {
+		msgs := make([]string, len(report.Errors))
+		for i, e := range report.Errors {
+			msgs[i] = e.Message
+			if e.Range != nil && e.Range.Filename != "" {
+				msgs[i] = fmt.Sprintf("%s:%d: %s", relativeFile(root, dir, e.Range.Filename), e.Range.Start.Line, e.Message)
+			}
+		}
+		return nil, fmt.Errorf("tflint failed: %s", strings.Join(msgs, "; "))
+	}
+
+	var findings []Finding
+	for _, issue := range report.Issues {
+		severity, ok := tflintSeverities[strings.ToLower(issue.Rule.Severity)]
+		if !ok {
+			return nil, fmt.Errorf("parsing tflint output: %s: unknown severity %q", issue.Rule.Name, issue.Rule.Severity)
+		}
+		file := relativeFile(root, dir, issue.Range.Filename)
+		findings = append(findings, Finding{
+			Tool:     ToolTFLint,
+			Rule:     issue.Rule.Name,
+			Severity: severity,
+			File:     file,
+			Line:     issue.Range.Start.Line,
+			EndLine:  issue.Range.End.Line,
+			Module:   ModuleOf(file),
+			Message:  issue.Message,
+		})
+	}
+	Sort(findings)
+	return findings, nil
+}
+
+// checkovReport is one framework's section of `checkov -o json` output.
+type checkovReport struct {
+	CheckType string `json:"check_type"`
+	Results   struct {
+		FailedChecks []struct {
+			CheckID       string  `json:"check_id"`
+			CheckName     string  `json:"check_name"`
+			Severity      *string `json:"severity"`
+			FilePath      string  `json:"file_path"`
+			FileAbsPath   string  `json:"file_abs_path"`
+			FileLineRange []int   `json:"file_line_range"`
+			Resource      string  `json:"resource"`
+		} `json:"failed_checks"`
+	} `json:"results"`
+}
+
+// ParseCheckov parses the output of `checkov -o json` run on dir. Checkov
+// prints a single report when one framework ran and a list otherwise; both
+// are accepted. Checks without a severity, which is all of them unless
+// checkov is connected to its platform, are reported as MEDIUM.
+func ParseCheckov(data []byte, root, dir string) ([]Finding, error) {
+	var reports []checkovReport
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &reports); err != nil {
+			return nil, fmt.Errorf("parsing checkov output: %w", err)
+		}
+	} else {
+		var report checkovReport
+		if err := json.Unmarshal(trimmed, &report); err != nil {
+			return nil, fmt.Errorf("parsing checkov output: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	var findings []Finding
+	for _, report := range reports {
+		for _, c := range report.Results.FailedChecks {
+			severity := SeverityMedium
+			if c.Severity != nil {
+				var err error
+				if severity, err = ParseSeverity(*c.Severity); err != nil {
+					return nil, fmt.Errorf("parsing checkov output: %s: %w", c.CheckID, err)
+				}
+			}
+
+			path := c.FileAbsPath
+			if path == "" {
+				// file_path is relative to the scanned directory, with
+				// a leading slash
+				path = strings.TrimPrefix(c.FilePath, "/")
+			}
+			file := relativeFile(root, dir, path)
+
+			f := Finding{
+				Tool:     ToolCheckov,
+				Rule:     c.CheckID,
+				Severity: severity,
+				File:     file,
+				Module:   ModuleOf(file),
+				Resource: c.Resource,
+				Message:  c.CheckName,
+			}
+			if len(c.FileLineRange) == 2 {
+				f.Line, f.EndLine = c.FileLineRange[0], c.FileLineRange[1]
+			}
+			findings = append(findings, f)
+		}
+	}
+	Sort(findings)
+	return findings, nil
+}
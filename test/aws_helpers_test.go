@@ -0,0 +1,400 @@
+package test
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/stretchr/testify/require"
+)
+
+// The helpers in this file look up resources the terratest aws package has no
+// getter for. They build their clients with terratest's constructors, so they
+// follow the same credentials and, in emulator mode, the same endpoint
+// redirection as the rest of the suite. Results are flattened into plain
+// structs so assertions can compare values rather than SDK pointers.
+
+// iamRegion is the region IAM clients are created in; IAM itself is global
+const iamRegion = "us-east-1"
+
+type vpcDetails struct {
+	Id                 string
+	CidrBlock          string
+	EnableDnsHostnames bool
+	EnableDnsSupport   bool
+}
+
+// getVpc returns a VPC with its CIDR block and DNS attributes
+func getVpc(t *testing.T, vpcId string, region string) vpcDetails {
+	t.Helper()
+	client := aws.NewEc2Client(t, region)
+
+	out, err := client.DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{awssdk.String(vpcId)}})
+	require.NoError(t, err)
+	require.Len(t, out.Vpcs, 1, "VPC %s", vpcId)
+
+	hostnames, err := client.DescribeVpcAttribute(&ec2.DescribeVpcAttributeInput{
+		VpcId:     awssdk.String(vpcId),
+		Attribute: awssdk.String(ec2.VpcAttributeNameEnableDnsHostnames),
+	})
+	require.NoError(t, err)
+	support, err := client.DescribeVpcAttribute(&ec2.DescribeVpcAttributeInput{
+		VpcId:     awssdk.String(vpcId),
+		Attribute: awssdk.String(ec2.VpcAttributeNameEnableDnsSupport),
+	})
+	require.NoError(t, err)
+
+	return vpcDetails{
+		Id:                 vpcId,
+		CidrBlock:          awssdk.StringValue(out.Vpcs[0].CidrBlock),
+		EnableDnsHostnames: hostnames.EnableDnsHostnames != nil && awssdk.BoolValue(hostnames.EnableDnsHostnames.Value),
+		EnableDnsSupport:   support.EnableDnsSupport != nil && awssdk.BoolValue(support.EnableDnsSupport.Value),
+	}
+}
+
+type subnetDetails struct {
+	Id                  string
+	VpcId               string
+	AvailabilityZone    string
+	MapPublicIpOnLaunch bool
+}
+
+// getSubnet returns a subnet by ID
+func getSubnet(t *testing.T, subnetId string, region string) subnetDetails {
+	t.Helper()
+
+	out, err := aws.NewEc2Client(t, region).DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: []*string{awssdk.String(subnetId)}})
+	require.NoError(t, err)
+	require.Len(t, out.Subnets, 1, "subnet %s", subnetId)
+
+	subnet := out.Subnets[0]
+	return subnetDetails{
+		Id:                  subnetId,
+		VpcId:               awssdk.StringValue(subnet.VpcId),
+		AvailabilityZone:    awssdk.StringValue(subnet.AvailabilityZone),
+		MapPublicIpOnLaunch: awssdk.BoolValue(subnet.MapPublicIpOnLaunch),
+	}
+}
+
+// deleteSubnet deletes a subnet out from under Terraform
+func deleteSubnet(t *testing.T, subnetId string, region string) {
+	t.Helper()
+
+	_, err := aws.NewEc2Client(t, region).DeleteSubnet(&ec2.DeleteSubnetInput{SubnetId: awssdk.String(subnetId)})
+	require.NoError(t, err)
+}
+
+type internetGatewayDetails struct {
+	Id    string
+	VpcId string
+}
+
+// getInternetGateway returns an internet gateway and the VPC it is attached to
+func getInternetGateway(t *testing.T, igwId string, region string) internetGatewayDetails {
+	t.Helper()
+
+	out, err := aws.NewEc2Client(t, region).DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{
+		InternetGatewayIds: []*string{awssdk.String(igwId)},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.InternetGateways, 1, "internet gateway %s", igwId)
+
+	igw := internetGatewayDetails{Id: igwId}
+	for _, attachment := range out.InternetGateways[0].Attachments {
+		igw.VpcId = awssdk.StringValue(attachment.VpcId)
+	}
+	return igw
+}
+
+type securityGroupDetails struct {
+	Id    string
+	Name  string
+	VpcId string
+}
+
+// getSecurityGroup returns a security group by ID
+func getSecurityGroup(t *testing.T, groupId string, region string) securityGroupDetails {
+	t.Helper()
+
+	out, err := aws.NewEc2Client(t, region).DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{awssdk.String(groupId)},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.SecurityGroups, 1, "security group %s", groupId)
+
+	return securityGroupDetails{
+		Id:    groupId,
+		Name:  awssdk.StringValue(out.SecurityGroups[0].GroupName),
+		VpcId: awssdk.StringValue(out.SecurityGroups[0].VpcId),
+	}
+}
+
+type launchTemplateDetails struct {
+	LaunchTemplateId   string
+	LaunchTemplateName string
+}
+
+// getLaunchTemplate returns a launch template by ID
+func getLaunchTemplate(t *testing.T, region string, templateId string) launchTemplateDetails {
+	t.Helper()
+
+	out, err := aws.NewEc2Client(t, region).DescribeLaunchTemplates(&ec2.DescribeLaunchTemplatesInput{
+		LaunchTemplateIds: []*string{awssdk.String(templateId)},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.LaunchTemplates, 1, "launch template %s", templateId)
+
+	return launchTemplateDetails{
+		LaunchTemplateId:   templateId,
+		LaunchTemplateName: awssdk.StringValue(out.LaunchTemplates[0].LaunchTemplateName),
+	}
+}
+
+type autoScalingGroupDetails struct {
+	AutoScalingGroupName string
+	DesiredCapacity      int64
+	MinSize              int64
+	MaxSize              int64
+}
+
+// getAutoScalingGroup returns the sizing of an Auto Scaling group
+func getAutoScalingGroup(t *testing.T, region string, asgName string) autoScalingGroupDetails {
+	t.Helper()
+
+	out, err := aws.NewAsgClient(t, region).DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{awssdk.String(asgName)},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.AutoScalingGroups, 1, "Auto Scaling group %s", asgName)
+
+	asg := out.AutoScalingGroups[0]
+	return autoScalingGroupDetails{
+		AutoScalingGroupName: asgName,
+		DesiredCapacity:      awssdk.Int64Value(asg.DesiredCapacity),
+		MinSize:              awssdk.Int64Value(asg.MinSize),
+		MaxSize:              awssdk.Int64Value(asg.MaxSize),
+	}
+}
+
+type instanceDetails struct {
+	InstanceId string
+	State      struct {
+		Name string
+	}
+}
+
+// getInstancesInAsg returns the instances currently in an Auto Scaling group
+func getInstancesInAsg(t *testing.T, asgName string, region string) []instanceDetails {
+	t.Helper()
+
+	ids := aws.GetInstanceIdsForAsg(t, asgName, region)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	out, err := aws.NewEc2Client(t, region).DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: awssdk.StringSlice(ids)})
+	require.NoError(t, err)
+
+	var instances []instanceDetails
+	for _, reservation := range out.Reservations {
+		for _, instance := range reservation.Instances {
+			details := instanceDetails{InstanceId: awssdk.StringValue(instance.InstanceId)}
+			if instance.State != nil {
+				details.State.Name = awssdk.StringValue(instance.State.Name)
+			}
+			instances = append(instances, details)
+		}
+	}
+	return instances
+}
+
+type loadBalancerDetails struct {
+	Arn     string
+	Type    string
+	Scheme  string
+	DNSName string
+}
+
+// getLoadBalancerV2 returns an application, network or gateway load balancer by ARN
+func getLoadBalancerV2(t *testing.T, region string, arn string) loadBalancerDetails {
+	t.Helper()
+
+	sess, err := aws.NewAuthenticatedSession(region)
+	require.NoError(t, err)
+
+	out, err := elbv2.New(sess).DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: []*string{awssdk.String(arn)},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.LoadBalancers, 1, "load balancer %s", arn)
+
+	lb := out.LoadBalancers[0]
+	return loadBalancerDetails{
+		Arn:     arn,
+		Type:    awssdk.StringValue(lb.Type),
+		Scheme:  awssdk.StringValue(lb.Scheme),
+		DNSName: awssdk.StringValue(lb.DNSName),
+	}
+}
+
+type iamUserDetails struct {
+	UserName string
+	Path     string
+}
+
+// getIamUser returns an IAM user by name
+func getIamUser(t *testing.T, userName string) iamUserDetails {
+	t.Helper()
+
+	out, err := aws.NewIamClient(t, iamRegion).GetUser(&iam.GetUserInput{UserName: awssdk.String(userName)})
+	require.NoError(t, err)
+
+	return iamUserDetails{UserName: awssdk.StringValue(out.User.UserName), Path: awssdk.StringValue(out.User.Path)}
+}
+
+// getIamUserPolicies returns the names of a user's inline policies
+func getIamUserPolicies(t *testing.T, userName string) []string {
+	t.Helper()
+
+	var names []string
+	err := aws.NewIamClient(t, iamRegion).ListUserPoliciesPages(&iam.ListUserPoliciesInput{UserName: awssdk.String(userName)},
+		func(page *iam.ListUserPoliciesOutput, lastPage bool) bool {
+			names = append(names, awssdk.StringValueSlice(page.PolicyNames)...)
+			return true
+		})
+	require.NoError(t, err)
+	return names
+}
+
+type iamGroupDetails struct {
+	GroupName string
+	Path      string
+}
+
+// getIamGroup returns an IAM group by name
+func getIamGroup(t *testing.T, groupName string) iamGroupDetails {
+	t.Helper()
+
+	out, err := aws.NewIamClient(t, iamRegion).GetGroup(&iam.GetGroupInput{GroupName: awssdk.String(groupName)})
+	require.NoError(t, err)
+
+	return iamGroupDetails{GroupName: awssdk.StringValue(out.Group.GroupName), Path: awssdk.StringValue(out.Group.Path)}
+}
+
+// getIamUsersInGroup returns the members of an IAM group
+func getIamUsersInGroup(t *testing.T, groupName string) []iamUserDetails {
+	t.Helper()
+
+	var users []iamUserDetails
+	err := aws.NewIamClient(t, iamRegion).GetGroupPages(&iam.GetGroupInput{GroupName: awssdk.String(groupName)},
+		func(page *iam.GetGroupOutput, lastPage bool) bool {
+			for _, user := range page.Users {
+				users = append(users, iamUserDetails{UserName: awssdk.StringValue(user.UserName), Path: awssdk.StringValue(user.Path)})
+			}
+			return true
+		})
+	require.NoError(t, err)
+	return users
+}
+
+type iamRoleDetails struct {
+	RoleName    string
+	Description string
+	Path        string
+}
+
+// getIamRole returns an IAM role by name
+func getIamRole(t *testing.T, roleName string) iamRoleDetails {
+	t.Helper()
+
+	out, err := aws.NewIamClient(t, iamRegion).GetRole(&iam.GetRoleInput{RoleName: awssdk.String(roleName)})
+	require.NoError(t, err)
+
+	return iamRoleDetails{
+		RoleName:    awssdk.StringValue(out.Role.RoleName),
+		Description: awssdk.StringValue(out.Role.Description),
+		Path:        awssdk.StringValue(out.Role.Path),
+	}
+}
+
+type iamInstanceProfileDetails struct {
+	InstanceProfileName string
+	Roles               []iamRoleDetails
+}
+
+// getIamInstanceProfile returns an instance profile and its roles
+func getIamInstanceProfile(t *testing.T, profileName string) iamInstanceProfileDetails {
+	t.Helper()
+
+	out, err := aws.NewIamClient(t, iamRegion).GetInstanceProfile(&iam.GetInstanceProfileInput{
+		InstanceProfileName: awssdk.String(profileName),
+	})
+	require.NoError(t, err)
+
+	profile := iamInstanceProfileDetails{InstanceProfileName: awssdk.StringValue(out.InstanceProfile.InstanceProfileName)}
+	for _, role := range out.InstanceProfile.Roles {
+		profile.Roles = append(profile.Roles, iamRoleDetails{
+			RoleName:    awssdk.StringValue(role.RoleName),
+			Description: awssdk.StringValue(role.Description),
+			Path:        awssdk.StringValue(role.Path),
+		})
+	}
+	return profile
+}
+
+type iamPolicyDetails struct {
+	PolicyName  string
+	Description string
+	Path        string
+}
+
+// getIamPolicy returns a managed policy by ARN
+func getIamPolicy(t *testing.T, policyArn string) iamPolicyDetails {
+	t.Helper()
+
+	out, err := aws.NewIamClient(t, iamRegion).GetPolicy(&iam.GetPolicyInput{PolicyArn: awssdk.String(policyArn)})
+	require.NoError(t, err)
+
+	return iamPolicyDetails{
+		PolicyName:  awssdk.StringValue(out.Policy.PolicyName),
+		Description: awssdk.StringValue(out.Policy.Description),
+		Path:        awssdk.StringValue(out.Policy.Path),
+	}
+}
+
+type passwordPolicyDetails struct {
+	MinimumPasswordLength      int
+	RequireLowercaseCharacters bool
+	RequireUppercaseCharacters bool
+	RequireNumbers             bool
+	RequireSymbols             bool
+	AllowUsersToChangePassword bool
+	MaxPasswordAge             int
+	PasswordReusePrevention    int
+	HardExpiry                 bool
+}
+
+// getAccountPasswordPolicy returns the account's IAM password policy
+func getAccountPasswordPolicy(t *testing.T) passwordPolicyDetails {
+	t.Helper()
+
+	out, err := aws.NewIamClient(t, iamRegion).GetAccountPasswordPolicy(&iam.GetAccountPasswordPolicyInput{})
+	require.NoError(t, err)
+
+	policy := out.PasswordPolicy
+	return passwordPolicyDetails{
+		MinimumPasswordLength:      int(awssdk.Int64Value(policy.MinimumPasswordLength)),
+		RequireLowercaseCharacters: awssdk.BoolValue(policy.RequireLowercaseCharacters),
+		RequireUppercaseCharacters: awssdk.BoolValue(policy.RequireUppercaseCharacters),
+		RequireNumbers:             awssdk.BoolValue(policy.RequireNumbers),
+		RequireSymbols:             awssdk.BoolValue(policy.RequireSymbols),
+		AllowUsersToChangePassword: awssdk.BoolValue(policy.AllowUsersToChangePassword),
+		MaxPasswordAge:             int(awssdk.Int64Value(policy.MaxPasswordAge)),
+		PasswordReusePrevention:    int(awssdk.Int64Value(policy.PasswordReusePrevention)),
+		HardExpiry:                 awssdk.BoolValue(policy.HardExpiry),
+	}
+}
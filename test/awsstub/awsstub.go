@@ -0,0 +1,307 @@
+// Package awsstub serves canned AWS API responses over HTTP so Terraform can
+// plan modules offline. Point the AWS provider's endpoints at Server.URL and
+// data sources such as aws_availability_zones read fixed, known values
+// instead of calling a real account.
+//
+// Only the query-protocol actions that data sources in this repository call
+// are implemented. Any other request fails with an UnsupportedOperation error
+// naming the action, and Start fails the test for it: the provider tolerates
+// errors from some calls, so an error response alone can go unnoticed.
+package awsstub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// Region is the region the stub answers for.
+	Region = "us-east-1"
+	// AccountID is the account returned by sts:GetCallerIdentity.
+	AccountID = "123456789012"
+)
+
+// AvailabilityZones are the zones returned by ec2:DescribeAvailabilityZones.
+var AvailabilityZones = []AvailabilityZone{
+	{Name: "us-east-1a", ID: "use1-az1"},
+	{Name: "us-east-1b", ID: "use1-az2"},
+	{Name: "us-east-1c", ID: "use1-az4"},
+}
+
+// AvailabilityZone is a canned availability zone.
+type AvailabilityZone struct {
+	Name string
+	ID   string
+}
+
+type handler func(form url.Values) (interface{}, error)
+
+// Server is a running AWS API stub.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	actions     []string
+	unsupported []string
+}
+
+// NewServer starts a stub server. Callers must Close it when done.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Start starts a stub server for t. It is closed when t finishes, and t fails
+// for every request the stub does not implement, naming its action.
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s := NewServer()
+	t.Cleanup(func() {
+		s.Close()
+		for _, action := range s.Unsupported() {
+			t.Errorf("awsstub: the plan called %s, which the stub does not implement; add a handler to test/awsstub", action)
+		}
+	})
+	return s
+}
+
+// Actions returns the API actions the server has been asked for, in order.
+func (s *Server) Actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.actions...)
+}
+
+// Unsupported returns the actions the server was asked for but does not
+// implement, in order.
+func (s *Server) Unsupported() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.unsupported...)
+}
+
+var handlers = map[string]handler{
+	"DescribeAccountAttributes":   describeAccountAttributes,
+	"DescribeAvailabilityZones":   describeAvailabilityZones,
+	"DescribeVpcEndpointServices": describeVpcEndpointServices,
+	"DescribeRouteTables":         describeRouteTables,
+	"GetCallerIdentity":           getCallerIdentity,
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "MalformedQueryString", err.Error())
+		return
+	}
+	action := requestAction(r)
+	h, ok := handlers[action]
+
+	s.mu.Lock()
+	s.actions = append(s.actions, action)
+	if !ok {
+		s.unsupported = append(s.unsupported, action)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, "UnsupportedOperation", fmt.Sprintf("awsstub does not implement %q (%s %s)", action, r.Method, r.URL.Path))
+		return
+	}
+
+	resp, err := h(r.Form)
+	if err != nil {
+		writeError(w, "InvalidParameterValue", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// requestAction names the API action of r: the Action parameter of the query
+// protocol, the operation in the X-Amz-Target header of the JSON protocol, or
+// the method and path of a REST call.
+func requestAction(r *http.Request) string {
+	if action := r.Form.Get("Action"); action != "" {
+		return action
+	}
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		return target
+	}
+	return strings.TrimSpace(r.Method + " " + r.URL.Path)
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Response"`
+	Code      string   `xml:"Errors>Error>Code"`
+	Message   string   `xml:"Errors>Error>Message"`
+	RequestID string   `xml:"RequestID"`
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(http.StatusBadRequest)
+	xml.NewEncoder(w).Encode(errorResponse{Code: code, Message: message, RequestID: requestID})
+}
+
+const (
+	requestID = "00000000-0000-0000-0000-000000000000"
+	ec2NS     = "http://ec2.amazonaws.com/doc/2016-11-15/"
+	stsNS     = "https://sts.amazonaws.com/doc/2011-06-15/"
+)
+
+type accountAttributeItem struct {
+	Name   string   `xml:"attributeName"`
+	Values []string `xml:"attributeValueSet>item>attributeValue"`
+}
+
+type describeAccountAttributesResponse struct {
+	XMLName    xml.Name               `xml:"DescribeAccountAttributesResponse"`
+	XMLNS      string                 `xml:"xmlns,attr"`
+	RequestID  string                 `xml:"requestId"`
+	Attributes []accountAttributeItem `xml:"accountAttributeSet>item"`
+}
+
+// describeAccountAttributes reports a VPC-only account without a default VPC.
+func describeAccountAttributes(url.Values) (interface{}, error) {
+	return describeAccountAttributesResponse{
+		XMLNS:     ec2NS,
+		RequestID: requestID,
+		Attributes: []accountAttributeItem{
+			{Name: "supported-platforms", Values: []string{"VPC"}},
+			{Name: "default-vpc", Values: []string{"none"}},
+		},
+	}, nil
+}
+
+type zoneItem struct {
+	ZoneName           string `xml:"zoneName"`
+	ZoneID             string `xml:"zoneId"`
+	ZoneState          string `xml:"zoneState"`
+	ZoneType           string `xml:"zoneType"`
+	RegionName         string `xml:"regionName"`
+	GroupName          string `xml:"groupName"`
+	NetworkBorderGroup string `xml:"networkBorderGroup"`
+	OptInStatus        string `xml:"optInStatus"`
+}
+
+type describeAvailabilityZonesResponse struct {
+	XMLName   xml.Name   `xml:"DescribeAvailabilityZonesResponse"`
+	XMLNS     string     `xml:"xmlns,attr"`
+	RequestID string     `xml:"requestId"`
+	Zones     []zoneItem `xml:"availabilityZoneInfo>item"`
+}
+
+func describeAvailabilityZones(url.Values) (interface{}, error) {
+	resp := describeAvailabilityZonesResponse{XMLNS: ec2NS, RequestID: requestID}
+	for _, az := range AvailabilityZones {
+		resp.Zones = append(resp.Zones, zoneItem{
+			ZoneName:           az.Name,
+			ZoneID:             az.ID,
+			ZoneState:          "available",
+			ZoneType:           "availability-zone",
+			RegionName:         Region,
+			GroupName:          Region,
+			NetworkBorderGroup: Region,
+			OptInStatus:        "opt-in-not-required",
+		})
+	}
+	return resp, nil
+}
+
+type serviceDetailItem struct {
+	ServiceName                string   `xml:"serviceName"`
+	ServiceID                  string   `xml:"serviceId"`
+	ServiceType                string   `xml:"serviceType>item>serviceType"`
+	AvailabilityZones          []string `xml:"availabilityZoneSet>item"`
+	Owner                      string   `xml:"owner"`
+	BaseEndpointDNSNames       []string `xml:"baseEndpointDnsNameSet>item"`
+	VpcEndpointPolicySupported bool     `xml:"vpcEndpointPolicySupported"`
+	AcceptanceRequired         bool     `xml:"acceptanceRequired"`
+	ManagesVpcEndpoints        bool     `xml:"managesVpcEndpoints"`
+}
+
+type describeVpcEndpointServicesResponse struct {
+	XMLName      xml.Name            `xml:"DescribeVpcEndpointServicesResponse"`
+	XMLNS        string              `xml:"xmlns,attr"`
+	RequestID    string              `xml:"requestId"`
+	ServiceNames []string            `xml:"serviceNameSet>item"`
+	Services     []serviceDetailItem `xml:"serviceDetailSet>item"`
+}
+
+// describeVpcEndpointServices echoes back each requested service name as an
+// Amazon-owned service of the type given by the service-type filter.
+func describeVpcEndpointServices(form url.Values) (interface{}, error) {
+	serviceType := "Interface"
+	for i := 1; form.Get(fmt.Sprintf("Filter.%d.Name", i)) != ""; i++ {
+		if form.Get(fmt.Sprintf("Filter.%d.Name", i)) == "service-type" {
+			serviceType = form.Get(fmt.Sprintf("Filter.%d.Value.1", i))
+		}
+	}
+
+	var zones []string
+	for _, az := range AvailabilityZones {
+		zones = append(zones, az.Name)
+	}
+
+	resp := describeVpcEndpointServicesResponse{XMLNS: ec2NS, RequestID: requestID}
+	for i := 1; form.Get(fmt.Sprintf("ServiceName.%d", i)) != ""; i++ {
+		name := form.Get(fmt.Sprintf("ServiceName.%d", i))
+		resp.ServiceNames = append(resp.ServiceNames, name)
+		resp.Services = append(resp.Services, serviceDetailItem{
+			ServiceName:                name,
+			ServiceID:                  fmt.Sprintf("vpce-svc-%017d", i),
+			ServiceType:                serviceType,
+			AvailabilityZones:          zones,
+			Owner:                      "amazon",
+			BaseEndpointDNSNames:       []string{name + ".amazonaws.com"},
+			VpcEndpointPolicySupported: true,
+		})
+	}
+	if len(resp.Services) == 0 {
+		return nil, fmt.Errorf("DescribeVpcEndpointServices needs at least one ServiceName")
+	}
+	return resp, nil
+}
+
+type describeRouteTablesResponse struct {
+	XMLName   xml.Name `xml:"DescribeRouteTablesResponse"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	RequestID string   `xml:"requestId"`
+	Tables    []string `xml:"routeTableSet>item"`
+}
+
+// describeRouteTables reports no route tables: the stub account is empty.
+func describeRouteTables(url.Values) (interface{}, error) {
+	return describeRouteTablesResponse{XMLNS: ec2NS, RequestID: requestID}, nil
+}
+
+type getCallerIdentityResponse struct {
+	XMLName   xml.Name `xml:"GetCallerIdentityResponse"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	Arn       string   `xml:"GetCallerIdentityResult>Arn"`
+	UserID    string   `xml:"GetCallerIdentityResult>UserId"`
+	Account   string   `xml:"GetCallerIdentityResult>Account"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+func getCallerIdentity(url.Values) (interface{}, error) {
+	return getCallerIdentityResponse{
+		XMLNS:     stsNS,
+		Arn:       fmt.Sprintf("arn:aws:iam::%s:user/awsstub", AccountID),
+		UserID:    "AIDAAWSSTUBAWSSTUB000",
+		Account:   AccountID,
+		RequestID: requestID,
+	}, nil
+}
@@ -0,0 +1,114 @@
+package awsstub
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func call(t *testing.T, s *Server, form url.Values) (int, string) {
+	t.Helper()
+	resp, err := http.PostForm(s.URL, form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	status, body := call(t, s, url.Values{"Action": {"DescribeAvailabilityZones"}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 3, strings.Count(body, "<zoneState>available</zoneState>"))
+	assert.Contains(t, body, "<zoneName>us-east-1a</zoneName>")
+
+	status, body = call(t, s, url.Values{
+		"Action":           {"DescribeVpcEndpointServices"},
+		"ServiceName.1":    {"com.amazonaws.us-east-1.s3"},
+		"Filter.1.Name":    {"service-type"},
+		"Filter.1.Value.1": {"Gateway"},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<serviceName>com.amazonaws.us-east-1.s3</serviceName>")
+	assert.Contains(t, body, "<serviceType><item><serviceType>Gateway</serviceType></item></serviceType>")
+
+	status, body = call(t, s, url.Values{"Action": {"DescribeAccountAttributes"}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<attributeName>default-vpc</attributeName>")
+
+	status, body = call(t, s, url.Values{"Action": {"GetCallerIdentity"}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<Account>123456789012</Account>")
+
+	status, body = call(t, s, url.Values{"Action": {"RunInstances"}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "<Code>UnsupportedOperation</Code>")
+	assert.Contains(t, body, "RunInstances")
+
+	assert.Equal(t, []string{
+		"DescribeAvailabilityZones",
+		"DescribeVpcEndpointServices",
+		"DescribeAccountAttributes",
+		"GetCallerIdentity",
+		"RunInstances",
+	}, s.Actions())
+	assert.Equal(t, []string{"RunInstances"}, s.Unsupported())
+}
+
+func TestServerNamesNonQueryActions(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, strings.NewReader("{}"))
+	require.NoError(t, err)
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerServiceV20141113.DescribeClusters")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(s.URL + "/2013-04-01/hostedzone")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{
+		"AmazonEC2ContainerServiceV20141113.DescribeClusters",
+		"GET /2013-04-01/hostedzone",
+	}, s.Unsupported())
+}
+
+// recorder is a testing.TB that records errors instead of failing.
+type recorder struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recorder) Helper()          {}
+func (r *recorder) Cleanup(f func()) { r.cleanups = append(r.cleanups, f) }
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestStartFailsOnUnsupportedActions(t *testing.T) {
+	r := &recorder{TB: t}
+	s := Start(r)
+
+	call(t, s, url.Values{"Action": {"DescribeAvailabilityZones"}})
+	call(t, s, url.Values{"Action": {"GetRole"}})
+	for _, f := range r.cleanups {
+		f()
+	}
+
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "GetRole")
+}
@@ -0,0 +1,299 @@
+// Command testrunner runs the test suite by tier, the same way on a developer
+// machine and in CI. It never prompts: the tiers to run are chosen with -tier,
+// and the tiers that create AWS resources only run when named.
+//
+// Run it from the test directory:
+//
+//	go run ./cmd/testrunner                        # static and unit tiers
+//	go run ./cmd/testrunner -tier emulator         # needs LOCAL_AWS_ENDPOINT
+//	go run ./cmd/testrunner -tier live -- -parallel 4
+//	go run ./cmd/testrunner -list
+//
+// Arguments after the flags are passed on to every `go test` invocation.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/terraform-aws-modules/test/tiers"
+)
+
+func main() {
+	tierList := flag.String("tier", "", "comma-separated tiers to run, or \"all\" (default \""+strings.Join(tiers.Default, ",")+"\")")
+	list := flag.Bool("list", false, "list the tiers and their tests and exit")
+	verbose := flag.Bool("v", false, "stream the output of every test, not just failing ones")
+	allowMissing := flag.Bool("allow-missing", false, "skip suites with missing prerequisites instead of failing")
+	flag.Parse()
+
+	selected, err := tiers.Select(*tierList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *list {
+		printTiers(os.Stdout, selected)
+		return
+	}
+
+	if _, err := os.Stat("go.mod"); err != nil {
+		fmt.Fprintln(os.Stderr, "testrunner must be run from the test directory")
+		os.Exit(2)
+	}
+
+	r := &runner{out: os.Stdout, verbose: *verbose, allowMissing: *allowMissing, args: flag.Args()}
+	var results []tierResult
+	for _, tier := range selected {
+		results = append(results, r.runTier(tier))
+	}
+
+	if !printSummary(os.Stdout, results) {
+		os.Exit(1)
+	}
+}
+
+// Suite outcomes.
+const (
+	statusPass    = "PASS"
+	statusFail    = "FAIL"
+	statusMissing = "MISSING"
+	statusSkipped = "SKIPPED"
+)
+
+type suiteResult struct {
+	suite               tiers.Suite
+	status              string
+	pass, fail, skipped int
+	detail              string
+	elapsed             time.Duration
+}
+
+type tierResult struct {
+	tier   tiers.Tier
+	suites []suiteResult
+}
+
+// ok reports whether the tier passed; suites skipped for missing
+// prerequisites with -allow-missing do not fail it.
+func (r tierResult) ok() bool {
+	for _, s := range r.suites {
+		if s.status == statusFail || s.status == statusMissing {
+			return false
+		}
+	}
+	return true
+}
+
+type runner struct {
+	out          io.Writer
+	verbose      bool
+	allowMissing bool
+	args         []string
+}
+
+func (r *runner) runTier(tier tiers.Tier) tierResult {
+	fmt.Fprintf(r.out, "=== TIER %s: %s\n", tier.Name, tier.Description)
+
+	result := tierResult{tier: tier}
+	for _, suite := range tier.Suites {
+		result.suites = append(result.suites, r.runSuite(tier, suite))
+	}
+	return result
+}
+
+func (r *runner) runSuite(tier tiers.Tier, suite tiers.Suite) suiteResult {
+	result := suiteResult{suite: suite}
+
+	if missing := suite.Missing(); len(missing) > 0 {
+		reasons := make([]string, len(missing))
+		for i, err := range missing {
+			reasons[i] = err.Error()
+		}
+		result.status = statusMissing
+		if r.allowMissing {
+			result.status = statusSkipped
+		}
+		result.detail = strings.Join(reasons, "; ")
+		fmt.Fprintf(r.out, "--- %s %s: %s\n", result.status, suiteName(suite), result.detail)
+		return result
+	}
+
+	args := []string{"test", "-json", "-count=1", "-timeout", tier.Timeout.String()}
+	if run := suite.Run(); run != "" {
+		args = append(args, "-run", run)
+	}
+	args = append(args, suite.Package)
+	args = append(args, r.args...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Env = environ(tier.Environment())
+	cmd.Stderr = r.out
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		result.status, result.detail = statusFail, err.Error()
+		return result
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		result.status, result.detail = statusFail, err.Error()
+		return result
+	}
+	r.consume(stdout, &result)
+	err = cmd.Wait()
+	result.elapsed = time.Since(start)
+
+	result.status = statusPass
+	if err != nil || result.fail > 0 {
+		result.status = statusFail
+		if err != nil && result.fail == 0 {
+			// A build failure or a panic outside any test
+			result.detail = err.Error()
+		}
+	}
+	return result
+}
+
+// event is a line of `go test -json` output.
+type event struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// consume reads the test2json stream, counting top-level test results and
+// printing output: all of it with -v, otherwise only that of failed tests.
+func (r *runner) consume(stream io.Reader, result *suiteResult) {
+	buffered := map[string][]string{}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Build errors are reported as plain text
+			fmt.Fprintln(r.out, scanner.Text())
+			continue
+		}
+
+		top := strings.SplitN(e.Test, "/", 2)[0]
+		switch e.Action {
+		case "output":
+			if r.verbose || e.Test == "" {
+				fmt.Fprint(r.out, e.Output)
+			} else {
+				buffered[top] = append(buffered[top], e.Output)
+			}
+		case "pass", "fail", "skip":
+			if e.Test == "" || strings.Contains(e.Test, "/") {
+				continue
+			}
+			switch e.Action {
+			case "pass":
+				result.pass++
+			case "fail":
+				result.fail++
+				if !r.verbose {
+					fmt.Fprint(r.out, strings.Join(buffered[top], ""))
+				}
+			case "skip":
+				result.skipped++
+			}
+			delete(buffered, top)
+		}
+	}
+}
+
+// environ returns the process environment with overrides applied; an empty
+// override removes the variable.
+func environ(overrides map[string]string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[name]; !ok {
+			env = append(env, kv)
+		}
+	}
+	for name, value := range overrides {
+		if value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+func suiteName(suite tiers.Suite) string {
+	switch len(suite.Tests) {
+	case 0:
+		return suite.Package
+	case 1:
+		return suite.Package + " " + suite.Tests[0]
+	default:
+		return fmt.Sprintf("%s %s (+%d)", suite.Package, suite.Tests[0], len(suite.Tests)-1)
+	}
+}
+
+// printSummary prints a line per tier, and one per suite that did not pass,
+// and reports whether every tier passed.
+func printSummary(out io.Writer, results []tierResult) bool {
+	fmt.Fprintln(out, "\n=== SUMMARY")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIER\tRESULT\tPASS\tFAIL\tSKIP\tTIME\t")
+
+	ok := true
+	for _, tr := range results {
+		var pass, fail, skipped int
+		var elapsed time.Duration
+		for _, s := range tr.suites {
+			pass, fail, skipped, elapsed = pass+s.pass, fail+s.fail, skipped+s.skipped, elapsed+s.elapsed
+		}
+		status := statusPass
+		if !tr.ok() {
+			status, ok = statusFail, false
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t\n", tr.tier.Name, status, pass, fail, skipped, elapsed.Round(time.Second))
+
+		for _, s := range tr.suites {
+			if s.status == statusPass {
+				continue
+			}
+			line := "  " + suiteName(s.suite) + "\t" + s.status + "\t\t\t\t\t"
+			if s.detail != "" {
+				line += s.detail
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	w.Flush()
+	return ok
+}
+
+func printTiers(out io.Writer, selected []tiers.Tier) {
+	for _, tier := range selected {
+		fmt.Fprintf(out, "%s: %s (timeout %s)\n", tier.Name, tier.Description, tier.Timeout)
+		for _, suite := range tier.Suites {
+			var requires []string
+			for _, p := range suite.Requires {
+				requires = append(requires, p.Name)
+			}
+			fmt.Fprintf(out, "  %s", suite.Package)
+			if len(requires) > 0 {
+				fmt.Fprintf(out, " (requires %s)", strings.Join(requires, ", "))
+			}
+			fmt.Fprintln(out)
+			for _, name := range suite.Tests {
+				fmt.Fprintf(out, "    %s\n", name)
+			}
+		}
+	}
+}
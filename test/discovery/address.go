@@ -0,0 +1,113 @@
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Locate returns the block declaring the resource at address, as it appears
+// in a plan of the configuration in dir: for example aws_vpc.this,
+// data.aws_ami.amazon_linux or module.vpc.aws_subnet.public[0]. Calls to local
+// modules are followed to the module's directory; instance keys are ignored.
+func Locate(dir, address string) (Resource, error) {
+	modules, mode, resourceType, name, err := parseAddress(address)
+	if err != nil {
+		return Resource{}, err
+	}
+
+	for _, call := range modules {
+		cfg, ok, err := Load(KindModule, dir)
+		if err != nil {
+			return Resource{}, err
+		}
+		if !ok {
+			return Resource{}, fmt.Errorf("locating %s: no configuration in %s", address, dir)
+		}
+		m, found := cfg.module(call)
+		if !found {
+			return Resource{}, fmt.Errorf("locating %s: %s declares no module %q", address, dir, call)
+		}
+		if !m.IsLocal() {
+			return Resource{}, fmt.Errorf("locating %s: module %q is not loaded from this repository", address, call)
+		}
+		dir = filepath.Join(dir, filepath.FromSlash(m.Source))
+	}
+
+	cfg, ok, err := Load(KindModule, dir)
+	if err != nil {
+		return Resource{}, err
+	}
+	if ok {
+		for _, r := range cfg.Resources {
+			if r.Mode == mode && r.Type == resourceType && r.Name == name {
+				return r, nil
+			}
+		}
+	}
+	return Resource{}, fmt.Errorf("locating %s: no %s %s.%s in %s", address, mode, resourceType, name, dir)
+}
+
+func (c Config) module(name string) (ModuleCall, bool) {
+	for _, m := range c.Modules {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return ModuleCall{}, false
+}
+
+// parseAddress splits a resource address into the names of the module calls
+// leading to it and the resource's mode, type and name.
+func parseAddress(address string) (modules []string, mode, resourceType, name string, err error) {
+	var parts []string
+	for rest := address; rest != ""; {
+		i := strings.IndexAny(rest, ".[")
+		if i < 0 {
+			parts = append(parts, rest)
+			break
+		}
+		parts = append(parts, rest[:i])
+		rest = rest[i:]
+
+		if rest[0] == '[' {
+			end := indexEnd(rest)
+			if end < 0 {
+				return nil, "", "", "", fmt.Errorf("invalid resource address %q: unterminated index", address)
+			}
+			rest = rest[end+1:]
+		}
+		rest = strings.TrimPrefix(rest, ".")
+	}
+
+	for len(parts) >= 2 && parts[0] == "module" {
+		modules = append(modules, parts[1])
+		parts = parts[2:]
+	}
+
+	mode = "resource"
+	if len(parts) == 3 && parts[0] == "data" {
+		mode, parts = "data", parts[1:]
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, "", "", "", fmt.Errorf("invalid resource address %q", address)
+	}
+	return modules, mode, parts[0], parts[1], nil
+}
+
+// indexEnd returns the position of the "]" closing the index s starts with,
+// skipping over quoted keys, or -1.
+func indexEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == ']' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
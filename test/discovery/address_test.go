@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	modules, mode, resourceType, name, err := parseAddress(`module.vpc.module.nat["us-east-1a"].aws_nat_gateway.this[0]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vpc", "nat"}, modules)
+	assert.Equal(t, "resource", mode)
+	assert.Equal(t, "aws_nat_gateway", resourceType)
+	assert.Equal(t, "this", name)
+
+	_, mode, resourceType, name, err = parseAddress(`data.aws_iam_policy_document.keys["a.b]c"]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data", "aws_iam_policy_document", "keys"}, []string{mode, resourceType, name})
+
+	for _, bad := range []string{"", "aws_vpc", "module.vpc", `aws_vpc.this["x]`} {
+		_, _, _, _, err := parseAddress(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "modules", "vpc", "main.tf"), `
+resource "aws_vpc" "this" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "public" {
+  count  = 2
+  vpc_id = aws_vpc.this.id
+}
+
+data "aws_availability_zones" "available" {}
+`)
+	writeFile(t, filepath.Join(root, "examples", "vpc-basic", "main.tf"), `
+module "vpc" {
+  source = "../../modules/vpc"
+}
+
+module "registry" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`)
+	example := filepath.Join(root, "examples", "vpc-basic")
+
+	r, err := Locate(example, "module.vpc.aws_subnet.public[1]")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "modules", "vpc", "main.tf"), r.DeclRange.Filename)
+	assert.Equal(t, 6, r.DeclRange.Start.Line)
+
+	r, err = Locate(filepath.Join(root, "modules", "vpc"), "data.aws_availability_zones.available")
+	require.NoError(t, err)
+	assert.Equal(t, 11, r.DeclRange.Start.Line)
+
+	_, err = Locate(example, "module.registry.aws_vpc.this")
+	assert.ErrorContains(t, err, "not loaded from this repository")
+	_, err = Locate(example, "module.vpc.aws_route_table.public")
+	assert.ErrorContains(t, err, "no resource aws_route_table.public")
+	_, err = Locate(example, "module.missing.aws_vpc.this")
+	assert.ErrorContains(t, err, `declares no module "missing"`)
+}
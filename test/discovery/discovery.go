@@ -0,0 +1,491 @@
+// Package discovery locates the Terraform configurations in this repository
+// so the test suite can iterate them instead of maintaining hardcoded lists.
+//
+// Every directory directly below modules/, examples/ and envs/ that contains
+// at least one .tf file is returned as a Config describing its kind, its path
+// and the variables and outputs it declares.
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Kind identifies the role a Terraform configuration plays in the repository.
+type Kind string
+
+const (
+	// KindModule is a reusable module under modules/.
+	KindModule Kind = "module"
+	// KindExample is a runnable example under examples/.
+	KindExample Kind = "example"
+	// KindEnv is an environment root configuration under envs/.
+	KindEnv Kind = "env"
+	// KindRoot is the repository root configuration. Discover never returns
+	// it; load it explicitly with Load.
+	KindRoot Kind = "root"
+)
+
+// kindDirs lists the top-level directory for each kind, in reporting order.
+var kindDirs = []struct {
+	kind Kind
+	dir  string
+}{
+	{KindModule, "modules"},
+	{KindExample, "examples"},
+	{KindEnv, "envs"},
+}
+
+// Variable describes a variable block declared by a configuration.
+type Variable struct {
+	Name string
+	// Type is the source text of the type constraint, or empty if none is set.
+	Type string
+	// Description is the literal description, or empty if none is set.
+	Description string
+	HasDefault  bool
+	Sensitive   bool
+	DeclRange   hcl.Range
+	// Attributes holds the raw attributes of the block, keyed by name.
+	Attributes hcl.Attributes
+	// Validations lists the validation blocks in declaration order.
+	Validations []Validation
+}
+
+// Validation describes a validation block of a variable.
+type Validation struct {
+	Condition    hcl.Expression
+	ErrorMessage hcl.Expression
+	DeclRange    hcl.Range
+}
+
+// Required reports whether callers must supply a value for the variable.
+func (v Variable) Required() bool {
+	return !v.HasDefault
+}
+
+// Output describes an output block declared by a configuration.
+type Output struct {
+	Name string
+	// Description is the literal description, or empty if none is set.
+	Description string
+	Sensitive   bool
+	DeclRange   hcl.Range
+	// Attributes holds the raw attributes of the block, keyed by name.
+	Attributes hcl.Attributes
+}
+
+// Resource describes a resource or data block declared by a configuration.
+type Resource struct {
+	// Mode is "resource" or "data".
+	Mode string
+	Type string
+	Name string
+	// Provider is the local name of the provider the block uses: the root of
+	// its provider meta-argument if set, otherwise the prefix of its type.
+	Provider  string
+	DeclRange hcl.Range
+}
+
+// ModuleCall describes a module block declared by a configuration.
+type ModuleCall struct {
+	Name string
+	// Source is the literal source address, or empty if it is not a literal.
+	Source string
+	// Arguments holds the input variable arguments of the block, keyed by
+	// name. Meta-arguments such as source, count and providers are left out.
+	Arguments hcl.Attributes
+	DeclRange hcl.Range
+}
+
+// IsLocal reports whether the module is loaded from a path in this
+// repository rather than a registry or remote address.
+func (m ModuleCall) IsLocal() bool {
+	return strings.HasPrefix(m.Source, "./") || strings.HasPrefix(m.Source, "../")
+}
+
+// ProviderRequirement describes an entry of terraform.required_providers.
+type ProviderRequirement struct {
+	Name string
+	// Source is the provider source address, or empty if none is set.
+	Source string
+	// Version is the version constraint, or empty if none is set.
+	Version   string
+	DeclRange hcl.Range
+}
+
+// Config describes a single Terraform configuration directory.
+type Config struct {
+	Kind Kind
+	// Name is the directory name, e.g. "vpc" for modules/vpc.
+	Name string
+	// Path is the directory path, joined onto the root passed to Discover.
+	Path string
+	// Files lists the .tf files of the configuration in lexical order.
+	Files     []string
+	Variables []Variable
+	Outputs   []Output
+	Resources []Resource
+	Modules   []ModuleCall
+	// Locals holds the local values of every locals block, keyed by name.
+	Locals hcl.Attributes
+	// RequiredProviders lists the entries of terraform.required_providers.
+	RequiredProviders []ProviderRequirement
+	// Diagnostics holds any problems found while parsing the configuration.
+	Diagnostics hcl.Diagnostics
+}
+
+// ID returns a stable, human readable identifier such as "modules/vpc",
+// suitable for subtest names.
+func (c Config) ID() string {
+	if c.Kind == KindRoot {
+		return "."
+	}
+	for _, kd := range kindDirs {
+		if kd.kind == c.Kind {
+			return kd.dir + "/" + c.Name
+		}
+	}
+	return c.Name
+}
+
+// Variable returns the variable with the given name, if declared.
+func (c Config) Variable(name string) (Variable, bool) {
+	for _, v := range c.Variables {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variable{}, false
+}
+
+// Output returns the output with the given name, if declared.
+func (c Config) Output(name string) (Output, bool) {
+	for _, o := range c.Outputs {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return Output{}, false
+}
+
+// Discover walks modules/, examples/ and envs/ below root and returns every
+// configuration found, ordered by kind and then by name. When kinds are given
+// only configurations of those kinds are returned. Missing top-level
+// directories are skipped; parse problems are recorded on the Config rather
+// than returned as errors.
+func Discover(root string, kinds ...Kind) ([]Config, error) {
+	var configs []Config
+
+	for _, kd := range kindDirs {
+		if len(kinds) > 0 && !containsKind(kinds, kd.kind) {
+			continue
+		}
+
+		base := filepath.Join(root, kd.dir)
+		entries, err := os.ReadDir(base)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", base, err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			cfg, ok, err := Load(kd.kind, filepath.Join(base, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				configs = append(configs, cfg)
+			}
+		}
+	}
+
+	return configs, nil
+}
+
+// Load parses the configuration in dir. It reports false if the directory
+// holds no .tf files.
+func Load(kind Kind, dir string) (Config, bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return Config{}, false, err
+	}
+	if len(files) == 0 {
+		return Config{}, false, nil
+	}
+	sort.Strings(files)
+
+	cfg := Config{
+		Kind:  kind,
+		Name:  filepath.Base(dir),
+		Path:  dir,
+		Files: files,
+	}
+
+	parser := hclparse.NewParser()
+	for _, filename := range files {
+		file, diags := parser.ParseHCLFile(filename)
+		cfg.Diagnostics = append(cfg.Diagnostics, diags...)
+		if file == nil {
+			continue
+		}
+		cfg.Diagnostics = append(cfg.Diagnostics, cfg.loadFile(file)...)
+	}
+
+	return cfg, true, nil
+}
+
+var fileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "terraform"},
+	},
+}
+
+var resourceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "provider"},
+	},
+}
+
+var terraformSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "required_providers"},
+	},
+}
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "description"},
+		{Name: "default"},
+		{Name: "sensitive"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "validation"},
+	},
+}
+
+var validationSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "condition", Required: true},
+		{Name: "error_message", Required: true},
+	},
+}
+
+// moduleMetaArguments lists the module block arguments that are not input
+// variables.
+var moduleMetaArguments = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"providers":  true,
+	"depends_on": true,
+}
+
+var outputSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "description"},
+		{Name: "value"},
+		{Name: "sensitive"},
+	},
+}
+
+func (c *Config) loadFile(file *hcl.File) hcl.Diagnostics {
+	content, _, diags := file.Body.PartialContent(fileSchema)
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "variable":
+			body, _, moreDiags := block.Body.PartialContent(variableSchema)
+			diags = append(diags, moreDiags...)
+
+			v := Variable{Name: block.Labels[0], DeclRange: block.DefRange, Attributes: body.Attributes}
+			if attr, ok := body.Attributes["type"]; ok {
+				v.Type = string(attr.Expr.Range().SliceBytes(file.Bytes))
+			}
+			v.Description = literalString(body.Attributes["description"])
+			_, v.HasDefault = body.Attributes["default"]
+			v.Sensitive = literalBool(body.Attributes["sensitive"])
+			for _, vb := range body.Blocks {
+				vbody, moreDiags := vb.Body.Content(validationSchema)
+				diags = append(diags, moreDiags...)
+				if moreDiags.HasErrors() {
+					continue
+				}
+				v.Validations = append(v.Validations, Validation{
+					Condition:    vbody.Attributes["condition"].Expr,
+					ErrorMessage: vbody.Attributes["error_message"].Expr,
+					DeclRange:    vb.DefRange,
+				})
+			}
+			c.Variables = append(c.Variables, v)
+
+		case "output":
+			body, _, moreDiags := block.Body.PartialContent(outputSchema)
+			diags = append(diags, moreDiags...)
+
+			o := Output{Name: block.Labels[0], DeclRange: block.DefRange, Attributes: body.Attributes}
+			o.Description = literalString(body.Attributes["description"])
+			o.Sensitive = literalBool(body.Attributes["sensitive"])
+			c.Outputs = append(c.Outputs, o)
+
+		case "resource", "data":
+			body, _, moreDiags := block.Body.PartialContent(resourceSchema)
+			diags = append(diags, moreDiags...)
+
+			r := Resource{
+				Mode:      block.Type,
+				Type:      block.Labels[0],
+				Name:      block.Labels[1],
+				Provider:  strings.SplitN(block.Labels[0], "_", 2)[0],
+				DeclRange: block.DefRange,
+			}
+			if attr, ok := body.Attributes["provider"]; ok {
+				traversal, moreDiags := hcl.AbsTraversalForExpr(attr.Expr)
+				diags = append(diags, moreDiags...)
+				if len(traversal) > 0 {
+					r.Provider = traversal.RootName()
+				}
+			}
+			c.Resources = append(c.Resources, r)
+
+		case "module":
+			attrs, moreDiags := block.Body.JustAttributes()
+			diags = append(diags, moreDiags...)
+
+			m := ModuleCall{
+				Name:      block.Labels[0],
+				Source:    literalString(attrs["source"]),
+				Arguments: hcl.Attributes{},
+				DeclRange: block.DefRange,
+			}
+			for name, attr := range attrs {
+				if !moduleMetaArguments[name] {
+					m.Arguments[name] = attr
+				}
+			}
+			c.Modules = append(c.Modules, m)
+
+		case "locals":
+			attrs, moreDiags := block.Body.JustAttributes()
+			diags = append(diags, moreDiags...)
+
+			if c.Locals == nil {
+				c.Locals = hcl.Attributes{}
+			}
+			for name, attr := range attrs {
+				c.Locals[name] = attr
+			}
+
+		case "terraform":
+			body, _, moreDiags := block.Body.PartialContent(terraformSchema)
+			diags = append(diags, moreDiags...)
+
+			for _, rp := range body.Blocks {
+				attrs, moreDiags := rp.Body.JustAttributes()
+				diags = append(diags, moreDiags...)
+				for _, attr := range attrs {
+					c.RequiredProviders = append(c.RequiredProviders, providerRequirement(attr))
+				}
+			}
+			sort.Slice(c.RequiredProviders, func(i, j int) bool {
+				return c.RequiredProviders[i].Name < c.RequiredProviders[j].Name
+			})
+		}
+	}
+
+	return diags
+}
+
+// providerRequirement reads a required_providers entry, which is either an
+// object with source and version keys or, in the legacy form, a bare version
+// constraint string.
+func providerRequirement(attr *hcl.Attribute) ProviderRequirement {
+	req := ProviderRequirement{Name: attr.Name, DeclRange: attr.Range}
+
+	pairs, diags := hcl.ExprMap(attr.Expr)
+	if diags.HasErrors() {
+		req.Version = literalString(attr)
+		return req
+	}
+	for _, pair := range pairs {
+		key, diags := pair.Key.Value(nil)
+		if diags.HasErrors() || !key.Type().Equals(cty.String) {
+			continue
+		}
+		value := literalString(&hcl.Attribute{Expr: pair.Value})
+		switch key.AsString() {
+		case "source":
+			req.Source = value
+		case "version":
+			req.Version = value
+		}
+	}
+	return req
+}
+
+func literalString(attr *hcl.Attribute) string {
+	if attr == nil {
+		return ""
+	}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || !val.Type().Equals(cty.String) {
+		return ""
+	}
+	return val.AsString()
+}
+
+func literalBool(attr *hcl.Attribute) bool {
+	if attr == nil {
+		return false
+	}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || !val.Type().Equals(cty.Bool) {
+		return false
+	}
+	return val.True()
+}
+
+// VariableReference returns the name of the variable expr is, when expr is
+// just var.<name>.
+func VariableReference(expr hcl.Expression) (string, bool) {
+	t, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(t.Traversal) != 2 || t.Traversal.RootName() != "var" {
+		return "", false
+	}
+	attr, ok := t.Traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+	return attr.Name, true
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
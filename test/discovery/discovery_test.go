@@ -0,0 +1,226 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "modules", "vpc", "variables.tf"), `
+variable "vpc_cidr_block" {
+  description = "CIDR block for the VPC"
+  type        = string
+  default     = "10.0.0.0/16"
+
+  validation {
+    condition     = can(cidrhost(var.vpc_cidr_block, 0))
+    error_message = "VPC CIDR block must be a valid IPv4 CIDR."
+  }
+}
+
+variable "subnet_cidrs" {
+  type = list(string)
+}
+`)
+	writeFile(t, filepath.Join(root, "modules", "vpc", "outputs.tf"), `
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = aws_vpc.this.id
+}
+
+output "password" {
+  value     = random_password.this.result
+  sensitive = true
+}
+`)
+	writeFile(t, filepath.Join(root, "modules", "empty", "README.md"), "# not a module\n")
+	writeFile(t, filepath.Join(root, "examples", "vpc-basic", "main.tf"), `
+module "vpc" {
+  source = "../../modules/vpc"
+}
+`)
+	writeFile(t, filepath.Join(root, "envs", "dev", "main.tf"), "")
+	writeFile(t, filepath.Join(root, "envs", "dev", ".terraform", "modules", "x.tf"), "")
+	writeFile(t, filepath.Join(root, "envs", ".hidden", "main.tf"), "")
+
+	configs, err := Discover(root)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, cfg := range configs {
+		ids = append(ids, cfg.ID())
+	}
+	assert.Equal(t, []string{"modules/vpc", "examples/vpc-basic", "envs/dev"}, ids)
+
+	vpc := configs[0]
+	assert.Equal(t, KindModule, vpc.Kind)
+	assert.Equal(t, filepath.Join(root, "modules", "vpc"), vpc.Path)
+	assert.Empty(t, vpc.Diagnostics)
+
+	cidr, ok := vpc.Variable("vpc_cidr_block")
+	require.True(t, ok)
+	assert.Equal(t, "string", cidr.Type)
+	assert.Equal(t, "CIDR block for the VPC", cidr.Description)
+	assert.False(t, cidr.Required())
+	assert.Equal(t, 2, cidr.DeclRange.Start.Line)
+	require.Len(t, cidr.Validations, 1)
+	assert.Equal(t, 7, cidr.Validations[0].DeclRange.Start.Line)
+	assert.Equal(t, 8, cidr.Validations[0].Condition.Range().Start.Line)
+
+	subnets, ok := vpc.Variable("subnet_cidrs")
+	require.True(t, ok)
+	assert.Equal(t, "list(string)", subnets.Type)
+	assert.True(t, subnets.Required())
+	assert.Empty(t, subnets.Validations)
+
+	password, ok := vpc.Output("password")
+	require.True(t, ok)
+	assert.True(t, password.Sensitive)
+	assert.Empty(t, password.Description)
+
+	examples, err := Discover(root, KindExample)
+	require.NoError(t, err)
+	require.Len(t, examples, 1)
+	assert.Equal(t, "vpc-basic", examples[0].Name)
+}
+
+func TestLoadResourcesAndProviders(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "versions.tf"), `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = ">= 3.1"
+  }
+}
+`)
+	writeFile(t, filepath.Join(root, "main.tf"), `
+resource "random_id" "this" {
+  byte_length = 4
+}
+
+data "aws_region" "current" {}
+
+resource "aws_s3_bucket" "replica" {
+  provider = aws.replica
+}
+
+resource "null_resource" "pinned" {
+  provider = mynull
+}
+`)
+
+	cfg, ok, err := Load(KindRoot, root)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+	assert.Equal(t, ".", cfg.ID())
+
+	assert.Equal(t, []ProviderRequirement{
+		{Name: "aws", Source: "hashicorp/aws", Version: "~> 5.0", DeclRange: cfg.RequiredProviders[0].DeclRange},
+		{Name: "random", Version: ">= 3.1", DeclRange: cfg.RequiredProviders[1].DeclRange},
+	}, cfg.RequiredProviders)
+	assert.Equal(t, 4, cfg.RequiredProviders[0].DeclRange.Start.Line)
+
+	var got []string
+	for _, r := range cfg.Resources {
+		got = append(got, r.Mode+"."+r.Type+"."+r.Name+"@"+r.Provider)
+	}
+	assert.Equal(t, []string{
+		"resource.random_id.this@random",
+		"data.aws_region.current@aws",
+		"resource.aws_s3_bucket.replica@aws",
+		"resource.null_resource.pinned@mynull",
+	}, got)
+}
+
+func TestLoadModuleCalls(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, filepath.Join(dir, "main.tf"), `
+module "vpc" {
+  source = "../../modules/vpc"
+  count  = 1
+
+  name_prefix = var.name_prefix
+  azs         = ["us-east-1a"]
+}
+
+module "registry" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0"
+}
+`)
+
+	cfg, ok, err := Load(KindEnv, dir)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+	require.Len(t, cfg.Modules, 2)
+
+	vpc := cfg.Modules[0]
+	assert.Equal(t, "vpc", vpc.Name)
+	assert.Equal(t, "../../modules/vpc", vpc.Source)
+	assert.True(t, vpc.IsLocal())
+	assert.Equal(t, 2, vpc.DeclRange.Start.Line)
+	assert.Len(t, vpc.Arguments, 2)
+	assert.Contains(t, vpc.Arguments, "name_prefix")
+	assert.Contains(t, vpc.Arguments, "azs")
+
+	assert.False(t, cfg.Modules[1].IsLocal())
+	assert.Empty(t, cfg.Modules[1].Arguments)
+}
+
+func TestLoadLocals(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "stack")
+	writeFile(t, filepath.Join(dir, "main.tf"), `
+locals {
+  name = "app"
+}
+
+locals {
+  tags = { Name = local.name }
+}
+`)
+
+	cfg, ok, err := Load(KindExample, dir)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+	require.Len(t, cfg.Locals, 2)
+	assert.Equal(t, 3, cfg.Locals["name"].Range.Start.Line)
+	assert.Contains(t, cfg.Locals, "tags")
+}
+
+func TestVariableReference(t *testing.T) {
+	for src, want := range map[string]string{
+		"var.vpc_id":           "vpc_id",
+		"var.subnets[0]":       "",
+		"local.vpc_id":         "",
+		"\"${var.name}-app\"":  "",
+		"coalesce(var.vpc_id)": "",
+	} {
+		expr, diags := hclsyntax.ParseExpression([]byte(src), "test.tf", hcl.InitialPos)
+		require.False(t, diags.HasErrors(), src)
+		name, ok := VariableReference(expr)
+		assert.Equal(t, want != "", ok, src)
+		assert.Equal(t, want, name, src)
+	}
+}
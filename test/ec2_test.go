@@ -1,7 +1,6 @@
 package test
 
 import (
-	"fmt"
 	"testing"
 	"time"
 
@@ -9,61 +8,56 @@ import (
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
 )
 
 func TestEC2Module(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
 	// Get the default VPC and subnets for testing
 	vpc := aws.GetDefaultVpc(t, awsRegion)
 	subnets := aws.GetSubnetsForVpc(t, vpc.Id, awsRegion)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/ec2-basic",
-
-		Vars: map[string]interface{}{
-			"name_prefix":       namePrefix,
-			"ami_id":           aws.GetAmazonLinuxAmi(t, awsRegion),
-			"instance_type":    "t2.micro",
-			"key_name":         "test-key", // You'll need to create this key pair
-			"subnet_ids":       []string{subnets[0].Id, subnets[1].Id},
-			"desired_capacity": 2,
-			"min_size":         1,
-			"max_size":         3,
-		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/ec2-basic", awsRegion, map[string]interface{}{
+		"name_prefix":      namePrefix,
+		"ami_id":           aws.GetAmazonLinuxAmi(t, awsRegion),
+		"instance_type":    "t2.micro",
+		"key_name":         "test-key", // You'll need to create this key pair
+		"subnet_ids":       []string{subnets[0].Id, subnets[1].Id},
+		"desired_capacity": 2,
+		"min_size":         1,
+		"max_size":         3,
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Get outputs
 	launchTemplateId := terraform.Output(t, terraformOptions, "launch_template_id")
 	asgName := terraform.Output(t, terraformOptions, "autoscaling_group_name")
 
 	// Verify Launch Template exists
-	launchTemplate := aws.GetLaunchTemplate(t, awsRegion, launchTemplateId)
+	launchTemplate := getLaunchTemplate(t, awsRegion, launchTemplateId)
 	assert.NotNil(t, launchTemplate)
 	assert.Contains(t, launchTemplate.LaunchTemplateName, namePrefix)
 
 	// Verify Auto Scaling Group exists and has correct configuration
-	asg := aws.GetAutoScalingGroup(t, awsRegion, asgName)
+	asg := getAutoScalingGroup(t, awsRegion, asgName)
 	assert.NotNil(t, asg)
 	assert.Equal(t, int64(2), asg.DesiredCapacity)
 	assert.Equal(t, int64(1), asg.MinSize)
 	assert.Equal(t, int64(3), asg.MaxSize)
 
 	// Wait for instances to be running
-	aws.WaitForCapacity(t, asg, 2, 5*time.Minute, awsRegion)
+	aws.WaitForCapacity(t, asgName, awsRegion, 30, 10*time.Second)
 
 	// Verify instances are running
-	instances := aws.GetInstancesInAsg(t, asg, awsRegion)
+	instances := getInstancesInAsg(t, asgName, awsRegion)
 	assert.Equal(t, 2, len(instances))
 
 	for _, instance := range instances {
@@ -72,38 +66,31 @@ func TestEC2Module(t *testing.T) {
 }
 
 func TestEC2ModuleWithScalingPolicies(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
 	vpc := aws.GetDefaultVpc(t, awsRegion)
 	subnets := aws.GetSubnetsForVpc(t, vpc.Id, awsRegion)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/ec2-with-scaling",
-
-		Vars: map[string]interface{}{
-			"name_prefix":             namePrefix,
-			"ami_id":                 aws.GetAmazonLinuxAmi(t, awsRegion),
-			"instance_type":          "t2.micro",
-			"key_name":               "test-key",
-			"subnet_ids":             []string{subnets[0].Id, subnets[1].Id},
-			"desired_capacity":       1,
-			"min_size":               1,
-			"max_size":               5,
-			"enable_scaling_policies": true,
-			"cpu_high_threshold":     80,
-			"cpu_low_threshold":      20,
-		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/ec2-with-scaling", awsRegion, map[string]interface{}{
+		"name_prefix":             namePrefix,
+		"ami_id":                  aws.GetAmazonLinuxAmi(t, awsRegion),
+		"instance_type":           "t2.micro",
+		"key_name":                "test-key",
+		"subnet_ids":              []string{subnets[0].Id, subnets[1].Id},
+		"desired_capacity":        1,
+		"min_size":                1,
+		"max_size":                5,
+		"enable_scaling_policies": true,
+		"cpu_high_threshold":      80,
+		"cpu_low_threshold":       20,
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify scaling policies were created
 	scaleUpPolicyArn := terraform.Output(t, terraformOptions, "scale_up_policy_arn")
@@ -118,4 +105,4 @@ func TestEC2ModuleWithScalingPolicies(t *testing.T) {
 
 	assert.NotEmpty(t, cpuHighAlarmArn)
 	assert.NotEmpty(t, cpuLowAlarmArn)
-}
\ No newline at end of file
+}
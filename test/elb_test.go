@@ -7,36 +7,31 @@ import (
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
 )
 
 func TestELBModule(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
 	// Get default VPC and subnets
 	vpc := aws.GetDefaultVpc(t, awsRegion)
 	subnets := aws.GetSubnetsForVpc(t, vpc.Id, awsRegion)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/elb-basic",
-
-		Vars: map[string]interface{}{
-			"name":                namePrefix,
-			"vpc_id":              vpc.Id,
-			"subnet_ids":          []string{subnets[0].Id, subnets[1].Id},
-			"load_balancer_type":  "application",
-			"internal":            false,
-		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/elb-basic", awsRegion, map[string]interface{}{
+		"name":               namePrefix,
+		"vpc_id":             vpc.Id,
+		"subnet_ids":         []string{subnets[0].Id, subnets[1].Id},
+		"load_balancer_type": "application",
+		"internal":           false,
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Get outputs
 	albArn := terraform.Output(t, terraformOptions, "load_balancer_arn")
@@ -44,64 +39,57 @@ func TestELBModule(t *testing.T) {
 	securityGroupId := terraform.Output(t, terraformOptions, "security_group_id")
 
 	// Verify ALB exists
-	alb := aws.GetLoadBalancerV2(t, awsRegion, albArn)
+	alb := getLoadBalancerV2(t, awsRegion, albArn)
 	assert.NotNil(t, alb)
 	assert.Equal(t, "application", alb.Type)
 	assert.Equal(t, "internet-facing", alb.Scheme)
 	assert.NotEmpty(t, albDnsName)
 
 	// Verify security group exists
-	sg := aws.GetSecurityGroupById(t, securityGroupId, awsRegion)
+	sg := getSecurityGroup(t, securityGroupId, awsRegion)
 	assert.NotNil(t, sg)
 	assert.Equal(t, vpc.Id, sg.VpcId)
 }
 
 func TestELBModuleWithTargetGroups(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
 	vpc := aws.GetDefaultVpc(t, awsRegion)
 	subnets := aws.GetSubnetsForVpc(t, vpc.Id, awsRegion)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/elb-with-target-groups",
-
-		Vars: map[string]interface{}{
-			"name":               namePrefix,
-			"vpc_id":             vpc.Id,
-			"subnet_ids":         []string{subnets[0].Id, subnets[1].Id},
-			"load_balancer_type": "application",
-			"target_groups": map[string]interface{}{
-				"web-servers": map[string]interface{}{
-					"port":     80,
-					"protocol": "HTTP",
-					"health_check": map[string]interface{}{
-						"path":    "/health",
-						"matcher": "200",
-					},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/elb-with-target-groups", awsRegion, map[string]interface{}{
+		"name":               namePrefix,
+		"vpc_id":             vpc.Id,
+		"subnet_ids":         []string{subnets[0].Id, subnets[1].Id},
+		"load_balancer_type": "application",
+		"target_groups": map[string]interface{}{
+			"web-servers": map[string]interface{}{
+				"port":     80,
+				"protocol": "HTTP",
+				"health_check": map[string]interface{}{
+					"path":    "/health",
+					"matcher": "200",
 				},
 			},
-			"listener_rules": map[string]interface{}{
-				"http": map[string]interface{}{
-					"port":     80,
-					"protocol": "HTTP",
-					"default_action": map[string]interface{}{
-						"type":               "forward",
-						"target_group_name":  "web-servers",
-					},
+		},
+		"listener_rules": map[string]interface{}{
+			"http": map[string]interface{}{
+				"port":     80,
+				"protocol": "HTTP",
+				"default_action": map[string]interface{}{
+					"type":              "forward",
+					"target_group_name": "web-servers",
 				},
 			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify target groups were created
 	targetGroupArns := terraform.OutputMap(t, terraformOptions, "target_group_arns")
@@ -112,4 +100,4 @@ func TestELBModuleWithTargetGroups(t *testing.T) {
 	listenerArns := terraform.OutputMap(t, terraformOptions, "listener_arns")
 	assert.Contains(t, listenerArns, "http")
 	assert.NotEmpty(t, listenerArns["http"])
-}
\ No newline at end of file
+}
@@ -0,0 +1,587 @@
+// Package fixtures finds the Terraform configurations the Go tests deploy by
+// reading the test sources, so a test that points at a missing directory or
+// passes variables the configuration does not declare fails in seconds
+// instead of halfway through an AWS run.
+//
+// Fixtures are recognised in two forms: terraform.Options composite literals
+// with a literal TerraformDir, and calls to the harness option builders with a
+// literal directory or a package-level string constant. A builder whose vars
+// are an entry of a package-level map literal keyed by directory, such as
+// offlinePlanVars[cfg.ID()], stands for every entry of the map. Any other
+// directory, such as one computed from discovery, cannot be resolved; Extract
+// reports those calls as skipped so the tests can log them.
+package fixtures
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
+)
+
+// Rule names reported by Check.
+const (
+	RuleFixtureDir      = "fixture-dir"
+	RuleFixtureVariable = "fixture-variable"
+	RuleFixtureType     = "fixture-type"
+	RuleFixtureRequired = "fixture-required"
+)
+
+// Fixture is one set of Terraform options built by a test.
+type Fixture struct {
+	// Pos is where the options are built.
+	Pos token.Position
+	// Func is the name of the enclosing function.
+	Func string
+	// Dir is the configuration directory relative to the repository root,
+	// with forward slashes, e.g. "examples/vpc-basic".
+	Dir string
+	// Vars holds the variables passed, or nil when Vars is not a literal map
+	// and cannot be checked.
+	Vars []Var
+}
+
+// Skipped is a set of options whose directory Extract could not resolve, so
+// the variables passed with it are not checked.
+type Skipped struct {
+	Pos  token.Position
+	Func string
+	// Dir is the source of the directory expression, e.g. "cfg.ID()".
+	Dir string
+}
+
+// Var is one entry of a Vars literal.
+type Var struct {
+	Name string
+	// Value is the statically known part of the Go value; anything computed
+	// at run time is unknown.
+	Value cty.Value
+	Pos   token.Position
+}
+
+// harnessBuilders maps the harness option builders to the positions of their
+// directory and vars arguments. WorkspaceOptions takes a directory inside a
+// staged workspace rather than one relative to the repository root.
+var harnessBuilders = map[string]struct{ dir, vars int }{
+	"Options":          {2, 4},
+	"OfflineOptions":   {3, 4},
+	"WorkspaceOptions": {1, 3},
+}
+
+// Extract parses the non-recursive *.go files in testDir and returns the
+// fixtures they build and the options it had to skip, each ordered by
+// position. TerraformDir values are resolved relative to testDir and reported
+// relative to root.
+func Extract(testDir, root string) ([]Fixture, []Skipped, error) {
+	filenames, err := filepath.Glob(filepath.Join(testDir, "*.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	absTest, err := filepath.Abs(testDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, file)
+	}
+
+	pkg := newPackageScope(files)
+	var fixtures []Fixture
+	var skipped []Skipped
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			x := &extractor{fset: fset, testDir: absTest, root: absRoot, pkg: pkg, fn: fn.Name.Name, joins: map[string]string{}}
+			ast.Inspect(fn.Body, x.visit)
+			fixtures = append(fixtures, x.fixtures...)
+			skipped = append(skipped, x.skipped...)
+		}
+	}
+
+	sort.SliceStable(fixtures, func(i, j int) bool {
+		return positionLess(fixtures[i].Pos, fixtures[j].Pos)
+	})
+	sort.SliceStable(skipped, func(i, j int) bool {
+		return positionLess(skipped[i].Pos, skipped[j].Pos)
+	})
+	return fixtures, skipped, nil
+}
+
+func positionLess(a, b token.Position) bool {
+	if a.Filename != b.Filename {
+		return a.Filename < b.Filename
+	}
+	return a.Offset < b.Offset
+}
+
+// packageScope holds the package-level declarations directories and vars
+// are resolved through.
+type packageScope struct {
+	// strings holds string constants and variables, e.g.
+	// const vpcModule = "modules/vpc".
+	strings map[string]string
+	// varMaps holds map literals of vars keyed by directory, e.g.
+	// var offlinePlanVars = map[string]map[string]interface{}{...}.
+	varMaps map[string]*ast.CompositeLit
+	// expanded records the varMaps already turned into fixtures, which is
+	// done once however many calls index them.
+	expanded map[string]bool
+}
+
+func newPackageScope(files []*ast.File) *packageScope {
+	pkg := &packageScope{strings: map[string]string{}, varMaps: map[string]*ast.CompositeLit{}, expanded: map[string]bool{}}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST && gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				value, ok := spec.(*ast.ValueSpec)
+				if !ok || len(value.Names) != len(value.Values) {
+					continue
+				}
+				for i, name := range value.Names {
+					if s, ok := stringLit(value.Values[i]); ok {
+						pkg.strings[name.Name] = s
+					}
+					if lit, ok := value.Values[i].(*ast.CompositeLit); ok && isVarMapType(lit.Type) {
+						pkg.varMaps[name.Name] = lit
+					}
+				}
+			}
+		}
+	}
+	return pkg
+}
+
+type extractor struct {
+	fset     *token.FileSet
+	testDir  string
+	root     string
+	pkg      *packageScope
+	fn       string
+	fixtures []Fixture
+	skipped  []Skipped
+	// joins records variables assigned a filepath.Join of a staged workspace
+	// and literal path elements, keyed by variable name, e.g.
+	// vpcDir := filepath.Join(workspace, "examples", "vpc-basic").
+	joins map[string]string
+}
+
+func (x *extractor) visit(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		for i, lhs := range n.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok || i >= len(n.Rhs) {
+				continue
+			}
+			if dir, ok := workspaceJoin(n.Rhs[i]); ok {
+				x.joins[ident.Name] = dir
+			}
+		}
+
+	case *ast.CompositeLit:
+		if isSelector(n.Type, "terraform", "Options") {
+			x.optionsLiteral(n)
+		}
+
+	case *ast.CallExpr:
+		sel, ok := n.Fun.(*ast.SelectorExpr)
+		if !ok || !isIdent(sel.X, "harness") {
+			return true
+		}
+		args, ok := harnessBuilders[sel.Sel.Name]
+		if !ok || len(n.Args) <= args.vars {
+			return true
+		}
+		dir, ok := x.harnessDir(sel.Sel.Name, n.Args[args.dir])
+		if !ok {
+			if !x.varMapEntries(n.Args[args.vars]) {
+				x.skip(n.Pos(), n.Args[args.dir])
+			}
+			return true
+		}
+		x.add(n.Pos(), dir, n.Args[args.vars])
+	}
+	return true
+}
+
+func (x *extractor) optionsLiteral(lit *ast.CompositeLit) {
+	var dir string
+	var vars ast.Expr
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		switch {
+		case isIdent(kv.Key, "TerraformDir"):
+			s, ok := stringLit(kv.Value)
+			if !ok {
+				x.skip(lit.Pos(), kv.Value)
+				return
+			}
+			dir = s
+		case isIdent(kv.Key, "Vars"):
+			vars = kv.Value
+		}
+	}
+	if dir == "" {
+		return
+	}
+
+	rel, err := filepath.Rel(x.root, filepath.Join(x.testDir, dir))
+	if err != nil {
+		return
+	}
+	x.add(lit.Pos(), filepath.ToSlash(rel), vars)
+}
+
+func (x *extractor) harnessDir(builder string, arg ast.Expr) (string, bool) {
+	if builder == "WorkspaceOptions" {
+		ident, ok := arg.(*ast.Ident)
+		if !ok {
+			return "", false
+		}
+		dir, ok := x.joins[ident.Name]
+		return dir, ok
+	}
+	if ident, ok := arg.(*ast.Ident); ok {
+		dir, ok := x.pkg.strings[ident.Name]
+		return dir, ok
+	}
+	return stringLit(arg)
+}
+
+// varMapEntries adds a fixture for every entry of the package-level map vars
+// indexes, reporting false if vars is not such an index expression.
+func (x *extractor) varMapEntries(vars ast.Expr) bool {
+	index, ok := vars.(*ast.IndexExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := index.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	lit, ok := x.pkg.varMaps[ident.Name]
+	if !ok {
+		return false
+	}
+	if x.pkg.expanded[ident.Name] {
+		return true
+	}
+	x.pkg.expanded[ident.Name] = true
+
+	fn := x.fn
+	x.fn = ident.Name
+	defer func() { x.fn = fn }()
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		dir, ok := stringLit(kv.Key)
+		if !ok {
+			x.skip(kv.Pos(), kv.Key)
+			continue
+		}
+		x.add(kv.Pos(), dir, kv.Value)
+	}
+	return true
+}
+
+func (x *extractor) skip(pos token.Pos, dir ast.Expr) {
+	x.skipped = append(x.skipped, Skipped{
+		Pos:  x.fset.Position(pos),
+		Func: x.fn,
+		Dir:  types.ExprString(dir),
+	})
+}
+
+func (x *extractor) add(pos token.Pos, dir string, vars ast.Expr) {
+	f := Fixture{
+		Pos:  x.fset.Position(pos),
+		Func: x.fn,
+		Dir:  dir,
+	}
+	// Entries of a map of vars maps leave out the inner map type
+	if lit, ok := vars.(*ast.CompositeLit); ok && (isMapType(lit.Type) || lit.Type == nil) {
+		f.Vars = []Var{}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			name, ok := stringLit(kv.Key)
+			if !ok {
+				continue
+			}
+			f.Vars = append(f.Vars, Var{
+				Name:  name,
+				Value: goValue(kv.Value),
+				Pos:   x.fset.Position(kv.Pos()),
+			})
+		}
+	}
+	x.fixtures = append(x.fixtures, f)
+}
+
+// workspaceJoin matches filepath.Join(<non-literal>, "a", "b", ...) and
+// returns "a/b".
+func workspaceJoin(expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || !isSelector(call.Fun, "filepath", "Join") || len(call.Args) < 2 {
+		return "", false
+	}
+	if _, ok := stringLit(call.Args[0]); ok {
+		return "", false
+	}
+
+	var parts []string
+	for _, arg := range call.Args[1:] {
+		s, ok := stringLit(arg)
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "/"), true
+}
+
+// goValue evaluates the statically known part of a Go expression as the
+// value Terraform would receive for it.
+func goValue(expr ast.Expr) cty.Value {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			s, err := strconv.Unquote(e.Value)
+			if err != nil {
+				return cty.DynamicVal
+			}
+			return cty.StringVal(s)
+		case token.INT, token.FLOAT:
+			n, err := cty.ParseNumberVal(e.Value)
+			if err != nil {
+				return cty.DynamicVal
+			}
+			return n
+		}
+
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return cty.True
+		case "false":
+			return cty.False
+		}
+
+	case *ast.BinaryExpr:
+		// Concatenation such as namePrefix + "-alb"
+		if e.Op == token.ADD {
+			if isString(goValue(e.X)) || isString(goValue(e.Y)) {
+				return cty.UnknownVal(cty.String)
+			}
+		}
+
+	case *ast.CompositeLit:
+		return compositeValue(e)
+
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return goValue(e.X)
+		}
+	}
+	return cty.DynamicVal
+}
+
+func compositeValue(lit *ast.CompositeLit) cty.Value {
+	switch lit.Type.(type) {
+	case *ast.ArrayType:
+		if len(lit.Elts) == 0 {
+			return cty.EmptyTupleVal
+		}
+		elems := make([]cty.Value, 0, len(lit.Elts))
+		for _, elt := range lit.Elts {
+			elems = append(elems, goValue(elt))
+		}
+		return cty.TupleVal(elems)
+
+	case *ast.MapType:
+		if len(lit.Elts) == 0 {
+			return cty.EmptyObjectVal
+		}
+		attrs := make(map[string]cty.Value, len(lit.Elts))
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return cty.DynamicVal
+			}
+			key, ok := stringLit(kv.Key)
+			if !ok {
+				// A computed key can only be assigned to a map, so any
+				// placeholder name will do
+				key = fmt.Sprintf("<key %d>", len(attrs))
+			}
+			attrs[key] = goValue(kv.Value)
+		}
+		return cty.ObjectVal(attrs)
+
+	default:
+		return cty.DynamicVal
+	}
+}
+
+func isString(v cty.Value) bool {
+	return v.Type() == cty.String
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+func isSelector(expr ast.Expr, pkg, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && isIdent(sel.X, pkg) && sel.Sel.Name == name
+}
+
+func isMapType(expr ast.Expr) bool {
+	_, ok := expr.(*ast.MapType)
+	return ok
+}
+
+// isVarMapType matches map[string]map[string]interface{} and the like: a map
+// whose values are maps.
+func isVarMapType(expr ast.Expr) bool {
+	m, ok := expr.(*ast.MapType)
+	return ok && isMapType(m.Value)
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// Check loads the fixture's configuration below root and reports a missing
+// or empty directory, variables it does not declare or whose values cannot convert to
+// the declared type, and required variables that are not passed. The
+// required check only applies when Vars is a literal.
+func Check(root string, f Fixture) ([]tfcheck.Finding, error) {
+	rng := positionRange(f.Pos)
+	dir := filepath.Join(root, filepath.FromSlash(f.Dir))
+
+	if _, err := os.Stat(dir); err != nil {
+		return []tfcheck.Finding{{
+			Rule:    RuleFixtureDir,
+			Message: fmt.Sprintf("%s uses %s, which does not exist", f.Func, f.Dir),
+			Range:   rng,
+		}}, nil
+	}
+
+	cfg, ok, err := discovery.Load(kindOf(f.Dir), dir)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []tfcheck.Finding{{
+			Rule:    RuleFixtureDir,
+			Message: fmt.Sprintf("%s uses %s, which holds no Terraform configuration", f.Func, f.Dir),
+			Range:   rng,
+		}}, nil
+	}
+	if f.Vars == nil {
+		return nil, nil
+	}
+
+	var findings []tfcheck.Finding
+	passed := map[string]bool{}
+	for _, v := range f.Vars {
+		passed[v.Name] = true
+
+		decl, ok := cfg.Variable(v.Name)
+		if !ok {
+			findings = append(findings, tfcheck.Finding{
+				Rule:    RuleFixtureVariable,
+				Message: fmt.Sprintf("%s passes %q, which %s does not declare", f.Func, v.Name, f.Dir),
+				Range:   positionRange(v.Pos),
+			})
+			continue
+		}
+
+		if _, err := tfcheck.ConvertVariable(decl, v.Value); err != nil {
+			findings = append(findings, tfcheck.Finding{
+				Rule:    RuleFixtureType,
+				Message: fmt.Sprintf("%s passes %q to %s: %s", f.Func, v.Name, f.Dir, err),
+				Range:   positionRange(v.Pos),
+			})
+		}
+	}
+
+	for _, decl := range cfg.Variables {
+		if decl.Required() && !passed[decl.Name] {
+			findings = append(findings, tfcheck.Finding{
+				Rule:    RuleFixtureRequired,
+				Message: fmt.Sprintf("%s does not pass %q, which %s requires", f.Func, decl.Name, f.Dir),
+				Range:   rng,
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+func kindOf(dir string) discovery.Kind {
+	switch {
+	case strings.HasPrefix(dir, "modules/"):
+		return discovery.KindModule
+	case strings.HasPrefix(dir, "envs/"):
+		return discovery.KindEnv
+	case strings.HasPrefix(dir, "examples/"):
+		return discovery.KindExample
+	default:
+		return discovery.KindRoot
+	}
+}
+
+func positionRange(pos token.Position) hcl.Range {
+	return hcl.Range{
+		Filename: pos.Filename,
+		Start:    hcl.Pos{Line: pos.Line, Column: pos.Column, Byte: pos.Offset},
+		End:      hcl.Pos{Line: pos.Line, Column: pos.Column, Byte: pos.Offset},
+	}
+}
@@ -0,0 +1,166 @@
+package fixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+const testSource = `package test
+
+func TestLiteral(t *testing.T) {
+	namePrefix := random.UniqueId()
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: "../examples/vpc-basic",
+		Vars: map[string]interface{}{
+			"name_prefix": namePrefix + "-vpc",
+			"cidrs":       []string{"10.0.1.0/24"},
+			"az_count":    "two",
+			"tags":        map[string]interface{}{"Team": "platform"},
+			"unknown":     true,
+		},
+	})
+}
+
+func TestHarness(t *testing.T) {
+	opts := harness.Options(t, repoRoot, "examples/missing", region, map[string]interface{}{})
+	computed := harness.Options(t, repoRoot, cfg.ID(), region, nil)
+}
+
+func TestStaged(t *testing.T) {
+	vpcDir := filepath.Join(workspace, "examples", "vpc-basic")
+	opts := harness.WorkspaceOptions(t, vpcDir, region, vars)
+}
+
+func TestPlans(t *testing.T) {
+	for _, cfg := range configs {
+		opts := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), planVars[cfg.ID()])
+	}
+	again := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), planVars[cfg.ID()])
+	constant := harness.OfflineOptions(t, stub, repoRoot, vpcModule, map[string]interface{}{"name_prefix": "c", "region": "r"})
+}
+
+const vpcModule = "examples/vpc-basic"
+
+var planVars = map[string]map[string]interface{}{
+	"examples/vpc-basic": {"name_prefix": "plan", "region": "us-east-1", "stale": 1},
+}
+`
+
+func TestExtractAndCheck(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "test", "sample_test.go"), testSource)
+	writeFile(t, filepath.Join(root, "examples", "vpc-basic", "main.tf"), `
+variable "name_prefix" {
+  type = string
+}
+
+variable "cidrs" {
+  type = list(string)
+}
+
+variable "az_count" {
+  type    = number
+  default = 2
+}
+
+variable "tags" {
+  type = object({
+    Team  = string
+    Owner = optional(string)
+  })
+}
+
+variable "region" {
+  type = string
+}
+`)
+
+	found, skipped, err := Extract(filepath.Join(root, "test"), root)
+	require.NoError(t, err)
+	require.Len(t, found, 5)
+
+	literal := found[0]
+	assert.Equal(t, "TestLiteral", literal.Func)
+	assert.Equal(t, "examples/vpc-basic", literal.Dir)
+	assert.Equal(t, 5, literal.Pos.Line)
+	require.Len(t, literal.Vars, 5)
+	assert.False(t, literal.Vars[0].Value.IsKnown())
+	assert.Equal(t, cty.String, literal.Vars[0].Value.Type())
+	assert.Equal(t, cty.TupleVal([]cty.Value{cty.StringVal("10.0.1.0/24")}), literal.Vars[1].Value)
+
+	findings, err := Check(root, literal)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"sample_test.go:10: TestLiteral passes \"az_count\" to examples/vpc-basic: string is not a valid number: a number is required (fixture-type)",
+		"sample_test.go:12: TestLiteral passes \"unknown\", which examples/vpc-basic does not declare (fixture-variable)",
+		"sample_test.go:5: TestLiteral does not pass \"region\", which examples/vpc-basic requires (fixture-required)",
+	}, formatFindings(findings))
+
+	missing := found[1]
+	assert.Equal(t, "TestHarness", missing.Func)
+	assert.Equal(t, "examples/missing", missing.Dir)
+	findings, err = Check(root, missing)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"sample_test.go:18: TestHarness uses examples/missing, which does not exist (fixture-dir)",
+	}, formatFindings(findings))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "examples", "missing"), 0o755))
+	writeFile(t, filepath.Join(root, "examples", "missing", "README.md"), "")
+	findings, err = Check(root, missing)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"sample_test.go:18: TestHarness uses examples/missing, which holds no Terraform configuration (fixture-dir)",
+	}, formatFindings(findings))
+
+	staged := found[2]
+	assert.Equal(t, "examples/vpc-basic", staged.Dir)
+	assert.Nil(t, staged.Vars)
+	findings, err = Check(root, staged)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	require.Len(t, skipped, 1)
+	assert.Equal(t, "TestHarness", skipped[0].Func)
+	assert.Equal(t, "cfg.ID()", skipped[0].Dir)
+	assert.Equal(t, 19, skipped[0].Pos.Line)
+
+	entry := found[4]
+	assert.Equal(t, "planVars", entry.Func)
+	assert.Equal(t, "examples/vpc-basic", entry.Dir)
+	require.Len(t, entry.Vars, 3)
+	findings, err = Check(root, entry)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"sample_test.go:38: planVars passes \"stale\", which examples/vpc-basic does not declare (fixture-variable)",
+		"sample_test.go:38: planVars does not pass \"cidrs\", which examples/vpc-basic requires (fixture-required)",
+		"sample_test.go:38: planVars does not pass \"tags\", which examples/vpc-basic requires (fixture-required)",
+	}, formatFindings(findings))
+
+	constant := found[3]
+	assert.Equal(t, "TestPlans", constant.Func)
+	assert.Equal(t, "examples/vpc-basic", constant.Dir)
+	require.Len(t, constant.Vars, 2)
+}
+
+func formatFindings(findings []tfcheck.Finding) []string {
+	var out []string
+	for _, f := range findings {
+		f.Range.Filename = filepath.Base(f.Range.Filename)
+		out = append(out, f.String())
+	}
+	return out
+}
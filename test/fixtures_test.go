@@ -0,0 +1,36 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/fixtures"
+)
+
+// TestFixtures checks that every configuration a test deploys exists and accepts the Vars passed,
+// so a broken fixture fails here in seconds instead of halfway through an AWS run
+func TestFixtures(t *testing.T) {
+	found, skipped, err := fixtures.Extract(".", repoRoot)
+	require.NoError(t, err)
+	require.NotEmpty(t, found, "no terraform.Options found in the test sources")
+
+	for _, s := range skipped {
+		t.Logf("%s:%d: %s uses a directory computed at run time (%s); its variables are not checked",
+			filepath.Base(s.Pos.Filename), s.Pos.Line, s.Func, s.Dir)
+	}
+
+	for _, f := range found {
+		f := f
+		t.Run(fmt.Sprintf("%s:%d", filepath.Base(f.Pos.Filename), f.Pos.Line), func(t *testing.T) {
+			findings, err := fixtures.Check(repoRoot, f)
+			require.NoError(t, err)
+
+			for _, finding := range findings {
+				t.Error(finding)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package golden compares test output with golden files checked in under
+// testdata/golden. A test package that uses it declares its own -update flag
+// and passes its value to Assert, which then rewrites the files from the
+// current output instead; review the change with git diff:
+//
+//	go test -run TestOfflinePlan . -update
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/stretchr/testify/require"
+)
+
+// Dir is where golden files live, relative to the test package.
+const Dir = "testdata/golden"
+
+// Path returns the golden file for name, which may contain slashes, such as
+// a module path: "modules/vpc" becomes testdata/golden/modules_vpc.json.
+func Path(name string) string {
+	return filepath.Join(Dir, strings.ReplaceAll(name, "/", "_")+".json")
+}
+
+// Assert fails t with a unified diff when got differs from the golden file
+// at path, and fails it when there is no golden file. With update set it
+// writes got to path instead.
+func Assert(t testing.TB, path string, got []byte, update bool) {
+	t.Helper()
+
+	if update {
+		require.NoError(t, Write(path, got))
+		return
+	}
+
+	diff, err := Compare(path, got)
+	if os.IsNotExist(err) {
+		test := strings.SplitN(t.Name(), "/", 2)[0]
+		t.Fatalf("golden file %s does not exist; create it with go test -run '^%s$' . -update and commit it", path, test)
+	}
+	require.NoError(t, err)
+	if diff != "" {
+		t.Errorf("output differs from golden file %s (run the test with -update to accept it):\n%s", path, diff)
+	}
+}
+
+// Compare returns a unified diff from the golden file at path to got, or ""
+// when they are equal.
+func Compare(path string, got []byte) (string, error) {
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(want, got) {
+		return "", nil
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(want)),
+		B:        difflib.SplitLines(string(got)),
+		FromFile: path,
+		ToFile:   "current output",
+		Context:  3,
+	})
+}
+
+// Write writes a golden file, creating its directory.
+func Write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("updating golden file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("testdata", "golden", "modules_vpc.json"), Path("modules/vpc"))
+}
+
+func TestCompare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "plan.json")
+	_, err := Compare(path, []byte("{}\n"))
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, Write(path, []byte("{\n  \"a\": 1,\n  \"b\": 2\n}\n")))
+
+	diff, err := Compare(path, []byte("{\n  \"a\": 1,\n  \"b\": 2\n}\n"))
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = Compare(path, []byte("{\n  \"a\": 1,\n  \"b\": 3\n}\n"))
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- "+path)
+	assert.Contains(t, diff, "+++ current output")
+	assert.Contains(t, diff, "-  \"b\": 2\n+  \"b\": 3\n")
+}
+
+func TestAssertUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	Assert(t, path, []byte("{}\n"), true)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+}
@@ -0,0 +1,193 @@
+package harness
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// EmulatorEndpointEnvVar names the environment variable that switches the
+// suite to a local AWS-compatible emulator such as LocalStack, e.g.
+// LOCAL_AWS_ENDPOINT=http://localhost:4566. When it is unset tests run
+// against real AWS.
+const EmulatorEndpointEnvVar = "LOCAL_AWS_ENDPOINT"
+
+// EmulatorRegion is the region used against the emulator unless
+// AWS_DEFAULT_REGION says otherwise.
+const EmulatorRegion = "us-east-1"
+
+// EmulatorEndpoint returns the emulator endpoint and whether emulator mode is
+// enabled.
+func EmulatorEndpoint() (string, bool) {
+	endpoint := os.Getenv(EmulatorEndpointEnvVar)
+	return endpoint, endpoint != ""
+}
+
+// Setup prepares the test process for the selected target. Call it from
+// TestMain before running tests. In emulator mode it gives the AWS SDK dummy
+// credentials and redirects every request the SDK sends to *.amazonaws.com to
+// the emulator, so the terratest aws helpers query the emulator too. The
+// helpers build their clients on http.DefaultClient, which is the only hook
+// available to redirect them without forking terratest.
+func Setup() error {
+	endpoint, ok := EmulatorEndpoint()
+	if !ok {
+		return nil
+	}
+
+	target, err := url.Parse(endpoint)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return fmt.Errorf("%s=%q is not an absolute URL", EmulatorEndpointEnvVar, endpoint)
+	}
+
+	for key, value := range emulatorEnv() {
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+
+	next := http.DefaultClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	http.DefaultClient.Transport = &redirectTransport{target: target, next: next}
+
+	return nil
+}
+
+// redirectTransport sends requests for AWS service hosts to target. The
+// original Host header is kept so the emulator can still tell services and
+// virtual-hosted S3 buckets apart.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isAWSHost(req.URL.Hostname()) {
+		return rt.next.RoundTrip(req)
+	}
+
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = rt.target.Scheme
+	redirected.URL.Host = rt.target.Host
+	if redirected.Host == "" {
+		redirected.Host = req.URL.Host
+	}
+	return rt.next.RoundTrip(redirected)
+}
+
+func isAWSHost(host string) bool {
+	return strings.HasSuffix(host, ".amazonaws.com") || strings.HasSuffix(host, ".amazonaws.com.cn")
+}
+
+func emulatorEnv() map[string]string {
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     "test",
+		"AWS_SECRET_ACCESS_KEY": "test",
+		"AWS_SESSION_TOKEN":     "",
+		"AWS_DEFAULT_REGION":    emulatorRegion(),
+		"AWS_REGION":            emulatorRegion(),
+	}
+}
+
+func emulatorRegion() string {
+	if region := os.Getenv("AWS_DEFAULT_REGION"); region != "" {
+		return region
+	}
+	return EmulatorRegion
+}
+
+// Region returns the region a test should deploy to: a random stable region
+// against real AWS, or the fixed emulator region.
+func Region(t testing.TB) string {
+	t.Helper()
+
+	if _, ok := EmulatorEndpoint(); ok {
+		return emulatorRegion()
+	}
+	return aws.GetRandomStableRegion(t, nil, nil)
+}
+
+// Options returns options for deploying dir, relative to the repository
+// root, to region. The configuration is copied into a fresh workspace first,
+// so parallel tests of the same directory each get their own .terraform dir
+// and state, with providers installed from the shared PluginCacheDir.
+// Against the emulator the workspace also gets an AWS provider configuration
+// that sends every API call to the emulator.
+func Options(t testing.TB, root, dir, region string, vars map[string]interface{}) *terraform.Options {
+	t.Helper()
+
+	return WorkspaceOptions(t, CopyWorkspace(t, root, dir), region, vars)
+}
+
+// WorkspaceOptions is Options for a configuration that has already been
+// copied into a workspace, such as one inside a StagedWorkspace.
+func WorkspaceOptions(t testing.TB, workDir, region string, vars map[string]interface{}) *terraform.Options {
+	t.Helper()
+
+	endpoint, ok := EmulatorEndpoint()
+	if !ok {
+		return &terraform.Options{
+			TerraformDir: workDir,
+			Vars:         vars,
+			EnvVars: pluginCacheEnv(map[string]string{
+				"AWS_DEFAULT_REGION": region,
+			}),
+		}
+	}
+
+	WriteProvider(t, workDir, ProviderSettings{
+		Region:         region,
+		Endpoint:       endpoint,
+		S3UsePathStyle: true,
+	})
+
+	env := emulatorEnv()
+	env["AWS_DEFAULT_REGION"] = region
+	return &terraform.Options{
+		TerraformDir: workDir,
+		Vars:         vars,
+		EnvVars:      pluginCacheEnv(env),
+	}
+}
+
+// TierEnvVar names the environment variable the test runner sets to the tier
+// it runs, e.g. TEST_TIER=live.
+const TierEnvVar = "TEST_TIER"
+
+// deployTiers are the tiers whose tests create resources, in the emulator or
+// in a real AWS account.
+var deployTiers = []string{"emulator", "live"}
+
+// RequireDeployTier skips the current test unless the test runner is running
+// the emulator or live tier. Every deployment test calls it first, so a plain
+// go test ./... never creates billable AWS resources.
+func RequireDeployTier(t testing.TB) {
+	t.Helper()
+
+	tier := os.Getenv(TierEnvVar)
+	for _, name := range deployTiers {
+		if tier == name {
+			return
+		}
+	}
+	t.Skipf("runs against AWS or the emulator; run it with go run ./cmd/testrunner -tier live or -tier emulator (%s=%q)", TierEnvVar, tier)
+}
+
+// SkipOnEmulator skips the current test when running against the emulator.
+// Use it in a subtest around checks the emulator does not support, so the
+// remaining checks still run.
+func SkipOnEmulator(t testing.TB, reason string) {
+	t.Helper()
+
+	if endpoint, ok := EmulatorEndpoint(); ok {
+		t.Skipf("not supported by the AWS emulator at %s: %s", endpoint, reason)
+	}
+}
@@ -0,0 +1,244 @@
+package harness
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestCopyWorkspace(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{
+		"versions.tf",
+		"modules/vpc/main.tf",
+		"modules/vpc/examples.tf",
+		"examples/vpc-basic/main.tf",
+		"examples/vpc-basic/examples.tf",
+		"envs/dev/.terraform/terraform.tfstate",
+		"envs/dev/terraform.tfstate",
+		"test/vpc_test.go",
+	} {
+		writeFile(t, filepath.Join(root, path), "")
+	}
+
+	dir := CopyWorkspace(t, root, "examples/vpc-basic")
+	copyRoot := filepath.Dir(filepath.Dir(dir))
+
+	for path, want := range map[string]bool{
+		"versions.tf":                    true,
+		"modules/vpc/main.tf":            true,
+		"modules/vpc/examples.tf":        false,
+		"examples/vpc-basic/main.tf":     true,
+		"examples/vpc-basic/examples.tf": true,
+		"envs/dev/.terraform":            false,
+		"envs/dev/terraform.tfstate":     false,
+		"test":                           false,
+	} {
+		_, err := os.Stat(filepath.Join(copyRoot, path))
+		assert.Equal(t, want, err == nil, path)
+	}
+}
+
+func TestOfflineOptions(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "modules", "vpc", "main.tf"), "")
+
+	stub := awsstub.Start(t)
+
+	opts := OfflineOptions(t, stub, root, "modules/vpc", map[string]interface{}{"name_prefix": "unit"})
+	assert.NotEqual(t, filepath.Join(root, "modules", "vpc"), opts.TerraformDir)
+	assert.Equal(t, "unit", opts.Vars["name_prefix"])
+
+	provider, err := os.ReadFile(filepath.Join(opts.TerraformDir, ProviderFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(provider), `region     = "us-east-1"`)
+	assert.Contains(t, string(provider), `ec2 = "`+stub.URL+`"`)
+	assert.Contains(t, string(provider), `skip_requesting_account_id  = true`)
+}
+
+func TestOfflineEnvOptions(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "envs", "dev", "main.tf"), "")
+	writeFile(t, filepath.Join(root, "envs", "dev", "dev.tfvars"), "")
+
+	stub := awsstub.Start(t)
+
+	opts := OfflineEnvOptions(t, stub, root, "envs/dev", []string{"dev.tfvars"})
+	assert.Equal(t, []string{"dev.tfvars"}, opts.VarFiles)
+	assert.FileExists(t, filepath.Join(opts.TerraformDir, "dev.tfvars"))
+	assert.NoFileExists(t, filepath.Join(opts.TerraformDir, ProviderFile))
+
+	override, err := os.ReadFile(filepath.Join(opts.TerraformDir, OverrideFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(override), `ec2 = "`+stub.URL+`"`)
+	assert.Contains(t, string(override), `backend "local" {}`)
+}
+
+func TestRedirectTransport(t *testing.T) {
+	var gotHost, gotPath string
+	emulator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotPath = r.Host, r.URL.Path
+	}))
+	defer emulator.Close()
+
+	target, err := url.Parse(emulator.URL)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &redirectTransport{target: target, next: http.DefaultTransport}}
+
+	resp, err := client.Get("https://my-bucket.s3.us-east-1.amazonaws.com/key")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "my-bucket.s3.us-east-1.amazonaws.com", gotHost)
+	assert.Equal(t, "/key", gotPath)
+
+	resp, err = client.Get(emulator.URL + "/direct")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, target.Host, gotHost)
+	assert.Equal(t, "/direct", gotPath)
+}
+
+func TestOptionsEmulatorMode(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "examples", "vpc-basic", "main.tf"), "")
+
+	t.Setenv(EmulatorEndpointEnvVar, "")
+	opts := Options(t, root, "examples/vpc-basic", "eu-west-1", nil)
+	assert.NotEqual(t, filepath.Join(root, "examples", "vpc-basic"), opts.TerraformDir)
+	assert.Equal(t, "eu-west-1", opts.EnvVars["AWS_DEFAULT_REGION"])
+	assert.NoFileExists(t, filepath.Join(opts.TerraformDir, ProviderFile))
+
+	t.Setenv(EmulatorEndpointEnvVar, "http://localhost:4566")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	assert.Equal(t, EmulatorRegion, Region(t))
+
+	opts = Options(t, root, "examples/vpc-basic", EmulatorRegion, nil)
+	assert.NotEqual(t, filepath.Join(root, "examples", "vpc-basic"), opts.TerraformDir)
+	assert.Equal(t, "test", opts.EnvVars["AWS_ACCESS_KEY_ID"])
+
+	provider, err := os.ReadFile(filepath.Join(opts.TerraformDir, ProviderFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(provider), `s3 = "http://localhost:4566"`)
+	assert.Contains(t, string(provider), `s3_use_path_style           = true`)
+}
+
+func TestRequireDeployTier(t *testing.T) {
+	for tier, runs := range map[string]bool{"": false, "unit": false, "emulator": true, "live": true} {
+		t.Setenv(TierEnvVar, tier)
+		ran := false
+		t.Run("tier="+tier, func(t *testing.T) {
+			RequireDeployTier(t)
+			ran = true
+		})
+		assert.Equal(t, runs, ran, "TEST_TIER=%q", tier)
+	}
+}
+
+func TestStagedWorkspace(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv(StageWorkspaceEnvVar, base)
+
+	t.Run("sub/test", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(base, "TestStagedWorkspace_sub_test"), StagedWorkspace(t))
+	})
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "examples", "vpc-basic", "main.tf"), "")
+	workspace := CopyWorkspaceTo(t, root, StagedWorkspace(t))
+	dir := filepath.Join(workspace, "examples", "vpc-basic")
+	assert.FileExists(t, filepath.Join(dir, "main.tf"))
+
+	assert.False(t, HasOptions(t, dir))
+	SaveOptions(t, WorkspaceOptions(t, dir, "eu-west-1", map[string]interface{}{"name_prefix": "unit"}))
+	assert.True(t, HasOptions(t, dir))
+	loaded := LoadOptions(t, dir)
+	assert.Equal(t, dir, loaded.TerraformDir)
+	assert.Equal(t, "unit", loaded.Vars["name_prefix"])
+
+	// A refresh of the configuration keeps the saved test data
+	CopyWorkspaceTo(t, root, workspace)
+	assert.True(t, HasOptions(t, dir))
+
+	CleanupStagedWorkspace(t)
+	assert.NoDirExists(t, workspace)
+}
+
+func TestOutputs(t *testing.T) {
+	var outputs Outputs
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"vpc_id": "vpc-123",
+		"desired_capacity": 2,
+		"subnet_ids": ["subnet-1", "subnet-2"],
+		"target_group_arns": {"web-servers": "arn:tg"}
+	}`), &outputs))
+
+	assert.Equal(t, "vpc-123", outputs.String(t, "vpc_id"))
+	assert.Equal(t, "2", outputs.String(t, "desired_capacity"))
+	assert.Equal(t, []string{"subnet-1", "subnet-2"}, outputs.List(t, "subnet_ids"))
+	assert.Equal(t, map[string]string{"web-servers": "arn:tg"}, outputs.Map(t, "target_group_arns"))
+}
+
+func TestPluginCache(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "examples", "vpc-basic", "main.tf"), "")
+
+	cache := t.TempDir()
+	t.Setenv(PluginCacheEnvVar, cache)
+	t.Setenv(EmulatorEndpointEnvVar, "")
+	assert.Equal(t, cache, PluginCacheDir())
+
+	// Parallel tests of the same directory get separate workspaces sharing
+	// one plugin cache
+	first := Options(t, root, "examples/vpc-basic", "eu-west-1", nil)
+	second := Options(t, root, "examples/vpc-basic", "eu-west-1", nil)
+	assert.NotEqual(t, first.TerraformDir, second.TerraformDir)
+	assert.Equal(t, cache, first.EnvVars[PluginCacheEnvVar])
+	assert.Equal(t, cache, second.EnvVars[PluginCacheEnvVar])
+	assert.Equal(t, "true", first.EnvVars["TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"])
+
+	stub := awsstub.Start(t)
+	offline := OfflineOptions(t, stub, root, "examples/vpc-basic", nil)
+	assert.Equal(t, cache, offline.EnvVars[PluginCacheEnvVar])
+	assert.Equal(t, "true", offline.EnvVars["AWS_EC2_METADATA_DISABLED"])
+
+	t.Setenv(PluginCacheEnvVar, "")
+	assert.NotEqual(t, "", PluginCacheDir())
+}
+
+func TestInitWithoutBackend(t *testing.T) {
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	// A stand-in binary recording the arguments it was run with
+	binary := filepath.Join(dir, "terraform")
+	writeFile(t, binary, "#!/bin/sh\necho \"$@\" > "+args+"\n")
+	require.NoError(t, os.Chmod(binary, 0o755))
+
+	options := &terraform.Options{
+		TerraformDir:    dir,
+		TerraformBinary: binary,
+		EnvVars:         map[string]string{PluginCacheEnvVar: filepath.Join(dir, "cache")},
+	}
+	InitWithoutBackend(t, options)
+
+	recorded, err := os.ReadFile(args)
+	require.NoError(t, err)
+	assert.Equal(t, "init -upgrade=false -backend=false", strings.TrimSpace(string(recorded)))
+	assert.DirExists(t, filepath.Join(dir, "cache"))
+}
@@ -0,0 +1,157 @@
+package harness
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+)
+
+// ProviderFile is the name of the file the harness writes into a workspace to
+// configure the AWS provider. The configuration under test must not declare
+// its own provider "aws" block.
+const ProviderFile = "zz_harness_provider.tf"
+
+// OverrideFile is the name of the override file the harness writes into the
+// workspace of an environment, which declares its own provider "aws" block
+// and backend. Terraform merges its provider block over the environment's and
+// its local backend replaces the environment's.
+const OverrideFile = "zz_harness_override.tf"
+
+// endpointServices lists the AWS provider endpoint keys redirected to a local
+// endpoint. It covers every service the modules in this repository use.
+var endpointServices = []string{
+	"acm",
+	"autoscaling",
+	"cloudwatch",
+	"cloudwatchlogs",
+	"ec2",
+	"ecs",
+	"efs",
+	"eks",
+	"elbv2",
+	"iam",
+	"kms",
+	"route53",
+	"s3",
+	"sns",
+	"sqs",
+	"sts",
+}
+
+// ProviderSettings describes the AWS provider block written by WriteProvider.
+type ProviderSettings struct {
+	Region   string
+	Endpoint string
+	// S3UsePathStyle addresses buckets as Endpoint/bucket rather than as a
+	// subdomain, which local endpoints cannot resolve.
+	S3UsePathStyle bool
+}
+
+var providerTemplate = template.Must(template.New("provider").Parse(`# Generated by the test harness. Do not edit.
+provider "aws" {
+  region     = "{{.Region}}"
+  access_key = "test"
+  secret_key = "test"
+
+  skip_credentials_validation = true
+  skip_metadata_api_check     = true
+  skip_region_validation      = true
+  skip_requesting_account_id  = true
+{{- if .S3UsePathStyle}}
+  s3_use_path_style           = true
+{{- end}}
+
+  endpoints {
+{{- range .Services}}
+    {{.}} = "{{$.Endpoint}}"
+{{- end}}
+  }
+}
+`))
+
+// localBackend is appended to the provider configuration in OverrideFile.
+const localBackend = `
+terraform {
+  backend "local" {}
+}
+`
+
+// WriteProvider writes an AWS provider configuration that sends every API call
+// to settings.Endpoint into dir.
+func WriteProvider(t testing.TB, dir string, settings ProviderSettings) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProviderFile), renderProvider(t, settings), 0o644))
+}
+
+// WriteOverride writes OverrideFile into dir: the provider configuration of
+// WriteProvider and a local backend.
+func WriteOverride(t testing.TB, dir string, settings ProviderSettings) {
+	t.Helper()
+
+	content := append(renderProvider(t, settings), localBackend...)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, OverrideFile), content, 0o644))
+}
+
+func renderProvider(t testing.TB, settings ProviderSettings) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, providerTemplate.Execute(&buf, struct {
+		ProviderSettings
+		Services []string
+	}{settings, endpointServices}))
+	return buf.Bytes()
+}
+
+// OfflineOptions returns options for planning dir, relative to the repository
+// root, without an AWS account. The configuration is copied into a fresh
+// workspace whose AWS provider talks to stub, so data sources resolve to the
+// stub's canned values: aws_availability_zones lists awsstub.AvailabilityZones
+// and aws_region reports awsstub.Region. Only plan is meaningful offline;
+// apply would fail on the first resource.
+func OfflineOptions(t testing.TB, stub *awsstub.Server, root, dir string, vars map[string]interface{}) *terraform.Options {
+	t.Helper()
+
+	workDir := CopyWorkspace(t, root, dir)
+	WriteProvider(t, workDir, ProviderSettings{Region: awsstub.Region, Endpoint: stub.URL})
+	return offlineOptions(workDir, vars)
+}
+
+// OfflineEnvOptions is OfflineOptions for an environment under envs/, planned
+// with varFiles, relative to dir. Environments configure their own AWS
+// provider and an S3 backend, so the harness writes OverrideFile instead of
+// ProviderFile: the plan talks to stub and keeps its state locally, needing
+// neither AWS credentials nor the state bucket.
+func OfflineEnvOptions(t testing.TB, stub *awsstub.Server, root, dir string, varFiles []string) *terraform.Options {
+	t.Helper()
+
+	workDir := CopyWorkspace(t, root, dir)
+	WriteOverride(t, workDir, ProviderSettings{Region: awsstub.Region, Endpoint: stub.URL})
+	options := offlineOptions(workDir, nil)
+	options.VarFiles = varFiles
+	return options
+}
+
+func offlineOptions(workDir string, vars map[string]interface{}) *terraform.Options {
+	return &terraform.Options{
+		TerraformDir: workDir,
+		Vars:         vars,
+		PlanFilePath: filepath.Join(workDir, "tfplan"),
+		NoColor:      true,
+		EnvVars: pluginCacheEnv(map[string]string{
+			// Keep the SDK away from shared config and instance metadata.
+			"AWS_PROFILE":                 "",
+			"AWS_CONFIG_FILE":             os.DevNull,
+			"AWS_SHARED_CREDENTIALS_FILE": os.DevNull,
+			"AWS_EC2_METADATA_DISABLED":   "true",
+		}),
+	}
+}
@@ -0,0 +1,145 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// PluginCacheEnvVar is the environment variable Terraform reads the provider
+// plugin cache directory from. When it is set for the test process that
+// directory is shared with the tests, otherwise PluginCacheDir picks one.
+const PluginCacheEnvVar = "TF_PLUGIN_CACHE_DIR"
+
+// initMu serialises terraform init across the tests of this process. Init is
+// the only command that writes to the plugin cache, and Terraform documents
+// the cache as not safe for concurrent use.
+var initMu sync.Mutex
+
+// PluginCacheDir returns the provider plugin cache shared by every
+// workspace the harness creates, so each per-test copy installs providers
+// with a link into the cache instead of a download.
+func PluginCacheDir() string {
+	if dir := os.Getenv(PluginCacheEnvVar); dir != "" {
+		return dir
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "terraform-aws-modules-test", "plugin-cache")
+}
+
+// PluginCacheEnv returns the environment variables that point terraform at
+// the shared plugin cache, for options not built by the harness.
+func PluginCacheEnv() map[string]string {
+	return map[string]string{
+		PluginCacheEnvVar: PluginCacheDir(),
+		// Workspaces are fresh copies whose .terraform.lock.hcl, if any,
+		// lacks checksums for the cached packages, and Terraform otherwise
+		// refuses to link a cached provider it cannot verify against it.
+		"TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE": "true",
+	}
+}
+
+// pluginCacheEnv adds PluginCacheEnv to env and returns it.
+func pluginCacheEnv(env map[string]string) map[string]string {
+	for k, v := range PluginCacheEnv() {
+		env[k] = v
+	}
+	return env
+}
+
+// Init runs terraform init for options, holding the harness lock so parallel
+// tests never write to the plugin cache at the same time. Everything after
+// init only reads from the cache and runs unlocked.
+func Init(t testing.TB, options *terraform.Options) string {
+	t.Helper()
+
+	out, err := InitE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// InitE is Init, returning the error instead of failing the test.
+func InitE(t testing.TB, options *terraform.Options) (string, error) {
+	t.Helper()
+
+	return lockedInit(options, func() (string, error) {
+		return terraform.InitE(t, options)
+	})
+}
+
+// InitWithoutBackend is Init with -backend=false. It is for commands such as
+// validate that never read state, so a configuration with a remote backend
+// initialises without credentials for it.
+func InitWithoutBackend(t testing.TB, options *terraform.Options) string {
+	t.Helper()
+
+	out, err := InitWithoutBackendE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// InitWithoutBackendE is InitWithoutBackend, returning the error instead of
+// failing the test.
+func InitWithoutBackendE(t testing.TB, options *terraform.Options) (string, error) {
+	t.Helper()
+
+	return lockedInit(options, func() (string, error) {
+		args := []string{"init", fmt.Sprintf("-upgrade=%t", options.Upgrade), "-backend=false"}
+		if options.NoColor {
+			args = append(args, "-no-color")
+		}
+		args = append(args, terraform.FormatTerraformPluginDirAsArgs(options.PluginDir)...)
+		return terraform.RunTerraformCommandE(t, options, args...)
+	})
+}
+
+// lockedInit runs init, creating the plugin cache directory of options and
+// holding initMu.
+func lockedInit(options *terraform.Options, init func() (string, error)) (string, error) {
+	if dir := options.EnvVars[PluginCacheEnvVar]; dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	return init()
+}
+
+// InitAndApply is terraform.InitAndApply with the init run through Init.
+func InitAndApply(t testing.TB, options *terraform.Options) string {
+	t.Helper()
+
+	Init(t, options)
+	return terraform.Apply(t, options)
+}
+
+// InitAndPlan is terraform.InitAndPlan with the init run through Init.
+func InitAndPlan(t testing.TB, options *terraform.Options) string {
+	t.Helper()
+
+	out, err := InitAndPlanE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// InitAndPlanE is InitAndPlan, returning the error instead of failing the
+// test.
+func InitAndPlanE(t testing.TB, options *terraform.Options) (string, error) {
+	t.Helper()
+
+	if _, err := InitE(t, options); err != nil {
+		return "", err
+	}
+	return terraform.PlanE(t, options)
+}
@@ -0,0 +1,141 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+// Staged tests split a deployment into stages run with
+// test_structure.RunTestStage, each of which can be skipped by setting
+// SKIP_<stage>. A typical loop is to deploy once, then iterate on the
+// assertions without rebuilding anything:
+//
+//	SKIP_teardown=true go test -run TestCompleteInfrastructureStack
+//	SKIP_setup=true SKIP_deploy=true SKIP_teardown=true go test -run TestCompleteInfrastructureStack
+//	SKIP_setup=true SKIP_deploy=true SKIP_validate=true go test -run TestCompleteInfrastructureStack
+//
+// For that to work everything a later stage needs is persisted between runs:
+// the workspace and its Terraform state, the options of each configuration
+// and their outputs, all under StagedWorkspace.
+
+// StageWorkspaceEnvVar overrides the directory staged workspaces are kept in.
+const StageWorkspaceEnvVar = "STAGE_WORKSPACE_DIR"
+
+// StagedWorkspace returns the persistent workspace root for the test. Unlike
+// CopyWorkspace its location only depends on the test name, so a later run
+// finds what an earlier run left behind. The setup stage fills it with
+// CopyWorkspaceTo and the teardown stage removes it with
+// CleanupStagedWorkspace.
+func StagedWorkspace(t testing.TB) string {
+	t.Helper()
+
+	base := os.Getenv(StageWorkspaceEnvVar)
+	if base == "" {
+		base = filepath.Join(os.TempDir(), "terratest-stages")
+	}
+	return filepath.Join(base, strings.ReplaceAll(t.Name(), "/", "_"))
+}
+
+// CleanupStagedWorkspace removes the test's staged workspace.
+func CleanupStagedWorkspace(t testing.TB) {
+	t.Helper()
+
+	require.NoError(t, os.RemoveAll(StagedWorkspace(t)))
+}
+
+// SaveOptions persists options next to their configuration so later stages
+// can LoadOptions them.
+func SaveOptions(t testing.TB, options *terraform.Options) {
+	t.Helper()
+
+	test_structure.SaveTerraformOptions(t, options.TerraformDir, options)
+}
+
+// LoadOptions loads the options saved for the configuration in dir.
+func LoadOptions(t testing.TB, dir string) *terraform.Options {
+	t.Helper()
+
+	return test_structure.LoadTerraformOptions(t, dir)
+}
+
+// HasOptions reports whether options were saved for the configuration in
+// dir, i.e. whether its deploy stage got as far as starting.
+func HasOptions(t testing.TB, dir string) bool {
+	t.Helper()
+
+	return test_structure.IsTestDataPresent(t, test_structure.FormatTestDataPath(dir, "TerraformOptions.json"))
+}
+
+// Outputs are the output values of an applied configuration.
+type Outputs map[string]interface{}
+
+// SaveOutputs reads every output of the configuration and persists them next
+// to its saved options.
+func SaveOutputs(t testing.TB, options *terraform.Options) Outputs {
+	t.Helper()
+
+	outputs := Outputs(terraform.OutputAll(t, options))
+	test_structure.SaveTestData(t, outputsPath(options.TerraformDir), true, outputs)
+	return outputs
+}
+
+// LoadOutputs loads the outputs saved for the configuration in dir.
+func LoadOutputs(t testing.TB, dir string) Outputs {
+	t.Helper()
+
+	var outputs Outputs
+	test_structure.LoadTestData(t, outputsPath(dir), &outputs)
+	return outputs
+}
+
+func outputsPath(dir string) string {
+	return test_structure.FormatTestDataPath(dir, "Outputs.json")
+}
+
+// String returns a string output.
+func (o Outputs) String(t testing.TB, name string) string {
+	t.Helper()
+
+	value, ok := o[name]
+	require.True(t, ok, "output %q not found", name)
+	return fmt.Sprint(value)
+}
+
+// List returns a list output as strings.
+func (o Outputs) List(t testing.TB, name string) []string {
+	t.Helper()
+
+	value, ok := o[name]
+	require.True(t, ok, "output %q not found", name)
+	list, ok := value.([]interface{})
+	require.True(t, ok, "output %q is not a list: %v", name, value)
+
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
+
+// Map returns a map output with its values as strings.
+func (o Outputs) Map(t testing.TB, name string) map[string]string {
+	t.Helper()
+
+	value, ok := o[name]
+	require.True(t, ok, "output %q not found", name)
+	m, ok := value.(map[string]interface{})
+	require.True(t, ok, "output %q is not a map: %v", name, value)
+
+	out := make(map[string]string, len(m))
+	for key, item := range m {
+		out[key] = fmt.Sprint(item)
+	}
+	return out
+}
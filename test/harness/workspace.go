@@ -0,0 +1,68 @@
+// Package harness prepares Terraform working directories and options for the
+// test suite, so tests never run terraform against the checked-in tree.
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/require"
+)
+
+// CopyWorkspace copies the repository at root into a temporary directory
+// owned by t and returns the path of dir, relative to root, inside the copy.
+// The whole repository is copied so relative module sources such as
+// ../../modules/vpc keep resolving.
+//
+// Hidden files, state files and the test/ directory are left out, as are the
+// modules/*/examples.tf usage snippets: their module blocks use sources
+// relative to the repository root and break init of any configuration that
+// loads the module.
+func CopyWorkspace(t testing.TB, root, dir string) string {
+	t.Helper()
+
+	src, err := filepath.Abs(root)
+	require.NoError(t, err)
+
+	return filepath.Join(CopyWorkspaceTo(t, root, filepath.Join(t.TempDir(), filepath.Base(src))), dir)
+}
+
+// CopyWorkspaceTo copies the repository at root into dest, with the same
+// exclusions as CopyWorkspace, and returns dest. Files already in dest are
+// overwritten but never removed, so Terraform state and saved test data from
+// an earlier run survive a refresh of the configuration.
+func CopyWorkspaceTo(t testing.TB, root, dest string) string {
+	t.Helper()
+
+	src, err := filepath.Abs(root)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(dest, 0o755))
+	require.NoError(t, files.CopyFolderContentsWithFilter(src, dest, func(path string) bool {
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return false
+		}
+		return includeInWorkspace(rel)
+	}))
+
+	return dest
+}
+
+func includeInWorkspace(rel string) bool {
+	if files.PathIsTerraformLockFile(rel) {
+		return true
+	}
+	if files.PathContainsHiddenFileOrFolder(rel) || files.PathContainsTerraformState(rel) {
+		return false
+	}
+	if rel == "test" {
+		return false
+	}
+	if filepath.Base(rel) == "examples.tf" && filepath.Base(filepath.Dir(filepath.Dir(rel))) == "modules" {
+		return false
+	}
+	return true
+}
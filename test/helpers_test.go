@@ -0,0 +1,105 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/sarif"
+)
+
+// repoRoot is the repository root relative to this test directory
+const repoRoot = ".."
+
+// TestMain points the suite at a local AWS emulator when LOCAL_AWS_ENDPOINT is set
+func TestMain(m *testing.M) {
+	if err := harness.Setup(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+// discoverConfigs returns the Terraform configurations of the given kinds
+// (all kinds if none are given). New modules, examples and environments are
+// picked up automatically, so tests never need a hardcoded list.
+func discoverConfigs(t *testing.T, kinds ...discovery.Kind) []discovery.Config {
+	t.Helper()
+
+	configs, err := discovery.Discover(repoRoot, kinds...)
+	require.NoError(t, err, "discovering Terraform configurations")
+	require.NotEmpty(t, configs, "no Terraform configurations found under %s", repoRoot)
+
+	return configs
+}
+
+// reportDiagnostics fails the test for every HCL parse problem in cfg, so
+// checks built on the parsed configuration never pass silently on a file
+// they could not read.
+func reportDiagnostics(t *testing.T, cfg discovery.Config) {
+	t.Helper()
+
+	for _, diag := range cfg.Diagnostics {
+		t.Error(diag)
+	}
+}
+
+// reportFileDiagnostics fails the test for every HCL parse problem in the
+// file of cfg named name and only logs the others. Each file is parsed on
+// its own, so a check that reads the blocks of that file still runs when
+// another file, such as a module's examples.tf, does not parse.
+func reportFileDiagnostics(t *testing.T, cfg discovery.Config, name string) {
+	t.Helper()
+
+	for _, diag := range cfg.Diagnostics {
+		if diag.Subject != nil && filepath.Base(diag.Subject.Filename) != name {
+			t.Log(diag)
+			continue
+		}
+		t.Error(diag)
+	}
+}
+
+// loadRootConfig parses the repository root configuration, which holds the
+// shared provider requirements in versions.tf.
+func loadRootConfig(t *testing.T) discovery.Config {
+	t.Helper()
+
+	cfg, ok, err := discovery.Load(discovery.KindRoot, repoRoot)
+	require.NoError(t, err, "loading root configuration")
+	require.True(t, ok, "no Terraform files found in %s", repoRoot)
+
+	return cfg
+}
+
+// sarifOutputDir is the environment variable naming the directory tests write SARIF reports of their
+// findings to. Reports are not written when it is unset
+const sarifOutputDir = "SARIF_OUTPUT_DIR"
+
+// sarifReport returns a SARIF builder for the findings of t, written to <SARIF_OUTPUT_DIR>/<test>.sarif
+// when t finishes, with the test's name as its category. Every report is written, even an empty
+// one, so a fixed finding clears its annotation
+func sarifReport(t *testing.T) *sarif.Builder {
+	t.Helper()
+
+	name := strings.ReplaceAll(t.Name(), "/", "_")
+	report := sarif.NewBuilder(name)
+	dir := os.Getenv(sarifOutputDir)
+	if dir == "" {
+		return report
+	}
+	t.Cleanup(func() {
+		path := filepath.Join(dir, name+".sarif")
+		if err := report.WriteFile(path); err != nil {
+			t.Errorf("writing SARIF report: %v", err)
+		}
+	})
+	return report
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/iameval"
+	"github.com/your-org/terraform-aws-modules/test/iampolicy"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// completeStack is the example whose roles TestIAMRolePermissions evaluates
+const completeStack = "examples/complete-stack"
+
+// TestIAMRolePermissions plans the complete-stack example offline and evaluates what its roles can do,
+// with the managed, inline and boundary policies they are planned with. Documents referencing resources
+// created in the same apply are read from the configuration, so the storage bucket is named by its
+// placeholder "${module.s3.bucket_arn}"
+func TestIAMRolePermissions(t *testing.T) {
+	var cfg discovery.Config
+	for _, c := range discoverConfigs(t, discovery.KindExample) {
+		if c.ID() == completeStack {
+			cfg = c
+		}
+	}
+	require.Equal(t, completeStack, cfg.ID(), "%s not found", completeStack)
+
+	stub := awsstub.Start(t)
+
+	terraformOptions := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), offlinePlanVars[cfg.ID()])
+	harness.InitAndPlan(t, terraformOptions)
+	plan := planjson.Show(t, terraformOptions)
+	resolve := iameval.Symbolic(iampolicy.Symbolic(cfg))
+
+	bucketObject := "${module.s3.bucket_arn}/reports/daily.csv"
+	otherObject := "arn:aws:s3:::some-other-bucket/reports/daily.csv"
+
+	t.Run("ec2-instance-role", func(t *testing.T) {
+		role, err := iameval.RoleFromPlan(plan, "ec2-instance-role", resolve)
+		require.NoError(t, err)
+		require.Empty(t, role.Unresolved, "policies of %s could not be read", role.Name)
+
+		d := role.Evaluate(iameval.Request{Action: "s3:GetObject", Resource: bucketObject})
+		require.True(t, d.Allowed, "s3:GetObject on its own bucket: %s", d)
+		require.Len(t, d.Statements, 1)
+		assert.Equal(t, "ec2-instance-role/s3-access", d.Statements[0].Policy)
+
+		for _, action := range []string{"s3:GetObject", "s3:PutObject"} {
+			d := role.Evaluate(iameval.Request{Action: action, Resource: otherObject})
+			assert.False(t, d.Allowed, "%s on another bucket: %s", action, d)
+		}
+		for _, action := range []string{"s3:DeleteObject", "s3:ListBucket", "iam:PassRole"} {
+			d := role.Evaluate(iameval.Request{Action: action, Resource: bucketObject})
+			assert.False(t, d.Allowed, "%s: %s", action, d)
+		}
+
+		assert.True(t, role.Allowed("ssm:UpdateInstanceInformation", "*"), "AmazonSSMManagedInstanceCore")
+		assert.True(t, role.Allowed("cloudwatch:PutMetricData", "*"), "CloudWatchAgentServerPolicy")
+	})
+
+	t.Run("ecs-execution-role", func(t *testing.T) {
+		role, err := iameval.RoleFromPlan(plan, "ecs-execution-role", resolve)
+		require.NoError(t, err)
+		require.Empty(t, role.Unresolved)
+
+		assert.True(t, role.Allowed("ecr:GetDownloadUrlForLayer", "arn:aws:ecr:us-east-1:123456789012:repository/app"))
+		assert.False(t, role.Allowed("s3:GetObject", bucketObject))
+	})
+
+	t.Run("s3-access-role", func(t *testing.T) {
+		role, err := iameval.RoleFromPlan(plan, "s3-access-role", resolve)
+		require.NoError(t, err)
+		require.Empty(t, role.Unresolved, "policies of %s could not be read", role.Name)
+
+		for _, action := range []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject"} {
+			d := role.Evaluate(iameval.Request{Action: action, Resource: bucketObject})
+			assert.True(t, d.Allowed, "%s on its own bucket: %s", action, d)
+			d = role.Evaluate(iameval.Request{Action: action, Resource: otherObject})
+			assert.False(t, d.Allowed, "%s on another bucket: %s", action, d)
+		}
+		assert.True(t, role.Allowed("s3:ListBucket", "${module.s3.bucket_arn}"))
+		assert.False(t, role.Allowed("s3:ListBucket", "arn:aws:s3:::some-other-bucket"))
+		assert.False(t, role.Allowed("s3:DeleteBucket", "${module.s3.bucket_arn}"))
+		assert.False(t, role.Allowed("iam:PassRole", "*"))
+	})
+}
@@ -4,49 +4,43 @@ import (
 	"encoding/json"
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
 )
 
 func TestIAMModuleBasic(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-basic",
-
-		Vars: map[string]interface{}{
-			"name_prefix": namePrefix,
-			"users": map[string]interface{}{
-				namePrefix + "-test-user": map[string]interface{}{
-					"create_login_profile": false,
-					"create_access_key":   true,
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/ReadOnlyAccess",
-					},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-basic", awsRegion, map[string]interface{}{
+		"name_prefix": namePrefix,
+		"users": map[string]interface{}{
+			namePrefix + "-test-user": map[string]interface{}{
+				"create_login_profile": false,
+				"create_access_key":    true,
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/ReadOnlyAccess",
 				},
 			},
-			"groups": map[string]interface{}{
-				namePrefix + "-test-group": map[string]interface{}{
-					"users": []string{namePrefix + "-test-user"},
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/AmazonEC2ReadOnlyAccess",
-					},
+		},
+		"groups": map[string]interface{}{
+			namePrefix + "-test-group": map[string]interface{}{
+				"users": []string{namePrefix + "-test-user"},
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/AmazonEC2ReadOnlyAccess",
 				},
 			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Test outputs
 	users := terraform.OutputMap(t, terraformOptions, "users")
@@ -65,25 +59,26 @@ func TestIAMModuleBasic(t *testing.T) {
 	assert.Contains(t, groupArns, expectedGroupName)
 
 	// Verify user exists in AWS
-	user := aws.GetIamUser(t, expectedUserName)
+	user := getIamUser(t, expectedUserName)
 	assert.Equal(t, expectedUserName, user.UserName)
 	assert.Equal(t, "/", user.Path)
 
 	// Verify group exists in AWS
-	group := aws.GetIamGroup(t, expectedGroupName)
+	group := getIamGroup(t, expectedGroupName)
 	assert.Equal(t, expectedGroupName, group.GroupName)
 	assert.Equal(t, "/", group.Path)
 
 	// Verify group membership
-	groupUsers := aws.GetIamUsersInGroup(t, expectedGroupName)
+	groupUsers := getIamUsersInGroup(t, expectedGroupName)
 	assert.Len(t, groupUsers, 1)
 	assert.Equal(t, expectedUserName, groupUsers[0].UserName)
 }
 
 func TestIAMModuleRoles(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
 	ec2AssumeRolePolicy := map[string]interface{}{
@@ -101,48 +96,40 @@ func TestIAMModuleRoles(t *testing.T) {
 
 	ec2AssumeRolePolicyJSON, _ := json.Marshal(ec2AssumeRolePolicy)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-roles",
-
-		Vars: map[string]interface{}{
-			"name_prefix": namePrefix,
-			"roles": map[string]interface{}{
-				namePrefix + "-ec2-role": map[string]interface{}{
-					"assume_role_policy":      string(ec2AssumeRolePolicyJSON),
-					"create_instance_profile": true,
-					"description":            "Test EC2 role",
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
-					},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-roles", awsRegion, map[string]interface{}{
+		"name_prefix": namePrefix,
+		"roles": map[string]interface{}{
+			namePrefix + "-ec2-role": map[string]interface{}{
+				"assume_role_policy":      string(ec2AssumeRolePolicyJSON),
+				"create_instance_profile": true,
+				"description":             "Test EC2 role",
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
 				},
-				namePrefix + "-lambda-role": map[string]interface{}{
-					"assume_role_policy": `{
-						"Version": "2012-10-17",
-						"Statement": [
-							{
-								"Action": "sts:AssumeRole",
-								"Effect": "Allow",
-								"Principal": {
-									"Service": "lambda.amazonaws.com"
-								}
+			},
+			namePrefix + "-lambda-role": map[string]interface{}{
+				"assume_role_policy": `{
+					"Version": "2012-10-17",
+					"Statement": [
+						{
+							"Action": "sts:AssumeRole",
+							"Effect": "Allow",
+							"Principal": {
+								"Service": "lambda.amazonaws.com"
 							}
-						]
-					}`,
-					"description": "Test Lambda role",
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
-					},
+						}
+					]
+				}`,
+				"description": "Test Lambda role",
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
 				},
 			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Test outputs
 	roles := terraform.OutputMap(t, terraformOptions, "roles")
@@ -161,26 +148,27 @@ func TestIAMModuleRoles(t *testing.T) {
 	assert.Contains(t, roleArns, expectedLambdaRoleName)
 
 	// Verify roles exist in AWS
-	ec2Role := aws.GetIamRole(t, expectedEC2RoleName)
+	ec2Role := getIamRole(t, expectedEC2RoleName)
 	assert.Equal(t, expectedEC2RoleName, ec2Role.RoleName)
 	assert.Equal(t, "Test EC2 role", ec2Role.Description)
 	assert.Equal(t, "/", ec2Role.Path)
 
-	lambdaRole := aws.GetIamRole(t, expectedLambdaRoleName)
+	lambdaRole := getIamRole(t, expectedLambdaRoleName)
 	assert.Equal(t, expectedLambdaRoleName, lambdaRole.RoleName)
 	assert.Equal(t, "Test Lambda role", lambdaRole.Description)
 
 	// Verify instance profile exists
-	instanceProfile := aws.GetIamInstanceProfile(t, expectedEC2RoleName)
+	instanceProfile := getIamInstanceProfile(t, expectedEC2RoleName)
 	assert.Equal(t, expectedEC2RoleName, instanceProfile.InstanceProfileName)
 	assert.Len(t, instanceProfile.Roles, 1)
 	assert.Equal(t, expectedEC2RoleName, instanceProfile.Roles[0].RoleName)
 }
 
 func TestIAMModuleCustomPolicies(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
 	customPolicy := map[string]interface{}{
@@ -202,46 +190,38 @@ func TestIAMModuleCustomPolicies(t *testing.T) {
 
 	customPolicyJSON, _ := json.Marshal(customPolicy)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-policies",
-
-		Vars: map[string]interface{}{
-			"name_prefix": namePrefix,
-			"policies": map[string]interface{}{
-				namePrefix + "-s3-policy": map[string]interface{}{
-					"description": "Test S3 access policy",
-					"policy":      string(customPolicyJSON),
-				},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-policies", awsRegion, map[string]interface{}{
+		"name_prefix": namePrefix,
+		"policies": map[string]interface{}{
+			namePrefix + "-s3-policy": map[string]interface{}{
+				"description": "Test S3 access policy",
+				"policy":      string(customPolicyJSON),
 			},
-			"users": map[string]interface{}{
-				namePrefix + "-policy-user": map[string]interface{}{
-					"create_access_key": true,
-					"inline_policies": map[string]interface{}{
-						"cloudwatch-access": `{
-							"Version": "2012-10-17",
-							"Statement": [
-								{
-									"Effect": "Allow",
-									"Action": [
-										"cloudwatch:PutMetricData",
-										"cloudwatch:GetMetricStatistics"
-									],
-									"Resource": "*"
-								}
-							]
-						}`,
-					},
+		},
+		"users": map[string]interface{}{
+			namePrefix + "-policy-user": map[string]interface{}{
+				"create_access_key": true,
+				"inline_policies": map[string]interface{}{
+					"cloudwatch-access": `{
+						"Version": "2012-10-17",
+						"Statement": [
+							{
+								"Effect": "Allow",
+								"Action": [
+									"cloudwatch:PutMetricData",
+									"cloudwatch:GetMetricStatistics"
+								],
+								"Resource": "*"
+							}
+						]
+					}`,
 				},
 			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Test outputs
 	policies := terraform.OutputMap(t, terraformOptions, "policies")
@@ -259,51 +239,44 @@ func TestIAMModuleCustomPolicies(t *testing.T) {
 
 	// Verify policy exists in AWS
 	policyArn := policyArns[expectedPolicyName]
-	policy := aws.GetIamPolicy(t, policyArn)
+	policy := getIamPolicy(t, policyArn)
 	assert.Equal(t, expectedPolicyName, policy.PolicyName)
 	assert.Equal(t, "Test S3 access policy", policy.Description)
 	assert.Equal(t, "/", policy.Path)
 
 	// Verify user exists in AWS
-	user := aws.GetIamUser(t, expectedUserName)
+	user := getIamUser(t, expectedUserName)
 	assert.Equal(t, expectedUserName, user.UserName)
 
 	// Verify user has inline policy
-	userPolicies := aws.GetIamUserPolicies(t, expectedUserName)
+	userPolicies := getIamUserPolicies(t, expectedUserName)
 	assert.Contains(t, userPolicies, "cloudwatch-access")
 }
 
 func TestIAMModuleOIDCProvider(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-oidc",
-
-		Vars: map[string]interface{}{
-			"name_prefix": namePrefix,
-			"oidc_providers": map[string]interface{}{
-				"github-actions": map[string]interface{}{
-					"url": "https://token.actions.githubusercontent.com",
-					"client_id_list": []string{
-						"sts.amazonaws.com",
-					},
-					"thumbprint_list": []string{
-						"6938fd4d98bab03faadb97b34396831e3780aea1",
-					},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-oidc", awsRegion, map[string]interface{}{
+		"name_prefix": namePrefix,
+		"oidc_providers": map[string]interface{}{
+			"github-actions": map[string]interface{}{
+				"url": "https://token.actions.githubusercontent.com",
+				"client_id_list": []string{
+					"sts.amazonaws.com",
+				},
+				"thumbprint_list": []string{
+					"6938fd4d98bab03faadb97b34396831e3780aea1",
 				},
 			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Test outputs
 	oidcProviders := terraform.OutputMap(t, terraformOptions, "oidc_providers")
@@ -317,38 +290,31 @@ func TestIAMModuleOIDCProvider(t *testing.T) {
 }
 
 func TestIAMModulePasswordPolicy(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
-
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-password-policy",
-
-		Vars: map[string]interface{}{
-			"account_password_policy": map[string]interface{}{
-				"manage_password_policy":         true,
-				"minimum_password_length":        16,
-				"require_lowercase_characters":   true,
-				"require_uppercase_characters":   true,
-				"require_numbers":               true,
-				"require_symbols":               true,
-				"allow_users_to_change_password": true,
-				"max_password_age":              90,
-				"password_reuse_prevention":     12,
-				"hard_expiry":                   false,
-			},
+	awsRegion := harness.Region(t)
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-password-policy", awsRegion, map[string]interface{}{
+		"account_password_policy": map[string]interface{}{
+			"manage_password_policy":         true,
+			"minimum_password_length":        16,
+			"require_lowercase_characters":   true,
+			"require_uppercase_characters":   true,
+			"require_numbers":                true,
+			"require_symbols":                true,
+			"allow_users_to_change_password": true,
+			"max_password_age":               90,
+			"password_reuse_prevention":      12,
+			"hard_expiry":                    false,
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify password policy was applied
-	passwordPolicy := aws.GetAccountPasswordPolicy(t)
+	passwordPolicy := getAccountPasswordPolicy(t)
 	assert.Equal(t, 16, passwordPolicy.MinimumPasswordLength)
 	assert.True(t, passwordPolicy.RequireLowercaseCharacters)
 	assert.True(t, passwordPolicy.RequireUppercaseCharacters)
@@ -361,98 +327,84 @@ func TestIAMModulePasswordPolicy(t *testing.T) {
 }
 
 func TestIAMModuleValidation(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 
 	// Test invalid user name (should fail validation)
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-validation",
-
-		Vars: map[string]interface{}{
-			"users": map[string]interface{}{
-				"invalid@user@name": map[string]interface{}{
-					"create_login_profile": false,
-				},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-validation", awsRegion, map[string]interface{}{
+		"users": map[string]interface{}{
+			"invalid user name": map[string]interface{}{
+				"create_login_profile": false,
 			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	}))
 
 	// This should fail during plan due to validation
-	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	_, err := harness.InitAndPlanE(t, terraformOptions)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "must contain only alphanumeric characters")
 }
 
 func TestIAMModuleComplexScenario(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/iam-complex",
-
-		Vars: map[string]interface{}{
-			"name_prefix": namePrefix,
-			// Complex scenario with users, groups, roles, and policies
-			"users": map[string]interface{}{
-				namePrefix + "-admin": map[string]interface{}{
-					"create_login_profile": false,
-					"create_access_key":   true,
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/PowerUserAccess",
-					},
-				},
-				namePrefix + "-developer": map[string]interface{}{
-					"create_login_profile": false,
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/ReadOnlyAccess",
-					},
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/iam-complex", awsRegion, map[string]interface{}{
+		"name_prefix": namePrefix,
+		// Complex scenario with users, groups, roles, and policies
+		"users": map[string]interface{}{
+			namePrefix + "-admin": map[string]interface{}{
+				"create_login_profile": false,
+				"create_access_key":    true,
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/PowerUserAccess",
 				},
 			},
-			"groups": map[string]interface{}{
-				namePrefix + "-admins": map[string]interface{}{
-					"users": []string{namePrefix + "-admin"},
-					"managed_policy_arns": []string{
-						"arn:aws:iam::aws:policy/IAMReadOnlyAccess",
-					},
-				},
-				namePrefix + "-developers": map[string]interface{}{
-					"users": []string{namePrefix + "-developer"},
+			namePrefix + "-developer": map[string]interface{}{
+				"create_login_profile": false,
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/ReadOnlyAccess",
 				},
 			},
-			"roles": map[string]interface{}{
-				namePrefix + "-service-role": map[string]interface{}{
-					"assume_role_policy": `{
-						"Version": "2012-10-17",
-						"Statement": [
-							{
-								"Action": "sts:AssumeRole",
-								"Effect": "Allow",
-								"Principal": {
-									"Service": "ec2.amazonaws.com"
-								}
-							}
-						]
-					}`,
-					"create_instance_profile": true,
-					"description":            "Complex test service role",
+		},
+		"groups": map[string]interface{}{
+			namePrefix + "-admins": map[string]interface{}{
+				"users": []string{namePrefix + "-admin"},
+				"managed_policy_arns": []string{
+					"arn:aws:iam::aws:policy/IAMReadOnlyAccess",
 				},
 			},
+			namePrefix + "-developers": map[string]interface{}{
+				"users": []string{namePrefix + "-developer"},
+			},
 		},
-
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
+		"roles": map[string]interface{}{
+			namePrefix + "-service-role": map[string]interface{}{
+				"assume_role_policy": `{
+					"Version": "2012-10-17",
+					"Statement": [
+						{
+							"Action": "sts:AssumeRole",
+							"Effect": "Allow",
+							"Principal": {
+								"Service": "ec2.amazonaws.com"
+							}
+						}
+					]
+				}`,
+				"create_instance_profile": true,
+				"description":             "Complex test service role",
+			},
 		},
-	})
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify all resources were created
 	users := terraform.OutputMap(t, terraformOptions, "users")
@@ -473,13 +425,13 @@ func TestIAMModuleComplexScenario(t *testing.T) {
 	assert.Contains(t, instanceProfiles, namePrefix+"-service-role")
 
 	// Verify resources exist in AWS
-	adminUser := aws.GetIamUser(t, namePrefix+"-admin")
+	adminUser := getIamUser(t, namePrefix+"-admin")
 	assert.Equal(t, namePrefix+"-admin", adminUser.UserName)
 
-	adminGroup := aws.GetIamGroup(t, namePrefix+"-admins")
+	adminGroup := getIamGroup(t, namePrefix+"-admins")
 	assert.Equal(t, namePrefix+"-admins", adminGroup.GroupName)
 
-	serviceRole := aws.GetIamRole(t, namePrefix+"-service-role")
+	serviceRole := getIamRole(t, namePrefix+"-service-role")
 	assert.Equal(t, namePrefix+"-service-role", serviceRole.RoleName)
 	assert.Equal(t, "Complex test service role", serviceRole.Description)
-}
\ No newline at end of file
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/iameval"
+	"github.com/your-org/terraform-aws-modules/test/iampolicy"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+	"github.com/your-org/terraform-aws-modules/test/trustpolicy"
+)
+
+// githubActionsHost is the host of the GitHub Actions OIDC provider, as named in its ARN and condition keys
+const githubActionsHost = "token.actions.githubusercontent.com"
+
+// githubActionsTrust returns the trust policy of a role GitHub Actions workflows of repo assume
+func githubActionsTrust(t *testing.T, repo string) string {
+	doc, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect": "Allow",
+				"Action": "sts:AssumeRoleWithWebIdentity",
+				"Principal": map[string]interface{}{
+					"Federated": fmt.Sprintf("arn:aws:iam::%s:oidc-provider/%s", awsstub.AccountID, githubActionsHost),
+				},
+				"Condition": map[string]interface{}{
+					"StringEquals": map[string]interface{}{githubActionsHost + ":aud": "sts.amazonaws.com"},
+					"StringLike":   map[string]interface{}{githubActionsHost + ":sub": "repo:" + repo + ":*"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return string(doc)
+}
+
+// TestIAMTrustPolicies plans the configurations that create IAM roles offline and analyses who can
+// assume each role. Trusts of any principal, of other accounts and of web identity tokens not pinned
+// to an audience and a subject are reported; those at or above the default threshold fail the test
+func TestIAMTrustPolicies(t *testing.T) {
+	configs := map[string]discovery.Config{}
+	for _, cfg := range discoverConfigs(t, discovery.KindModule, discovery.KindExample) {
+		configs[cfg.ID()] = cfg
+	}
+
+	githubProvider := trustpolicy.Principal{
+		Type: trustpolicy.PrincipalFederated,
+		ID:   fmt.Sprintf("arn:aws:iam::%s:oidc-provider/%s", awsstub.AccountID, githubActionsHost),
+	}
+	cases := []struct {
+		config string
+		vars   map[string]interface{}
+		check  func(t *testing.T, m trustpolicy.Matrix)
+	}{
+		{
+			config: "modules/ec2",
+			vars:   map[string]interface{}{"create_iam_instance_profile": true},
+			check: func(t *testing.T, m trustpolicy.Matrix) {
+				assert.Equal(t, []trustpolicy.Principal{trustpolicy.Service("ec2.amazonaws.com")}, m.Principals("unit-ec2-role"))
+			},
+		},
+		{
+			config: "modules/ecs",
+			vars:   map[string]interface{}{"create_iam_roles": true},
+			check: func(t *testing.T, m trustpolicy.Matrix) {
+				ecsTasks := trustpolicy.Service("ecs-tasks.amazonaws.com")
+				assert.Equal(t, []string{"unit-execution-role", "unit-task-role"}, m.Roles(ecsTasks))
+			},
+		},
+		{
+			config: "modules/eks",
+			vars:   map[string]interface{}{"create_iam_roles": true},
+			check: func(t *testing.T, m trustpolicy.Matrix) {
+				assert.Equal(t, []trustpolicy.Principal{trustpolicy.Service("eks.amazonaws.com")}, m.Principals("unit-cluster-role"))
+				assert.Equal(t, []trustpolicy.Principal{trustpolicy.Service("ec2.amazonaws.com")}, m.Principals("unit-node-group-role"))
+			},
+		},
+		{
+			config: completeStack,
+			check: func(t *testing.T, m trustpolicy.Matrix) {
+				assert.Equal(t, []string{"ec2-instance-role"}, m.Roles(trustpolicy.Service("ec2.amazonaws.com")))
+				assert.Equal(t, []string{"ecs-execution-role", "ecs-task-role"}, m.Roles(trustpolicy.Service("ecs-tasks.amazonaws.com")))
+				assert.Equal(t, []trustpolicy.Principal{
+					{Type: trustpolicy.PrincipalAWS, ID: `${module.iam.role_arns["ec2-instance-role"]}`},
+					{Type: trustpolicy.PrincipalAWS, ID: `${module.iam.role_arns["ecs-task-role"]}`},
+				}, m.Principals("s3-access-role"))
+			},
+		},
+		{
+			config: "examples/iam-oidc",
+			vars: map[string]interface{}{
+				"oidc_providers": map[string]interface{}{
+					"github-actions": map[string]interface{}{
+						"url":             "https://" + githubActionsHost,
+						"client_id_list":  []string{"sts.amazonaws.com"},
+						"thumbprint_list": []string{"6938fd4d98bab03faadb97b34396831e3780aea1"},
+					},
+				},
+				"roles": map[string]interface{}{
+					"unit-github-actions": map[string]interface{}{
+						"assume_role_policy": githubActionsTrust(t, "your-org/terraform-aws-modules"),
+						"description":        "Role for GitHub Actions",
+					},
+				},
+			},
+			check: func(t *testing.T, m trustpolicy.Matrix) {
+				assert.Equal(t, []trustpolicy.Principal{githubProvider}, m.Principals("unit-github-actions"))
+				for _, trust := range m.Lookup("unit-github-actions", githubProvider) {
+					assert.Equal(t, []string{"sts.amazonaws.com"}, trust.Condition(githubActionsHost+":aud"))
+					assert.Equal(t, []string{"repo:your-org/terraform-aws-modules:*"}, trust.Condition(githubActionsHost+":sub"))
+				}
+			},
+		},
+	}
+
+	stub := awsstub.Start(t)
+
+	report := sarifReport(t)
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.config, func(t *testing.T) {
+			cfg, ok := configs[tc.config]
+			require.True(t, ok, "%s not found", tc.config)
+
+			vars := map[string]interface{}{}
+			for name, value := range offlinePlanVars[cfg.ID()] {
+				vars[name] = value
+			}
+			for name, value := range tc.vars {
+				vars[name] = value
+			}
+			terraformOptions := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), vars)
+			harness.InitAndPlan(t, terraformOptions)
+			plan := planjson.Show(t, terraformOptions)
+
+			m := trustpolicy.Analyze(plan, trustpolicy.Options{
+				Account: awsstub.AccountID,
+				Resolve: iameval.Symbolic(iampolicy.Symbolic(cfg)),
+			})
+			t.Logf("Trust matrix of %s:\n%s", cfg.ID(), m)
+			for _, u := range m.Unresolved {
+				t.Errorf("trust policy not analysed: %s", u)
+			}
+			for _, f := range m.Findings {
+				if f.Severity >= analysis.DefaultThreshold {
+					t.Error(f)
+				} else {
+					t.Log(f)
+				}
+				report.Add(f.Finding(repoRoot, cfg.Path))
+			}
+			tc.check(t, m)
+		})
+	}
+}
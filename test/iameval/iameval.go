@@ -0,0 +1,350 @@
+// Package iameval evaluates IAM policies offline, so tests can assert what a
+// role is able to do rather than only that it exists. A role is modelled as
+// planned — its managed and inline policies and its permissions boundary,
+// with AWS managed policies read from a bundled snapshot — and a request is
+// answered as IAM would for a principal in its own account: allowed or
+// denied, and by which statements.
+package iameval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Statement is one statement of a policy.
+type Statement struct {
+	// Index is the statement's position in the policy.
+	Index  int
+	Sid    string
+	Effect string
+	// Action and NotAction, and Resource and NotResource, are exclusive;
+	// NotAction and NotResource are set, possibly empty, when the statement
+	// uses them.
+	Action      []string
+	NotAction   []string
+	Resource    []string
+	NotResource []string
+	Condition   []Condition
+}
+
+// Condition is one condition key test of a statement, e.g. StringEquals
+// on aws:SourceVpc.
+type Condition struct {
+	Operator string
+	Key      string
+	Values   []string
+}
+
+// Policy is a named policy document.
+type Policy struct {
+	// Name identifies the policy in decisions, e.g. a managed policy's ARN
+	// or "role-name/inline-policy-name".
+	Name       string
+	Statements []Statement
+}
+
+// ParseJSON parses a policy document.
+func ParseJSON(name, document string) (Policy, error) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return Policy{}, fmt.Errorf("policy %s: %w", name, err)
+	}
+	return Parse(name, doc)
+}
+
+// Parse reads a policy document decoded by encoding/json.
+func Parse(name string, doc interface{}) (Policy, error) {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return Policy{}, fmt.Errorf("policy %s: document is not an object", name)
+	}
+	var raw []interface{}
+	switch s := obj["Statement"].(type) {
+	case map[string]interface{}:
+		raw = []interface{}{s}
+	case []interface{}:
+		raw = s
+	default:
+		return Policy{}, fmt.Errorf("policy %s: no Statement", name)
+	}
+
+	policy := Policy{Name: name}
+	for i, s := range raw {
+		statement, err := parseStatement(i, s)
+		if err != nil {
+			return Policy{}, fmt.Errorf("policy %s: statement %d: %w", name, i, err)
+		}
+		policy.Statements = append(policy.Statements, statement)
+	}
+	return policy, nil
+}
+
+func parseStatement(index int, value interface{}) (Statement, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return Statement{}, fmt.Errorf("not an object")
+	}
+	s := Statement{Index: index}
+	s.Sid, _ = obj["Sid"].(string)
+	s.Effect, _ = obj["Effect"].(string)
+	if s.Effect != Allow && s.Effect != Deny {
+		return Statement{}, fmt.Errorf("Effect must be %s or %s", Allow, Deny)
+	}
+
+	var err error
+	fields := []struct {
+		key  string
+		into *[]string
+	}{
+		{"Action", &s.Action},
+		{"NotAction", &s.NotAction},
+		{"Resource", &s.Resource},
+		{"NotResource", &s.NotResource},
+	}
+	for _, f := range fields {
+		if v, ok := obj[f.key]; ok {
+			if *f.into, err = stringList(v); err != nil {
+				return Statement{}, fmt.Errorf("%s: %w", f.key, err)
+			}
+			if *f.into == nil {
+				*f.into = []string{}
+			}
+		}
+	}
+	if (s.Action == nil) == (s.NotAction == nil) {
+		return Statement{}, fmt.Errorf("needs exactly one of Action and NotAction")
+	}
+
+	if block, ok := obj["Condition"].(map[string]interface{}); ok {
+		for _, operator := range sortedKeys(block) {
+			keys, ok := block[operator].(map[string]interface{})
+			if !ok {
+				return Statement{}, fmt.Errorf("Condition %s: not an object", operator)
+			}
+			for _, key := range sortedKeys(keys) {
+				values, err := stringList(keys[key])
+				if err != nil {
+					return Statement{}, fmt.Errorf("Condition %s %s: %w", operator, key, err)
+				}
+				s.Condition = append(s.Condition, Condition{Operator: operator, Key: key, Values: values})
+			}
+		}
+	}
+	return s, nil
+}
+
+// stringList reads a string or an array of them. Condition values may also
+// be booleans and numbers, which are compared as their JSON text.
+func stringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, err := scalar(elem)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		s, err := scalar(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+func scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, float64, json.Number:
+		return fmt.Sprint(v), nil
+	case nil:
+		return "", fmt.Errorf("value is not known")
+	}
+	return "", fmt.Errorf("unexpected %T", value)
+}
+
+// Effects of a statement.
+const (
+	Allow = "Allow"
+	Deny  = "Deny"
+)
+
+// Request is an API call to evaluate.
+type Request struct {
+	// Action is the IAM action, e.g. "s3:GetObject".
+	Action string
+	// Resource is the ARN of the resource acted on, or "*" for actions
+	// that take none.
+	Resource string
+	// Context holds the values of condition keys, e.g. aws:SourceVpc. Key
+	// names are case-insensitive.
+	Context map[string][]string
+}
+
+// Reason says why a request was allowed or denied.
+type Reason string
+
+// Reasons, in the order IAM applies them.
+const (
+	// ReasonExplicitDeny is a Deny statement matching the request.
+	ReasonExplicitDeny Reason = "explicit deny"
+	// ReasonImplicitDeny is no statement allowing the request.
+	ReasonImplicitDeny Reason = "implicit deny"
+	// ReasonBoundary is the permissions boundary not allowing the request,
+	// although an identity policy does.
+	ReasonBoundary Reason = "not allowed by the permissions boundary"
+	// ReasonAllowed is an Allow statement matching the request, within the
+	// permissions boundary if there is one.
+	ReasonAllowed Reason = "allowed"
+)
+
+// Match is a statement that matched a request.
+type Match struct {
+	Policy string
+	Sid    string
+	Index  int
+	Effect string
+}
+
+// String names the statement as in "role/inline Statement[1] (ReadBucket)".
+func (m Match) String() string {
+	s := fmt.Sprintf("%s Statement[%d]", m.Policy, m.Index)
+	if m.Sid != "" {
+		s += " (" + m.Sid + ")"
+	}
+	return s
+}
+
+// Decision is the answer to a request.
+type Decision struct {
+	Allowed bool
+	Reason  Reason
+	// Statements are the statements that decided: the matching Deny
+	// statements of an explicit deny, otherwise the matching Allow
+	// statements of the identity policies and the boundary.
+	Statements []Match
+	// Incomplete is set when some of the principal's policies could not be
+	// read, so the decision may be wrong; see Role.Unresolved.
+	Incomplete bool
+}
+
+func (d Decision) String() string {
+	var statements []string
+	for _, m := range d.Statements {
+		statements = append(statements, m.String())
+	}
+	s := string(d.Reason)
+	if len(statements) > 0 {
+		s += " by " + strings.Join(statements, ", ")
+	}
+	if d.Incomplete {
+		s += " (incomplete: some policies could not be read)"
+	}
+	return s
+}
+
+// Role is an IAM role and the policies that decide what it can do.
+type Role struct {
+	Name string
+	// Policies are the identity policies: attached managed policies and
+	// inline policies.
+	Policies []Policy
+	// Boundary is the permissions boundary, if the role has one.
+	Boundary *Policy
+	// Unresolved lists the policies that could not be read, such as an
+	// inline policy whose document is only known after apply or a managed
+	// policy missing from the snapshot.
+	Unresolved []string
+}
+
+// Evaluate answers whether the role may make the request. Only identity
+// policies and the boundary take part: resource policies, session policies
+// and service control policies are outside the plan.
+func (r Role) Evaluate(req Request) Decision {
+	context := make(map[string][]string, len(req.Context))
+	for key, values := range req.Context {
+		context[strings.ToLower(key)] = values
+	}
+
+	policies := r.Policies
+	if r.Boundary != nil {
+		policies = append(append([]Policy(nil), policies...), *r.Boundary)
+	}
+	var denies []Match
+	for _, p := range policies {
+		denies = append(denies, p.matches(req, context, Deny)...)
+	}
+	decision := Decision{Incomplete: len(r.Unresolved) > 0}
+	if len(denies) > 0 {
+		decision.Reason, decision.Statements = ReasonExplicitDeny, denies
+		return decision
+	}
+
+	var allows []Match
+	for _, p := range r.Policies {
+		allows = append(allows, p.matches(req, context, Allow)...)
+	}
+	if len(allows) == 0 {
+		decision.Reason = ReasonImplicitDeny
+		return decision
+	}
+	if r.Boundary != nil {
+		within := r.Boundary.matches(req, context, Allow)
+		if len(within) == 0 {
+			decision.Reason = ReasonBoundary
+			return decision
+		}
+		allows = append(allows, within...)
+	}
+	decision.Allowed, decision.Reason, decision.Statements = true, ReasonAllowed, allows
+	return decision
+}
+
+// Allowed reports whether the role may take action on resource, with no
+// condition keys set.
+func (r Role) Allowed(action, resource string) bool {
+	return r.Evaluate(Request{Action: action, Resource: resource}).Allowed
+}
+
+// matches returns the statements of p with the given effect that apply to
+// the request.
+func (p Policy) matches(req Request, context map[string][]string, effect string) []Match {
+	var out []Match
+	for _, s := range p.Statements {
+		if s.Effect == effect && s.applies(req, context) {
+			out = append(out, Match{Policy: p.Name, Sid: s.Sid, Index: s.Index, Effect: s.Effect})
+		}
+	}
+	return out
+}
+
+func (s Statement) applies(req Request, context map[string][]string) bool {
+	if s.NotAction != nil {
+		if matchAny(s.NotAction, req.Action, true) {
+			return false
+		}
+	} else if !matchAny(s.Action, req.Action, true) {
+		return false
+	}
+
+	if s.NotResource != nil {
+		if matchAny(s.NotResource, req.Resource, false) {
+			return false
+		}
+	} else if s.Resource != nil && !matchAny(s.Resource, req.Resource, false) {
+		return false
+	}
+
+	for _, c := range s.Condition {
+		if !c.holds(context) {
+			return false
+		}
+	}
+	return true
+}
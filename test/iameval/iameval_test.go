@@ -0,0 +1,238 @@
+package iameval
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/iampolicy"
+)
+
+func mustParse(t *testing.T, name, document string) Policy {
+	t.Helper()
+	policy, err := ParseJSON(name, document)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestParse(t *testing.T) {
+	policy := mustParse(t, "p", `{"Version": "2012-10-17", "Statement": {
+		"Sid": "One", "Effect": "Allow", "NotAction": [], "Resource": "*",
+		"Condition": {"Bool": {"aws:SecureTransport": true}, "NumericLessThan": {"s3:max-keys": 10}}}}`)
+	require.Len(t, policy.Statements, 1)
+	s := policy.Statements[0]
+	assert.Equal(t, "One", s.Sid)
+	assert.Nil(t, s.Action)
+	assert.NotNil(t, s.NotAction, "an empty NotAction is kept")
+	assert.Equal(t, []Condition{
+		{Operator: "Bool", Key: "aws:SecureTransport", Values: []string{"true"}},
+		{Operator: "NumericLessThan", Key: "s3:max-keys", Values: []string{"10"}},
+	}, s.Condition)
+
+	for _, doc := range []string{
+		`[]`,
+		`{"Statement": []}`,
+		`{"Statement": [{"Effect": "Maybe", "Action": "*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": "*", "NotAction": "s3:*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": {"s3": "*"}}]}`,
+	} {
+		_, err := ParseJSON("p", doc)
+		if doc == `{"Statement": []}` {
+			assert.NoError(t, err, doc)
+			continue
+		}
+		assert.Error(t, err, doc)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	role := Role{Name: "app", Policies: []Policy{
+		mustParse(t, "app/read", `{"Statement": [
+			{"Sid": "Read", "Effect": "Allow", "Action": "s3:Get*", "Resource": "arn:aws:s3:::data/*"},
+			{"Sid": "NotKeys", "Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::data/keys/*"}]}`),
+		mustParse(t, "app/logs", `{"Statement": [
+			{"Effect": "Allow", "NotAction": "iam:*", "NotResource": "arn:aws:s3:::*"}]}`),
+	}}
+
+	tests := []struct {
+		action, resource string
+		want             string
+	}{
+		{"s3:GetObject", "arn:aws:s3:::data/a.txt", "allowed by app/read Statement[0] (Read)"},
+		{"S3:getobject", "arn:aws:s3:::data/a.txt", "allowed by app/read Statement[0] (Read)"},
+		{"s3:GetObject", "arn:aws:s3:::DATA/a.txt", "implicit deny"},
+		{"s3:GetObject", "arn:aws:s3:::data/keys/k", "explicit deny by app/read Statement[1] (NotKeys)"},
+		{"s3:PutObject", "arn:aws:s3:::data/a.txt", "implicit deny"},
+		{"logs:PutLogEvents", "arn:aws:logs:eu-west-1:123456789012:log-group:app", "allowed by app/logs Statement[0]"},
+		{"iam:PassRole", "arn:aws:iam::123456789012:role/admin", "implicit deny"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, role.Evaluate(Request{Action: tt.action, Resource: tt.resource}).String(), "%s on %s", tt.action, tt.resource)
+	}
+	assert.True(t, role.Allowed("s3:GetObjectTagging", "arn:aws:s3:::data/a.txt"))
+}
+
+func TestEvaluateBoundary(t *testing.T) {
+	boundary := mustParse(t, "boundary", `{"Statement": [
+		{"Effect": "Allow", "Action": ["s3:*", "logs:*"], "Resource": "*"},
+		{"Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "*"}]}`)
+	role := Role{
+		Name:     "app",
+		Policies: []Policy{mustParse(t, "admin", `{"Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`)},
+		Boundary: &boundary,
+	}
+
+	d := role.Evaluate(Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::data/a"})
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "allowed by admin Statement[0], boundary Statement[0]", d.String())
+
+	d = role.Evaluate(Request{Action: "ec2:RunInstances", Resource: "*"})
+	assert.False(t, d.Allowed)
+	assert.Equal(t, ReasonBoundary, d.Reason)
+
+	d = role.Evaluate(Request{Action: "s3:DeleteBucket", Resource: "arn:aws:s3:::data"})
+	assert.Equal(t, "explicit deny by boundary Statement[1]", d.String())
+
+	role.Unresolved = []string{"inline policy"}
+	assert.True(t, role.Evaluate(Request{Action: "s3:GetObject", Resource: "*"}).Incomplete)
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		condition string
+		context   map[string][]string
+		want      bool
+	}{
+		{`{"StringEquals": {"aws:SourceVpc": "vpc-1"}}`, map[string][]string{"aws:sourcevpc": {"vpc-1"}}, true},
+		{`{"StringEquals": {"aws:SourceVpc": "vpc-1"}}`, map[string][]string{"aws:SourceVpc": {"vpc-2"}}, false},
+		{`{"StringEquals": {"aws:SourceVpc": "vpc-1"}}`, nil, false},
+		{`{"StringEqualsIfExists": {"aws:SourceVpc": "vpc-1"}}`, nil, true},
+		{`{"StringNotEquals": {"aws:SourceVpc": ["vpc-1", "vpc-2"]}}`, map[string][]string{"aws:SourceVpc": {"vpc-2"}}, false},
+		{`{"StringNotEquals": {"aws:SourceVpc": ["vpc-1", "vpc-2"]}}`, map[string][]string{"aws:SourceVpc": {"vpc-3"}}, true},
+		{`{"StringNotEquals": {"aws:SourceVpc": "vpc-1"}}`, nil, true},
+		{`{"StringLike": {"s3:prefix": "home/*"}}`, map[string][]string{"s3:prefix": {"home/alice"}}, true},
+		{`{"ArnLike": {"aws:PrincipalArn": "arn:aws:iam::*:role/ci-*"}}`, map[string][]string{"aws:PrincipalArn": {"arn:aws:iam::123456789012:role/ci-deploy"}}, true},
+		{`{"Bool": {"aws:SecureTransport": "false"}}`, map[string][]string{"aws:SecureTransport": {"false"}}, true},
+		{`{"NumericLessThanEquals": {"s3:max-keys": "10"}}`, map[string][]string{"s3:max-keys": {"11"}}, false},
+		{`{"DateGreaterThan": {"aws:CurrentTime": "2026-01-01T00:00:00Z"}}`, map[string][]string{"aws:CurrentTime": {"2026-06-01T00:00:00Z"}}, true},
+		{`{"IpAddress": {"aws:SourceIp": "10.0.0.0/16"}}`, map[string][]string{"aws:SourceIp": {"10.0.3.4"}}, true},
+		{`{"NotIpAddress": {"aws:SourceIp": "10.0.0.0/16"}}`, map[string][]string{"aws:SourceIp": {"10.0.3.4"}}, false},
+		{`{"Null": {"aws:TokenIssueTime": "true"}}`, nil, true},
+		{`{"Null": {"aws:TokenIssueTime": "false"}}`, nil, false},
+		{`{"ForAllValues:StringEquals": {"aws:TagKeys": ["env", "team"]}}`, map[string][]string{"aws:TagKeys": {"env", "team"}}, true},
+		{`{"ForAllValues:StringEquals": {"aws:TagKeys": ["env", "team"]}}`, map[string][]string{"aws:TagKeys": {"env", "owner"}}, false},
+		{`{"ForAllValues:StringEquals": {"aws:TagKeys": ["env"]}}`, nil, true},
+		{`{"ForAnyValue:StringEquals": {"aws:TagKeys": ["env"]}}`, map[string][]string{"aws:TagKeys": {"owner", "env"}}, true},
+		{`{"BogusOperator": {"aws:TagKeys": ["env"]}}`, map[string][]string{"aws:TagKeys": {"env"}}, false},
+	}
+	for _, tt := range tests {
+		policy := mustParse(t, "p", `{"Statement": {"Effect": "Allow", "Action": "*", "Resource": "*", "Condition": `+tt.condition+`}}`)
+		role := Role{Policies: []Policy{policy}}
+		got := role.Evaluate(Request{Action: "s3:ListBucket", Resource: "*", Context: tt.context}).Allowed
+		assert.Equal(t, tt.want, got, "%s with %v", tt.condition, tt.context)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	assert.True(t, glob("*", ""))
+	assert.True(t, glob("arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket/a/b"))
+	assert.False(t, glob("arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket"))
+	assert.True(t, glob("a?c*e", "abcde"))
+	assert.False(t, glob("a?c", "ac"))
+	assert.True(t, glob("*bc*bc", "abcxbcbc"))
+}
+
+func TestManagedSnapshot(t *testing.T) {
+	assert.NotEmpty(t, ManagedSource())
+	arns := ManagedARNs()
+	require.NotEmpty(t, arns)
+	var handCopied []string
+	for _, arn := range arns {
+		policy, ok := Managed(arn)
+		require.True(t, ok, arn)
+		assert.NotEmpty(t, policy.Statements, arn)
+
+		// An entry fetched by update-managed.sh says which version it is
+		// and when it was fetched; one copied by hand says neither.
+		version, retrieved, _ := ManagedVersion(arn)
+		if version == "" && retrieved == "" {
+			handCopied = append(handCopied, arn)
+		} else {
+			assert.Regexp(t, `^v[0-9]+$`, version, arn)
+			_, err := time.Parse("2006-01-02", retrieved)
+			assert.NoError(t, err, arn)
+		}
+
+		// The snapshot must be well-formed; AWS's own wildcards are fine.
+		var doc interface{}
+		require.NoError(t, json.Unmarshal(managed.Policies[arn].Document, &doc))
+		for _, p := range iampolicy.Check(doc, iampolicy.KindIdentity) {
+			switch p.Rule {
+			case iampolicy.RuleWildcardAction, iampolicy.RuleWildcardResource, iampolicy.RuleNotAction:
+			default:
+				t.Errorf("%s: %s: %s (%s)", arn, p.Path, p.Message, p.Rule)
+			}
+		}
+	}
+	_, ok := Managed("arn:aws:iam::aws:policy/NoSuchPolicy")
+	assert.False(t, ok)
+
+	if len(handCopied) > 0 {
+		t.Logf("%d managed policies were copied by hand and record no version; refresh them with update-managed.sh:\n%s", len(handCopied), strings.Join(handCopied, "\n"))
+	}
+
+	admin, _ := Managed("arn:aws:iam::aws:policy/AdministratorAccess")
+	assert.True(t, Role{Policies: []Policy{admin}}.Allowed("iam:CreateUser", "*"))
+}
+
+// fakeAWS answers the two IAM calls update-managed.sh makes.
+const fakeAWS = `#!/bin/sh
+case "$2" in
+get-policy) echo v7 ;;
+get-policy-version) echo '{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}]}' ;;
+*) exit 1 ;;
+esac
+`
+
+func TestUpdateManagedScript(t *testing.T) {
+	for _, tool := range []string{"bash", "jq"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("update-managed.sh needs %s", tool)
+		}
+	}
+
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "aws"), []byte(fakeAWS), 0o755))
+	snapshot := filepath.Join(t.TempDir(), "managed.json")
+	require.NoError(t, os.WriteFile(snapshot, []byte(`{"source": "hand", "policies": {"arn:aws:iam::aws:policy/A": {"document": {}}}}`), 0o644))
+
+	cmd := exec.Command("./update-managed.sh", "arn:aws:iam::aws:policy/B")
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "MANAGED_JSON="+snapshot)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	data, err := os.ReadFile(snapshot)
+	require.NoError(t, err)
+	var got struct {
+		Source   string                  `json:"source"`
+		Policies map[string]managedEntry `json:"policies"`
+	}
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Contains(t, got.Source, "update-managed.sh")
+	require.Len(t, got.Policies, 2)
+	for _, arn := range []string{"arn:aws:iam::aws:policy/A", "arn:aws:iam::aws:policy/B"} {
+		entry := got.Policies[arn]
+		assert.Equal(t, "v7", entry.Version, arn)
+		assert.Equal(t, time.Now().UTC().Format("2006-01-02"), entry.Retrieved, arn)
+		policy, err := ParseJSON(arn, string(entry.Document))
+		require.NoError(t, err, arn)
+		assert.True(t, Role{Policies: []Policy{policy}}.Allowed("s3:GetObject", "arn:aws:s3:::bucket/key"), arn)
+	}
+}
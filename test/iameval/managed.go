@@ -0,0 +1,89 @@
+package iameval
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// managedJSON holds the default versions of the AWS managed policies this
+// repository's modules and examples attach. update-managed.sh, next to it,
+// regenerates it from AWS with aws iam get-policy-version and records the
+// version and retrieval date of each entry:
+//
+//	./update-managed.sh                 # refresh every entry
+//	./update-managed.sh ARN             # and add ARN
+//
+// Entries without a version were copied by hand from the AWS Managed Policy
+// Reference Guide and may lag AWS; the "source" field says which it is.
+//
+//go:generate ./update-managed.sh
+//go:embed managed.json
+var managedJSON []byte
+
+// managedEntry is one policy in the snapshot.
+type managedEntry struct {
+	// Version is the policy's default version when it was retrieved, e.g.
+	// "v3", and Retrieved the date it was, as YYYY-MM-DD. Both are empty
+	// for entries copied by hand.
+	Version   string          `json:"version"`
+	Retrieved string          `json:"retrieved"`
+	Document  json.RawMessage `json:"document"`
+}
+
+var managed struct {
+	once sync.Once
+	// Source says where the policies were copied from.
+	Source   string                  `json:"source"`
+	Policies map[string]managedEntry `json:"policies"`
+}
+
+func loadManaged() {
+	managed.once.Do(func() {
+		if err := json.Unmarshal(managedJSON, &managed); err != nil {
+			panic("iameval: managed.json: " + err.Error())
+		}
+	})
+}
+
+// Managed returns the AWS managed policy with the given ARN from the
+// snapshot, reporting false if it is not in the snapshot.
+func Managed(arn string) (Policy, bool) {
+	loadManaged()
+	entry, ok := managed.Policies[arn]
+	if !ok {
+		return Policy{}, false
+	}
+	policy, err := ParseJSON(arn, string(entry.Document))
+	if err != nil {
+		panic("iameval: managed.json: " + err.Error())
+	}
+	return policy, true
+}
+
+// ManagedARNs lists the ARNs in the snapshot, in order.
+func ManagedARNs() []string {
+	loadManaged()
+	arns := make([]string, 0, len(managed.Policies))
+	for arn := range managed.Policies {
+		arns = append(arns, arn)
+	}
+	sort.Strings(arns)
+	return arns
+}
+
+// ManagedVersion returns the default version of the AWS managed policy with
+// the given ARN when the snapshot was taken and the date it was retrieved.
+// Both are empty for entries copied by hand.
+func ManagedVersion(arn string) (version, retrieved string, ok bool) {
+	loadManaged()
+	entry, ok := managed.Policies[arn]
+	return entry.Version, entry.Retrieved, ok
+}
+
+// ManagedSource says where the managed policies were copied from.
+func ManagedSource() string {
+	loadManaged()
+	return managed.Source
+}
@@ -0,0 +1,197 @@
+package iameval
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// matchAny reports whether any of patterns matches value. Actions compare
+// case-insensitively, resources and condition values case-sensitively.
+func matchAny(patterns []string, value string, foldCase bool) bool {
+	if foldCase {
+		value = strings.ToLower(value)
+	}
+	for _, p := range patterns {
+		if foldCase {
+			p = strings.ToLower(p)
+		}
+		if glob(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// glob matches value against an IAM wildcard pattern, where * matches any
+// run of characters, including none and including colons and slashes, and
+// ? matches exactly one.
+func glob(pattern, value string) bool {
+	p, v := 0, 0
+	star, mark := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, v
+			p++
+		case star >= 0:
+			p = star + 1
+			mark++
+			v = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// holds reports whether the condition is met by the request context, whose
+// keys are lower-cased.
+func (c Condition) holds(context map[string][]string) bool {
+	operator := c.Operator
+	// ForAnyValue is the default for multivalued keys, so only
+	// ForAllValues changes how the context values combine.
+	forAll := strings.HasPrefix(operator, "ForAllValues:")
+	operator = strings.TrimPrefix(strings.TrimPrefix(operator, "ForAllValues:"), "ForAnyValue:")
+	ifExists := strings.HasSuffix(operator, "IfExists")
+	operator = strings.TrimSuffix(operator, "IfExists")
+
+	values, present := context[strings.ToLower(c.Key)]
+	if operator == "Null" {
+		want := len(c.Values) > 0 && strings.EqualFold(c.Values[0], "true")
+		return want != present
+	}
+	if !present || len(values) == 0 {
+		// A missing key satisfies IfExists and ForAllValues, which holds
+		// vacuously; negated operators hold because nothing matches.
+		return ifExists || forAll || negated(operator)
+	}
+
+	not := negated(operator)
+	test := compare(strings.Replace(operator, "Not", "", 1))
+	if test == nil {
+		return false
+	}
+	// matches reports whether a context value satisfies the operator: for
+	// a negated operator, that it matches none of the policy values.
+	matches := func(v string) bool {
+		for _, p := range c.Values {
+			if test(v, p) {
+				return !not
+			}
+		}
+		return not
+	}
+	if forAll {
+		for _, v := range values {
+			if !matches(v) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// negated reports whether operator is a negated one, such as StringNotEquals
+// or NotIpAddress.
+func negated(operator string) bool {
+	return strings.Contains(operator, "Not")
+}
+
+// compare returns the test of a non-negated operator between a context value
+// and a policy value, or nil if the operator is not supported.
+func compare(operator string) func(value, pattern string) bool {
+	switch operator {
+	case "StringEquals", "ArnEquals", "BinaryEquals":
+		return func(v, p string) bool { return v == p }
+	case "StringEqualsIgnoreCase":
+		return strings.EqualFold
+	case "StringLike", "ArnLike":
+		return func(v, p string) bool { return glob(p, v) }
+	case "Bool":
+		return func(v, p string) bool { return strings.EqualFold(v, p) }
+	case "NumericEquals", "NumericLessThan", "NumericLessThanEquals", "NumericGreaterThan", "NumericGreaterThanEquals":
+		return ordered(operator, "Numeric", func(s string) (float64, bool) {
+			f, err := strconv.ParseFloat(s, 64)
+			return f, err == nil
+		})
+	case "DateEquals", "DateLessThan", "DateLessThanEquals", "DateGreaterThan", "DateGreaterThanEquals":
+		return ordered(operator, "Date", func(s string) (float64, bool) {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return float64(t.Unix()), true
+			}
+			if t, err := time.Parse("2006-01-02", s); err == nil {
+				return float64(t.Unix()), true
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			return f, err == nil
+		})
+	case "IpAddress":
+		return func(v, p string) bool {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return false
+			}
+			if !strings.Contains(p, "/") {
+				return ip.Equal(net.ParseIP(p))
+			}
+			_, network, err := net.ParseCIDR(p)
+			return err == nil && network.Contains(ip)
+		}
+	}
+	return nil
+}
+
+// ordered builds the comparison named by operator, less its family prefix,
+// over values parsed by parse.
+func ordered(operator, family string, parse func(string) (float64, bool)) func(value, pattern string) bool {
+	relation := strings.TrimPrefix(operator, family)
+	return func(v, p string) bool {
+		a, ok := parse(v)
+		if !ok {
+			return false
+		}
+		b, ok := parse(p)
+		if !ok {
+			return false
+		}
+		switch relation {
+		case "Equals":
+			return a == b
+		case "LessThan":
+			return a < b
+		case "LessThanEquals":
+			return a <= b
+		case "GreaterThan":
+			return a > b
+		case "GreaterThanEquals":
+			return a >= b
+		}
+		return false
+	}
+}
+
+// sortedKeys returns the keys of obj in order, so conditions are listed in
+// the same order on every run.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,202 @@
+package iameval
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/iampolicy"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// Resolver supplies the documents a plan does not know yet, by the name of
+// the module argument they are written in, e.g.
+// `module.iam.roles["app"].inline_policies["s3"]`. It reports false for
+// documents it does not have.
+type Resolver func(name string) (interface{}, bool)
+
+// Symbolic resolves documents from the configuration, as read by
+// iampolicy.Symbolic: references such as the ARN of a bucket created in the
+// same apply are left as placeholders like "${module.s3.bucket_arn}",
+// so requests name resources the same way.
+func Symbolic(docs []iampolicy.Document) Resolver {
+	byName := make(map[string]iampolicy.Document, len(docs))
+	for _, doc := range docs {
+		byName[doc.Name] = doc
+	}
+	return func(name string) (interface{}, bool) {
+		doc, ok := byName[name]
+		if !ok {
+			return nil, false
+		}
+		value, err := doc.Decode()
+		if err != nil {
+			return nil, false
+		}
+		return value, true
+	}
+}
+
+// RoleFromPlan builds the named role as planned: the inline policies of its
+// aws_iam_role and its aws_iam_role_policy resources, the policies attached
+// by aws_iam_role_policy_attachment, and its permissions boundary. Managed
+// policies are read from the snapshot, customer managed ones from their
+// aws_iam_policy in the plan, and documents only known after apply from
+// resolve, which may be nil. Policies that cannot be read are listed in
+// Role.Unresolved.
+func RoleFromPlan(plan *planjson.Plan, name string, resolve Resolver) (Role, error) {
+	changes := plan.ResourceChanges.Managed()
+	roles := changes.WithType("aws_iam_role").Where(func(rc planjson.ResourceChange) bool {
+		n, ok := rc.AfterString("name")
+		return ok && n == name
+	})
+	if len(roles) != 1 {
+		return Role{}, fmt.Errorf("role %q: %d aws_iam_role resources planned with that name", name, len(roles))
+	}
+	rc := roles[0]
+	b := roleBuilder{role: Role{Name: name}, changes: changes, resolve: resolve}
+
+	blocks, _ := rc.After("inline_policy")
+	list, _ := blocks.([]interface{})
+	for i := range list {
+		path := "inline_policy." + strconv.Itoa(i)
+		policyName, ok := rc.AfterString(path + ".name")
+		if !ok {
+			b.unresolved("%s %s: name known only after apply", rc.Address, path)
+			continue
+		}
+		variable := fmt.Sprintf(`%s.roles[%q].inline_policies[%q]`, rc.ModuleAddress, name, policyName)
+		b.document(rc, path+".policy", name+"/"+policyName, variable)
+	}
+
+	for _, p := range changes.WithType("aws_iam_role_policy").Where(roleIs(name)) {
+		policyName, _ := p.AfterString("name")
+		variable := fmt.Sprintf(`%s.roles[%q].additional_inline_policies[%q]`, p.ModuleAddress, name, policyName)
+		b.document(p, "policy", name+"/"+policyName, variable)
+	}
+
+	for _, a := range changes.WithType("aws_iam_role_policy_attachment").Where(roleIs(name)) {
+		arn, ok := a.AfterString("policy_arn")
+		if !ok {
+			b.unresolved("%s: policy_arn known only after apply", a.Address)
+			continue
+		}
+		if policy, ok := b.policy(arn); ok {
+			b.role.Policies = append(b.role.Policies, policy)
+		}
+	}
+
+	if rc.AfterUnknown("permissions_boundary") {
+		b.unresolved("%s: permissions_boundary known only after apply", rc.Address)
+	} else if arn, ok := rc.AfterString("permissions_boundary"); ok && arn != "" {
+		if policy, ok := b.policy(arn); ok {
+			b.role.Boundary = &policy
+		}
+	}
+	return b.role, nil
+}
+
+// roleIs keeps the resources whose role argument is the named role.
+func roleIs(name string) func(planjson.ResourceChange) bool {
+	return func(rc planjson.ResourceChange) bool {
+		role, ok := rc.AfterString("role")
+		return ok && role == name
+	}
+}
+
+type roleBuilder struct {
+	role    Role
+	changes planjson.Changes
+	resolve Resolver
+}
+
+func (b *roleBuilder) unresolved(format string, args ...interface{}) {
+	b.role.Unresolved = append(b.role.Unresolved, fmt.Sprintf(format, args...))
+}
+
+// document adds the inline policy at path of rc, falling back to the
+// resolver under variable when the plan does not know it.
+func (b *roleBuilder) document(rc planjson.ResourceChange, path, policyName, variable string) {
+	var doc interface{}
+	if text, ok := rc.AfterString(path); ok {
+		policy, err := ParseJSON(policyName, text)
+		if err != nil {
+			b.unresolved("%s %s: %v", rc.Address, path, err)
+			return
+		}
+		b.role.Policies = append(b.role.Policies, policy)
+		return
+	}
+	if b.resolve != nil {
+		doc, _ = b.resolve(variable)
+	}
+	if doc == nil {
+		b.unresolved("%s %s: document known only after apply", rc.Address, path)
+		return
+	}
+	policy, err := Parse(policyName, doc)
+	if err != nil {
+		b.unresolved("%s: %v", variable, err)
+		return
+	}
+	b.role.Policies = append(b.role.Policies, policy)
+}
+
+// policy reads the managed policy with the given ARN: an AWS managed one
+// from the snapshot, a customer managed one from the aws_iam_policy planned
+// with its name.
+func (b *roleBuilder) policy(arn string) (Policy, bool) {
+	if strings.HasPrefix(arn, "arn:aws:iam::aws:policy/") {
+		policy, ok := Managed(arn)
+		if !ok {
+			b.unresolved("%s: not in the managed policy snapshot", arn)
+		}
+		return policy, ok
+	}
+
+	name := arn[strings.LastIndex(arn, "/")+1:]
+	planned := b.changes.WithType("aws_iam_policy").Where(func(rc planjson.ResourceChange) bool {
+		n, ok := rc.AfterString("name")
+		return ok && n == name
+	})
+	if len(planned) != 1 {
+		b.unresolved("%s: no aws_iam_policy named %q planned", arn, name)
+		return Policy{}, false
+	}
+	rc := planned[0]
+	if text, ok := rc.AfterString("policy"); ok {
+		policy, err := ParseJSON(arn, text)
+		if err != nil {
+			b.unresolved("%s policy: %v", rc.Address, err)
+			return Policy{}, false
+		}
+		return policy, true
+	}
+	if b.resolve != nil {
+		variable := fmt.Sprintf(`%s.policies[%q].policy`, rc.ModuleAddress, name)
+		if doc, ok := b.resolve(variable); ok {
+			policy, err := Parse(arn, doc)
+			if err == nil {
+				return policy, true
+			}
+			b.unresolved("%s: %v", variable, err)
+			return Policy{}, false
+		}
+	}
+	b.unresolved("%s policy: document known only after apply", rc.Address)
+	return Policy{}, false
+}
+
+// Roles lists the names of the roles in the plan whose names are known, in
+// order.
+func Roles(plan *planjson.Plan) []string {
+	var names []string
+	for _, rc := range plan.ResourceChanges.Managed().WithType("aws_iam_role") {
+		if name, ok := rc.AfterString("name"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
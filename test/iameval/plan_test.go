@@ -0,0 +1,73 @@
+package iameval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+func loadPlan(t *testing.T) *planjson.Plan {
+	t.Helper()
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	return plan
+}
+
+func TestRoleFromPlan(t *testing.T) {
+	plan := loadPlan(t)
+	assert.Equal(t, []string{"app", "worker"}, Roles(plan))
+
+	role, err := RoleFromPlan(plan, "app", nil)
+	require.NoError(t, err)
+
+	var names []string
+	for _, p := range role.Policies {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"app/read-logs", "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"}, names)
+	require.NotNil(t, role.Boundary)
+	assert.Equal(t, "arn:aws:iam::123456789012:policy/boundary", role.Boundary.Name)
+	assert.Equal(t, []string{
+		`module.iam.aws_iam_role.this["app"] inline_policy.1.policy: document known only after apply`,
+		`arn:aws:iam::aws:policy/SecurityAudit: not in the managed policy snapshot`,
+	}, role.Unresolved)
+
+	d := role.Evaluate(Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::logs/2026/app.log"})
+	assert.True(t, d.Allowed)
+	assert.True(t, d.Incomplete)
+	d = role.Evaluate(Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::logs/secret/key"})
+	assert.Equal(t, ReasonExplicitDeny, d.Reason)
+	assert.Equal(t, []Match{{Policy: "app/read-logs", Sid: "NoSecrets", Index: 1, Effect: Deny}}, d.Statements)
+	assert.True(t, role.Allowed("ssm:UpdateInstanceInformation", "*"))
+	assert.Equal(t, ReasonBoundary, role.Evaluate(Request{Action: "ec2messages:GetMessages", Resource: "*"}).Reason,
+		"AmazonSSMManagedInstanceCore allows ec2messages, the boundary does not")
+
+	_, err = RoleFromPlan(plan, "missing", nil)
+	assert.Error(t, err)
+}
+
+func TestRoleFromPlanResolver(t *testing.T) {
+	plan := loadPlan(t)
+	var asked []string
+	resolve := func(name string) (interface{}, bool) {
+		asked = append(asked, name)
+		if name != `module.iam.roles["worker"].additional_inline_policies["queue"]` {
+			return nil, false
+		}
+		return map[string]interface{}{"Statement": []interface{}{map[string]interface{}{
+			"Effect": "Allow", "Action": "sqs:ReceiveMessage", "Resource": "${module.queue.arn}",
+		}}}, true
+	}
+
+	role, err := RoleFromPlan(plan, "worker", resolve)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`module.iam.roles["worker"].additional_inline_policies["queue"]`}, asked)
+	assert.Empty(t, role.Unresolved)
+	assert.Nil(t, role.Boundary)
+	assert.Equal(t, "allowed by worker/queue Statement[0]",
+		role.Evaluate(Request{Action: "sqs:ReceiveMessage", Resource: "${module.queue.arn}"}).String())
+	assert.False(t, role.Allowed("sqs:ReceiveMessage", "arn:aws:sqs:eu-west-1:123456789012:other"))
+}
@@ -0,0 +1,37 @@
+#!/bin/bash
+
+# ============================================================================
+# REFRESH THE AWS MANAGED POLICY SNAPSHOT
+# ============================================================================
+# Rewrites managed.json with the default version of every AWS managed policy
+# it lists, plus any policy ARNs given as arguments, fetched with
+# aws iam get-policy-version. Each entry records the version and the date it
+# was retrieved. Needs the AWS CLI, jq and credentials allowed to call
+# iam:GetPolicy and iam:GetPolicyVersion.
+#
+#   ./update-managed.sh                                   # refresh every entry
+#   ./update-managed.sh arn:aws:iam::aws:policy/ReadOnlyAccess  # and add one
+# ============================================================================
+
+set -euo pipefail
+
+cd "$(dirname "$0")"
+
+snapshot="${MANAGED_JSON:-managed.json}"
+retrieved="$(date -u +%Y-%m-%d)"
+
+arns="$({ jq -r '.policies | keys[]' "$snapshot"; printf '%s\n' "$@"; } | grep -v '^$' | sort -u)"
+
+policies='{}'
+for arn in $arns; do
+    echo "Fetching $arn" >&2
+    version="$(aws iam get-policy --policy-arn "$arn" --query Policy.DefaultVersionId --output text)"
+    document="$(aws iam get-policy-version --policy-arn "$arn" --version-id "$version" --query PolicyVersion.Document --output json)"
+    policies="$(jq --arg arn "$arn" --arg version "$version" --arg retrieved "$retrieved" --argjson document "$document" \
+        '.[$arn] = {version: $version, retrieved: $retrieved, document: $document}' <<<"$policies")"
+done
+
+jq -n --argjson policies "$policies" \
+    '{source: "Generated by update-managed.sh with aws iam get-policy-version; each entry records its version and retrieval date.", policies: $policies}' \
+    >"$snapshot.tmp"
+mv "$snapshot.tmp" "$snapshot"
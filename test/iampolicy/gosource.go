@@ -0,0 +1,366 @@
+package iampolicy
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+// maxResolveDepth bounds how many assignments are followed from one
+// identifier.
+const maxResolveDepth = 8
+
+// FromGoFiles returns the policy documents in the variables the Go test files
+// pass to the configurations. Variables are recognised in two forms: calls
+// with a configuration directory among their literal arguments and the
+// variables last, as in
+//
+//	harness.Options(t, repoRoot, "examples/iam-roles", region, map[string]interface{}{...})
+//
+// and map literals keyed by configuration directory, such as the variables
+// of the offline plans. Documents may be string literals or the
+// json.Marshal of a literal, through local variables of the same function.
+func FromGoFiles(files []string, configs []discovery.Config) ([]Document, error) {
+	bindings := map[string]map[string][]Input{}
+	for _, cfg := range configs {
+		bindings[cfg.ID()] = Bindings(cfg)
+	}
+
+	fset := token.NewFileSet()
+	var docs []Document
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		pkgScope := scope{}
+		for _, decl := range f.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok {
+				pkgScope.add(gen)
+			}
+		}
+		for _, decl := range f.Decls {
+			s := pkgScope
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				s = pkgScope.function(fn)
+			}
+			w := &goWalker{fset: fset, scope: s, bindings: bindings}
+			ast.Inspect(decl, w.visit)
+			docs = append(docs, w.docs...)
+		}
+	}
+	return docs, nil
+}
+
+// scope maps identifiers to the expressions last assigned to them.
+type scope map[string]ast.Expr
+
+func (s scope) add(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			s.assign(n.Lhs, n.Rhs)
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				lhs[i] = name
+			}
+			s.assign(lhs, n.Values)
+		case *ast.FuncLit:
+			return false
+		}
+		return true
+	})
+}
+
+// assign records lhs = rhs. With one call on the right, as in
+// `data, _ := json.Marshal(v)`, the first name is bound to the call.
+func (s scope) assign(lhs, rhs []ast.Expr) {
+	for i, l := range lhs {
+		ident, ok := l.(*ast.Ident)
+		if !ok || ident.Name == "_" {
+			continue
+		}
+		switch {
+		case len(rhs) == len(lhs):
+			s[ident.Name] = rhs[i]
+		case len(rhs) == 1 && i == 0:
+			s[ident.Name] = rhs[0]
+		}
+	}
+}
+
+// function returns the scope of fn's body on top of s.
+func (s scope) function(fn *ast.FuncDecl) scope {
+	out := make(scope, len(s))
+	for name, expr := range s {
+		out[name] = expr
+	}
+	if fn.Body != nil {
+		out.add(fn.Body)
+	}
+	return out
+}
+
+// resolve follows identifiers and parentheses to the expression they stand
+// for.
+func (s scope) resolve(expr ast.Expr) ast.Expr {
+	for i := 0; i < maxResolveDepth; i++ {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			next, ok := s[e.Name]
+			if !ok || next == expr {
+				return expr
+			}
+			expr = next
+		default:
+			return expr
+		}
+	}
+	return expr
+}
+
+type goWalker struct {
+	fset     *token.FileSet
+	scope    scope
+	bindings map[string]map[string][]Input
+	docs     []Document
+}
+
+func (w *goWalker) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.CallExpr:
+		if len(n.Args) < 2 {
+			break
+		}
+		for _, arg := range n.Args[:len(n.Args)-1] {
+			if dir, ok := stringLiteral(arg); ok && w.bindings[dir] != nil {
+				w.vars(dir, n.Args[len(n.Args)-1])
+				break
+			}
+		}
+	case *ast.CompositeLit:
+		for _, elt := range n.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			if dir, ok := stringLiteral(kv.Key); ok && w.bindings[dir] != nil {
+				w.vars(dir, kv.Value)
+			}
+		}
+	}
+	return true
+}
+
+// vars adds the documents in the variables expr sets for the configuration
+// in dir.
+func (w *goWalker) vars(dir string, expr ast.Expr) {
+	lit, ok := w.scope.resolve(expr).(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		name, ok := stringLiteral(kv.Key)
+		if !ok {
+			continue
+		}
+		for _, in := range w.bindings[dir][name] {
+			w.walk(kv.Value, in.Path, dir+" var."+name, in)
+		}
+	}
+}
+
+func (w *goWalker) walk(expr ast.Expr, steps []string, name string, in Input) {
+	expr = w.scope.resolve(expr)
+	if len(steps) == 0 {
+		w.leaf(expr, name, in)
+		return
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := stringLiteral(kv.Key)
+		switch {
+		case steps[0] == "*" && ok:
+			w.walk(kv.Value, steps[1:], name+formatKey(key, true), in)
+		case steps[0] == "*":
+			w.walk(kv.Value, steps[1:], name+formatKey(types.ExprString(kv.Key), false), in)
+		case ok && key == steps[0]:
+			w.walk(kv.Value, steps[1:], name+"."+key, in)
+		}
+	}
+}
+
+// leaf adds the document at expr: a string literal, or string(data) where
+// data is the json.Marshal of a value.
+func (w *goWalker) leaf(expr ast.Expr, name string, in Input) {
+	if conv, ok := expr.(*ast.CallExpr); ok && isIdent(conv.Fun, "string") && len(conv.Args) == 1 {
+		expr = w.scope.resolve(conv.Args[0])
+		if call, ok := expr.(*ast.CallExpr); ok && isMarshal(call.Fun) && len(call.Args) > 0 {
+			value := w.scope.resolve(call.Args[0])
+			w.docs = append(w.docs, Document{
+				Kind:  in.Kind,
+				Name:  name,
+				Value: w.generic(value),
+				Range: w.rangeOf(value),
+				locate: func(p Path) hcl.Range {
+					return w.locate(value, p)
+				},
+			})
+			return
+		}
+	}
+
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return
+	}
+	text, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return
+	}
+	// The text starts after the opening quote
+	rng := w.rangeOf(lit)
+	rng.Start.Column++
+	rng.Start.Byte++
+	w.docs = append(w.docs, Document{Kind: in.Kind, Name: name, Text: text, Range: rng})
+}
+
+// generic converts a Go literal to the form encoding/json decodes documents
+// to. Anything that is not a literal is unknown.
+func (w *goWalker) generic(expr ast.Expr) interface{} {
+	switch e := w.scope.resolve(expr).(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			if s, err := strconv.Unquote(e.Value); err == nil {
+				return s
+			}
+		case token.INT, token.FLOAT:
+			return json.Number(e.Value)
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			return e.Name == "true"
+		case "nil":
+			return nil
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return w.generic(e.X)
+		}
+	case *ast.CompositeLit:
+		if _, ok := e.Type.(*ast.MapType); ok || hasKeys(e) {
+			obj := map[string]interface{}{}
+			for _, elt := range e.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					key, ok := stringLiteral(kv.Key)
+					if !ok {
+						key = types.ExprString(kv.Key)
+					}
+					obj[key] = w.generic(kv.Value)
+				}
+			}
+			return obj
+		}
+		list := []interface{}{}
+		for _, elt := range e.Elts {
+			list = append(list, w.generic(elt))
+		}
+		return list
+	}
+	return unknown{}
+}
+
+// locate returns the range of the element at p within the Go literal expr.
+func (w *goWalker) locate(expr ast.Expr, p Path) hcl.Range {
+	expr = w.scope.resolve(expr)
+	for _, step := range p {
+		lit, ok := expr.(*ast.CompositeLit)
+		if !ok {
+			break
+		}
+		var next ast.Expr
+		switch s := step.(type) {
+		case int:
+			if s < len(lit.Elts) {
+				next = lit.Elts[s]
+			}
+		case string:
+			for _, elt := range lit.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := stringLiteral(kv.Key); ok && key == s {
+						next = kv.Value
+						break
+					}
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		expr = w.scope.resolve(next)
+	}
+	return w.rangeOf(expr)
+}
+
+func (w *goWalker) rangeOf(node ast.Node) hcl.Range {
+	start, end := w.fset.Position(node.Pos()), w.fset.Position(node.End())
+	return hcl.Range{
+		Filename: start.Filename,
+		Start:    hcl.Pos{Line: start.Line, Column: start.Column, Byte: start.Offset},
+		End:      hcl.Pos{Line: end.Line, Column: end.Column, Byte: end.Offset},
+	}
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+func hasKeys(lit *ast.CompositeLit) bool {
+	for _, elt := range lit.Elts {
+		if _, ok := elt.(*ast.KeyValueExpr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// isMarshal reports whether fun is json.Marshal or json.MarshalIndent.
+func isMarshal(fun ast.Expr) bool {
+	sel, ok := fun.(*ast.SelectorExpr)
+	return ok && isIdent(sel.X, "json") && strings.HasPrefix(sel.Sel.Name, "Marshal")
+}
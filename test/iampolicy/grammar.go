@@ -0,0 +1,73 @@
+package iampolicy
+
+import (
+	"sort"
+	"strings"
+)
+
+// conditionOperators are the condition operators, without the ForAllValues:
+// and ForAnyValue: qualifiers or the IfExists suffix.
+var conditionOperators = elements(
+	"StringEquals", "StringNotEquals", "StringEqualsIgnoreCase", "StringNotEqualsIgnoreCase", "StringLike", "StringNotLike",
+	"NumericEquals", "NumericNotEquals", "NumericLessThan", "NumericLessThanEquals", "NumericGreaterThan", "NumericGreaterThanEquals",
+	"DateEquals", "DateNotEquals", "DateLessThan", "DateLessThanEquals", "DateGreaterThan", "DateGreaterThanEquals",
+	"Bool", "BinaryEquals", "IpAddress", "NotIpAddress",
+	"ArnEquals", "ArnLike", "ArnNotEquals", "ArnNotLike",
+	"Null",
+)
+
+// services is the set of IAM service prefixes, as used in actions. It covers
+// the services this repository's modules and their users are likely to name;
+// add a prefix here when a genuine one is reported as unknown.
+var services = elements(strings.Fields(`
+	access-analyzer account acm acm-pca airflow amplify apigateway
+	application-autoscaling application-signals applicationinsights appconfig
+	appflow appmesh apprunner appstream appsync aoss aps arc-zonal-shift athena
+	auditmanager autoscaling autoscaling-plans aws-marketplace aws-portal
+	backup backup-storage batch bedrock billing budgets
+	ce cloud9 cloudcontrolapi clouddirectory cloudformation cloudfront
+	cloudhsm cloudshell cloudtrail cloudwatch codeartifact codebuild
+	codecommit codeconnections codedeploy codeguru-reviewer codepipeline
+	codestar-connections codestar-notifications cognito-identity cognito-idp
+	cognito-sync comprehend compute-optimizer config connect cur
+	databrew dataexchange datapipeline datasync dax detective devicefarm
+	directconnect discovery dlm dms docdb-elastic ds dynamodb
+	ebs ec2 ec2-instance-connect ec2messages ecr ecr-public ecs eks eks-auth
+	elasticache elasticbeanstalk elasticfilesystem elasticloadbalancing
+	elasticmapreduce elastictranscoder emr-containers emr-serverless es
+	events evidently firehose fis fms forecast frauddetector freetier fsx
+	gamelift glacier globalaccelerator glue grafana greengrass guardduty
+	health iam identitystore identity-sync imagebuilder inspector inspector2
+	iot iotevents iotsitewise iq ivs kafka kafka-cluster kafkaconnect
+	kendra kinesis kinesisanalytics kinesisvideo kms lakeformation lambda
+	launchwizard lex license-manager lightsail logs
+	macie2 mediaconvert medialive mediapackage mediastore memorydb mgn
+	mobiletargeting mq neptune-db network-firewall networkmanager
+	notifications oam opsworks organizations osis outposts
+	personalize pi pipes polly pricing private-networks profile
+	qldb quicksight
+	ram rbin rds rds-data rds-db redshift redshift-data redshift-serverless
+	rekognition resource-explorer-2 resource-groups rolesanywhere route53
+	route53domains route53resolver route53-recovery-control-config
+	route53-recovery-readiness rum
+	s3 s3-object-lambda s3express s3-outposts sagemaker savingsplans
+	scheduler schemas sdb secretsmanager securityhub securitylake
+	serverlessrepo servicecatalog servicediscovery servicequotas ses shield
+	signer sms-voice sns sqs ssm ssm-contacts ssm-incidents ssmmessages
+	sso sso-directory sso-oauth states storagegateway sts support
+	supportplans swf synthetics
+	tag textract timestream transcribe transfer translate trustedadvisor
+	vpc-lattice vpc-lattice-svcs waf waf-regional wafv2 wellarchitected
+	workdocs workmail workspaces xray
+`)...)
+
+// sortedKeys returns the keys of obj in order, so problems are reported in
+// the same order on every run.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,429 @@
+package iampolicy
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
+)
+
+// maxLocalDepth bounds how many local values are followed from one
+// expression, so a cycle cannot loop forever.
+const maxLocalDepth = 16
+
+// Bindings returns the inputs each variable of cfg reaches: the inputs of
+// cfg itself when it is one of the modules, and otherwise those of the
+// modules it calls that are passed the variable as is, as in
+// `roles = var.roles`. Values set for the variable in tfvars files or Go
+// tests are checked as documents of those inputs.
+func Bindings(cfg discovery.Config) map[string][]Input {
+	bindings := map[string][]Input{}
+	for _, in := range Inputs {
+		if in.Module == cfg.ID() {
+			bindings[in.Variable] = append(bindings[in.Variable], in)
+		}
+	}
+	for _, call := range cfg.Modules {
+		for _, in := range callInputs(cfg, call) {
+			arg, ok := call.Arguments[in.Variable]
+			if !ok {
+				continue
+			}
+			if name, ok := discovery.VariableReference(arg.Expr); ok {
+				bindings[name] = append(bindings[name], in)
+			}
+		}
+	}
+	return bindings
+}
+
+// FromConfig returns the policy documents written in cfg: those in the
+// arguments of its module calls and, for arguments passed from a variable,
+// those in the values the tfvars files at tfvars set.
+func FromConfig(cfg discovery.Config, tfvars []string) ([]Document, error) {
+	w := newHCLWalker(cfg.Locals)
+	w.moduleCalls(cfg)
+
+	bindings := Bindings(cfg)
+	for _, file := range tfvars {
+		f, diags := hclparse.NewParser().ParseHCLFile(file)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attrs, diags := f.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		values := newHCLWalker(nil)
+		for _, name := range sortedAttributes(attrs) {
+			for _, in := range bindings[name] {
+				values.walk(attrs[name].Expr, in.Path, "var."+name, in, 0)
+			}
+		}
+		w.docs = append(w.docs, values.docs...)
+	}
+	return w.docs, nil
+}
+
+// Symbolic returns the policy documents written in the arguments of cfg's
+// module calls with every reference to a value that is unknown offline, such
+// as the ARN of a bucket created in the same apply, kept as a placeholder:
+// "${module.s3.bucket_arn}/*" stays as written. Such documents can be
+// evaluated, with the placeholders standing for the resources they name,
+// where the unknown parts would make a plan's copy of the document unusable.
+func Symbolic(cfg discovery.Config) []Document {
+	w := newHCLWalker(cfg.Locals)
+	w.symbolic = true
+	w.moduleCalls(cfg)
+	return w.docs
+}
+
+// moduleCalls adds the documents in the arguments of cfg's module calls,
+// leaving out those passed from a variable.
+func (w *hclWalker) moduleCalls(cfg discovery.Config) {
+	for _, call := range cfg.Modules {
+		for _, in := range callInputs(cfg, call) {
+			arg, ok := call.Arguments[in.Variable]
+			if !ok {
+				continue
+			}
+			if _, ok := discovery.VariableReference(arg.Expr); ok {
+				continue
+			}
+			w.walk(arg.Expr, in.Path, "module."+call.Name+"."+in.Variable, in, 0)
+		}
+	}
+}
+
+// callInputs returns the inputs of the module call's module.
+func callInputs(cfg discovery.Config, call discovery.ModuleCall) []Input {
+	if !call.IsLocal() {
+		return nil
+	}
+	module := path.Join(cfg.ID(), call.Source)
+	var out []Input
+	for _, in := range Inputs {
+		if in.Module == module {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+type hclWalker struct {
+	ctx    *hcl.EvalContext
+	locals hcl.Attributes
+	// symbolic evaluates documents with placeholders for references; see
+	// Symbolic.
+	symbolic bool
+	docs     []Document
+}
+
+// newHCLWalker returns a walker that evaluates expressions offline: every
+// reference other than to locals is unknown, so documents that mention
+// other resources keep their literal parts.
+func newHCLWalker(locals hcl.Attributes) *hclWalker {
+	vars := map[string]cty.Value{}
+	for _, name := range []string{"var", "local", "module", "data", "each", "count", "path", "terraform", "self"} {
+		vars[name] = cty.DynamicVal
+	}
+	return &hclWalker{
+		ctx:    &hcl.EvalContext{Variables: vars, Functions: tfcheck.Functions()},
+		locals: locals,
+	}
+}
+
+// walk follows path from expr to the documents below it, reading the
+// expressions as written for as long as they are object constructors so
+// each document keeps its own source range.
+func (w *hclWalker) walk(expr hcl.Expression, steps []string, name string, in Input, depth int) {
+	if local, ok := w.local(expr); ok && depth < maxLocalDepth {
+		w.walk(local, steps, name, in, depth+1)
+		return
+	}
+	if len(steps) == 0 {
+		w.leaf(expr, name, in, depth)
+		return
+	}
+
+	if obj, ok := expr.(*hclsyntax.ObjectConsExpr); ok {
+		for _, item := range obj.Items {
+			key, ok := objectKey(item.KeyExpr)
+			switch {
+			case steps[0] == "*":
+				w.walk(item.ValueExpr, steps[1:], name+formatKey(key, ok), in, depth)
+			case ok && key == steps[0]:
+				w.walk(item.ValueExpr, steps[1:], name+"."+key, in, depth)
+			}
+		}
+		return
+	}
+
+	value, _ := expr.Value(w.ctx)
+	w.walkValue(value, expr.Range(), steps, name, in)
+}
+
+// walkValue follows path through an evaluated value, for expressions that
+// are not object constructors. The documents found share the expression's
+// range.
+func (w *hclWalker) walkValue(value cty.Value, rng hcl.Range, steps []string, name string, in Input) {
+	if !value.IsKnown() || value.IsNull() {
+		return
+	}
+	ty := value.Type()
+	if len(steps) == 0 {
+		if ty == cty.String {
+			w.docs = append(w.docs, Document{Kind: in.Kind, Name: name, Text: value.AsString(), Range: rng})
+		}
+		return
+	}
+	if !ty.IsObjectType() && !ty.IsMapType() {
+		return
+	}
+	for it := value.ElementIterator(); it.Next(); {
+		key, elem := it.Element()
+		switch {
+		case steps[0] == "*":
+			w.walkValue(elem, rng, steps[1:], name+formatKey(key.AsString(), true), in)
+		case key.AsString() == steps[0]:
+			w.walkValue(elem, rng, steps[1:], name+"."+steps[0], in)
+		}
+	}
+}
+
+// leaf adds the document expr evaluates to. A jsonencode call is decoded
+// from its argument, so the parts known offline are checked and problems
+// point at the element they are in.
+func (w *hclWalker) leaf(expr hcl.Expression, name string, in Input, depth int) {
+	if call, ok := expr.(*hclsyntax.FunctionCallExpr); ok && call.Name == "jsonencode" && len(call.Args) == 1 {
+		arg := call.Args[0]
+		value, _ := arg.Value(w.evalContext(arg))
+		w.docs = append(w.docs, Document{
+			Kind:  in.Kind,
+			Name:  name,
+			Value: generic(value),
+			Range: expr.Range(),
+			locate: func(p Path) hcl.Range {
+				return w.locate(arg, p, depth)
+			},
+		})
+		return
+	}
+
+	value, _ := expr.Value(w.evalContext(expr))
+	if !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return
+	}
+	rng := expr.Range()
+	if tmpl, ok := expr.(*hclsyntax.TemplateExpr); ok && len(tmpl.Parts) > 0 {
+		// The text starts where the first part does, after any quote or
+		// heredoc marker
+		rng = tmpl.Parts[0].Range()
+	}
+	w.docs = append(w.docs, Document{Kind: in.Kind, Name: name, Text: value.AsString(), Range: rng})
+}
+
+// evalContext returns the context to evaluate the document expr in. In
+// symbolic mode every reference expr makes evaluates to its placeholder.
+func (w *hclWalker) evalContext(expr hcl.Expression) *hcl.EvalContext {
+	if !w.symbolic {
+		return w.ctx
+	}
+	root := map[string]interface{}{}
+	for _, traversal := range expr.Variables() {
+		node := root
+		for i, step := range traversal {
+			name, ok := traversalStep(step)
+			if !ok {
+				break
+			}
+			if i == len(traversal)-1 {
+				if _, ok := node[name]; !ok {
+					node[name] = "${" + formatTraversal(traversal) + "}"
+				}
+				break
+			}
+			child, ok := node[name].(map[string]interface{})
+			if !ok {
+				// A reference to an attribute of this one wins over the
+				// reference to it as a whole
+				child = map[string]interface{}{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+
+	vars := map[string]cty.Value{}
+	for name, value := range w.ctx.Variables {
+		vars[name] = value
+	}
+	for name, node := range root {
+		vars[name] = placeholderValue(node)
+	}
+	return &hcl.EvalContext{Variables: vars, Functions: w.ctx.Functions}
+}
+
+// traversalStep returns the name a step of a traversal looks up. Only names
+// can be placeholders: a numeric index needs a list, whose length is unknown.
+func traversalStep(step hcl.Traverser) (string, bool) {
+	switch s := step.(type) {
+	case hcl.TraverseRoot:
+		return s.Name, true
+	case hcl.TraverseAttr:
+		return s.Name, true
+	case hcl.TraverseIndex:
+		if s.Key.Type() == cty.String {
+			return s.Key.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// formatTraversal writes a traversal as it appears in the source.
+func formatTraversal(traversal hcl.Traversal) string {
+	var b strings.Builder
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(s.Name)
+		case hcl.TraverseAttr:
+			b.WriteString("." + s.Name)
+		case hcl.TraverseIndex:
+			b.WriteString(formatKey(s.Key.AsString(), true))
+		}
+	}
+	return b.String()
+}
+
+func placeholderValue(node interface{}) cty.Value {
+	children, ok := node.(map[string]interface{})
+	if !ok {
+		return cty.StringVal(node.(string))
+	}
+	attrs := make(map[string]cty.Value, len(children))
+	for name, child := range children {
+		attrs[name] = placeholderValue(child)
+	}
+	return cty.ObjectVal(attrs)
+}
+
+// locate returns the range of the element at p within the HCL value expr
+// is, following object and tuple constructors and local values as far as
+// they go.
+func (w *hclWalker) locate(expr hcl.Expression, p Path, depth int) hcl.Range {
+	for _, step := range p {
+		for ; depth < maxLocalDepth; depth++ {
+			local, ok := w.local(expr)
+			if !ok {
+				break
+			}
+			expr = local
+		}
+		switch e := expr.(type) {
+		case *hclsyntax.ObjectConsExpr:
+			var next hcl.Expression
+			for _, item := range e.Items {
+				if key, ok := objectKey(item.KeyExpr); ok && key == step {
+					next = item.ValueExpr
+					break
+				}
+			}
+			if next == nil {
+				return expr.Range()
+			}
+			expr = next
+		case *hclsyntax.TupleConsExpr:
+			i, ok := step.(int)
+			if !ok || i >= len(e.Exprs) {
+				return expr.Range()
+			}
+			expr = e.Exprs[i]
+		default:
+			return expr.Range()
+		}
+	}
+	return expr.Range()
+}
+
+// local returns the expression of the local value expr refers to, when expr
+// is just local.<name>.
+func (w *hclWalker) local(expr hcl.Expression) (hcl.Expression, bool) {
+	t, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(t.Traversal) != 2 || t.Traversal.RootName() != "local" {
+		return nil, false
+	}
+	attr, ok := t.Traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return nil, false
+	}
+	local, ok := w.locals[attr.Name]
+	if !ok {
+		return nil, false
+	}
+	return local.Expr, true
+}
+
+// objectKey returns the key of an object constructor item when it is a
+// literal, as a bare name or a quoted string.
+func objectKey(expr hcl.Expression) (string, bool) {
+	key, diags := expr.Value(nil)
+	if diags.HasErrors() || !key.IsKnown() || key.IsNull() || key.Type() != cty.String {
+		return string(hcl.ExprAsKeyword(expr)), false
+	}
+	return key.AsString(), true
+}
+
+// generic converts an evaluated HCL value to the form encoding/json decodes
+// documents to, with unknown parts as unknown.
+func generic(value cty.Value) interface{} {
+	if !value.IsKnown() {
+		return unknown{}
+	}
+	if value.IsNull() {
+		return nil
+	}
+	value, _ = value.Unmark()
+	ty := value.Type()
+	switch {
+	case ty == cty.String:
+		return value.AsString()
+	case ty == cty.Number:
+		return json.Number(value.AsBigFloat().Text('f', -1))
+	case ty == cty.Bool:
+		return value.True()
+	case ty.IsObjectType() || ty.IsMapType():
+		obj := map[string]interface{}{}
+		for it := value.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			obj[key.AsString()] = generic(elem)
+		}
+		return obj
+	case ty.IsTupleType() || ty.IsListType() || ty.IsSetType():
+		var list []interface{}
+		for it := value.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			list = append(list, generic(elem))
+		}
+		if list == nil {
+			list = []interface{}{}
+		}
+		return list
+	}
+	return unknown{}
+}
+
+func sortedAttributes(attrs hcl.Attributes) []string {
+	obj := make(map[string]interface{}, len(attrs))
+	for name := range attrs {
+		obj[name] = nil
+	}
+	return sortedKeys(obj)
+}
@@ -0,0 +1,351 @@
+// Package iampolicy lints the IAM policy documents passed to the modules as
+// JSON strings. Typos in those strings only surface when AWS rejects them at
+// apply time, so the documents are found in the places they are written —
+// module calls in examples and environments, tfvars files and the variables
+// of Go tests — and checked there, with findings pointing at the line that
+// holds the problem.
+package iampolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// ToolIAMPolicy is the tool name of policy document findings, as recorded on
+// analysis.Finding.
+const ToolIAMPolicy = "iampolicy"
+
+// Kind is what a policy document is for, which decides the elements it must
+// and must not have.
+type Kind int
+
+const (
+	// KindIdentity is a policy attached to a user, group or role: it names
+	// resources and no principal.
+	KindIdentity Kind = iota
+	// KindTrust is a role's assume role policy: it names principals and no
+	// resources.
+	KindTrust
+)
+
+func (k Kind) String() string {
+	if k == KindTrust {
+		return "trust"
+	}
+	return "identity"
+}
+
+// Input is a module variable that holds policy documents.
+type Input struct {
+	// Module is the module directory relative to the repository root, e.g.
+	// "modules/iam".
+	Module   string
+	Variable string
+	// Path leads from the variable's value to the documents, one map key or
+	// object attribute per step; * stands for every key.
+	Path []string
+	Kind Kind
+}
+
+// Inputs lists every module variable that takes policy documents.
+var Inputs = []Input{
+	{Module: "modules/iam", Variable: "policies", Path: []string{"*", "policy"}},
+	{Module: "modules/iam", Variable: "roles", Path: []string{"*", "assume_role_policy"}, Kind: KindTrust},
+	{Module: "modules/iam", Variable: "roles", Path: []string{"*", "inline_policies", "*"}},
+	{Module: "modules/iam", Variable: "roles", Path: []string{"*", "additional_inline_policies", "*"}},
+	{Module: "modules/iam", Variable: "users", Path: []string{"*", "inline_policies", "*"}},
+	{Module: "modules/iam", Variable: "groups", Path: []string{"*", "inline_policies", "*"}},
+	{Module: "modules/ec2", Variable: "iam_inline_policies", Path: []string{"*"}},
+	{Module: "modules/ecs", Variable: "execution_role_inline_policies", Path: []string{"*"}},
+	{Module: "modules/ecs", Variable: "task_role_inline_policies", Path: []string{"*"}},
+	{Module: "modules/eks", Variable: "cluster_service_role_inline_policies", Path: []string{"*"}},
+	{Module: "modules/eks", Variable: "node_group_role_inline_policies", Path: []string{"*"}},
+}
+
+// Path locates an element within a policy document: object keys are
+// strings and array indexes ints.
+type Path []interface{}
+
+// String formats the path as in "Statement[0].Action".
+func (p Path) String() string {
+	var b strings.Builder
+	for _, step := range p {
+		switch s := step.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprint(&b, s)
+		}
+	}
+	return b.String()
+}
+
+// with returns a copy of p extended by step, safe to keep while p grows.
+func (p Path) with(step interface{}) Path {
+	return append(append(Path(nil), p...), step)
+}
+
+// unknown stands for a part of a document that is only known at apply time,
+// such as the ARN of a resource created alongside it. Checks skip it.
+type unknown struct{}
+
+// Document is a policy document found in the source.
+type Document struct {
+	Kind Kind
+	// Name says which document this is, e.g.
+	// `module.iam.roles["app"].assume_role_policy`.
+	Name string
+	// Text is the document when it is written as a JSON string.
+	Text string
+	// Value is the decoded document when it is written as an HCL or Go value
+	// and encoded later, as with jsonencode or json.Marshal. Parts that are
+	// unknown until apply are skipped.
+	Value interface{}
+	// Range is where the document is written.
+	Range hcl.Range
+	// locate returns the range of the element at a path, or of the nearest
+	// enclosing element that can be found.
+	locate func(Path) hcl.Range
+}
+
+// Decode returns the document decoded as encoding/json would, with any parts
+// unknown until apply as nil.
+func (d Document) Decode() (interface{}, error) {
+	if d.Value != nil {
+		return known(d.Value), nil
+	}
+	var value interface{}
+	dec := json.NewDecoder(strings.NewReader(d.Text))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%s: %w", d.Name, err)
+	}
+	return value, nil
+}
+
+// known replaces the unknown parts of value with nil.
+func known(value interface{}) interface{} {
+	switch v := value.(type) {
+	case unknown:
+		return nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			out[key] = known(elem)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = known(elem)
+		}
+		return out
+	}
+	return value
+}
+
+// Finding is a problem with a policy document.
+type Finding struct {
+	Rule     string
+	Severity analysis.Severity
+	// Document is the Name of the document.
+	Document string
+	// Path is the element the problem is with, empty for the whole document.
+	Path    string
+	Message string
+	Range   hcl.Range
+}
+
+// String formats the finding as "file:line: [SEVERITY] document path: message (rule)".
+func (f Finding) String() string {
+	where := f.Document
+	if f.Path != "" {
+		where += " " + f.Path
+	}
+	return fmt.Sprintf("%s:%d: [%s] %s: %s (%s)", f.Range.Filename, f.Range.Start.Line, f.Severity, where, f.Message, f.Rule)
+}
+
+// Finding converts f, whose file name is relative to the working directory,
+// to a finding with its file relative to root.
+func (f Finding) Finding(root string) analysis.Finding {
+	where := f.Document
+	if f.Path != "" {
+		where += " " + f.Path
+	}
+	return analysis.At(analysis.Finding{
+		Tool:     ToolIAMPolicy,
+		Rule:     f.Rule,
+		Severity: f.Severity,
+		Message:  where + ": " + f.Message,
+	}, root, f.Range)
+}
+
+// Lint checks every document and returns the findings ordered by position.
+// A document reached more than once, such as a local value used by several
+// roles, is reported once.
+func Lint(docs []Document) []Finding {
+	seen := map[string]bool{}
+	var findings []Finding
+	for _, doc := range docs {
+		for _, f := range doc.Lint() {
+			key := fmt.Sprintf("%s:%d:%d %s %s %s", f.Range.Filename, f.Range.Start.Line, f.Range.Start.Column, f.Rule, f.Path, f.Message)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Range, findings[j].Range
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Start.Line != b.Start.Line {
+			return a.Start.Line < b.Start.Line
+		}
+		return a.Start.Column < b.Start.Column
+	})
+	return findings
+}
+
+// Lint checks the document.
+func (d Document) Lint() []Finding {
+	value, locate := d.Value, d.locate
+	if value == nil {
+		if strings.TrimSpace(d.Text) == "" {
+			return []Finding{d.finding(Problem{Rule: RuleJSON, Severity: analysis.SeverityHigh, Message: "policy document is empty"}, d.Range)}
+		}
+		dec := json.NewDecoder(strings.NewReader(d.Text))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return []Finding{d.finding(Problem{Rule: RuleJSON, Severity: analysis.SeverityHigh, Message: "invalid JSON: " + err.Error()}, d.textRange(jsonErrorOffset(err)))}
+		}
+		offsets := textOffsets(d.Text)
+		locate = func(p Path) hcl.Range {
+			for ; len(p) > 0; p = p[:len(p)-1] {
+				if off, ok := offsets[p.String()]; ok {
+					return d.textRange(off)
+				}
+			}
+			return d.Range
+		}
+	}
+	if locate == nil {
+		locate = func(Path) hcl.Range { return d.Range }
+	}
+
+	var findings []Finding
+	for _, p := range Check(value, d.Kind) {
+		findings = append(findings, d.finding(p, locate(p.Path)))
+	}
+	return findings
+}
+
+func (d Document) finding(p Problem, rng hcl.Range) Finding {
+	return Finding{
+		Rule:     p.Rule,
+		Severity: p.Severity,
+		Document: d.Name,
+		Path:     p.Path.String(),
+		Message:  p.Message,
+		Range:    rng,
+	}
+}
+
+// textRange returns the position of a byte offset into Text. Text is taken
+// to start at Range and to be written as is, as in a raw Go string or an HCL
+// heredoc; where escapes shift it, the line is still right.
+func (d Document) textRange(offset int) hcl.Range {
+	if offset <= 0 || offset > len(d.Text) {
+		return d.Range
+	}
+	pos := d.Range.Start
+	before := d.Text[:offset]
+	if n := strings.Count(before, "\n"); n > 0 {
+		pos.Line += n
+		pos.Column = offset - strings.LastIndex(before, "\n")
+	} else {
+		pos.Column += offset
+	}
+	pos.Byte += offset
+	return hcl.Range{Filename: d.Range.Filename, Start: pos, End: pos}
+}
+
+// textOffsets maps the path of every element of a JSON document to the byte
+// offset it starts at.
+func textOffsets(text string) map[string]int {
+	offsets := map[string]int{}
+	dec := json.NewDecoder(strings.NewReader(text))
+
+	var walk func(path Path) bool
+	walk = func(path Path) bool {
+		offsets[path.String()] = skipSeparators(text, int(dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return false
+				}
+				if !walk(path.with(fmt.Sprint(key))) {
+					return false
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if !walk(path.with(i)) {
+					return false
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err == nil
+	}
+	walk(nil)
+	return offsets
+}
+
+// skipSeparators returns the offset of the first character at or after
+// offset that starts a JSON value.
+func skipSeparators(text string, offset int) int {
+	for offset < len(text) && strings.IndexByte(" \t\r\n:,", text[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// jsonErrorOffset returns the byte offset a decoding error is at, or 0.
+func jsonErrorOffset(err error) int {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return int(e.Offset)
+	case *json.UnmarshalTypeError:
+		return int(e.Offset)
+	}
+	return 0
+}
+
+// formatKey formats a map key as a step of a document name: literal keys as
+// ["key"], keys that are expressions as [expr].
+func formatKey(key string, literal bool) string {
+	if literal {
+		return "[" + strconv.Quote(key) + "]"
+	}
+	return "[" + key + "]"
+}
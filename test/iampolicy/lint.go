@@ -0,0 +1,482 @@
+package iampolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// Rule names reported on findings.
+const (
+	// RuleJSON is a document that is not valid JSON.
+	RuleJSON = "iam-json"
+	// RuleGrammar is a document AWS would reject as malformed: a missing or
+	// misspelt element, or a value of the wrong shape.
+	RuleGrammar = "iam-grammar"
+	// RuleAction is an action not of the form service:Action.
+	RuleAction = "iam-action"
+	// RuleUnknownService is an action of a service prefix AWS does not have.
+	RuleUnknownService = "iam-unknown-service"
+	// RuleCondition is a condition with an unknown operator or malformed key.
+	RuleCondition = "iam-condition"
+	// RuleWildcardAction is a statement allowing every action.
+	RuleWildcardAction = "iam-wildcard-action"
+	// RuleWildcardResource is a statement allowing actions on every resource.
+	RuleWildcardResource = "iam-wildcard-resource"
+	// RuleNotAction is a statement allowing every action but those listed.
+	RuleNotAction = "iam-not-action"
+)
+
+// Problem is a problem with an element of a policy document.
+type Problem struct {
+	Rule     string
+	Severity analysis.Severity
+	Path     Path
+	Message  string
+}
+
+// Versions of the policy language. Only 2012-10-17 supports policy variables.
+const (
+	Version     = "2012-10-17"
+	versionPrev = "2008-10-17"
+)
+
+var (
+	documentElements  = elements("Version", "Id", "Statement")
+	statementElements = elements("Sid", "Effect", "Principal", "NotPrincipal", "Action", "NotAction", "Resource", "NotResource", "Condition")
+	principalTypes    = elements("AWS", "Service", "Federated", "CanonicalUser")
+
+	sidPattern    = regexp.MustCompile(`^[A-Za-z0-9]*$`)
+	actionPattern = regexp.MustCompile(`^([A-Za-z0-9-]+):([A-Za-z0-9*?]+)$`)
+)
+
+func elements(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Check returns the problems of a decoded policy document of the given kind.
+// Values of the document may be unknown, and are then not checked.
+func Check(doc interface{}, kind Kind) []Problem {
+	c := &checker{kind: kind, sids: map[string]bool{}}
+	c.document(doc)
+	return c.problems
+}
+
+type checker struct {
+	kind     Kind
+	sids     map[string]bool
+	problems []Problem
+}
+
+func (c *checker) report(rule string, severity analysis.Severity, path Path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Rule: rule, Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) grammar(path Path, format string, args ...interface{}) {
+	c.report(RuleGrammar, analysis.SeverityHigh, path, format, args...)
+}
+
+func (c *checker) document(doc interface{}) {
+	if isUnknown(doc) {
+		return
+	}
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		c.grammar(nil, "policy document must be a JSON object, not %s", describe(doc))
+		return
+	}
+	c.unknownElements(obj, documentElements, nil)
+
+	switch version, ok := obj["Version"]; {
+	case !ok:
+		c.report(RuleGrammar, analysis.SeverityMedium, nil, "no Version: the document is read as %s, which does not support policy variables; set Version to %q", versionPrev, Version)
+	case isUnknown(version):
+	case version == versionPrev:
+		c.report(RuleGrammar, analysis.SeverityLow, Path{"Version"}, "Version %s does not support policy variables; use %q", versionPrev, Version)
+	case version != Version:
+		c.grammar(Path{"Version"}, "Version must be %q, not %s", Version, describe(version))
+	}
+
+	statements, ok := obj["Statement"]
+	switch s := statements.(type) {
+	case nil:
+		if !ok {
+			c.grammar(nil, "no Statement")
+		} else {
+			c.grammar(Path{"Statement"}, "Statement must be an object or an array of objects, not null")
+		}
+	case unknown:
+	case map[string]interface{}:
+		c.statement(s, Path{"Statement"})
+	case []interface{}:
+		if len(s) == 0 {
+			c.grammar(Path{"Statement"}, "Statement is empty")
+		}
+		for i, statement := range s {
+			c.statement(statement, Path{"Statement", i})
+		}
+	default:
+		c.grammar(Path{"Statement"}, "Statement must be an object or an array of objects, not %s", describe(s))
+	}
+}
+
+func (c *checker) statement(value interface{}, path Path) {
+	if isUnknown(value) {
+		return
+	}
+	s, ok := value.(map[string]interface{})
+	if !ok {
+		c.grammar(path, "statement must be an object, not %s", describe(value))
+		return
+	}
+	c.unknownElements(s, statementElements, path)
+
+	if sid, ok := s["Sid"]; ok && !isUnknown(sid) {
+		switch id, _ := sid.(string); {
+		case id != sid:
+			c.grammar(path.with("Sid"), "Sid must be a string, not %s", describe(sid))
+		case c.kind == KindIdentity && !sidPattern.MatchString(id):
+			c.grammar(path.with("Sid"), "Sid %q may only contain letters and digits", id)
+		case c.sids[id]:
+			c.grammar(path.with("Sid"), "Sid %q is used by another statement", id)
+		default:
+			c.sids[id] = true
+		}
+	}
+
+	effect, hasEffect := s["Effect"]
+	switch {
+	case !hasEffect:
+		c.grammar(path, "no Effect")
+	case isUnknown(effect):
+	case effect != "Allow" && effect != "Deny":
+		c.grammar(path.with("Effect"), `Effect must be "Allow" or "Deny", not %s`, describe(effect))
+	}
+	allow := effect == "Allow"
+
+	actionKey, actions := c.exactlyOne(s, path, "Action", "NotAction")
+	for i, action := range actions {
+		c.action(action, elementPath(path, actionKey, s[actionKey], i))
+	}
+	everyAction := actionKey == "Action" && containsWildcard(actions, "*", "*:*")
+	if allow && everyAction {
+		c.report(RuleWildcardAction, analysis.SeverityHigh, path.with("Action"), "allows every action on %s", c.resourcesDescription(s))
+	}
+	if allow && actionKey == "NotAction" {
+		c.report(RuleNotAction, analysis.SeverityHigh, path.with("NotAction"), "Allow with NotAction grants every action not listed, including those of services added later; list the allowed actions instead")
+	}
+
+	switch c.kind {
+	case KindIdentity:
+		for _, key := range []string{"Principal", "NotPrincipal"} {
+			if _, ok := s[key]; ok {
+				c.grammar(path.with(key), "%s is not allowed in a policy attached to a user, group or role", key)
+			}
+		}
+		resourceKey, resources := c.exactlyOne(s, path, "Resource", "NotResource")
+		for i, resource := range resources {
+			c.resource(resource, elementPath(path, resourceKey, s[resourceKey], i))
+		}
+		// Every action on every resource is reported as a wildcard action
+		if allow && resourceKey == "Resource" && actionKey != "" && !everyAction && containsWildcard(resources, "*") {
+			c.report(RuleWildcardResource, analysis.SeverityMedium, path.with("Resource"), "allows %s on every resource", actionsDescription(actionKey, actions))
+		}
+
+	case KindTrust:
+		for _, key := range []string{"Resource", "NotResource"} {
+			if _, ok := s[key]; ok {
+				c.grammar(path.with(key), "%s is not allowed in a trust policy", key)
+			}
+		}
+		_, hasPrincipal := s["Principal"]
+		_, hasNotPrincipal := s["NotPrincipal"]
+		switch {
+		case hasPrincipal && hasNotPrincipal:
+			c.grammar(path, "Principal and NotPrincipal cannot both be set")
+		case hasPrincipal:
+			c.principal(s["Principal"], path.with("Principal"))
+		case hasNotPrincipal:
+			c.principal(s["NotPrincipal"], path.with("NotPrincipal"))
+		default:
+			c.grammar(path, "no Principal: a trust policy must say who can assume the role")
+		}
+		for i, action := range actions {
+			if name, ok := action.(string); ok && actionKey == "Action" && name != "*" && !strings.HasPrefix(strings.ToLower(name), "sts:") {
+				c.grammar(elementPath(path, actionKey, s[actionKey], i), "%q is not an action a trust policy can allow; use sts:AssumeRole and its variants", name)
+			}
+		}
+	}
+
+	if condition, ok := s["Condition"]; ok {
+		c.condition(condition, path.with("Condition"))
+	}
+}
+
+// unknownElements reports the keys of obj that are not allowed elements.
+// AWS rejects them, and they are most often misspelt elements that were
+// meant to restrict the statement.
+func (c *checker) unknownElements(obj map[string]interface{}, allowed map[string]bool, path Path) {
+	for _, key := range sortedKeys(obj) {
+		if allowed[key] {
+			continue
+		}
+		msg := fmt.Sprintf("unknown element %q", key)
+		for name := range allowed {
+			if strings.EqualFold(name, key) || strings.EqualFold(name+"s", key) || strings.EqualFold(name, key+"s") {
+				msg += fmt.Sprintf("; did you mean %q?", name)
+				break
+			}
+		}
+		c.grammar(path.with(key), "%s", msg)
+	}
+}
+
+// exactlyOne checks s has one of the two elements, as a string or a non-empty
+// array of them, and returns which one and its values.
+func (c *checker) exactlyOne(s map[string]interface{}, path Path, key, notKey string) (string, []interface{}) {
+	value, has := s[key]
+	notValue, hasNot := s[notKey]
+	switch {
+	case has && hasNot:
+		c.grammar(path, "%s and %s cannot both be set", key, notKey)
+		return "", nil
+	case !has && !hasNot:
+		c.grammar(path, "no %s or %s", key, notKey)
+		return "", nil
+	case hasNot:
+		key, value = notKey, notValue
+	}
+	return key, c.stringList(value, path.with(key))
+}
+
+// stringList returns a value that must be a string or a non-empty array of
+// strings as a list, reporting any other shape. Elements that are unknown or
+// not strings are returned as unknown, so indexes still match the array.
+func (c *checker) stringList(value interface{}, path Path) []interface{} {
+	switch v := value.(type) {
+	case unknown:
+		return nil
+	case string:
+		return []interface{}{v}
+	case []interface{}:
+		if len(v) == 0 {
+			c.grammar(path, "%s is empty", path[len(path)-1])
+		}
+		var out []interface{}
+		for i, elem := range v {
+			switch elem.(type) {
+			case string:
+				out = append(out, elem)
+			case unknown:
+				out = append(out, elem)
+			default:
+				c.grammar(path.with(i), "must be a string, not %s", describe(elem))
+				out = append(out, unknown{})
+			}
+		}
+		return out
+	}
+	c.grammar(path, "must be a string or an array of strings, not %s", describe(value))
+	return nil
+}
+
+func (c *checker) action(value interface{}, path Path) {
+	action, ok := value.(string)
+	if !ok || action == "*" {
+		return
+	}
+	m := actionPattern.FindStringSubmatch(action)
+	if m == nil {
+		c.report(RuleAction, analysis.SeverityHigh, path, "%q is not an action: actions are service:Action, e.g. s3:GetObject", action)
+		return
+	}
+	if service := strings.ToLower(m[1]); !strings.ContainsAny(service, "*?") && !services[service] {
+		c.report(RuleUnknownService, analysis.SeverityHigh, path, "%q is not an AWS service prefix", m[1])
+	}
+}
+
+func (c *checker) resource(value interface{}, path Path) {
+	resource, ok := value.(string)
+	if !ok || resource == "*" {
+		return
+	}
+	if !strings.HasPrefix(resource, "arn:") {
+		c.grammar(path, "%q is not an ARN or *", resource)
+		return
+	}
+	if parts := strings.SplitN(resource, ":", 6); len(parts) < 6 {
+		c.grammar(path, "%q is not an ARN: ARNs are arn:partition:service:region:account:resource", resource)
+	}
+}
+
+func (c *checker) principal(value interface{}, path Path) {
+	switch p := value.(type) {
+	case unknown:
+	case string:
+		if p != "*" {
+			c.grammar(path, `principal must be "*" or an object such as {"Service": "ec2.amazonaws.com"}, not %q`, p)
+		}
+	case map[string]interface{}:
+		if len(p) == 0 {
+			c.grammar(path, "principal is empty")
+		}
+		for _, key := range sortedKeys(p) {
+			if !principalTypes[key] {
+				c.grammar(path.with(key), "unknown principal type %q; use AWS, Service, Federated or CanonicalUser", key)
+				continue
+			}
+			c.stringList(p[key], path.with(key))
+		}
+	default:
+		c.grammar(path, "principal must be \"*\" or an object, not %s", describe(value))
+	}
+}
+
+func (c *checker) condition(value interface{}, path Path) {
+	if isUnknown(value) {
+		return
+	}
+	block, ok := value.(map[string]interface{})
+	if !ok {
+		c.grammar(path, "Condition must be an object of operators, not %s", describe(value))
+		return
+	}
+	for _, operator := range sortedKeys(block) {
+		opPath := path.with(operator)
+		if msg, ok := checkOperator(operator); !ok {
+			c.report(RuleCondition, analysis.SeverityHigh, opPath, "%s", msg)
+		}
+		if isUnknown(block[operator]) {
+			continue
+		}
+		keys, ok := block[operator].(map[string]interface{})
+		if !ok {
+			c.report(RuleCondition, analysis.SeverityHigh, opPath, "%s must map condition keys to values, not %s", operator, describe(block[operator]))
+			continue
+		}
+		for _, key := range sortedKeys(keys) {
+			if !strings.Contains(key, ":") {
+				c.report(RuleCondition, analysis.SeverityHigh, opPath.with(key), "%q is not a condition key: keys are prefix:name, e.g. aws:SourceIp", key)
+			}
+			switch v := keys[key].(type) {
+			case string, bool, json.Number, float64, unknown:
+			case []interface{}:
+				if len(v) == 0 {
+					c.report(RuleCondition, analysis.SeverityHigh, opPath.with(key), "no values to compare %s with", key)
+				}
+			default:
+				c.report(RuleCondition, analysis.SeverityHigh, opPath.with(key), "condition value must be a string, number, boolean or an array of them, not %s", describe(v))
+			}
+		}
+	}
+}
+
+// checkOperator reports whether a condition operator, with its optional
+// ForAllValues:/ForAnyValue: qualifier and IfExists suffix, is valid and
+// says why when it is not.
+func checkOperator(operator string) (string, bool) {
+	base := operator
+	for _, qualifier := range []string{"ForAllValues:", "ForAnyValue:"} {
+		base = strings.TrimPrefix(base, qualifier)
+	}
+	ifExists := strings.HasSuffix(base, "IfExists")
+	base = strings.TrimSuffix(base, "IfExists")
+
+	if !conditionOperators[base] {
+		msg := fmt.Sprintf("unknown condition operator %q", operator)
+		for known := range conditionOperators {
+			if strings.EqualFold(known, base) {
+				msg += fmt.Sprintf("; operators are case sensitive, did you mean %q?", strings.Replace(operator, base, known, 1))
+				break
+			}
+		}
+		return msg, false
+	}
+	if ifExists && base == "Null" {
+		return "the Null operator cannot take IfExists", false
+	}
+	return "", true
+}
+
+// resourcesDescription describes what a statement's Resource or NotResource
+// covers, for messages.
+func (c *checker) resourcesDescription(s map[string]interface{}) string {
+	if c.kind == KindTrust {
+		return "the role"
+	}
+	if _, ok := s["NotResource"]; ok {
+		return "every resource not listed"
+	}
+	if r, ok := s["Resource"]; ok && (r == "*" || containsWildcard(asList(r), "*")) {
+		return "every resource"
+	}
+	return "the listed resources"
+}
+
+func actionsDescription(key string, actions []interface{}) string {
+	if key == "NotAction" {
+		return "every action not listed"
+	}
+	if len(actions) == 1 {
+		if s, ok := actions[0].(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprintf("%d actions", len(actions))
+}
+
+// elementPath returns the path of the i-th value of an element that may be
+// a single string or an array.
+func elementPath(path Path, key string, value interface{}, i int) Path {
+	if _, ok := value.([]interface{}); ok {
+		return path.with(key).with(i)
+	}
+	return path.with(key)
+}
+
+func containsWildcard(values []interface{}, wildcards ...string) bool {
+	for _, v := range values {
+		for _, w := range wildcards {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+func isUnknown(value interface{}) bool {
+	_, ok := value.(unknown)
+	return ok
+}
+
+// describe names the JSON type of value, for messages.
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case bool:
+		return "a boolean"
+	case json.Number, float64, int:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,168 @@
+package iampolicy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// check decodes a JSON document and returns its problems as
+// "path: message (rule)".
+func check(t *testing.T, doc string, kind Kind) []string {
+	t.Helper()
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(doc), &value))
+	var got []string
+	for _, p := range Check(value, kind) {
+		got = append(got, p.Path.String()+": "+p.Message+" ("+p.Rule+")")
+	}
+	return got
+}
+
+func TestCheckValid(t *testing.T) {
+	assert.Empty(t, check(t, `{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Sid": "ReadApp",
+			"Effect": "Allow",
+			"Action": ["s3:GetObject", "s3:List*"],
+			"Resource": ["arn:aws:s3:::app", "arn:aws:s3:::app/*"],
+			"Condition": {"ForAnyValue:StringLikeIfExists": {"aws:PrincipalTag/team": ["app*"]}}
+		}, {
+			"Effect": "Deny",
+			"NotAction": "iam:*",
+			"NotResource": "arn:aws:iam::123456789012:role/app"
+		}]
+	}`, KindIdentity))
+
+	assert.Empty(t, check(t, `{
+		"Version": "2012-10-17",
+		"Statement": {
+			"Effect": "Allow",
+			"Action": "sts:AssumeRoleWithWebIdentity",
+			"Principal": {"Federated": "arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"},
+			"Condition": {"StringEquals": {"token.actions.githubusercontent.com:aud": "sts.amazonaws.com"}}
+		}
+	}`, KindTrust))
+}
+
+func TestCheckGrammar(t *testing.T) {
+	for name, tc := range map[string]struct {
+		doc  string
+		kind Kind
+		want []string
+	}{
+		"no version": {
+			doc:  `{"Statement": {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::app/*"}}`,
+			want: []string{`: no Version: the document is read as 2008-10-17, which does not support policy variables; set Version to "2012-10-17" (iam-grammar)`},
+		},
+		"bad version and element": {
+			doc: `{"Version": "2012-10-18", "Statements": []}`,
+			want: []string{
+				`Statements: unknown element "Statements"; did you mean "Statement"? (iam-grammar)`,
+				`Version: Version must be "2012-10-17", not "2012-10-18" (iam-grammar)`,
+				`: no Statement (iam-grammar)`,
+			},
+		},
+		"statement": {
+			doc: `{"Version": "2012-10-17", "Statement": [{
+				"Sid": "read-app", "Effect": "allow", "Action": "s3:GetObject", "NotAction": "s3:PutObject",
+				"Resource": "app", "Principal": "*"
+			}]}`,
+			want: []string{
+				`Statement[0].Sid: Sid "read-app" may only contain letters and digits (iam-grammar)`,
+				`Statement[0].Effect: Effect must be "Allow" or "Deny", not "allow" (iam-grammar)`,
+				`Statement[0]: Action and NotAction cannot both be set (iam-grammar)`,
+				`Statement[0].Principal: Principal is not allowed in a policy attached to a user, group or role (iam-grammar)`,
+				`Statement[0].Resource: "app" is not an ARN or * (iam-grammar)`,
+			},
+		},
+		"actions": {
+			doc: `{"Version": "2012-10-17", "Statement": {
+				"Effect": "Deny", "Action": ["s3GetObject", "s4:GetObject", 3], "Resource": []
+			}}`,
+			want: []string{
+				`Statement.Action[2]: must be a string, not a number (iam-grammar)`,
+				`Statement.Action[0]: "s3GetObject" is not an action: actions are service:Action, e.g. s3:GetObject (iam-action)`,
+				`Statement.Action[1]: "s4" is not an AWS service prefix (iam-unknown-service)`,
+				`Statement.Resource: Resource is empty (iam-grammar)`,
+			},
+		},
+		"conditions": {
+			doc: `{"Version": "2012-10-17", "Statement": {
+				"Effect": "Deny", "Action": "s3:*", "Resource": "*",
+				"Condition": {"stringEquals": {"aws:SourceVpc": "vpc-1"}, "NullIfExists": {"aws:TokenIssueTime": "true"}, "Bool": {"SecureTransport": false}}
+			}}`,
+			want: []string{
+				`Statement.Condition.Bool.SecureTransport: "SecureTransport" is not a condition key: keys are prefix:name, e.g. aws:SourceIp (iam-condition)`,
+				`Statement.Condition.NullIfExists: the Null operator cannot take IfExists (iam-condition)`,
+				`Statement.Condition.stringEquals: unknown condition operator "stringEquals"; operators are case sensitive, did you mean "StringEquals"? (iam-condition)`,
+			},
+		},
+		"trust": {
+			doc:  `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "ec2:RunInstances", "Resource": "*", "Principal": {"Services": "ec2.amazonaws.com"}}}`,
+			kind: KindTrust,
+			want: []string{
+				`Statement.Resource: Resource is not allowed in a trust policy (iam-grammar)`,
+				`Statement.Principal.Services: unknown principal type "Services"; use AWS, Service, Federated or CanonicalUser (iam-grammar)`,
+				`Statement.Action: "ec2:RunInstances" is not an action a trust policy can allow; use sts:AssumeRole and its variants (iam-grammar)`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, check(t, tc.doc, tc.kind))
+		})
+	}
+}
+
+func TestCheckWildcards(t *testing.T) {
+	assert.Equal(t, []string{
+		`Statement[0].Action: allows every action on every resource (iam-wildcard-action)`,
+		`Statement[1].Resource: allows ec2:Describe* on every resource (iam-wildcard-resource)`,
+		`Statement[2].NotAction: Allow with NotAction grants every action not listed, including those of services added later; list the allowed actions instead (iam-not-action)`,
+	}, check(t, `{"Version": "2012-10-17", "Statement": [
+		{"Effect": "Allow", "Action": "*", "Resource": "*"},
+		{"Effect": "Allow", "Action": "ec2:Describe*", "Resource": "*"},
+		{"Effect": "Allow", "NotAction": "iam:*", "Resource": "arn:aws:s3:::app/*"},
+		{"Effect": "Deny", "Action": "*", "Resource": "*"}
+	]}`, KindIdentity))
+}
+
+func TestCheckUnknown(t *testing.T) {
+	doc := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []interface{}{
+			map[string]interface{}{"Effect": "Allow", "Action": "s3:GetObject", "Resource": unknown{}},
+			unknown{},
+		},
+	}
+	assert.Empty(t, Check(doc, KindIdentity))
+}
+
+func TestDocumentLintText(t *testing.T) {
+	doc := Document{
+		Name: "var.policy",
+		Text: "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\"Effect\": \"Allow\", \"Action\": \"*\", \"Resource\": \"*\"}\n  ]\n}",
+	}
+	doc.Range.Filename = "policy.tf"
+	doc.Range.Start.Line, doc.Range.Start.Column = 10, 5
+
+	findings := doc.Lint()
+	require.Len(t, findings, 1)
+	assert.Equal(t, RuleWildcardAction, findings[0].Rule)
+	assert.Equal(t, 13, findings[0].Range.Start.Line)
+	assert.Equal(t, `policy.tf:13: [HIGH] var.policy Statement[0].Action: allows every action on every resource (iam-wildcard-action)`, findings[0].String())
+
+	doc.Text = "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n}"
+	findings = doc.Lint()
+	require.Len(t, findings, 1)
+	assert.Equal(t, RuleJSON, findings[0].Rule)
+	assert.Equal(t, 13, findings[0].Range.Start.Line)
+}
+
+func TestPathString(t *testing.T) {
+	assert.Equal(t, "", Path(nil).String())
+	assert.Equal(t, "Statement[0].Condition.StringEquals", Path{"Statement", 0, "Condition", "StringEquals"}.String())
+}
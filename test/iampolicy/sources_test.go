@@ -0,0 +1,127 @@
+package iampolicy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+var stackDir = filepath.Join("testdata", "examples", "stack")
+
+func loadStack(t *testing.T) discovery.Config {
+	t.Helper()
+	cfg, ok, err := discovery.Load(discovery.KindExample, stackDir)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+	return cfg
+}
+
+func findingStrings(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.String())
+	}
+	return out
+}
+
+func TestBindings(t *testing.T) {
+	bindings := Bindings(loadStack(t))
+	assert.Len(t, bindings["roles"], 3)
+	assert.NotContains(t, bindings, "policies")
+
+	bindings = Bindings(discovery.Config{Kind: discovery.KindModule, Name: "ec2"})
+	require.Len(t, bindings["iam_inline_policies"], 1)
+	assert.Equal(t, []string{"*"}, bindings["iam_inline_policies"][0].Path)
+}
+
+func TestFromConfig(t *testing.T) {
+	docs, err := FromConfig(loadStack(t), []string{filepath.Join(stackDir, "stack.tfvars")})
+	require.NoError(t, err)
+
+	var names []string
+	for _, doc := range docs {
+		names = append(names, doc.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		`module.iam.policies["admin"].policy`,
+		`module.app.task_role_inline_policies["task"]`,
+		`var.roles["app"].assume_role_policy`,
+	}, names)
+
+	assert.Equal(t, []string{
+		`testdata/examples/stack/main.tf:6: [HIGH] module.app.task_role_inline_policies["task"] Statement[0]: no Resource or NotResource (iam-grammar)`,
+		`testdata/examples/stack/main.tf:9: [HIGH] module.app.task_role_inline_policies["task"] Statement[0].Resources: unknown element "Resources"; did you mean "Resource"? (iam-grammar)`,
+		`testdata/examples/stack/main.tf:28: [HIGH] module.iam.policies["admin"].policy Statement[0].Action: allows every action on every resource (iam-wildcard-action)`,
+		`testdata/examples/stack/main.tf:36: [HIGH] module.iam.policies["admin"].policy Statement[1].Condition.StringEqual: unknown condition operator "StringEqual" (iam-condition)`,
+		`testdata/examples/stack/stack.tfvars:7: [HIGH] var.roles["app"].assume_role_policy Statement[0]: no Principal: a trust policy must say who can assume the role (iam-grammar)`,
+		`testdata/examples/stack/stack.tfvars:10: [HIGH] var.roles["app"].assume_role_policy Statement[0].Resource: Resource is not allowed in a trust policy (iam-grammar)`,
+	}, findingStrings(Lint(docs)))
+}
+
+func TestFromGoFiles(t *testing.T) {
+	docs, err := FromGoFiles([]string{filepath.Join("testdata", "vars_test.go")}, []discovery.Config{loadStack(t)})
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+
+	assert.Equal(t, `examples/stack var.roles[prefix + "-role"].assume_role_policy`, docs[0].Name)
+	assert.Equal(t, KindTrust, docs[0].Kind)
+	assert.Equal(t, 9, docs[0].Range.Start.Line, "a marshalled document is located at its literal")
+	assert.Equal(t, `examples/stack var.roles["raw"].assume_role_policy`, docs[1].Name)
+	assert.Equal(t, `examples/stack var.roles["broken"].assume_role_policy`, docs[2].Name)
+
+	assert.Equal(t, []string{
+		`testdata/vars_test.go:19: [HIGH] examples/stack var.roles[prefix + "-role"].assume_role_policy Statement[1].NotAction: Allow with NotAction grants every action not listed, including those of services added later; list the allowed actions instead (iam-not-action)`,
+		`testdata/vars_test.go:49: [HIGH] examples/stack var.roles["broken"].assume_role_policy: invalid JSON: invalid character '}' looking for beginning of value (iam-json)`,
+	}, findingStrings(Lint(docs)))
+}
+
+func TestLintDeduplicates(t *testing.T) {
+	doc := Document{Kind: KindTrust, Text: `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "sts:AssumeRole"}}`}
+	doc.Range.Filename = "main.tf"
+	a, b := doc, doc
+	a.Name, b.Name = `module.iam.roles["a"].assume_role_policy`, `module.iam.roles["b"].assume_role_policy`
+	findings := Lint([]Document{a, b})
+	require.Len(t, findings, 1)
+	assert.Equal(t, a.Name, findings[0].Document)
+}
+
+func TestFindingConversion(t *testing.T) {
+	f := Finding{
+		Rule:     RuleWildcardAction,
+		Severity: analysis.SeverityHigh,
+		Document: "var.policy",
+		Path:     "Statement[0].Action",
+		Message:  "allows every action on every resource",
+	}
+	f.Range.Filename = filepath.Join("..", "examples", "stack", "main.tf")
+	f.Range.Start.Line, f.Range.End.Line = 4, 6
+
+	got := f.Finding("..")
+	assert.Equal(t, ToolIAMPolicy, got.Tool)
+	assert.Equal(t, "examples/stack/main.tf", got.File)
+	assert.Equal(t, 4, got.Line)
+	assert.Equal(t, "var.policy Statement[0].Action: allows every action on every resource", got.Message)
+}
+
+func TestSymbolic(t *testing.T) {
+	var admin *Document
+	for _, doc := range Symbolic(loadStack(t)) {
+		doc := doc
+		if doc.Name == `module.iam.policies["admin"].policy` {
+			admin = &doc
+		}
+	}
+	require.NotNil(t, admin)
+
+	value, err := admin.Decode()
+	require.NoError(t, err)
+	statements := value.(map[string]interface{})["Statement"].([]interface{})
+	require.Len(t, statements, 2)
+	assert.Equal(t, "${module.bucket.arn}/*", statements[1].(map[string]interface{})["Resource"])
+}
@@ -0,0 +1,55 @@
+locals {
+  task_policy = <<-EOT
+    {
+      "Version": "2012-10-17",
+      "Statement": [
+        {
+          "Effect": "Allow",
+          "Action": "s3:GetObjects",
+          "Resources": "arn:aws:s3:::app/*"
+        }
+      ]
+    }
+  EOT
+}
+
+module "iam" {
+  source = "../../modules/iam"
+
+  roles = var.roles
+
+  policies = {
+    "admin" = {
+      policy = jsonencode({
+        Version = "2012-10-17"
+        Statement = [
+          {
+            Effect   = "Allow"
+            Action   = "*"
+            Resource = "*"
+          },
+          {
+            Effect   = "Allow"
+            Action   = ["s3:GetObject"]
+            Resource = "${module.bucket.arn}/*"
+            Condition = {
+              StringEqual = { "aws:PrincipalTag/team" = "app" }
+            }
+          }
+        ]
+      })
+    }
+  }
+}
+
+module "app" {
+  source = "../../modules/ecs"
+
+  task_role_inline_policies = {
+    "task" = local.task_policy
+  }
+}
+
+variable "roles" {
+  type = any
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStack(t *testing.T) {
+	trust := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":    "Allow",
+				"Action":    "sts:AssumeRole",
+				"Principal": map[string]interface{}{"Service": "ec2.amazonaws.com"},
+			},
+			{
+				"Effect":    "Allow",
+				"NotAction": "sts:TagSession",
+				"Principal": map[string]interface{}{"Service": "ec2.amazonaws.com"},
+			},
+		},
+	}
+	trustJSON, _ := json.Marshal(trust)
+
+	options(t, "examples/stack", map[string]interface{}{
+		"roles": map[string]interface{}{
+			prefix + "-role": map[string]interface{}{
+				"assume_role_policy": string(trustJSON),
+			},
+			"raw": map[string]interface{}{
+				"assume_role_policy": `{
+					"Version": "2012-10-17",
+					"Statement": {
+						"Effect": "Allow",
+						"Action": "sts:AssumeRole",
+						"Principal": {"Service": "lambda.amazonaws.com"}
+					}
+				}`,
+			},
+		},
+	})
+}
+
+var planVars = map[string]map[string]interface{}{
+	"examples/stack": {
+		"roles": map[string]interface{}{
+			"broken": map[string]interface{}{
+				"assume_role_policy": `{"Version": "2012-10-17", "Statement": [}`,
+			},
+		},
+	},
+}
@@ -1,154 +1,149 @@
 package test
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
 )
 
-// TestCompleteInfrastructureStack tests a complete infrastructure stack
+// TestCompleteInfrastructureStack tests a complete infrastructure stack. It runs in stages that
+// can be skipped with SKIP_setup, SKIP_deploy, SKIP_validate and SKIP_teardown, e.g. deploy once
+// with SKIP_teardown=true and then iterate on the assertions with only the validate stage
 func TestCompleteInfrastructureStack(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
-	namePrefix := random.UniqueId()
-
-	// VPC Configuration
-	vpcOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/vpc-basic",
-		Vars: map[string]interface{}{
-			"name_prefix":             namePrefix,
-			"vpc_cidr_block":          "10.0.0.0/16",
-			"public_subnet_cidrs":     []string{"10.0.1.0/24", "10.0.2.0/24"},
-			"private_subnet_cidrs":    []string{"10.0.10.0/24", "10.0.20.0/24"},
-			"allowed_ips":             []string{"0.0.0.0/0"},
-		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
+	workspace := harness.StagedWorkspace(t)
+	vpcDir := filepath.Join(workspace, "examples", "vpc-basic")
+	elbDir := filepath.Join(workspace, "examples", "elb-with-vpc")
+	ec2Dir := filepath.Join(workspace, "examples", "ec2-with-alb")
+
+	// Destroy in reverse order of deployment, skipping anything that never started deploying
+	defer test_structure.RunTestStage(t, "teardown", func() {
+		for _, dir := range []string{ec2Dir, elbDir, vpcDir} {
+			if harness.HasOptions(t, dir) {
+				terraform.Destroy(t, harness.LoadOptions(t, dir))
+			}
+		}
+		harness.CleanupStagedWorkspace(t)
 	})
 
-	// Clean up VPC resources
-	defer terraform.Destroy(t, vpcOptions)
-
-	// Deploy VPC
-	terraform.InitAndApply(t, vpcOptions)
-
-	// Get VPC outputs
-	vpcId := terraform.Output(t, vpcOptions, "vpc_id")
-	publicSubnetIds := terraform.OutputList(t, vpcOptions, "public_subnet_ids")
-	privateSubnetIds := terraform.OutputList(t, vpcOptions, "private_subnet_ids")
+	test_structure.RunTestStage(t, "setup", func() {
+		harness.CopyWorkspaceTo(t, repoRoot, workspace)
 
-	// Verify VPC was created correctly
-	vpc := aws.GetVpcById(t, vpcId, awsRegion)
-	assert.Equal(t, "10.0.0.0/16", vpc.CidrBlock)
-
-	// ELB Configuration
-	elbOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/elb-with-vpc",
-		Vars: map[string]interface{}{
-			"name":       namePrefix + "-alb",
-			"vpc_id":     vpcId,
-			"subnet_ids": publicSubnetIds,
-		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
+		awsRegion := harness.Region(t)
+		test_structure.SaveString(t, workspace, "awsRegion", awsRegion)
+		test_structure.SaveString(t, workspace, "namePrefix", random.UniqueId())
+		test_structure.SaveAmiId(t, workspace, aws.GetAmazonLinuxAmi(t, awsRegion))
 	})
 
-	// Clean up ELB resources
-	defer terraform.Destroy(t, elbOptions)
-
-	// Deploy ELB
-	terraform.InitAndApply(t, elbOptions)
-
-	// Get ELB outputs
-	albArn := terraform.Output(t, elbOptions, "load_balancer_arn")
-	targetGroupArns := terraform.OutputMap(t, elbOptions, "target_group_arns")
-
-	// Verify ALB was created
-	alb := aws.GetLoadBalancerV2(t, awsRegion, albArn)
-	assert.NotNil(t, alb)
-	assert.Equal(t, "application", alb.Type)
-
-	// EC2 Configuration
-	ec2Options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/ec2-with-alb",
-		Vars: map[string]interface{}{
-			"name_prefix":        namePrefix + "-ec2",
-			"ami_id":            aws.GetAmazonLinuxAmi(t, awsRegion),
+	test_structure.RunTestStage(t, "deploy", func() {
+		awsRegion := test_structure.LoadString(t, workspace, "awsRegion")
+		namePrefix := test_structure.LoadString(t, workspace, "namePrefix")
+
+		// Deploy VPC
+		vpcOptions := terraform.WithDefaultRetryableErrors(t, harness.WorkspaceOptions(t, vpcDir, awsRegion, map[string]interface{}{
+			"name_prefix":          namePrefix,
+			"vpc_cidr_block":       "10.0.0.0/16",
+			"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+			"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.20.0/24"},
+			"allowed_ips":          []string{"0.0.0.0/0"},
+		}))
+		harness.SaveOptions(t, vpcOptions)
+		harness.InitAndApply(t, vpcOptions)
+		vpcOutputs := harness.SaveOutputs(t, vpcOptions)
+
+		// Deploy ELB into the VPC's public subnets
+		elbOptions := terraform.WithDefaultRetryableErrors(t, harness.WorkspaceOptions(t, elbDir, awsRegion, map[string]interface{}{
+			"name":       namePrefix + "-alb",
+			"vpc_id":     vpcOutputs.String(t, "vpc_id"),
+			"subnet_ids": vpcOutputs.List(t, "public_subnet_ids"),
+		}))
+		harness.SaveOptions(t, elbOptions)
+		harness.InitAndApply(t, elbOptions)
+		elbOutputs := harness.SaveOutputs(t, elbOptions)
+
+		// Deploy EC2 into the VPC's private subnets behind the ALB
+		ec2Options := terraform.WithDefaultRetryableErrors(t, harness.WorkspaceOptions(t, ec2Dir, awsRegion, map[string]interface{}{
+			"name_prefix":       namePrefix + "-ec2",
+			"ami_id":            test_structure.LoadAmiId(t, workspace),
 			"instance_type":     "t2.micro",
 			"key_name":          "test-key",
-			"subnet_ids":        privateSubnetIds,
-			"target_group_arns": []string{targetGroupArns["web-servers"]},
+			"subnet_ids":        vpcOutputs.List(t, "private_subnet_ids"),
+			"target_group_arns": []string{elbOutputs.Map(t, "target_group_arns")["web-servers"]},
 			"desired_capacity":  2,
 			"min_size":          1,
 			"max_size":          3,
-		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
+		}))
+		harness.SaveOptions(t, ec2Options)
+		harness.InitAndApply(t, ec2Options)
+		harness.SaveOutputs(t, ec2Options)
 	})
 
-	// Clean up EC2 resources
-	defer terraform.Destroy(t, ec2Options)
+	test_structure.RunTestStage(t, "validate", func() {
+		awsRegion := test_structure.LoadString(t, workspace, "awsRegion")
 
-	// Deploy EC2
-	terraform.InitAndApply(t, ec2Options)
+		// Verify VPC was created correctly
+		vpc := getVpc(t, harness.LoadOutputs(t, vpcDir).String(t, "vpc_id"), awsRegion)
+		assert.Equal(t, "10.0.0.0/16", vpc.CidrBlock)
 
-	// Get EC2 outputs
-	asgName := terraform.Output(t, ec2Options, "autoscaling_group_name")
+		// Verify ALB was created
+		alb := getLoadBalancerV2(t, awsRegion, harness.LoadOutputs(t, elbDir).String(t, "load_balancer_arn"))
+		assert.NotNil(t, alb)
+		assert.Equal(t, "application", alb.Type)
 
-	// Verify Auto Scaling Group
-	asg := aws.GetAutoScalingGroup(t, awsRegion, asgName)
-	assert.NotNil(t, asg)
-	assert.Equal(t, int64(2), asg.DesiredCapacity)
+		// Verify Auto Scaling Group
+		asgName := harness.LoadOutputs(t, ec2Dir).String(t, "autoscaling_group_name")
+		asg := getAutoScalingGroup(t, awsRegion, asgName)
+		assert.NotNil(t, asg)
+		assert.Equal(t, int64(2), asg.DesiredCapacity)
 
-	// Wait for instances to be healthy
-	aws.WaitForCapacity(t, asg, 2, 10*time.Minute, awsRegion)
+		// Wait for instances to be healthy
+		aws.WaitForCapacity(t, asgName, awsRegion, 60, 10*time.Second)
 
-	// Verify instances are running and healthy
-	instances := aws.GetInstancesInAsg(t, asg, awsRegion)
-	assert.Equal(t, 2, len(instances))
+		// Verify instances are running and healthy
+		instances := getInstancesInAsg(t, asgName, awsRegion)
+		assert.Equal(t, 2, len(instances))
 
-	for _, instance := range instances {
-		assert.Equal(t, "running", instance.State.Name)
-	}
+		for _, instance := range instances {
+			assert.Equal(t, "running", instance.State.Name)
+		}
 
-	// Test load balancer health
-	// Note: In a real test, you might want to deploy a simple web server
-	// and test that the load balancer can reach it
+		// Test load balancer health
+		// Note: In a real test, you might want to deploy a simple web server
+		// and test that the load balancer can reach it
+	})
 }
 
 // TestModuleUpgrade tests upgrading a module to a new version
 func TestModuleUpgrade(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/vpc-basic",
-		Vars: map[string]interface{}{
-			"name_prefix":             namePrefix,
-			"vpc_cidr_block":          "10.0.0.0/16",
-			"public_subnet_cidrs":     []string{"10.0.1.0/24", "10.0.2.0/24"},
-			"private_subnet_cidrs":    []string{"10.0.10.0/24", "10.0.20.0/24"},
-			"allowed_ips":             []string{"0.0.0.0/0"},
-		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/vpc-basic", awsRegion, map[string]interface{}{
+		"name_prefix":          namePrefix,
+		"vpc_cidr_block":       "10.0.0.0/16",
+		"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+		"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.20.0/24"},
+		"allowed_ips":          []string{"0.0.0.0/0"},
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Get initial state
 	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
@@ -171,40 +166,50 @@ func TestModuleUpgrade(t *testing.T) {
 
 // TestDisasterRecovery tests disaster recovery scenarios
 func TestDisasterRecovery(t *testing.T) {
+	harness.RequireDeployTier(t)
 	t.Parallel()
 
-	awsRegion := aws.GetRandomStableRegion(t, nil, nil)
+	awsRegion := harness.Region(t)
 	namePrefix := random.UniqueId()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../examples/vpc-basic",
-		Vars: map[string]interface{}{
-			"name_prefix":             namePrefix,
-			"vpc_cidr_block":          "10.0.0.0/16",
-			"public_subnet_cidrs":     []string{"10.0.1.0/24", "10.0.2.0/24"},
-			"private_subnet_cidrs":    []string{"10.0.10.0/24", "10.0.20.0/24"},
-			"allowed_ips":             []string{"0.0.0.0/0"},
-		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
-		},
-	})
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "examples/vpc-basic", awsRegion, map[string]interface{}{
+		"name_prefix":          namePrefix,
+		"vpc_cidr_block":       "10.0.0.0/16",
+		"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+		"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.20.0/24"},
+		"allowed_ips":          []string{"0.0.0.0/0"},
+	}))
 
 	defer terraform.Destroy(t, terraformOptions)
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Get initial outputs
 	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
 	subnetIds := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
 
 	// Simulate disaster by manually deleting a subnet
-	aws.DeleteSubnet(t, subnetIds[0], awsRegion)
+	deleteSubnet(t, subnetIds[0], awsRegion)
 
 	// Run terraform plan to see what needs to be recreated
-	planOutput := terraform.Plan(t, terraformOptions)
-	assert.Contains(t, planOutput, "will be created")
+	planOptions := *terraformOptions
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "tfplan")
+	terraform.Plan(t, &planOptions)
+	plan := planjson.Show(t, &planOptions)
+
+	planjson.AssertAction(t, plan, "module.vpc.aws_subnet.public[0]", planjson.ActionCreate)
+
+	// The NAT gateway and route table association in the deleted subnet are recreated with it; the VPC and
+	// the other subnets must stay as they are
+	planjson.AssertAction(t, plan, "module.vpc.aws_vpc.this", planjson.ActionNoOp)
+	untouched := plan.ResourceChanges.WithType("aws_subnet").InModule("module.vpc").Where(func(rc planjson.ResourceChange) bool {
+		return rc.Address != "module.vpc.aws_subnet.public[0]"
+	})
+	assert.Len(t, untouched, 3)
+	for _, subnet := range untouched {
+		planjson.AssertAction(t, plan, subnet.Address, planjson.ActionNoOp)
+	}
 
 	// Apply to recover from disaster
 	terraform.Apply(t, terraformOptions)
@@ -215,4 +220,4 @@ func TestDisasterRecovery(t *testing.T) {
 
 	assert.Equal(t, vpcId, newVpcId)
 	assert.Equal(t, 2, len(newSubnetIds))
-}
\ No newline at end of file
+}
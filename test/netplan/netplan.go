@@ -0,0 +1,225 @@
+// Package netplan checks the IPv4 address plan of the repository's networks
+// offline: that every subnet lies inside its VPC, that the subnets of a VPC
+// do not overlap, and that networks meant to be connected — the environments
+// and the VPCs attached to one transit gateway — do not overlap each other.
+// It also proposes free ranges for a new network.
+//
+// Networks are read from the CIDR inputs of modules/vpc and
+// modules/vpc-transit-gw wherever they are set: module calls in envs and
+// examples with their tfvars files, and the variables the Go tests pass.
+package netplan
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// ToolNetplan is the tool name of address plan findings, as recorded on
+// analysis.Finding.
+const ToolNetplan = "netplan"
+
+// Rule names reported on problems.
+const (
+	// RuleInvalid is a CIDR block that does not parse, is not IPv4 or has
+	// host bits set.
+	RuleInvalid = "cidr-invalid"
+	// RuleSize is a block of a size AWS does not allow.
+	RuleSize = "cidr-size"
+	// RuleContainment is a subnet outside its VPC.
+	RuleContainment = "cidr-containment"
+	// RuleSubnetOverlap is two subnets of one VPC overlapping.
+	RuleSubnetOverlap = "cidr-subnet-overlap"
+	// RuleNetworkOverlap is two networks of a group overlapping.
+	RuleNetworkOverlap = "cidr-network-overlap"
+)
+
+// Prefix lengths AWS allows for VPC and subnet IPv4 blocks.
+const (
+	MinPrefixBits = 16
+	MaxPrefixBits = 28
+)
+
+// MaxTransitGatewayBits is the longest prefix AWS allows for a transit
+// gateway CIDR block.
+const MaxTransitGatewayBits = 24
+
+// GroupEnvironments groups the VPCs of envs/, which are expected to be
+// connected to each other.
+const GroupEnvironments = "envs"
+
+// Block is one CIDR block input.
+type Block struct {
+	// Name says which input this is, e.g. "private_subnet_cidrs[1]".
+	Name string
+	// CIDR is the value as written.
+	CIDR string
+	// Prefix is the parsed block; it is invalid when CIDR is not a valid
+	// IPv4 network.
+	Prefix netip.Prefix
+	// Range is where the value is written.
+	Range hcl.Range
+}
+
+func newBlock(name, cidr string, rng hcl.Range) Block {
+	b := Block{Name: name, CIDR: cidr, Range: rng}
+	if p, err := netip.ParsePrefix(cidr); err == nil && p.Addr().Is4() && p == p.Masked() {
+		b.Prefix = p
+	}
+	return b
+}
+
+// Network is a VPC and its subnets, as one configuration or test sets them.
+type Network struct {
+	// Name says where the network is set, e.g. "envs/dev module.vpc".
+	Name string
+	// Groups name the sets of networks this one must not overlap with, such
+	// as GroupEnvironments or the attachments of a transit gateway. Networks
+	// in no group, like those of independent examples and tests, are only
+	// checked on their own.
+	Groups []string
+	// VPC is the VPC block, or nil when it is not known offline.
+	VPC *Block
+	// Subnets are the subnet blocks known offline.
+	Subnets []Block
+	// Unknown lists the inputs that could not be evaluated offline.
+	Unknown []string
+	// TransitGateway marks a transit gateway CIDR block, held in VPC, which
+	// is sized by MaxTransitGatewayBits rather than the VPC limits.
+	TransitGateway bool
+}
+
+func (n *Network) inGroup(group string) bool {
+	for _, g := range n.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Problem is a mistake in the address plan.
+type Problem struct {
+	Rule     string
+	Severity analysis.Severity
+	Network  string
+	Message  string
+	Range    hcl.Range
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: [%s] %s: %s (%s)", p.Range.Filename, p.Range.Start.Line, p.Severity, p.Network, p.Message, p.Rule)
+}
+
+// Finding converts p to a finding with its file relative to root.
+func (p Problem) Finding(root string) analysis.Finding {
+	return analysis.At(analysis.Finding{
+		Tool:     ToolNetplan,
+		Rule:     p.Rule,
+		Severity: p.Severity,
+		Message:  p.Network + ": " + p.Message,
+	}, root, p.Range)
+}
+
+// Check returns the problems of networks, ordered by location.
+func Check(networks []Network) []Problem {
+	var problems []Problem
+	report := func(rule string, n Network, rng hcl.Range, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Rule:     rule,
+			Severity: analysis.SeverityHigh,
+			Network:  n.Name,
+			Message:  fmt.Sprintf(format, args...),
+			Range:    rng,
+		})
+	}
+
+	for _, n := range networks {
+		blocks := n.Subnets
+		if n.VPC != nil {
+			blocks = append([]Block{*n.VPC}, blocks...)
+		}
+		for _, b := range blocks {
+			switch {
+			case !b.Prefix.IsValid():
+				message := fmt.Sprintf("%s %q is not an IPv4 network", b.Name, b.CIDR)
+				if p, err := netip.ParsePrefix(b.CIDR); err == nil && p.Addr().Is4() {
+					message += fmt.Sprintf("; did you mean %s?", p.Masked())
+				}
+				report(RuleInvalid, n, b.Range, "%s", message)
+			case n.TransitGateway:
+				if b.Prefix.Bits() > MaxTransitGatewayBits {
+					report(RuleSize, n, b.Range, "%s %s is a /%d; AWS allows /%d or larger", b.Name, b.Prefix, b.Prefix.Bits(), MaxTransitGatewayBits)
+				}
+			case b.Prefix.Bits() < MinPrefixBits || b.Prefix.Bits() > MaxPrefixBits:
+				report(RuleSize, n, b.Range, "%s %s is a /%d; AWS allows /%d to /%d", b.Name, b.Prefix, b.Prefix.Bits(), MinPrefixBits, MaxPrefixBits)
+			}
+		}
+
+		if n.VPC != nil && n.VPC.Prefix.IsValid() {
+			for _, s := range n.Subnets {
+				if s.Prefix.IsValid() && !contains(n.VPC.Prefix, s.Prefix) {
+					report(RuleContainment, n, s.Range, "%s %s is outside vpc_cidr_block %s", s.Name, s.Prefix, n.VPC.Prefix)
+				}
+			}
+		}
+		for i, a := range n.Subnets {
+			for _, b := range n.Subnets[i+1:] {
+				if a.Prefix.IsValid() && b.Prefix.IsValid() && a.Prefix.Overlaps(b.Prefix) {
+					report(RuleSubnetOverlap, n, b.Range, "%s %s overlaps %s %s", b.Name, b.Prefix, a.Name, a.Prefix)
+				}
+			}
+		}
+	}
+
+	for _, group := range groups(networks) {
+		var members []Network
+		for _, n := range networks {
+			if n.inGroup(group) && n.VPC != nil && n.VPC.Prefix.IsValid() {
+				members = append(members, n)
+			}
+		}
+		for i, a := range members {
+			for _, b := range members[i+1:] {
+				if a.VPC.Prefix.Overlaps(b.VPC.Prefix) {
+					report(RuleNetworkOverlap, b, b.VPC.Range, "%s %s overlaps %s %s of %s, both in %s",
+						b.VPC.Name, b.VPC.Prefix, a.VPC.Name, a.VPC.Prefix, a.Name, group)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i].Range, problems[j].Range
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Start.Line < b.Start.Line
+	})
+	return problems
+}
+
+// groups returns the groups of networks, in order.
+func groups(networks []Network) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, n := range networks {
+		for _, g := range n.Groups {
+			if !seen[g] {
+				seen[g] = true
+				out = append(out, g)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// contains reports whether outer contains all of inner.
+func contains(outer, inner netip.Prefix) bool {
+	return outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr())
+}
@@ -0,0 +1,160 @@
+package netplan
+
+import (
+	"fmt"
+	"go/token"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/fixtures"
+)
+
+func load(t *testing.T) ([]discovery.Config, []Network) {
+	t.Helper()
+	configs, err := discovery.Discover("testdata")
+	require.NoError(t, err)
+	var networks []Network
+	for _, cfg := range configs {
+		tfvars, err := filepath.Glob(filepath.Join(cfg.Path, "*.tfvars"))
+		require.NoError(t, err)
+		n, err := FromConfig(cfg, tfvars)
+		require.NoError(t, err)
+		networks = append(networks, n...)
+	}
+	return configs, networks
+}
+
+func blockStrings(blocks []Block) []string {
+	var out []string
+	for _, b := range blocks {
+		out = append(out, b.Name+"="+b.CIDR)
+	}
+	return out
+}
+
+func TestFromConfig(t *testing.T) {
+	_, networks := load(t)
+	byName := map[string][]Network{}
+	for _, n := range networks {
+		byName[n.Name] = append(byName[n.Name], n)
+	}
+
+	a := byName["envs/a module.vpc"]
+	require.Len(t, a, 1)
+	assert.Equal(t, []string{GroupEnvironments}, a[0].Groups)
+	require.NotNil(t, a[0].VPC)
+	assert.Equal(t, "10.1.0.0/16", a[0].VPC.CIDR, "set by a.tfvars")
+	assert.Equal(t, filepath.Join("testdata", "envs", "a", "a.tfvars"), a[0].VPC.Range.Filename)
+	assert.Equal(t, []string{
+		"public_subnet_cidrs[0]=10.0.1.0/24",
+		"public_subnet_cidrs[1]=10.0.2.0/24",
+		"private_subnet_cidrs[0]=10.1.10.0/24",
+		"private_subnet_cidrs[1]=10.1.20.0/24",
+	}, blockStrings(a[0].Subnets))
+	assert.Empty(t, a[0].Unknown)
+
+	b := byName["envs/b module.vpc"]
+	require.Len(t, b, 1)
+	assert.Equal(t, []string{"private_subnet_cidrs"}, b[0].Unknown)
+	assert.Equal(t, 9, b[0].Subnets[1].Range.Start.Line)
+
+	vpcA := byName["examples/tgw module.vpc_a"]
+	require.Len(t, vpcA, 1)
+	assert.Equal(t, []string{"examples/tgw module.tgw"}, vpcA[0].Groups)
+
+	tgw := byName["examples/tgw module.tgw"]
+	require.Len(t, tgw, 2, "IPv6 blocks are skipped")
+	assert.True(t, tgw[0].TransitGateway)
+	assert.Equal(t, "transit_gateway_cidr_blocks[0]", tgw[0].VPC.Name)
+}
+
+func TestFromFixture(t *testing.T) {
+	configs, _ := load(t)
+	pos := token.Position{Filename: "vpc_test.go", Line: 12, Column: 3}
+	vars := []fixtures.Var{
+		{Name: "vpc_cidr_block", Value: cty.StringVal("10.2.0.0/16"), Pos: pos},
+		{Name: "public_subnet_cidrs", Value: cty.TupleVal([]cty.Value{cty.StringVal("10.2.1.0/24")}), Pos: pos},
+	}
+
+	direct := FromFixture(fixtures.Fixture{Pos: pos, Func: "TestVPC", Dir: VPCModule, Vars: vars}, configs)
+	require.Len(t, direct, 1)
+	assert.Equal(t, "vpc_test.go:12 TestVPC", direct[0].Name)
+	assert.Equal(t, "10.2.0.0/16", direct[0].VPC.CIDR)
+	assert.Equal(t, 12, direct[0].VPC.Range.Start.Line)
+	assert.Equal(t, []string{"public_subnet_cidrs[0]=10.2.1.0/24"}, blockStrings(direct[0].Subnets))
+	assert.Equal(t, []string{"private_subnet_cidrs"}, direct[0].Unknown)
+
+	env := FromFixture(fixtures.Fixture{Pos: pos, Func: "TestEnv", Dir: "envs/a", Vars: vars[:1]}, configs)
+	require.Len(t, env, 1)
+	assert.Equal(t, "vpc_test.go:12 TestEnv module.vpc", env[0].Name)
+	assert.Equal(t, "10.2.0.0/16", env[0].VPC.CIDR)
+	assert.Equal(t, "10.2.10.0/24", env[0].Subnets[2].CIDR)
+
+	assert.Empty(t, FromFixture(fixtures.Fixture{Pos: pos, Dir: "modules/missing", Vars: vars}, configs))
+}
+
+func TestCheck(t *testing.T) {
+	_, networks := load(t)
+	var got []string
+	for _, p := range Check(networks) {
+		got = append(got, fmt.Sprintf("%s:%d %s %s: %s", filepath.Base(p.Range.Filename), p.Range.Start.Line, p.Rule, p.Network, p.Message))
+	}
+	assert.Equal(t, []string{
+		"main.tf:10 cidr-containment envs/a module.vpc: public_subnet_cidrs[0] 10.0.1.0/24 is outside vpc_cidr_block 10.1.0.0/16",
+		"main.tf:10 cidr-containment envs/a module.vpc: public_subnet_cidrs[1] 10.0.2.0/24 is outside vpc_cidr_block 10.1.0.0/16",
+		"main.tf:8 cidr-network-overlap envs/b module.vpc: vpc_cidr_block 10.1.0.0/16 overlaps vpc_cidr_block 10.1.0.0/16 of envs/a module.vpc, both in envs",
+	}, got[:3])
+	assert.Contains(t, got, `main.tf:9 cidr-invalid envs/b module.vpc: public_subnet_cidrs[2] "10.1.3.5/24" is not an IPv4 network; did you mean 10.1.3.0/24?`)
+	assert.Contains(t, got, "main.tf:9 cidr-subnet-overlap envs/b module.vpc: public_subnet_cidrs[1] 10.1.1.128/25 overlaps public_subnet_cidrs[0] 10.1.1.0/24")
+	assert.Contains(t, got, "main.tf:12 cidr-network-overlap examples/tgw module.vpc_b: vpc_cidr_block 172.16.128.0/17 overlaps vpc_cidr_block 172.16.0.0/16 of examples/tgw module.vpc_a, both in examples/tgw module.tgw")
+	assert.Contains(t, got, "main.tf:20 cidr-size examples/tgw module.tgw: transit_gateway_cidr_blocks[1] 192.168.1.0/28 is a /28; AWS allows /24 or larger")
+	assert.Len(t, got, 7)
+}
+
+func TestCheckSize(t *testing.T) {
+	vpc := newBlock("vpc_cidr_block", "10.0.0.0/8", hcl.Range{})
+	problems := Check([]Network{{Name: "n", VPC: &vpc, Subnets: []Block{newBlock("s", "10.0.0.0/29", hcl.Range{})}}})
+	require.Len(t, problems, 2)
+	assert.Equal(t, "vpc_cidr_block 10.0.0.0/8 is a /8; AWS allows /16 to /28", problems[0].Message)
+	assert.Equal(t, "s 10.0.0.0/29 is a /29; AWS allows /16 to /28", problems[1].Message)
+}
+
+func TestPropose(t *testing.T) {
+	pool := netip.MustParsePrefix("10.0.0.0/8")
+	used := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("10.1.0.0/17")}
+
+	a, err := Propose(pool, used, DefaultLayout)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"vpc_cidr_block":       "10.2.0.0/16",
+		"public_subnet_cidrs":  []string{"10.2.1.0/24", "10.2.2.0/24"},
+		"private_subnet_cidrs": []string{"10.2.10.0/24", "10.2.20.0/24"},
+	}, a.Vars())
+
+	// A /20 split into /22s has no block 10 or 20; those subnets take the
+	// first free blocks instead.
+	small, err := Propose(pool, used, Layout{VPCBits: 20, SubnetBits: 22, Public: 1, Private: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.128.0/20", small.VPC.String())
+	assert.Equal(t, []string{"10.1.132.0/22"}, prefixStrings(small.Public))
+	assert.Equal(t, []string{"10.1.128.0/22", "10.1.136.0/22"}, prefixStrings(small.Private))
+
+	_, err = Propose(netip.MustParsePrefix("10.0.0.0/15"), used, DefaultLayout)
+	assert.EqualError(t, err, "no free /16 left in 10.0.0.0/15")
+	_, err = Propose(pool, nil, Layout{VPCBits: 24, SubnetBits: 26, Public: 3, Private: 2})
+	assert.EqualError(t, err, "a /24 VPC has room for 4 /26 subnets, not 5")
+	_, err = Propose(pool, nil, Layout{VPCBits: 12, SubnetBits: 24})
+	assert.Error(t, err)
+}
+
+func TestUsed(t *testing.T) {
+	_, networks := load(t)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("10.1.0.0/16")}, Used(networks, GroupEnvironments))
+}
@@ -0,0 +1,125 @@
+package netplan
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// Layout is the shape of a VPC to allocate: its prefix length, the prefix
+// length of its subnets and how many of each tier it has.
+type Layout struct {
+	VPCBits    int
+	SubnetBits int
+	Public     int
+	Private    int
+}
+
+// DefaultLayout is the layout of the repository's VPCs: a /16 with one /24
+// public and private subnet per availability zone.
+var DefaultLayout = Layout{VPCBits: 16, SubnetBits: 24, Public: 2, Private: 2}
+
+// Allocation is a proposed VPC and its subnets.
+type Allocation struct {
+	VPC     netip.Prefix
+	Public  []netip.Prefix
+	Private []netip.Prefix
+}
+
+// Vars returns the allocation as the variables of modules/vpc.
+func (a Allocation) Vars() map[string]interface{} {
+	return map[string]interface{}{
+		"vpc_cidr_block":       a.VPC.String(),
+		"public_subnet_cidrs":  prefixStrings(a.Public),
+		"private_subnet_cidrs": prefixStrings(a.Private),
+	}
+}
+
+// Propose allocates the first VPC of the layout in pool that overlaps none
+// of used. Subnets follow the numbering of the existing VPCs: the i-th
+// public subnet is block i+1 of the VPC and the i-th private subnet block
+// 10*(i+1), falling back to the next free blocks when those run out.
+func Propose(pool netip.Prefix, used []netip.Prefix, layout Layout) (Allocation, error) {
+	if !pool.IsValid() || !pool.Addr().Is4() || pool != pool.Masked() {
+		return Allocation{}, fmt.Errorf("pool %s is not an IPv4 network", pool)
+	}
+	if layout.VPCBits < pool.Bits() || layout.VPCBits < MinPrefixBits || layout.VPCBits > MaxPrefixBits {
+		return Allocation{}, fmt.Errorf("a /%d VPC does not fit pool %s and the /%d to /%d AWS allows",
+			layout.VPCBits, pool, MinPrefixBits, MaxPrefixBits)
+	}
+	if layout.SubnetBits < layout.VPCBits || layout.SubnetBits > MaxPrefixBits {
+		return Allocation{}, fmt.Errorf("a /%d subnet does not fit a /%d VPC", layout.SubnetBits, layout.VPCBits)
+	}
+	subnets := 1 << (layout.SubnetBits - layout.VPCBits)
+	if layout.Public+layout.Private > subnets {
+		return Allocation{}, fmt.Errorf("a /%d VPC has room for %d /%d subnets, not %d",
+			layout.VPCBits, subnets, layout.SubnetBits, layout.Public+layout.Private)
+	}
+
+	for i := 0; i < 1<<(layout.VPCBits-pool.Bits()); i++ {
+		vpc := nth(pool, layout.VPCBits, i)
+		if overlapsAny(vpc, used) {
+			continue
+		}
+		a := Allocation{VPC: vpc}
+		taken := map[int]bool{}
+		pick := func(preferred int) netip.Prefix {
+			if preferred >= subnets || taken[preferred] {
+				for preferred = 0; taken[preferred]; preferred++ {
+				}
+			}
+			taken[preferred] = true
+			return nth(vpc, layout.SubnetBits, preferred)
+		}
+		for j := 0; j < layout.Public; j++ {
+			a.Public = append(a.Public, pick(j+1))
+		}
+		for j := 0; j < layout.Private; j++ {
+			a.Private = append(a.Private, pick(10*(j+1)))
+		}
+		return a, nil
+	}
+	return Allocation{}, fmt.Errorf("no free /%d left in %s", layout.VPCBits, pool)
+}
+
+// Used returns the VPC blocks of the networks in group, ordered by address.
+func Used(networks []Network, group string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, n := range networks {
+		if n.inGroup(group) && n.VPC != nil && n.VPC.Prefix.IsValid() {
+			out = append(out, n.VPC.Prefix)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if c := out[i].Addr().Compare(out[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return out[i].Bits() < out[j].Bits()
+	})
+	return out
+}
+
+// nth returns the i-th block of the given prefix length within p.
+func nth(p netip.Prefix, bits, i int) netip.Prefix {
+	base := p.Addr().As4()
+	n := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	n += uint32(i) << (32 - bits)
+	return netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}), bits)
+}
+
+func overlapsAny(p netip.Prefix, used []netip.Prefix) bool {
+	for _, u := range used {
+		if p.Overlaps(u) {
+			return true
+		}
+	}
+	return false
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	out := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		out[i] = p.String()
+	}
+	return out
+}
@@ -0,0 +1,269 @@
+package netplan
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/fixtures"
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
+)
+
+// The modules whose CIDR inputs make up the address plan.
+const (
+	VPCModule            = "modules/vpc"
+	TransitGatewayModule = "modules/vpc-transit-gw"
+)
+
+// input is the value of a variable and where it is set. expr is nil for
+// values set from Go, which are located at rng as a whole.
+type input struct {
+	value cty.Value
+	expr  hcl.Expression
+	rng   hcl.Range
+}
+
+// FromConfig returns the networks set by cfg's calls of modules/vpc, with
+// variables taken from the tfvars files at tfvars over their defaults. The
+// VPCs of environments are in GroupEnvironments, and the VPCs a call of
+// modules/vpc-transit-gw attaches are grouped with its
+// transit_gateway_cidr_blocks.
+func FromConfig(cfg discovery.Config, tfvars []string) ([]Network, error) {
+	vars := defaults(cfg)
+	for _, file := range tfvars {
+		f, diags := hclparse.NewParser().ParseHCLFile(file)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attrs, diags := f.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for name, attr := range attrs {
+			if value, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+				vars[name] = input{value: value, expr: attr.Expr, rng: attr.Expr.Range()}
+			}
+		}
+	}
+	return evaluate(cfg, cfg.ID(), vars), nil
+}
+
+// FromFixture returns the networks a Go test sets up by passing f's
+// variables to its configuration: modules/vpc itself, or a configuration
+// calling it, found in configs by ID. Fixtures of other configurations set
+// up no networks.
+func FromFixture(f fixtures.Fixture, configs []discovery.Config) []Network {
+	vars := map[string]input{}
+	name := fmt.Sprintf("%s:%d %s", filepath.Base(f.Pos.Filename), f.Pos.Line, f.Func)
+	for _, cfg := range configs {
+		if cfg.ID() != f.Dir {
+			continue
+		}
+		vars = defaults(cfg)
+		for _, v := range f.Vars {
+			vars[v.Name] = input{value: v.Value, rng: hcl.Range{
+				Filename: f.Pos.Filename,
+				Start:    hcl.Pos{Line: v.Pos.Line, Column: v.Pos.Column, Byte: v.Pos.Offset},
+				End:      hcl.Pos{Line: v.Pos.Line, Column: v.Pos.Column, Byte: v.Pos.Offset},
+			}}
+		}
+		if f.Dir == VPCModule {
+			n := network(name, func(arg string) (input, bool) {
+				in, ok := vars[arg]
+				return in, ok
+			})
+			return []Network{n}
+		}
+		return evaluate(cfg, name, vars)
+	}
+	return nil
+}
+
+// defaults returns the default values of cfg's variables.
+func defaults(cfg discovery.Config) map[string]input {
+	vars := map[string]input{}
+	for _, v := range cfg.Variables {
+		attr, ok := v.Attributes["default"]
+		if !ok {
+			continue
+		}
+		if value, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+			vars[v.Name] = input{value: value, expr: attr.Expr, rng: attr.Expr.Range()}
+		}
+	}
+	return vars
+}
+
+// evaluate returns the networks of cfg's module calls, named after prefix,
+// with its variables set to vars.
+func evaluate(cfg discovery.Config, prefix string, vars map[string]input) []Network {
+	known := map[string]cty.Value{}
+	for name, in := range vars {
+		known[name] = in.value
+	}
+	for _, v := range cfg.Variables {
+		if _, ok := known[v.Name]; !ok {
+			known[v.Name] = cty.DynamicVal
+		}
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(known)},
+		Functions: tfcheck.Functions(),
+	}
+
+	var networks []Network
+	byCall := map[string]int{}
+	for _, call := range cfg.Modules {
+		if !call.IsLocal() || path.Join(cfg.ID(), call.Source) != VPCModule {
+			continue
+		}
+		n := network(prefix+" module."+call.Name, func(arg string) (input, bool) {
+			attr, ok := call.Arguments[arg]
+			if !ok {
+				return input{}, false
+			}
+			if name, ok := discovery.VariableReference(attr.Expr); ok {
+				in, ok := vars[name]
+				return in, ok
+			}
+			value, diags := attr.Expr.Value(ctx)
+			if diags.HasErrors() {
+				value = cty.DynamicVal
+			}
+			return input{value: value, expr: attr.Expr, rng: attr.Expr.Range()}, true
+		})
+		if cfg.Kind == discovery.KindEnv {
+			n.Groups = append(n.Groups, GroupEnvironments)
+		}
+		byCall[call.Name] = len(networks)
+		networks = append(networks, n)
+	}
+
+	for _, call := range cfg.Modules {
+		if !call.IsLocal() || path.Join(cfg.ID(), call.Source) != TransitGatewayModule {
+			continue
+		}
+		group := fmt.Sprintf("%s module.%s", prefix, call.Name)
+		if attr, ok := call.Arguments["vpc_attachments"]; ok {
+			for _, vpc := range attachedModules(attr.Expr) {
+				if i, ok := byCall[vpc]; ok {
+					networks[i].Groups = append(networks[i].Groups, group)
+				}
+			}
+		}
+		if attr, ok := call.Arguments["transit_gateway_cidr_blocks"]; ok {
+			value, diags := attr.Expr.Value(ctx)
+			if diags.HasErrors() {
+				continue
+			}
+			in := input{value: value, expr: attr.Expr, rng: attr.Expr.Range()}
+			list, _ := blockList("transit_gateway_cidr_blocks", in)
+			for _, b := range list {
+				b := b
+				if p, err := netip.ParsePrefix(b.CIDR); err == nil && p.Addr().Is6() {
+					// IPv6 blocks are outside the IPv4 plan.
+					continue
+				}
+				networks = append(networks, Network{Name: group, Groups: []string{group}, VPC: &b, TransitGateway: true})
+			}
+		}
+	}
+	return networks
+}
+
+// network builds a network from the inputs of modules/vpc, which arg looks
+// up by variable name.
+func network(name string, arg func(string) (input, bool)) Network {
+	n := Network{Name: name}
+	if in, ok := arg("vpc_cidr_block"); ok {
+		if in.value.IsWhollyKnown() && !in.value.IsNull() && in.value.Type() == cty.String {
+			b := newBlock("vpc_cidr_block", in.value.AsString(), in.rng)
+			n.VPC = &b
+		} else {
+			n.Unknown = append(n.Unknown, "vpc_cidr_block")
+		}
+	} else {
+		n.Unknown = append(n.Unknown, "vpc_cidr_block")
+	}
+	for _, variable := range []string{"public_subnet_cidrs", "private_subnet_cidrs"} {
+		in, ok := arg(variable)
+		if !ok {
+			n.Unknown = append(n.Unknown, variable)
+			continue
+		}
+		blocks, ok := blockList(variable, in)
+		if !ok {
+			n.Unknown = append(n.Unknown, variable)
+		}
+		n.Subnets = append(n.Subnets, blocks...)
+	}
+	return n
+}
+
+// blockList reads a list of CIDR strings, locating each element where it is
+// written when the list is a literal. It reports false if any element is
+// not known.
+func blockList(name string, in input) ([]Block, bool) {
+	if !in.value.IsKnown() || in.value.IsNull() || !in.value.CanIterateElements() {
+		return nil, false
+	}
+	var elems []hclsyntax.Expression
+	if tuple, ok := in.expr.(*hclsyntax.TupleConsExpr); ok {
+		elems = tuple.Exprs
+	}
+	var blocks []Block
+	complete := true
+	i := 0
+	for it := in.value.ElementIterator(); it.Next(); i++ {
+		_, elem := it.Element()
+		if !elem.IsKnown() || elem.IsNull() || elem.Type() != cty.String {
+			complete = false
+			continue
+		}
+		rng := in.rng
+		if i < len(elems) {
+			rng = elems[i].Range()
+		}
+		blocks = append(blocks, newBlock(fmt.Sprintf("%s[%d]", name, i), elem.AsString(), rng))
+	}
+	return blocks, complete
+}
+
+// attachedModules returns the module calls whose vpc_id the attachments of
+// a vpc_attachments object name, as in vpc_id = module.vpc_a.vpc_id.
+func attachedModules(expr hcl.Expression) []string {
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, item := range obj.Items {
+		attachment, ok := item.ValueExpr.(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			continue
+		}
+		for _, field := range attachment.Items {
+			key, diags := field.KeyExpr.Value(nil)
+			if diags.HasErrors() || key.Type() != cty.String || key.AsString() != "vpc_id" {
+				continue
+			}
+			ref, ok := field.ValueExpr.(*hclsyntax.ScopeTraversalExpr)
+			if !ok || len(ref.Traversal) < 2 || ref.Traversal.RootName() != "module" {
+				continue
+			}
+			if attr, ok := ref.Traversal[1].(hcl.TraverseAttr); ok {
+				out = append(out, attr.Name)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,12 @@
+variable "vpc_cidr_block" {
+  type    = string
+  default = "10.0.0.0/16"
+}
+
+module "vpc" {
+  source = "../../modules/vpc"
+
+  vpc_cidr_block       = var.vpc_cidr_block
+  public_subnet_cidrs  = ["10.0.1.0/24", "10.0.2.0/24"]
+  private_subnet_cidrs = [cidrsubnet(var.vpc_cidr_block, 8, 10), cidrsubnet(var.vpc_cidr_block, 8, 20)]
+}
@@ -0,0 +1,11 @@
+variable "private_subnet_cidrs" {
+  type = list(string)
+}
+
+module "vpc" {
+  source = "../../modules/vpc"
+
+  vpc_cidr_block       = "10.1.0.0/16"
+  public_subnet_cidrs  = ["10.1.1.0/24", "10.1.1.128/25", "10.1.3.5/24"]
+  private_subnet_cidrs = var.private_subnet_cidrs
+}
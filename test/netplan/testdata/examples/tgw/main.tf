@@ -0,0 +1,25 @@
+module "vpc_a" {
+  source = "../../modules/vpc"
+
+  vpc_cidr_block       = "172.16.0.0/16"
+  public_subnet_cidrs  = ["172.16.1.0/24"]
+  private_subnet_cidrs = ["172.16.10.0/24"]
+}
+
+module "vpc_b" {
+  source = "../../modules/vpc"
+
+  vpc_cidr_block       = "172.16.128.0/17"
+  public_subnet_cidrs  = ["172.16.129.0/24"]
+  private_subnet_cidrs = ["172.16.138.0/24"]
+}
+
+module "tgw" {
+  source = "../../modules/vpc-transit-gw"
+
+  transit_gateway_cidr_blocks = ["192.168.0.0/24", "192.168.1.0/28", "fd00::/56"]
+  vpc_attachments = {
+    a = { vpc_id = module.vpc_a.vpc_id }
+    b = { vpc_id = module.vpc_b.vpc_id }
+  }
+}
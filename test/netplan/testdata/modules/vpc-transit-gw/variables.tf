@@ -0,0 +1,9 @@
+variable "transit_gateway_cidr_blocks" {
+  type    = list(string)
+  default = []
+}
+
+variable "vpc_attachments" {
+  type    = any
+  default = {}
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/fixtures"
+	"github.com/your-org/terraform-aws-modules/test/netplan"
+)
+
+// addressPool is the private range new environment VPCs are allocated from
+var addressPool = netip.MustParsePrefix("10.0.0.0/8")
+
+// TestNetworkPlan checks the CIDR blocks of every VPC set up by the environments, the examples and the
+// variables the Go tests pass: subnets must lie inside their VPC and not overlap each other, and the
+// environments and the VPCs attached to one transit gateway must not overlap. It then proposes the
+// blocks of a new environment that overlap none of the existing ones
+func TestNetworkPlan(t *testing.T) {
+	report := sarifReport(t)
+	configs := discoverConfigs(t, discovery.KindModule, discovery.KindExample, discovery.KindEnv)
+
+	var networks []netplan.Network
+	for _, cfg := range configs {
+		tfvars, err := filepath.Glob(filepath.Join(cfg.Path, "*.tfvars"))
+		require.NoError(t, err)
+		found, err := netplan.FromConfig(cfg, tfvars)
+		require.NoError(t, err)
+		networks = append(networks, found...)
+	}
+
+	found, _, err := fixtures.Extract(".", repoRoot)
+	require.NoError(t, err)
+	for _, f := range found {
+		networks = append(networks, netplan.FromFixture(f, configs)...)
+	}
+	require.NotEmpty(t, networks, "no calls of %s found", netplan.VPCModule)
+
+	for _, n := range networks {
+		if len(n.Unknown) > 0 {
+			t.Logf("%s: %v not known offline", n.Name, n.Unknown)
+		}
+	}
+	for _, p := range netplan.Check(networks) {
+		t.Error(p)
+		report.Add(p.Finding(repoRoot))
+	}
+
+	used := netplan.Used(networks, netplan.GroupEnvironments)
+	allocation, err := netplan.Propose(addressPool, used, netplan.DefaultLayout)
+	require.NoError(t, err)
+	for _, u := range used {
+		assert.False(t, allocation.VPC.Overlaps(u), "proposed %s overlaps %s", allocation.VPC, u)
+	}
+	t.Logf("checked %d networks; a new environment could use %v", len(networks), allocation.Vars())
+}
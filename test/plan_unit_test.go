@@ -0,0 +1,237 @@
+package test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/golden"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// updateGolden rewrites the golden plan snapshots from the current plans instead of comparing with them
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in "+golden.Dir+" with the current output")
+
+// offlinePlanVars holds values for the required variables of each module and
+// example, chosen to pass their validation blocks. IDs are fake; nothing is
+// looked up in AWS.
+var offlinePlanVars = map[string]map[string]interface{}{
+	"modules/ec2": {
+		"name_prefix": "unit",
+		"subnet_ids":  []string{"subnet-0a1b2c3d4e5f60001"},
+	},
+	"modules/ecs": {
+		"cluster_name": "unit",
+	},
+	"modules/efs": {
+		"name":       "unit",
+		"vpc_id":     "vpc-0a1b2c3d4e5f60001",
+		"subnet_ids": []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"modules/eks": {
+		"cluster_name":       "unit",
+		"private_subnet_ids": []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"modules/elb": {
+		"name":       "unit",
+		"vpc_id":     "vpc-0a1b2c3d4e5f60001",
+		"subnet_ids": []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"modules/s3": {
+		"bucket_name": "unit-test-bucket",
+	},
+	"modules/sns": {
+		"topic_name": "unit",
+	},
+	"modules/sqs": {
+		"queue_name": "unit",
+	},
+	"modules/vpc-endpoints": {
+		"vpc_id": "vpc-0a1b2c3d4e5f60001",
+		// Exercise the aws_vpc_endpoint_service data source against the stub
+		"endpoints": map[string]interface{}{
+			"s3": map[string]interface{}{
+				"service_name":      "s3",
+				"vpc_endpoint_type": "Gateway",
+			},
+		},
+	},
+	"modules/vpc-transit-gw": {
+		"name": "unit",
+	},
+	"examples/ec2-basic": {
+		"name_prefix":      "unit",
+		"ami_id":           "ami-0a1b2c3d4e5f60001",
+		"instance_type":    "t3.micro",
+		"key_name":         "unit",
+		"subnet_ids":       []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+		"desired_capacity": 1,
+		"min_size":         1,
+		"max_size":         2,
+	},
+	"examples/ec2-iam-integration": {
+		"name_prefix": "unit",
+		"ami_id":      "ami-0a1b2c3d4e5f60001",
+		"key_name":    "unit",
+		"subnet_ids":  []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"examples/ec2-with-alb": {
+		"name_prefix":       "unit",
+		"ami_id":            "ami-0a1b2c3d4e5f60001",
+		"instance_type":     "t3.micro",
+		"key_name":          "unit",
+		"subnet_ids":        []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+		"target_group_arns": []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/unit/0a1b2c3d4e5f6071"},
+		"desired_capacity":  1,
+		"min_size":          1,
+		"max_size":          2,
+	},
+	"examples/ec2-with-scaling": {
+		"name_prefix":      "unit",
+		"ami_id":           "ami-0a1b2c3d4e5f60001",
+		"instance_type":    "t3.micro",
+		"key_name":         "unit",
+		"subnet_ids":       []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+		"desired_capacity": 1,
+		"min_size":         1,
+		"max_size":         2,
+	},
+	"examples/elb-basic": {
+		"name":       "unit",
+		"vpc_id":     "vpc-0a1b2c3d4e5f60001",
+		"subnet_ids": []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"examples/elb-with-target-groups": {
+		"name":       "unit",
+		"vpc_id":     "vpc-0a1b2c3d4e5f60001",
+		"subnet_ids": []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"examples/elb-with-vpc": {
+		"name":       "unit",
+		"vpc_id":     "vpc-0a1b2c3d4e5f60001",
+		"subnet_ids": []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+	},
+	"examples/iam-basic": {
+		"name_prefix": "unit",
+	},
+	"examples/iam-complex": {
+		"name_prefix": "unit",
+	},
+	"examples/iam-oidc": {
+		"name_prefix": "unit",
+	},
+	"examples/iam-policies": {
+		"name_prefix": "unit",
+	},
+	"examples/iam-roles": {
+		"name_prefix": "unit",
+	},
+	"examples/vpc-basic": {
+		"name_prefix":          "unit",
+		"vpc_cidr_block":       "10.0.0.0/16",
+		"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+		"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.20.0/24"},
+		"allowed_ips":          []string{"10.0.0.0/8"},
+	},
+	"examples/vpc-custom": {
+		"name_prefix":          "unit",
+		"vpc_cidr_block":       "172.16.0.0/16",
+		"public_subnet_cidrs":  []string{"172.16.1.0/24", "172.16.2.0/24", "172.16.3.0/24"},
+		"private_subnet_cidrs": []string{"172.16.10.0/24", "172.16.20.0/24", "172.16.30.0/24"},
+		"allowed_ips":          []string{"172.16.0.0/16"},
+	},
+}
+
+// TestOfflinePlan plans every module and example against a stubbed AWS API, so plan-level logic is
+// exercised on every commit without an AWS account or network access to AWS. Each plan is compared
+// with its snapshot in testdata/golden; run with -update to regenerate the snapshots
+func TestOfflinePlan(t *testing.T) {
+	stub := awsstub.Start(t)
+
+	for _, cfg := range discoverConfigs(t, discovery.KindModule, discovery.KindExample) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			terraformOptions := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), offlinePlanVars[cfg.ID()])
+
+			harness.InitAndPlan(t, terraformOptions)
+
+			snapshot, err := planjson.Show(t, terraformOptions).MarshalSnapshot()
+			require.NoError(t, err)
+			golden.Assert(t, golden.Path(cfg.ID()), snapshot, *updateGolden)
+		})
+	}
+}
+
+// TestVPCModuleOfflinePlan checks the planned subnets of the VPC module land in the canned availability zones
+func TestVPCModuleOfflinePlan(t *testing.T) {
+	stub := awsstub.Start(t)
+
+	terraformOptions := harness.OfflineOptions(t, stub, repoRoot, "modules/vpc", map[string]interface{}{
+		"name_prefix":          "unit",
+		"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+		"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.20.0/24"},
+	})
+
+	harness.InitAndPlan(t, terraformOptions)
+	plan := planjson.Show(t, terraformOptions)
+
+	planjson.AssertCreated(t, plan, "aws_vpc.this", map[string]interface{}{
+		"cidr_block": "10.0.0.0/16",
+	})
+	for i, subnet := range []string{"aws_subnet.public[0]", "aws_subnet.public[1]"} {
+		planjson.AssertCreated(t, plan, subnet, map[string]interface{}{
+			"availability_zone":       awsstub.AvailabilityZones[i].Name,
+			"map_public_ip_on_launch": true,
+			"tags.Name":               fmt.Sprintf("unit-public-subnet-%d", i+1),
+		})
+	}
+	assert.Len(t, plan.ResourceChanges.WithType("aws_subnet").WithAction(planjson.ActionCreate), 4)
+	assert.Len(t, plan.ResourceChanges.WithType("aws_nat_gateway").WithAction(planjson.ActionCreate), 2)
+}
+
+// TestSQSModuleOfflinePlan checks the queue plans kms_master_key_id and redrive_policy as arguments, the
+// redrive policy pointing at the dead letter queue
+func TestSQSModuleOfflinePlan(t *testing.T) {
+	stub := awsstub.Start(t)
+
+	plan := planjson.InitAndPlan(t, harness.OfflineOptions(t, stub, repoRoot, "modules/sqs", map[string]interface{}{
+		"queue_name":        "unit",
+		"create_dlq":        true,
+		"kms_master_key_id": "alias/aws/sqs",
+	}))
+
+	planjson.AssertCreated(t, plan, "aws_sqs_queue.main[0]", map[string]interface{}{
+		"kms_master_key_id": "alias/aws/sqs",
+	})
+	planjson.AssertAction(t, plan, "aws_sqs_queue.dlq[0]", planjson.ActionCreate)
+
+	// The policy embeds the dead letter queue ARN, so its value is only known
+	// after apply; the configuration shows where it comes from
+	queue, _ := plan.ResourceChange("aws_sqs_queue.main[0]")
+	assert.True(t, queue.AfterUnknown("redrive_policy"), "redrive_policy should be planned")
+
+	var config struct {
+		RootModule struct {
+			Resources []struct {
+				Address     string `json:"address"`
+				Expressions map[string]struct {
+					References []string `json:"references"`
+				} `json:"expressions"`
+			} `json:"resources"`
+		} `json:"root_module"`
+	}
+	require.NoError(t, json.Unmarshal(plan.Configuration, &config))
+	for _, resource := range config.RootModule.Resources {
+		if resource.Address == "aws_sqs_queue.main" {
+			assert.Contains(t, resource.Expressions["redrive_policy"].References, "aws_sqs_queue.dlq[0].arn")
+			assert.Contains(t, resource.Expressions["kms_master_key_id"].References, "var.kms_master_key_id")
+		}
+	}
+}
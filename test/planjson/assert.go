@@ -0,0 +1,88 @@
+package planjson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// AssertAction asserts that the resource at address is planned with action.
+func AssertAction(t testing.TB, plan *Plan, address string, action Action) bool {
+	t.Helper()
+
+	rc, ok := plan.ResourceChange(address)
+	if !ok {
+		return assert.Fail(t, "resource not in plan", "%s has no resource change; planned: %v", address, plan.ResourceChanges.Addresses())
+	}
+	return assert.Equal(t, action, rc.Change.Actions.Kind(), "planned action for %s", address)
+}
+
+// AssertCreated asserts that the resource at address is planned for creation
+// with the given attributes. attrs maps After paths to expected values, which
+// are compared after a JSON round trip so ints and typed slices match the
+// decoded plan.
+func AssertCreated(t testing.TB, plan *Plan, address string, attrs map[string]interface{}) bool {
+	t.Helper()
+
+	if !AssertAction(t, plan, address, ActionCreate) {
+		return false
+	}
+	rc, _ := plan.ResourceChange(address)
+	return AssertAttributes(t, rc, attrs)
+}
+
+// AssertAttributes asserts that the planned values of rc match attrs, keyed by
+// After path. Values that are unknown until apply fail the assertion.
+func AssertAttributes(t testing.TB, rc ResourceChange, attrs map[string]interface{}) bool {
+	t.Helper()
+
+	ok := true
+	for path, want := range attrs {
+		if rc.AfterUnknown(path) {
+			ok = assert.Fail(t, "attribute unknown", "%s.%s is only known after apply", rc.Address, path) && ok
+			continue
+		}
+		got, found := rc.After(path)
+		if !found {
+			ok = assert.Fail(t, "attribute missing", "%s has no planned value at %s", rc.Address, path) && ok
+			continue
+		}
+		ok = assert.Equal(t, normalize(t, want), got, "%s.%s", rc.Address, path) && ok
+	}
+	return ok
+}
+
+// AssertNoReplacements asserts that no resource is planned for replacement.
+func AssertNoReplacements(t testing.TB, plan *Plan) bool {
+	t.Helper()
+
+	replaced := plan.ResourceChanges.WithAction(ActionReplace)
+	return assert.Empty(t, replaced.Addresses(), "resources planned for replacement")
+}
+
+// AssertNoDeletions asserts that no resource is planned for deletion, either
+// outright or as part of a replacement.
+func AssertNoDeletions(t testing.TB, plan *Plan) bool {
+	t.Helper()
+
+	deleted := plan.ResourceChanges.Where(func(rc ResourceChange) bool {
+		return rc.Change.Actions.contains(ActionDelete)
+	})
+	return assert.Empty(t, deleted.Addresses(), "resources planned for deletion")
+}
+
+// normalize converts v to the shape encoding/json decodes plan values into.
+func normalize(t testing.TB, v interface{}) interface{} {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("normalizing %v: %v", v, err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("normalizing %v: %v", v, err)
+	}
+	return out
+}
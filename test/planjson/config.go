@@ -0,0 +1,330 @@
+package planjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Config is the configuration section of a plan: the resources and module
+// calls as written, with each argument's references to other objects. It
+// answers questions planned values cannot, such as which security group a
+// load balancer uses when the group's ID is only known after apply.
+type Config struct {
+	RootModule ConfigModule `json:"root_module"`
+}
+
+// ConfigModule is one module of the configuration.
+type ConfigModule struct {
+	Resources   []ConfigResource      `json:"resources"`
+	ModuleCalls map[string]ModuleCall `json:"module_calls"`
+}
+
+// ModuleCall is a module block and the configuration of the module it calls.
+type ModuleCall struct {
+	Source string       `json:"source"`
+	Module ConfigModule `json:"module"`
+}
+
+// ConfigResource is a resource block. Its address is relative to the module
+// it is declared in and has no instance key, e.g. "aws_security_group.alb".
+type ConfigResource struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	// Expressions maps each argument to its expression, or for nested
+	// blocks to a list of objects of expressions; see References.
+	Expressions map[string]json.RawMessage `json:"expressions"`
+}
+
+// Config decodes the configuration section of the plan.
+func (p *Plan) Config() (Config, error) {
+	var config Config
+	if len(p.Configuration) == 0 {
+		return config, nil
+	}
+	if err := json.Unmarshal(p.Configuration, &config); err != nil {
+		return Config{}, fmt.Errorf("parsing plan configuration: %w", err)
+	}
+	return config, nil
+}
+
+// Module returns the configuration of the module at address, a module
+// address as in ResourceChange.ModuleAddress. Instance keys are ignored,
+// since every instance of a module call shares its configuration.
+func (c Config) Module(address string) (ConfigModule, bool) {
+	module := c.RootModule
+	for _, name := range moduleNames(address) {
+		call, ok := module.ModuleCalls[name]
+		if !ok {
+			return ConfigModule{}, false
+		}
+		module = call.Module
+	}
+	return module, true
+}
+
+// Resource returns the resource block at address, relative to the module.
+func (m ConfigModule) Resource(address string) (ConfigResource, bool) {
+	for _, r := range m.Resources {
+		if r.Address == address {
+			return r, true
+		}
+	}
+	return ConfigResource{}, false
+}
+
+// ReferencedBy returns the resource blocks of type resourceType whose
+// argument refers to the resource at address.
+func (m ConfigModule) ReferencedBy(resourceType, argument, address string) []ConfigResource {
+	var out []ConfigResource
+	for _, r := range m.Resources {
+		if r.Type != resourceType {
+			continue
+		}
+		for _, ref := range r.References(argument) {
+			if ref == address {
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// References returns the resources the expression of argument refers to, as
+// addresses relative to the module without instance keys or attributes. A
+// path such as "vpc_config.security_group_ids" reaches into nested blocks.
+func (r ConfigResource) References(argument string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, ref := range r.rawReferences(argument) {
+		if address, ok := resourceReference(ref); ok && !seen[address] {
+			seen[address] = true
+			out = append(out, address)
+		}
+	}
+	return out
+}
+
+// InstanceReferences returns the resource instances the expression of
+// argument refers to from the instance of r with the given key, as in
+// ResourceChange.Index. A reference indexed by a literal key names that
+// instance. One indexed by count.index or each.key is taken to name the
+// instance with the referring instance's own key, the usual way of pairing
+// resources created together; other references come back without a key.
+func (r ConfigResource) InstanceReferences(argument string, key interface{}) []string {
+	refs := r.rawReferences(argument)
+	byKey := false
+	for _, ref := range refs {
+		if ref == "count.index" || ref == "each.key" {
+			byKey = true
+		}
+	}
+
+	seen := map[string]bool{}
+	var out []string
+	add := func(address string) {
+		if !seen[address] {
+			seen[address] = true
+			out = append(out, address)
+		}
+	}
+	indexed := map[string]bool{}
+	var bare []string
+	for _, ref := range refs {
+		base, ok := resourceReference(ref)
+		if !ok || !strings.HasPrefix(ref, base) {
+			continue
+		}
+		if end := keyEnd(ref[len(base):]); end > 0 {
+			indexed[base] = true
+			add(ref[:len(base)+end])
+		} else {
+			bare = append(bare, base)
+		}
+	}
+	for _, base := range bare {
+		if indexed[base] {
+			// Terraform lists the resource alongside its indexed instance.
+			continue
+		}
+		address := base
+		if byKey {
+			switch k := key.(type) {
+			case float64:
+				address = fmt.Sprintf("%s[%d]", base, int(k))
+			case string:
+				address = fmt.Sprintf("%s[%q]", base, k)
+			}
+		}
+		add(address)
+	}
+	return out
+}
+
+// Blocks returns the nested blocks of type name, each as a ConfigResource
+// holding the block's arguments, so that References, InstanceReferences and
+// Constant apply to one block at a time.
+func (r ConfigResource) Blocks(name string) []ConfigResource {
+	var blocks []map[string]json.RawMessage
+	if err := json.Unmarshal(r.Expressions[name], &blocks); err != nil {
+		return nil
+	}
+	out := make([]ConfigResource, len(blocks))
+	for i, b := range blocks {
+		out[i] = ConfigResource{Address: r.Address, Mode: r.Mode, Type: r.Type, Name: r.Name, Expressions: b}
+	}
+	return out
+}
+
+// Constant returns the value of argument when it is written as a constant.
+func (r ConfigResource) Constant(argument string) (interface{}, bool) {
+	var expr map[string]interface{}
+	if err := json.Unmarshal(r.Expressions[argument], &expr); err != nil {
+		return nil, false
+	}
+	value, ok := expr["constant_value"]
+	return value, ok
+}
+
+// rawReferences returns the references of argument as the plan lists them,
+// e.g. "aws_nat_gateway.this[0].id" or "count.index".
+func (r ConfigResource) rawReferences(argument string) []string {
+	parts := splitPath(argument)
+	if len(parts) == 0 {
+		return nil
+	}
+	raw, ok := r.Expressions[parts[0]]
+	if !ok {
+		return nil
+	}
+	var expr interface{}
+	if err := json.Unmarshal(raw, &expr); err != nil {
+		return nil
+	}
+	return expressionReferences(expr, parts[1:])
+}
+
+// keyEnd returns the length of the instance key s starts with, such as [0]
+// or ["a]"], or 0 if it starts with none.
+func keyEnd(s string) int {
+	if !strings.HasPrefix(s, "[") {
+		return 0
+	}
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ']':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ConfigAddress returns the address of the resource block rc is an instance
+// of, relative to its module.
+func (rc ResourceChange) ConfigAddress() string {
+	if rc.Mode == "data" {
+		return "data." + rc.Type + "." + rc.Name
+	}
+	return rc.Type + "." + rc.Name
+}
+
+// expressionReferences collects the references of an expression, descending
+// through nested blocks along path; with path exhausted, every reference
+// under expr counts.
+func expressionReferences(expr interface{}, path []string) []string {
+	switch e := expr.(type) {
+	case []interface{}:
+		var out []string
+		for _, block := range e {
+			out = append(out, expressionReferences(block, path)...)
+		}
+		return out
+	case map[string]interface{}:
+		if len(path) > 0 {
+			return expressionReferences(e[path[0]], path[1:])
+		}
+		var out []string
+		if refs, ok := e["references"].([]interface{}); ok {
+			for _, ref := range refs {
+				if s, ok := ref.(string); ok {
+					out = append(out, s)
+				}
+			}
+		}
+		for key, child := range e {
+			if key != "references" && key != "constant_value" {
+				out = append(out, expressionReferences(child, nil)...)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// resourceReference returns the resource address a reference such as
+// "aws_security_group.alb[0].id" is to, or false for references to
+// variables, locals, modules and other non-resource objects.
+func resourceReference(ref string) (string, bool) {
+	parts := strings.Split(stripKeys(ref), ".")
+	switch parts[0] {
+	case "var", "local", "module", "each", "count", "path", "terraform", "self":
+		return "", false
+	case "data":
+		if len(parts) < 3 {
+			return "", false
+		}
+		return strings.Join(parts[:3], "."), true
+	}
+	if len(parts) < 2 {
+		return "", false
+	}
+	return parts[0] + "." + parts[1], true
+}
+
+// moduleNames returns the names of the module calls in a module address,
+// e.g. ["a", "b"] for `module.a["x"].module.b`.
+func moduleNames(address string) []string {
+	var names []string
+	parts := strings.Split(stripKeys(address), ".")
+	for i := 0; i+1 < len(parts); i += 2 {
+		if parts[i] == "module" {
+			names = append(names, parts[i+1])
+		}
+	}
+	return names
+}
+
+// stripKeys removes instance keys such as [0] and ["a.b"] from an address.
+func stripKeys(address string) string {
+	var b strings.Builder
+	depth, quoted := 0, false
+	for i := 0; i < len(address); i++ {
+		c := address[i]
+		switch {
+		case quoted:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+		case c == '"' && depth > 0:
+			quoted = true
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package planjson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const configPlan = `{
+  "format_version": "1.2",
+  "configuration": {
+    "root_module": {
+      "module_calls": {
+        "web": {
+          "source": "../../modules/elb",
+          "module": {
+            "resources": [
+              {
+                "address": "aws_lb.this", "mode": "managed", "type": "aws_lb", "name": "this",
+                "expressions": {
+                  "name": {"references": ["var.name"]},
+                  "security_groups": {"references": ["aws_security_group.alb[0].id", "aws_security_group.alb", "var.load_balancer_type"]},
+                  "access_logs": [{"bucket": {"references": ["data.aws_s3_bucket.logs.id", "data.aws_s3_bucket.logs"]}}]
+                }
+              },
+              {"address": "aws_security_group.alb", "mode": "managed", "type": "aws_security_group", "name": "alb", "expressions": {"vpc_id": {"references": ["var.vpc_id"]}}}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestConfig(t *testing.T) {
+	plan, err := Parse([]byte(configPlan))
+	require.NoError(t, err)
+	config, err := plan.Config()
+	require.NoError(t, err)
+
+	_, ok := config.Module("module.missing")
+	assert.False(t, ok)
+
+	module, ok := config.Module(`module.web["a.b"]`)
+	require.True(t, ok)
+	lb, ok := module.Resource("aws_lb.this")
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"aws_security_group.alb"}, lb.References("security_groups"))
+	assert.Equal(t, []string{"data.aws_s3_bucket.logs"}, lb.References("access_logs"))
+	assert.Equal(t, []string{"data.aws_s3_bucket.logs"}, lb.References("access_logs.bucket"))
+	assert.Empty(t, lb.References("name"))
+	assert.Empty(t, lb.References("subnets"))
+
+	assert.Equal(t, []ConfigResource{lb}, module.ReferencedBy("aws_lb", "security_groups", "aws_security_group.alb"))
+	assert.Empty(t, module.ReferencedBy("aws_lb", "security_groups", "aws_security_group.other"))
+}
+
+func TestConfigAddress(t *testing.T) {
+	assert.Equal(t, "aws_subnet.public", ResourceChange{Mode: "managed", Type: "aws_subnet", Name: "public"}.ConfigAddress())
+	assert.Equal(t, "data.aws_region.current", ResourceChange{Mode: "data", Type: "aws_region", Name: "current"}.ConfigAddress())
+}
+
+func TestStripKeys(t *testing.T) {
+	assert.Equal(t, "module.a.module.b.aws_subnet.x", stripKeys(`module.a["k.[1]"].module.b[0].aws_subnet.x[2]`))
+	assert.Equal(t, []string{"a", "b"}, moduleNames(`module.a["x"].module.b`))
+	assert.Empty(t, moduleNames(""))
+}
+
+func TestInstanceReferences(t *testing.T) {
+	var table ConfigResource
+	require.NoError(t, json.Unmarshal([]byte(`{
+	  "address": "aws_route_table.private", "mode": "managed", "type": "aws_route_table", "name": "private",
+	  "expressions": {
+	    "vpc_id": {"references": ["aws_vpc.this.id", "aws_vpc.this"]},
+	    "route": [
+	      {"cidr_block": {"constant_value": "0.0.0.0/0"}, "nat_gateway_id": {"references": ["aws_nat_gateway.this", "count.index"]}},
+	      {"cidr_block": {"references": ["var.peer_cidr"]}, "gateway_id": {"references": ["aws_internet_gateway.this[\"a]\"].id", "aws_internet_gateway.this[\"a]\"]", "aws_internet_gateway.this"]}}
+	    ]
+	  }
+	}`), &table))
+
+	assert.Equal(t, []string{"aws_vpc.this"}, table.InstanceReferences("vpc_id", float64(1)))
+	routes := table.Blocks("route")
+	require.Len(t, routes, 2)
+	assert.Empty(t, table.Blocks("vpc_id"))
+
+	assert.Equal(t, []string{"aws_nat_gateway.this[1]"}, routes[0].InstanceReferences("nat_gateway_id", float64(1)))
+	assert.Equal(t, []string{`aws_nat_gateway.this["b"]`}, routes[0].InstanceReferences("nat_gateway_id", "b"))
+	assert.Equal(t, []string{"aws_nat_gateway.this"}, routes[0].InstanceReferences("nat_gateway_id", nil))
+	assert.Equal(t, []string{`aws_internet_gateway.this["a]"]`}, routes[1].InstanceReferences("gateway_id", float64(1)))
+	assert.Empty(t, routes[1].InstanceReferences("cidr_block", nil))
+
+	cidr, ok := routes[0].Constant("cidr_block")
+	assert.True(t, ok)
+	assert.Equal(t, "0.0.0.0/0", cidr)
+	_, ok = routes[1].Constant("cidr_block")
+	assert.False(t, ok)
+}
@@ -0,0 +1,286 @@
+// Package planjson loads the machine-readable plan produced by
+// `terraform show -json` into typed structs and answers questions about it:
+// which resources change, how, and with what planned attribute values.
+//
+// Attribute values keep the shapes encoding/json gives them: objects are
+// map[string]interface{}, lists are []interface{} and numbers are float64.
+package planjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
+)
+
+// Action is a change action. Plans only contain the primitive actions; a
+// replacement shows up as a delete and a create in either order, which
+// Actions.Kind reports as ActionReplace.
+type Action string
+
+const (
+	ActionNoOp    Action = "no-op"
+	ActionCreate  Action = "create"
+	ActionRead    Action = "read"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionReplace Action = "replace"
+)
+
+// Actions is the list of actions planned for one resource.
+type Actions []Action
+
+// Kind summarises the actions as a single Action.
+func (a Actions) Kind() Action {
+	switch {
+	case len(a) == 1:
+		return a[0]
+	case len(a) == 2 && a.contains(ActionDelete) && a.contains(ActionCreate):
+		return ActionReplace
+	default:
+		return Action(fmt.Sprint([]Action(a)))
+	}
+}
+
+func (a Actions) contains(action Action) bool {
+	for _, x := range a {
+		if x == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan is the top-level document printed by `terraform show -json <planfile>`.
+type Plan struct {
+	FormatVersion    string                   `json:"format_version"`
+	TerraformVersion string                   `json:"terraform_version"`
+	Variables        map[string]VariableValue `json:"variables"`
+	ResourceChanges  Changes                  `json:"resource_changes"`
+	OutputChanges    map[string]Change        `json:"output_changes"`
+	// Configuration is kept raw; its schema is large and rarely needed.
+	Configuration json.RawMessage `json:"configuration"`
+}
+
+// VariableValue is the value a root module variable was planned with.
+type VariableValue struct {
+	Value interface{} `json:"value"`
+}
+
+// ResourceChange is one entry of resource_changes.
+type ResourceChange struct {
+	Address string `json:"address"`
+	// ModuleAddress is empty for resources in the root module.
+	ModuleAddress string `json:"module_address"`
+	Mode          string `json:"mode"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	// Index is the count (float64) or for_each (string) key, or nil.
+	Index        interface{} `json:"index"`
+	ProviderName string      `json:"provider_name"`
+	ActionReason string      `json:"action_reason"`
+	Change       Change      `json:"change"`
+}
+
+// Change describes the before and after values of a resource or output.
+type Change struct {
+	Actions         Actions         `json:"actions"`
+	Before          interface{}     `json:"before"`
+	After           interface{}     `json:"after"`
+	AfterUnknown    interface{}     `json:"after_unknown"`
+	BeforeSensitive interface{}     `json:"before_sensitive"`
+	AfterSensitive  interface{}     `json:"after_sensitive"`
+	ReplacePaths    [][]interface{} `json:"replace_paths"`
+}
+
+// Parse decodes plan JSON.
+func Parse(data []byte) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("parsing plan JSON: %w", err)
+	}
+	if plan.FormatVersion == "" {
+		return nil, fmt.Errorf("parsing plan JSON: missing format_version, is this `terraform show -json` output?")
+	}
+	return &plan, nil
+}
+
+// Load reads and decodes a plan JSON file.
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Show runs `terraform show -json` on the plan file at options.PlanFilePath
+// and decodes the result.
+func Show(t testing.TB, options *terraform.Options) *Plan {
+	t.Helper()
+
+	plan, err := Parse([]byte(terraform.Show(t, options)))
+	require.NoError(t, err)
+	return plan
+}
+
+// InitAndPlan runs init and plan, writing the plan to options.PlanFilePath,
+// and returns the decoded plan. Init goes through harness.Init, so parallel
+// tests sharing the provider plugin cache never init at the same time.
+func InitAndPlan(t testing.TB, options *terraform.Options) *Plan {
+	t.Helper()
+
+	harness.InitAndPlan(t, options)
+	return Show(t, options)
+}
+
+// ResourceChange returns the change for the resource at address.
+func (p *Plan) ResourceChange(address string) (ResourceChange, bool) {
+	for _, rc := range p.ResourceChanges {
+		if rc.Address == address {
+			return rc, true
+		}
+	}
+	return ResourceChange{}, false
+}
+
+// Changes is a list of resource changes that can be narrowed with the With*
+// and In* methods, e.g.
+//
+//	plan.ResourceChanges.InModule("module.vpc").WithType("aws_subnet").WithAction(planjson.ActionCreate)
+type Changes []ResourceChange
+
+// Where returns the changes for which keep returns true.
+func (c Changes) Where(keep func(ResourceChange) bool) Changes {
+	var out Changes
+	for _, rc := range c {
+		if keep(rc) {
+			out = append(out, rc)
+		}
+	}
+	return out
+}
+
+// WithType keeps changes to resources of the given type.
+func (c Changes) WithType(resourceType string) Changes {
+	return c.Where(func(rc ResourceChange) bool { return rc.Type == resourceType })
+}
+
+// WithAction keeps changes whose Actions.Kind is action.
+func (c Changes) WithAction(action Action) Changes {
+	return c.Where(func(rc ResourceChange) bool { return rc.Change.Actions.Kind() == action })
+}
+
+// InModule keeps changes to resources declared directly in the module at
+// address, e.g. "module.vpc". An empty address selects the root module.
+func (c Changes) InModule(address string) Changes {
+	return c.Where(func(rc ResourceChange) bool { return rc.ModuleAddress == address })
+}
+
+// Managed keeps managed resources, dropping data sources.
+func (c Changes) Managed() Changes {
+	return c.Where(func(rc ResourceChange) bool { return rc.Mode == "managed" })
+}
+
+// Addresses returns the address of each change.
+func (c Changes) Addresses() []string {
+	out := make([]string, 0, len(c))
+	for _, rc := range c {
+		out = append(out, rc.Address)
+	}
+	return out
+}
+
+// After returns the planned value at path, a dot-separated list of object
+// keys and list indexes such as "tags.Name" or "ingress.0.cidr_blocks". It
+// reports false if the path does not exist or the value is not yet known.
+func (rc ResourceChange) After(path string) (interface{}, bool) {
+	if rc.AfterUnknown(path) {
+		return nil, false
+	}
+	return lookup(rc.Change.After, path)
+}
+
+// Before returns the prior value at path.
+func (rc ResourceChange) Before(path string) (interface{}, bool) {
+	return lookup(rc.Change.Before, path)
+}
+
+// AfterUnknown reports whether the value at path, or any value containing
+// it, is only known after apply.
+func (rc ResourceChange) AfterUnknown(path string) bool {
+	node := rc.Change.AfterUnknown
+	if b, ok := node.(bool); ok && b {
+		return true
+	}
+	for _, key := range splitPath(path) {
+		next, ok := step(node, key)
+		if !ok {
+			return false
+		}
+		if b, ok := next.(bool); ok && b {
+			return true
+		}
+		node = next
+	}
+	return false
+}
+
+// AfterString is After for string values.
+func (rc ResourceChange) AfterString(path string) (string, bool) {
+	v, ok := rc.After(path)
+	s, isString := v.(string)
+	return s, ok && isString
+}
+
+// Exists reports whether the resource will exist after apply: its change
+// neither deletes it nor only reads it, as for data sources.
+func (rc ResourceChange) Exists() bool {
+	switch rc.Change.Actions.Kind() {
+	case ActionDelete, ActionRead:
+		return false
+	}
+	return true
+}
+
+func lookup(value interface{}, path string) (interface{}, bool) {
+	node := value
+	for _, key := range splitPath(path) {
+		next, ok := step(node, key)
+		if !ok {
+			return nil, false
+		}
+		node = next
+	}
+	return node, true
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+func step(node interface{}, key string) (interface{}, bool) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		v, ok := n[key]
+		return v, ok
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(n) {
+			return nil, false
+		}
+		return n[i], true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,86 @@
+package planjson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadPlan(t *testing.T) *Plan {
+	t.Helper()
+	plan, err := Load("testdata/plan.json")
+	require.NoError(t, err)
+	return plan
+}
+
+func TestParseRejectsNonPlan(t *testing.T) {
+	_, err := Parse([]byte(`{"values": {}}`))
+	assert.Error(t, err)
+}
+
+func TestQueries(t *testing.T) {
+	plan := loadPlan(t)
+
+	assert.Equal(t, "1.6.6", plan.TerraformVersion)
+	assert.Equal(t, "unit", plan.Variables["name_prefix"].Value)
+	assert.Equal(t, ActionCreate, plan.OutputChanges["vpc_id"].Actions.Kind())
+
+	assert.Equal(t, []string{
+		"module.vpc.aws_vpc.this",
+		"module.vpc.aws_subnet.public[0]",
+	}, plan.ResourceChanges.InModule("module.vpc").WithAction(ActionCreate).Addresses())
+	assert.Equal(t, []string{"random_id.suffix"}, plan.ResourceChanges.InModule("").Managed().Addresses())
+	assert.Equal(t, []string{"module.vpc.aws_security_group.default"}, plan.ResourceChanges.WithAction(ActionReplace).Addresses())
+	assert.Len(t, plan.ResourceChanges.WithType("aws_subnet"), 1)
+	assert.Equal(t, []string{
+		"module.vpc.aws_vpc.this",
+		"module.vpc.aws_subnet.public[0]",
+		"module.vpc.aws_security_group.default",
+		"random_id.suffix",
+	}, plan.ResourceChanges.Where(ResourceChange.Exists).Addresses())
+
+	subnet, ok := plan.ResourceChange("module.vpc.aws_subnet.public[0]")
+	require.True(t, ok)
+	assert.Equal(t, float64(0), subnet.Index)
+
+	az, ok := subnet.AfterString("availability_zone")
+	assert.True(t, ok)
+	assert.Equal(t, "us-east-1a", az)
+
+	name, ok := subnet.After("tags.Name")
+	assert.True(t, ok)
+	assert.Equal(t, "unit-public-subnet-1", name)
+
+	_, ok = subnet.After("vpc_id")
+	assert.False(t, ok, "unknown values are not returned")
+	assert.True(t, subnet.AfterUnknown("vpc_id"))
+	assert.False(t, subnet.AfterUnknown("cidr_block"))
+
+	sg, _ := plan.ResourceChange("module.vpc.aws_security_group.default")
+	port, ok := sg.After("ingress.0.from_port")
+	assert.True(t, ok)
+	assert.Equal(t, float64(443), port)
+	before, _ := sg.Before("ingress.0.cidr_blocks.0")
+	assert.Equal(t, "10.0.0.0/8", before)
+	_, ok = sg.After("ingress.1")
+	assert.False(t, ok)
+}
+
+func TestAssertions(t *testing.T) {
+	plan := loadPlan(t)
+
+	assert.True(t, AssertCreated(t, plan, "module.vpc.aws_subnet.public[0]", map[string]interface{}{
+		"cidr_block":              "10.0.1.0/24",
+		"map_public_ip_on_launch": true,
+		"tags":                    map[string]string{"Name": "unit-public-subnet-1", "Type": "Public"},
+	}))
+	assert.True(t, AssertAction(t, plan, "random_id.suffix", ActionNoOp))
+
+	mock := &testing.T{}
+	assert.False(t, AssertCreated(mock, plan, "module.vpc.aws_vpc.this", map[string]interface{}{"id": "vpc-123"}), "unknown attribute")
+	assert.False(t, AssertCreated(mock, plan, "module.vpc.aws_vpc.this", map[string]interface{}{"cidr_block": "10.1.0.0/16"}), "wrong value")
+	assert.False(t, AssertAction(mock, plan, "module.vpc.aws_vpc.missing", ActionCreate), "missing resource")
+	assert.False(t, AssertNoReplacements(mock, plan))
+	assert.False(t, AssertNoDeletions(mock, plan))
+}
@@ -0,0 +1,134 @@
+package planjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Placeholders for values a snapshot cannot or must not record.
+const (
+	UnknownValue   = "(known after apply)"
+	SensitiveValue = "(sensitive value)"
+)
+
+// Snapshot is the part of a plan worth comparing between runs: what each
+// resource and output is planned to become. Terraform and provider versions,
+// prior state and the raw configuration are left out so a snapshot only
+// changes when the planned infrastructure does.
+type Snapshot struct {
+	Resources []SnapshotResource     `json:"resources"`
+	Outputs   map[string]interface{} `json:"outputs,omitempty"`
+}
+
+// SnapshotResource is one planned resource change in a Snapshot.
+type SnapshotResource struct {
+	Address string `json:"address"`
+	Action  Action `json:"action"`
+	Reason  string `json:"reason,omitempty"`
+	// Values is the planned value, with values known only after apply
+	// replaced by UnknownValue and sensitive ones by SensitiveValue.
+	Values interface{} `json:"values"`
+}
+
+// Snapshot returns the normalized form of the plan: resources ordered by
+// address and every unknown or sensitive value masked. Unknown attributes
+// the plan leaves out of the planned value are added as UnknownValue, so a
+// computed attribute disappearing from a resource shows up as a change.
+func (p *Plan) Snapshot() Snapshot {
+	snapshot := Snapshot{Resources: []SnapshotResource{}}
+	for _, rc := range p.ResourceChanges {
+		snapshot.Resources = append(snapshot.Resources, SnapshotResource{
+			Address: rc.Address,
+			Action:  rc.Change.Actions.Kind(),
+			Reason:  rc.ActionReason,
+			Values:  rc.Planned(),
+		})
+	}
+	sort.Slice(snapshot.Resources, func(i, j int) bool {
+		return snapshot.Resources[i].Address < snapshot.Resources[j].Address
+	})
+
+	if len(p.OutputChanges) > 0 {
+		snapshot.Outputs = map[string]interface{}{}
+		for name, change := range p.OutputChanges {
+			snapshot.Outputs[name] = mask(change.After, change.AfterUnknown, change.AfterSensitive)
+		}
+	}
+	return snapshot
+}
+
+// Planned returns the planned value of the resource as a snapshot records
+// it: values known only after apply are UnknownValue, including attributes
+// and map keys the plan's after value leaves out, and sensitive values are
+// SensitiveValue.
+func (rc ResourceChange) Planned() interface{} {
+	return mask(rc.Change.After, rc.Change.AfterUnknown, rc.Change.AfterSensitive)
+}
+
+// MarshalSnapshot renders the plan's Snapshot as indented JSON with sorted
+// object keys, ending in a newline, ready to be written to a golden file.
+func (p *Plan) MarshalSnapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p.Snapshot()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mask replaces the parts of value marked in the parallel unknown and
+// sensitive structures of a plan change.
+func mask(value, unknown, sensitive interface{}) interface{} {
+	if b, ok := sensitive.(bool); ok && b {
+		return SensitiveValue
+	}
+	if b, ok := unknown.(bool); ok && b {
+		return UnknownValue
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			out[key] = mask(elem, child(unknown, key), child(sensitive, key))
+		}
+		if u, ok := unknown.(map[string]interface{}); ok {
+			for key, elem := range u {
+				if _, ok := v[key]; !ok {
+					if b, ok := elem.(bool); ok && b {
+						out[key] = UnknownValue
+					}
+				}
+			}
+		}
+		return out
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			key := strconv.Itoa(i)
+			out[i] = mask(elem, child(unknown, key), child(sensitive, key))
+		}
+		return out
+
+	case nil:
+		// A resource whose whole value is unknown, such as a data source
+		// read during apply, only has the unknown structure
+		if u, ok := unknown.(map[string]interface{}); ok && len(u) > 0 {
+			return mask(map[string]interface{}{}, unknown, sensitive)
+		}
+		return nil
+
+	default:
+		return v
+	}
+}
+
+func child(node interface{}, key string) interface{} {
+	next, _ := step(node, key)
+	return next
+}
@@ -0,0 +1,64 @@
+package planjson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	snapshot := loadPlan(t).Snapshot()
+
+	var addresses []string
+	for _, r := range snapshot.Resources {
+		addresses = append(addresses, r.Address)
+	}
+	assert.Equal(t, []string{
+		"data.aws_caller_identity.current",
+		"module.vpc.aws_security_group.default",
+		"module.vpc.aws_subnet.public[0]",
+		"module.vpc.aws_vpc.this",
+		"random_id.suffix",
+	}, addresses, "resources are ordered by address")
+
+	sg := snapshot.Resources[1]
+	assert.Equal(t, ActionReplace, sg.Action)
+	assert.Equal(t, "replace_because_cannot_update", sg.Reason)
+
+	vpc := snapshot.Resources[3].Values.(map[string]interface{})
+	assert.Equal(t, "10.0.0.0/16", vpc["cidr_block"])
+	assert.Equal(t, UnknownValue, vpc["arn"], "unknown attributes missing from after are added")
+	assert.Equal(t, map[string]interface{}{"Name": "unit-vpc"}, vpc["tags"])
+
+	identity := snapshot.Resources[0].Values.(map[string]interface{})
+	assert.Equal(t, UnknownValue, identity["account_id"])
+}
+
+func TestMaskSensitive(t *testing.T) {
+	masked := mask(
+		map[string]interface{}{
+			"name":     "db",
+			"password": "hunter2",
+			"users":    []interface{}{"a", "b"},
+		},
+		map[string]interface{}{"users": []interface{}{false, true}},
+		map[string]interface{}{"password": true},
+	)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "db",
+		"password": SensitiveValue,
+		"users":    []interface{}{"a", UnknownValue},
+	}, masked)
+}
+
+func TestMarshalSnapshotIsStable(t *testing.T) {
+	first, err := loadPlan(t).MarshalSnapshot()
+	require.NoError(t, err)
+	second, err := loadPlan(t).MarshalSnapshot()
+	require.NoError(t, err)
+
+	assert.Equal(t, string(first), string(second))
+	assert.NotContains(t, string(first), "1.6.6", "the Terraform version is not part of a snapshot")
+	assert.Contains(t, string(first), `"arn": "(known after apply)"`)
+}
@@ -0,0 +1,146 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// DefaultEnv is the key of the settings that apply to every environment.
+const DefaultEnv = "default"
+
+// Settings configures one rule in one environment. Unset fields keep the
+// rule's own behaviour.
+type Settings struct {
+	// Enabled turns the rule on or off, overriding Rule.Envs.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Severity overrides Rule.Severity.
+	Severity *analysis.Severity `json:"severity,omitempty"`
+	// Exempt lists resource addresses the rule does not report. A * matches
+	// any run of characters, e.g. "module.bastion.*".
+	Exempt []string `json:"exempt,omitempty"`
+	// Reason says why the rule is configured this way. It is required with
+	// Exempt, so every exemption carries its justification.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (s Settings) enabled(rule Rule, env string) bool {
+	if s.Enabled != nil {
+		return *s.Enabled
+	}
+	return rule.appliesTo(env)
+}
+
+func (s Settings) exempt(address string) bool {
+	for _, pattern := range s.Exempt {
+		if match(pattern, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is the per-environment rule configuration, read from a JSON file
+// such as
+//
+//	{
+//	  "envs": {
+//	    "default": {"sg-open-ingress": {"exempt": ["module.bastion.*"], "reason": "SSH jump host"}},
+//	    "prod":    {"eks-public-endpoint": {"severity": "CRITICAL"}}
+//	  }
+//	}
+type Config struct {
+	// Envs maps an environment to the settings of each rule, by rule ID.
+	// The DefaultEnv settings apply to environments without their own.
+	Envs map[string]map[string]Settings `json:"envs"`
+}
+
+// LoadConfig reads and validates a rule configuration file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("parsing policy config %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, fmt.Errorf("policy config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks every configured rule is registered and every exemption
+// has a reason.
+func (c Config) Validate() error {
+	var problems []string
+	for env, rules := range c.Envs {
+		for id, settings := range rules {
+			if _, ok := Lookup(id); !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown rule %q", env, id))
+			}
+			if len(settings.Exempt) > 0 && strings.TrimSpace(settings.Reason) == "" {
+				problems = append(problems, fmt.Sprintf("%s: %s exempts resources without a reason", env, id))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Settings returns the settings of rule in env: the environment's own
+// settings on top of the default ones, field by field.
+func (c Config) Settings(env, rule string) Settings {
+	settings := c.Envs[DefaultEnv][rule]
+	if env == DefaultEnv {
+		return settings
+	}
+	own, ok := c.Envs[env][rule]
+	if !ok {
+		return settings
+	}
+	if own.Enabled != nil {
+		settings.Enabled = own.Enabled
+	}
+	if own.Severity != nil {
+		settings.Severity = own.Severity
+	}
+	settings.Exempt = append(append([]string(nil), settings.Exempt...), own.Exempt...)
+	if own.Reason != "" {
+		settings.Reason = own.Reason
+	}
+	return settings
+}
+
+// match reports whether address matches pattern, in which * matches any run
+// of characters and everything else matches itself.
+func match(pattern, address string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == address
+	}
+	if !strings.HasPrefix(address, parts[0]) {
+		return false
+	}
+	rest := address[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return strings.HasSuffix(rest, parts[len(parts)-1])
+}
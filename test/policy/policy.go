@@ -0,0 +1,167 @@
+// Package policy checks planned resources against the organization's rules,
+// written in Go and evaluated on plan JSON without any external tool. Rules
+// are registered in the Rules table; which of them run, at what severity and
+// with which resources exempt is configured per environment.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// ToolPolicy is the tool name of policy findings, as recorded on
+// analysis.Finding.
+const ToolPolicy = "policy"
+
+// Rule is one organization rule.
+type Rule struct {
+	// ID names the rule in configuration and reports, e.g. "sg-open-ingress".
+	ID          string
+	Description string
+	// Severity is the severity of violations unless configured otherwise.
+	Severity analysis.Severity
+	// Envs lists the environments the rule applies to unless configured
+	// otherwise. Empty means every environment.
+	Envs []string
+	// Check returns the violations of the rule in the plan. Rule, Severity
+	// and the exemptions are filled in by Evaluate.
+	Check func(in *Input) []Violation
+}
+
+// appliesTo reports whether the rule runs in env by default.
+func (r Rule) appliesTo(env string) bool {
+	if len(r.Envs) == 0 {
+		return true
+	}
+	for _, e := range r.Envs {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation is a planned resource breaking a rule.
+type Violation struct {
+	Rule     string
+	Severity analysis.Severity
+	Address  string
+	Message  string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: [%s] %s (%s)", v.Address, v.Severity, v.Message, v.Rule)
+}
+
+// Finding converts v, found in a plan of the configuration in dir, to a
+// finding located at the block declaring the resource, with its file
+// relative to root.
+func (v Violation) Finding(root, dir string) analysis.Finding {
+	return analysis.Locate(analysis.Finding{
+		Tool:     ToolPolicy,
+		Rule:     v.Rule,
+		Severity: v.Severity,
+		Resource: v.Address,
+		Message:  v.Message,
+	}, root, dir)
+}
+
+// Input is what a rule checks: the plan, and its configuration for rules
+// that need to follow references between resources.
+type Input struct {
+	Plan   *planjson.Plan
+	Config planjson.Config
+}
+
+// NewInput prepares plan for evaluation.
+func NewInput(plan *planjson.Plan) (*Input, error) {
+	config, err := plan.Config()
+	if err != nil {
+		return nil, err
+	}
+	return &Input{Plan: plan, Config: config}, nil
+}
+
+// Resources returns the managed resources of the given type that will exist
+// after apply.
+func (in *Input) Resources(resourceType string) planjson.Changes {
+	return in.Plan.ResourceChanges.Managed().WithType(resourceType).Where(planjson.ResourceChange.Exists)
+}
+
+// Instances returns the planned instances, in the module instance at
+// moduleAddress, of the resource block at configAddress.
+func (in *Input) Instances(moduleAddress, configAddress string) planjson.Changes {
+	return in.Plan.ResourceChanges.Managed().InModule(moduleAddress).Where(func(rc planjson.ResourceChange) bool {
+		return rc.ConfigAddress() == configAddress && rc.Exists()
+	})
+}
+
+// Module returns the configuration of the module rc is declared in.
+func (in *Input) Module(rc planjson.ResourceChange) planjson.ConfigModule {
+	module, _ := in.Config.Module(rc.ModuleAddress)
+	return module
+}
+
+// Evaluate runs every rule enabled in env by config on plan and returns the
+// violations not exempt, ordered by address and rule.
+func Evaluate(plan *planjson.Plan, rules []Rule, config Config, env string) ([]Violation, error) {
+	in, err := NewInput(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+	for _, rule := range rules {
+		settings := config.Settings(env, rule.ID)
+		if !settings.enabled(rule, env) {
+			continue
+		}
+		severity := rule.Severity
+		if settings.Severity != nil {
+			severity = *settings.Severity
+		}
+		for _, v := range rule.Check(in) {
+			if settings.exempt(v.Address) {
+				continue
+			}
+			v.Rule, v.Severity = rule.ID, severity
+			violations = append(violations, v)
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Rule < b.Rule
+	})
+	return violations, nil
+}
+
+// Lookup returns the registered rule with the given ID.
+func Lookup(id string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Describe lists the registered rules, one per line, for a test log.
+func Describe(rules []Rule) string {
+	var b strings.Builder
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "%-24s %-8s %s", rule.ID, rule.Severity, rule.Description)
+		if len(rule.Envs) > 0 {
+			fmt.Fprintf(&b, " (%s only)", strings.Join(rule.Envs, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+func loadInput(t *testing.T) *Input {
+	t.Helper()
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	in, err := NewInput(plan)
+	require.NoError(t, err)
+	return in
+}
+
+// check runs one registered rule on the test plan and returns its violations as strings
+func check(t *testing.T, id string) []string {
+	t.Helper()
+	rule, ok := Lookup(id)
+	require.True(t, ok, "rule %s is not registered", id)
+
+	var out []string
+	for _, v := range rule.Check(loadInput(t)) {
+		out = append(out, v.Address+": "+v.Message)
+	}
+	return out
+}
+
+func TestOpenIngress(t *testing.T) {
+	assert.Equal(t, []string{
+		"module.nlb.aws_security_group.nlb: allows ingress from 0.0.0.0/0 to tcp port 8443",
+		"module.vpc.aws_security_group.default: allows ingress from 0.0.0.0/0 to tcp port 22",
+		"module.vpc.aws_security_group.default: allows ingress from ::/0 to all traffic",
+		"module.vpc.aws_security_group_rule.ssh: allows ingress from 0.0.0.0/0 to tcp port 22",
+	}, check(t, "sg-open-ingress"))
+}
+
+func TestPublicAccessBlock(t *testing.T) {
+	assert.Equal(t, []string{
+		"module.s3.aws_s3_bucket.logs: has no public access block",
+		"module.s3.aws_s3_bucket.legacy: public access block module.s3.aws_s3_bucket_public_access_block.legacy does not enable block_public_policy, restrict_public_buckets",
+	}, check(t, "s3-public-access-block"))
+}
+
+func TestBucketEncryption(t *testing.T) {
+	assert.Equal(t, []string{
+		"module.s3.aws_s3_bucket.logs: has no server-side encryption configuration",
+	}, check(t, "s3-encryption"))
+}
+
+func TestEKSPublicEndpoint(t *testing.T) {
+	assert.Equal(t, []string{
+		"module.eks.aws_eks_cluster.this: has a public API endpoint reachable from 0.0.0.0/0",
+	}, check(t, "eks-public-endpoint"))
+}
+
+func TestLBDeletionProtection(t *testing.T) {
+	assert.Equal(t, []string{
+		"module.web.aws_lb.this: does not enable deletion protection",
+	}, check(t, "lb-deletion-protection"))
+}
+
+func TestRulesAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, rule := range Rules {
+		assert.False(t, seen[rule.ID], "rule %s registered twice", rule.ID)
+		seen[rule.ID] = true
+		assert.NotEmpty(t, rule.Description, rule.ID)
+		assert.NotEqual(t, analysis.SeverityUnknown, rule.Severity, rule.ID)
+		assert.NotNil(t, rule.Check, rule.ID)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	in := loadInput(t)
+	medium := analysis.SeverityMedium
+	disabled := false
+	config := Config{Envs: map[string]map[string]Settings{
+		DefaultEnv: {
+			"sg-open-ingress": {Exempt: []string{"module.nlb.*"}},
+		},
+		"dev": {
+			"s3-encryption":       {Enabled: &disabled},
+			"eks-public-endpoint": {Severity: &medium},
+			"sg-open-ingress":     {Exempt: []string{"module.vpc.aws_security_group_rule.ssh"}},
+		},
+	}}
+
+	rules := func(env string) map[string]int {
+		violations, err := Evaluate(in.Plan, Rules, config, env)
+		require.NoError(t, err)
+		counts := map[string]int{}
+		for _, v := range violations {
+			counts[v.Rule+" "+v.Severity.String()]++
+		}
+		return counts
+	}
+
+	assert.Equal(t, map[string]int{
+		"sg-open-ingress HIGH":          3,
+		"s3-public-access-block HIGH":   2,
+		"s3-encryption HIGH":            1,
+		"eks-public-endpoint HIGH":      1,
+		"lb-deletion-protection MEDIUM": 1,
+	}, rules("prod"))
+
+	assert.Equal(t, map[string]int{
+		"sg-open-ingress HIGH":        2,
+		"s3-public-access-block HIGH": 2,
+		"eks-public-endpoint MEDIUM":  1,
+	}, rules("dev"))
+}
+
+func TestConfigSettings(t *testing.T) {
+	enabled, high := true, analysis.SeverityHigh
+	config := Config{Envs: map[string]map[string]Settings{
+		DefaultEnv: {"lb-deletion-protection": {Exempt: []string{"a"}}},
+		"lab":      {"lb-deletion-protection": {Enabled: &enabled, Severity: &high, Exempt: []string{"b"}}},
+	}}
+
+	settings := config.Settings("lab", "lb-deletion-protection")
+	assert.Equal(t, &enabled, settings.Enabled)
+	assert.Equal(t, &high, settings.Severity)
+	assert.Equal(t, []string{"a", "b"}, settings.Exempt)
+
+	assert.Equal(t, Settings{Exempt: []string{"a"}}, config.Settings("dev", "lb-deletion-protection"))
+	assert.Equal(t, Settings{}, config.Settings("dev", "s3-encryption"))
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"envs": {"prod": {"eks-public-endpoint": {"severity": "critical"}}}}`), 0o644))
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, analysis.SeverityCritical, *config.Settings("prod", "eks-public-endpoint").Severity)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"envs": {"prod": {"no-such-rule": {}}}}`), 0o644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, `unknown rule "no-such-rule"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"envs": {"prod": {"s3-encryption": {"exempts": []}}}}`), 0o644))
+	_, err = LoadConfig(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"envs": {"dev": {"sg-open-ingress": {"exempt": ["module.bastion.*"]}}}}`), 0o644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "dev: sg-open-ingress exempts resources without a reason")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"envs": {"dev": {"sg-open-ingress": {"exempt": ["module.bastion.*"], "reason": "SSH jump host"}}}}`), 0o644))
+	_, err = LoadConfig(path)
+	assert.NoError(t, err)
+}
+
+func TestRepositoryConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join("..", "policy_rules.json"))
+	require.NoError(t, err)
+	assert.Contains(t, config.Envs, "prod")
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, match("module.vpc.aws_subnet.public[0]", "module.vpc.aws_subnet.public[0]"))
+	assert.True(t, match("module.vpc.*", "module.vpc.aws_subnet.public[0]"))
+	assert.True(t, match("*.aws_security_group.alb*", "module.web.aws_security_group.alb[0]"))
+	assert.False(t, match("module.vpc.*", "module.vpc2.aws_subnet.public[0]"))
+	assert.False(t, match("aws_s3_bucket.logs", "aws_s3_bucket.logs[0]"))
+}
+
+func TestViolationFinding(t *testing.T) {
+	f := Violation{Rule: "sg-open-ingress", Severity: analysis.SeverityHigh, Address: "aws_security_group.default", Message: "allows ingress from 0.0.0.0/0 to tcp port 22"}.
+		Finding("../..", "../../modules/vpc")
+	assert.Equal(t, ToolPolicy, f.Tool)
+	assert.Equal(t, "modules/vpc/main.tf", f.File)
+	assert.NotZero(t, f.Line)
+}
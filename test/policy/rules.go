@@ -0,0 +1,279 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// Rules is the table of registered rules. Add a rule by appending it here;
+// Config refers to rules by ID.
+var Rules = []Rule{
+	{
+		ID:          "sg-open-ingress",
+		Description: "Security groups other than an application load balancer's must not allow ingress from anywhere",
+		Severity:    analysis.SeverityHigh,
+		Check:       checkOpenIngress,
+	},
+	{
+		ID:          "s3-public-access-block",
+		Description: "S3 buckets must have a public access block with every setting enabled",
+		Severity:    analysis.SeverityHigh,
+		Check:       checkPublicAccessBlock,
+	},
+	{
+		ID:          "s3-encryption",
+		Description: "S3 buckets must have default server-side encryption configured",
+		Severity:    analysis.SeverityHigh,
+		Check:       checkBucketEncryption,
+	},
+	{
+		ID:          "eks-public-endpoint",
+		Description: "EKS clusters with a public API endpoint must restrict the CIDRs that can reach it",
+		Severity:    analysis.SeverityHigh,
+		Check:       checkEKSPublicEndpoint,
+	},
+	{
+		ID:          "lb-deletion-protection",
+		Description: "Load balancers must have deletion protection enabled",
+		Severity:    analysis.SeverityMedium,
+		Envs:        []string{"prod"},
+		Check:       checkLBDeletionProtection,
+	},
+}
+
+// openCIDRs are the source ranges that mean anywhere.
+var openCIDRs = map[string]bool{"0.0.0.0/0": true, "::/0": true}
+
+// checkOpenIngress reports security groups, and standalone ingress rules,
+// open to anywhere. Groups attached to an application load balancer are
+// allowed to be: that is what an internet-facing ALB is for.
+func checkOpenIngress(in *Input) []Violation {
+	var violations []Violation
+
+	for _, rc := range in.Resources("aws_security_group") {
+		if in.albSecurityGroup(rc.ModuleAddress, rc.ConfigAddress()) {
+			continue
+		}
+		rules, _ := rc.After("ingress")
+		blocks, _ := rules.([]interface{})
+		for _, block := range blocks {
+			block, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, cidr := range openSources(block["cidr_blocks"], block["ipv6_cidr_blocks"]) {
+				violations = append(violations, Violation{
+					Address: rc.Address,
+					Message: fmt.Sprintf("allows ingress from %s to %s", cidr, portRange(block["protocol"], block["from_port"], block["to_port"])),
+				})
+			}
+		}
+	}
+
+	for _, rc := range in.Resources("aws_security_group_rule") {
+		if kind, _ := rc.AfterString("type"); kind != "ingress" || in.attachedToALB(rc) {
+			continue
+		}
+		cidrs, _ := rc.After("cidr_blocks")
+		ipv6, _ := rc.After("ipv6_cidr_blocks")
+		protocol, _ := rc.After("protocol")
+		from, _ := rc.After("from_port")
+		to, _ := rc.After("to_port")
+		for _, cidr := range openSources(cidrs, ipv6) {
+			violations = append(violations, Violation{
+				Address: rc.Address,
+				Message: fmt.Sprintf("allows ingress from %s to %s", cidr, portRange(protocol, from, to)),
+			})
+		}
+	}
+
+	for _, rc := range in.Resources("aws_vpc_security_group_ingress_rule") {
+		if in.attachedToALB(rc) {
+			continue
+		}
+		ipv4, _ := rc.After("cidr_ipv4")
+		ipv6, _ := rc.After("cidr_ipv6")
+		protocol, _ := rc.After("ip_protocol")
+		from, _ := rc.After("from_port")
+		to, _ := rc.After("to_port")
+		for _, cidr := range openSources([]interface{}{ipv4, ipv6}) {
+			violations = append(violations, Violation{
+				Address: rc.Address,
+				Message: fmt.Sprintf("allows ingress from %s to %s", cidr, portRange(protocol, from, to)),
+			})
+		}
+	}
+	return violations
+}
+
+// openSources returns the open CIDRs among lists of planned CIDR values.
+func openSources(lists ...interface{}) []string {
+	var out []string
+	for _, list := range lists {
+		cidrs, _ := list.([]interface{})
+		for _, cidr := range cidrs {
+			if s, ok := cidr.(string); ok && openCIDRs[s] {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// portRange describes the traffic a security group rule allows.
+func portRange(protocol, from, to interface{}) string {
+	p := fmt.Sprint(protocol)
+	if p == "-1" || p == "all" {
+		return "all traffic"
+	}
+	if from == to {
+		return fmt.Sprintf("%s port %v", p, from)
+	}
+	return fmt.Sprintf("%s ports %v-%v", p, from, to)
+}
+
+// attachedToALB reports whether a standalone security group rule belongs to
+// a group attached to an application load balancer.
+func (in *Input) attachedToALB(rc planjson.ResourceChange) bool {
+	r, ok := in.Module(rc).Resource(rc.ConfigAddress())
+	if !ok {
+		return false
+	}
+	for _, group := range r.References("security_group_id") {
+		if in.albSecurityGroup(rc.ModuleAddress, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// albSecurityGroup reports whether the security group block at configAddress
+// is attached to an application load balancer planned in the same module
+// instance. Group IDs are unknown until apply, so the attachment is found
+// through the load balancer's security_groups references.
+func (in *Input) albSecurityGroup(moduleAddress, configAddress string) bool {
+	module, _ := in.Config.Module(moduleAddress)
+	for _, types := range []string{"aws_lb", "aws_alb"} {
+		for _, lb := range module.ReferencedBy(types, "security_groups", configAddress) {
+			for _, rc := range in.Instances(moduleAddress, lb.Address) {
+				// load_balancer_type defaults to application
+				if kind, ok := rc.AfterString("load_balancer_type"); !ok || kind == "application" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// publicAccessSettings are the settings a public access block must enable.
+var publicAccessSettings = []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"}
+
+// checkPublicAccessBlock reports buckets without a public access block, or
+// with one that leaves a setting disabled.
+func checkPublicAccessBlock(in *Input) []Violation {
+	var violations []Violation
+	for _, bucket := range in.Resources("aws_s3_bucket") {
+		blocks := in.bucketResources(bucket, "aws_s3_bucket_public_access_block")
+		if len(blocks) == 0 {
+			violations = append(violations, Violation{Address: bucket.Address, Message: "has no public access block"})
+			continue
+		}
+		for _, block := range blocks {
+			var disabled []string
+			for _, setting := range publicAccessSettings {
+				if enabled, ok := block.After(setting); ok && enabled != true {
+					disabled = append(disabled, setting)
+				}
+			}
+			if len(disabled) > 0 {
+				violations = append(violations, Violation{
+					Address: bucket.Address,
+					Message: fmt.Sprintf("public access block %s does not enable %s", block.Address, strings.Join(disabled, ", ")),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// checkBucketEncryption reports buckets without default encryption, set
+// either inline on the bucket or by a separate encryption configuration.
+func checkBucketEncryption(in *Input) []Violation {
+	var violations []Violation
+	for _, bucket := range in.Resources("aws_s3_bucket") {
+		if inline, _ := bucket.After("server_side_encryption_configuration"); len(asList(inline)) > 0 {
+			continue
+		}
+		if bucket.AfterUnknown("server_side_encryption_configuration") {
+			continue
+		}
+		if len(in.bucketResources(bucket, "aws_s3_bucket_server_side_encryption_configuration")) > 0 {
+			continue
+		}
+		violations = append(violations, Violation{Address: bucket.Address, Message: "has no server-side encryption configuration"})
+	}
+	return violations
+}
+
+// bucketResources returns the planned resources of resourceType whose
+// bucket argument refers to bucket, keeping those with the bucket's instance
+// key when they have one.
+func (in *Input) bucketResources(bucket planjson.ResourceChange, resourceType string) planjson.Changes {
+	var out planjson.Changes
+	for _, r := range in.Module(bucket).ReferencedBy(resourceType, "bucket", bucket.ConfigAddress()) {
+		for _, rc := range in.Instances(bucket.ModuleAddress, r.Address) {
+			if rc.Index == nil || rc.Index == bucket.Index {
+				out = append(out, rc)
+			}
+		}
+	}
+	return out
+}
+
+// checkEKSPublicEndpoint reports clusters whose public endpoint is open to
+// anywhere. An empty public_access_cidrs means anywhere too.
+func checkEKSPublicEndpoint(in *Input) []Violation {
+	var violations []Violation
+	for _, rc := range in.Resources("aws_eks_cluster") {
+		if public, ok := rc.After("vpc_config.0.endpoint_public_access"); !ok || public != true {
+			continue
+		}
+		if rc.AfterUnknown("vpc_config.0.public_access_cidrs") {
+			continue
+		}
+		cidrs, _ := rc.After("vpc_config.0.public_access_cidrs")
+		open := openSources(cidrs)
+		if len(asList(cidrs)) == 0 {
+			open = []string{"0.0.0.0/0"}
+		}
+		for _, cidr := range open {
+			violations = append(violations, Violation{
+				Address: rc.Address,
+				Message: fmt.Sprintf("has a public API endpoint reachable from %s", cidr),
+			})
+		}
+	}
+	return violations
+}
+
+// checkLBDeletionProtection reports load balancers that can be deleted.
+func checkLBDeletionProtection(in *Input) []Violation {
+	var violations []Violation
+	for _, types := range []string{"aws_lb", "aws_alb"} {
+		for _, rc := range in.Resources(types) {
+			if enabled, ok := rc.After("enable_deletion_protection"); ok && enabled != true {
+				violations = append(violations, Violation{Address: rc.Address, Message: "does not enable deletion protection"})
+			}
+		}
+	}
+	return violations
+}
+
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/regopolicy"
+)
+
+// regoPolicyDir holds the Rego policies, relative to the repository root
+const regoPolicyDir = "policy"
+
+// TestRegoPolicyTests runs the Rego unit tests of the policies, one subtest per test rule
+func TestRegoPolicyTests(t *testing.T) {
+	results, err := regopolicy.RunTests(context.Background(), filepath.Join(repoRoot, regoPolicyDir))
+	require.NoError(t, err)
+	require.NotEmpty(t, results, "no Rego tests found in %s", regoPolicyDir)
+
+	for _, result := range results {
+		result := result
+		t.Run(result.Name, func(t *testing.T) {
+			switch {
+			case result.Err != nil:
+				t.Errorf("%s: %v", result.Location, result.Err)
+			case result.Skipped:
+				t.Skipf("%s: skipped", result.Location)
+			case !result.Passed:
+				t.Errorf("%s: failed", result.Location)
+			}
+		})
+	}
+}
+
+// TestRegoPolicies plans every module and environment offline and evaluates the Rego policies against
+// each plan, failing on every deny with the resource it is about. Environments are planned with their
+// tfvars files and a local backend
+func TestRegoPolicies(t *testing.T) {
+	ctx := context.Background()
+	policies, err := regopolicy.Load(ctx, filepath.Join(repoRoot, regoPolicyDir))
+	require.NoError(t, err)
+
+	stub := awsstub.Start(t)
+
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindModule, discovery.KindEnv) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			var terraformOptions *terraform.Options
+			if cfg.Kind == discovery.KindEnv {
+				terraformOptions = envPlanOptions(t, stub, cfg)
+			} else {
+				terraformOptions = harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), offlinePlanVars[cfg.ID()])
+			}
+
+			harness.InitAndPlan(t, terraformOptions)
+			plan := terraform.Show(t, terraformOptions)
+
+			denials, err := policies.EvalPlan(ctx, []byte(plan))
+			require.NoError(t, err)
+
+			for _, d := range denials {
+				t.Error(d)
+				report.Add(d.Finding(repoRoot, cfg.Path))
+			}
+		})
+	}
+}
+
+// envPlanOptions returns offline plan options for the environment cfg with its tfvars files
+func envPlanOptions(t *testing.T, stub *awsstub.Server, cfg discovery.Config) *terraform.Options {
+	t.Helper()
+
+	tfvars, err := filepath.Glob(filepath.Join(cfg.Path, "*.tfvars"))
+	require.NoError(t, err)
+	for i, file := range tfvars {
+		tfvars[i] = filepath.Base(file)
+	}
+	return harness.OfflineEnvOptions(t, stub, repoRoot, cfg.ID(), tfvars)
+}
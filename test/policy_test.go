@@ -0,0 +1,75 @@
+package test
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+	"github.com/your-org/terraform-aws-modules/test/policy"
+)
+
+// policyConfig is the committed per-environment configuration of the policy rules
+const policyConfig = "policy_rules.json"
+
+// TestPolicyRules plans every module offline and checks the planned resources against the registered
+// policy rules, once for each environment configured in policy_rules.json, or only for the environment
+// named by POLICY_ENV when it is set
+func TestPolicyRules(t *testing.T) {
+	config, err := policy.LoadConfig(policyConfig)
+	require.NoError(t, err)
+
+	envs := policyEnvs(t, config)
+	t.Logf("Policy rules, configured for %s:\n%s", strings.Join(envs, ", "), policy.Describe(policy.Rules))
+
+	stub := awsstub.Start(t)
+
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindModule) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			terraformOptions := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), offlinePlanVars[cfg.ID()])
+
+			harness.InitAndPlan(t, terraformOptions)
+			plan := planjson.Show(t, terraformOptions)
+
+			for _, env := range envs {
+				env := env
+				t.Run(env, func(t *testing.T) {
+					violations, err := policy.Evaluate(plan, policy.Rules, config, env)
+					require.NoError(t, err)
+
+					for _, v := range violations {
+						t.Error(v)
+						report.Add(v.Finding(repoRoot, cfg.Path))
+					}
+				})
+			}
+		})
+	}
+}
+
+// policyEnvs returns the environments TestPolicyRules evaluates: the one named by POLICY_ENV, which must
+// be configured, or every configured environment in name order
+func policyEnvs(t *testing.T, config policy.Config) []string {
+	t.Helper()
+
+	if env := os.Getenv("POLICY_ENV"); env != "" {
+		_, ok := config.Envs[env]
+		require.True(t, ok || env == policy.DefaultEnv, "POLICY_ENV=%s is not configured in %s", env, policyConfig)
+		return []string{env}
+	}
+
+	var envs []string
+	for env := range config.Envs {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
@@ -0,0 +1,191 @@
+// Package regopolicy evaluates the Rego policies of the repository's policy/
+// directory against plan JSON, with OPA's evaluator embedded in the test
+// binary: no opa executable, server or network access is needed.
+//
+// A policy is a package under terraform, e.g. terraform.s3, with deny rules
+// producing either a message or an object
+//
+//	{"address": "<resource address>", "msg": "<message>", "severity": "HIGH"}
+//
+// where severity is optional. Rego unit tests, rules named test_* in
+// *_test.rego files, run with RunTests.
+package regopolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/tester"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// ToolRego is the tool name of Rego policy findings, as recorded on
+// analysis.Finding.
+const ToolRego = "rego"
+
+// query collects the deny rules of every package under data.terraform,
+// binding policy to the package name below terraform.
+const query = "deny := data.terraform[policy].deny"
+
+// Denial is one deny result of a policy.
+type Denial struct {
+	// Policy is the package name below terraform, e.g. "s3".
+	Policy string
+	// Address is the resource the denial is about, when the policy says.
+	Address  string
+	Message  string
+	Severity analysis.Severity
+}
+
+func (d Denial) String() string {
+	if d.Address == "" {
+		return fmt.Sprintf("[%s] %s (%s)", d.Severity, d.Message, d.Policy)
+	}
+	return fmt.Sprintf("%s: [%s] %s (%s)", d.Address, d.Severity, d.Message, d.Policy)
+}
+
+// Finding converts d, found in a plan of the configuration in dir, to a
+// finding located at the block declaring the resource, with its file
+// relative to root.
+func (d Denial) Finding(root, dir string) analysis.Finding {
+	f := analysis.Finding{
+		Tool:     ToolRego,
+		Rule:     "terraform." + d.Policy,
+		Severity: d.Severity,
+		Resource: d.Address,
+		Message:  d.Message,
+	}
+	if d.Address == "" {
+		return f
+	}
+	return analysis.Locate(f, root, dir)
+}
+
+// Policies are the compiled policies of a directory.
+type Policies struct {
+	query rego.PreparedEvalQuery
+}
+
+// Load compiles the Rego modules and data files under dir.
+func Load(ctx context.Context, dir string) (*Policies, error) {
+	prepared, err := rego.New(
+		rego.Query(query),
+		rego.Load([]string{dir}, nil),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading policies from %s: %w", dir, err)
+	}
+	return &Policies{query: prepared}, nil
+}
+
+// EvalPlan evaluates the policies against plan JSON, the output of
+// `terraform show -json`, and returns the denials ordered by address,
+// policy and message.
+func (p *Policies) EvalPlan(ctx context.Context, plan []byte) ([]Denial, error) {
+	var input interface{}
+	if err := json.Unmarshal(plan, &input); err != nil {
+		return nil, fmt.Errorf("parsing plan JSON: %w", err)
+	}
+	return p.Eval(ctx, input)
+}
+
+// Eval evaluates the policies against input.
+func (p *Policies) Eval(ctx context.Context, input interface{}) ([]Denial, error) {
+	results, err := p.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policies: %w", err)
+	}
+
+	var denials []Denial
+	for _, result := range results {
+		policy, _ := result.Bindings["policy"].(string)
+		values, ok := result.Bindings["deny"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("terraform.%s: deny is not a set", policy)
+		}
+		for _, value := range values {
+			d, err := denial(policy, value)
+			if err != nil {
+				return nil, err
+			}
+			denials = append(denials, d)
+		}
+	}
+
+	sort.Slice(denials, func(i, j int) bool {
+		a, b := denials[i], denials[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		return a.Message < b.Message
+	})
+	return denials, nil
+}
+
+// denial decodes one value of a deny set.
+func denial(policy string, value interface{}) (Denial, error) {
+	d := Denial{Policy: policy, Severity: analysis.SeverityHigh}
+	switch v := value.(type) {
+	case string:
+		d.Message = v
+	case map[string]interface{}:
+		msg, ok := v["msg"].(string)
+		if !ok {
+			return Denial{}, fmt.Errorf("terraform.%s: deny object %v has no msg", policy, v)
+		}
+		d.Message = msg
+		d.Address, _ = v["address"].(string)
+		if name, ok := v["severity"].(string); ok {
+			severity, err := analysis.ParseSeverity(name)
+			if err != nil {
+				return Denial{}, fmt.Errorf("terraform.%s: %w", policy, err)
+			}
+			d.Severity = severity
+		}
+	default:
+		return Denial{}, fmt.Errorf("terraform.%s: deny value %v is neither a message nor an object", policy, value)
+	}
+	return d, nil
+}
+
+// TestResult is the outcome of one Rego unit test.
+type TestResult struct {
+	// Name is the test rule's package and name, e.g.
+	// "data.terraform.s3_test.test_public_acl_denied".
+	Name     string
+	Location string
+	Passed   bool
+	Skipped  bool
+	// Err is set when the test could not be evaluated.
+	Err error
+}
+
+// RunTests runs the Rego unit tests under dir.
+func RunTests(ctx context.Context, dir string) ([]TestResult, error) {
+	results, err := tester.Run(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("running Rego tests in %s: %w", dir, err)
+	}
+
+	out := make([]TestResult, 0, len(results))
+	for _, r := range results {
+		result := TestResult{
+			Name:    r.Package + "." + r.Name,
+			Passed:  r.Pass(),
+			Skipped: r.Skip,
+			Err:     r.Error,
+		}
+		if r.Location != nil {
+			result.Location = r.Location.String()
+		}
+		out = append(out, result)
+	}
+	return out, nil
+}
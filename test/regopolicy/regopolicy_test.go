@@ -0,0 +1,54 @@
+package regopolicy
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+func TestEvalPlan(t *testing.T) {
+	ctx := context.Background()
+	policies, err := Load(ctx, "testdata/policy")
+	require.NoError(t, err)
+
+	plan, err := os.ReadFile("testdata/plan.json")
+	require.NoError(t, err)
+
+	denials, err := policies.EvalPlan(ctx, plan)
+	require.NoError(t, err)
+	assert.Equal(t, []Denial{
+		{Policy: "buckets", Message: "plan destroys resources", Severity: analysis.SeverityHigh},
+		{Policy: "buckets", Address: "module.s3.aws_s3_bucket.logs", Message: "bucket is public", Severity: analysis.SeverityCritical},
+	}, denials)
+}
+
+func TestEvalRejectsMalformedDenials(t *testing.T) {
+	_, err := denial("x", map[string]interface{}{"address": "a"})
+	assert.ErrorContains(t, err, "has no msg")
+
+	_, err = denial("x", 42)
+	assert.ErrorContains(t, err, "neither a message nor an object")
+
+	_, err = denial("x", map[string]interface{}{"msg": "m", "severity": "urgent"})
+	assert.ErrorContains(t, err, "unknown severity")
+}
+
+func TestRunTests(t *testing.T) {
+	results, err := RunTests(context.Background(), "testdata/policy")
+	require.NoError(t, err)
+
+	passed := map[string]bool{}
+	for _, r := range results {
+		require.NoError(t, r.Err, r.Name)
+		passed[r.Name] = r.Passed
+	}
+	assert.Equal(t, map[string]bool{
+		"data.terraform.buckets_test.test_private_bucket_allowed": true,
+		"data.terraform.buckets_test.test_fails":                  false,
+	}, passed)
+}
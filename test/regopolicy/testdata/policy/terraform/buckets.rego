@@ -0,0 +1,14 @@
+package terraform.buckets
+
+import rego.v1
+
+deny contains {"address": rc.address, "msg": "bucket is public", "severity": "critical"} if {
+	some rc in input.resource_changes
+	rc.type == "aws_s3_bucket"
+	rc.change.after.acl == "public-read"
+}
+
+deny contains "plan destroys resources" if {
+	some rc in input.resource_changes
+	rc.change.actions == ["delete"]
+}
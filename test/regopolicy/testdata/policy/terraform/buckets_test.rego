@@ -0,0 +1,15 @@
+package terraform.buckets_test
+
+import rego.v1
+
+import data.terraform.buckets
+
+test_private_bucket_allowed if {
+	p := {"resource_changes": [{"address": "aws_s3_bucket.b", "type": "aws_s3_bucket", "change": {"actions": ["create"], "after": {"acl": "private"}}}]}
+	count(buckets.deny) == 0 with input as p
+}
+
+test_fails if {
+	p := {"resource_changes": []}
+	count(buckets.deny) == 1 with input as p
+}
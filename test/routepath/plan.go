@@ -0,0 +1,217 @@
+package routepath
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// Resource types the graph is built from.
+const (
+	resourceSubnet      = "aws_subnet"
+	resourceRouteTable  = "aws_route_table"
+	resourceRoute       = "aws_route"
+	resourceAssociation = "aws_route_table_association"
+	resourceNATGateway  = "aws_nat_gateway"
+	resourceVPNGateway  = "aws_vpn_gateway"
+)
+
+// targetArguments are the arguments of a route that name its target, in the
+// order they are looked for, with the kind of target each names.
+var targetArguments = []struct {
+	argument string
+	kind     TargetKind
+}{
+	{"gateway_id", TargetInternetGateway},
+	{"nat_gateway_id", TargetNATGateway},
+	{"transit_gateway_id", TargetTransitGateway},
+	{"vpc_peering_connection_id", TargetOther},
+	{"network_interface_id", TargetOther},
+	{"vpc_endpoint_id", TargetOther},
+	{"egress_only_gateway_id", TargetOther},
+	{"carrier_gateway_id", TargetOther},
+	{"local_gateway_id", TargetOther},
+	{"core_network_arn", TargetOther},
+}
+
+// FromPlan builds the route graph of the resources plan creates or keeps.
+// Route targets, and the IDs tying subnets to route tables and NAT gateways,
+// are unknown until apply, so the graph follows the references of the plan's
+// configuration instead; a reference indexed by count.index or each.key is
+// taken to name the instance with the referring instance's key. The plan must
+// include its configuration, as `terraform show -json` writes it.
+func FromPlan(plan *planjson.Plan) (Graph, error) {
+	config, err := plan.Config()
+	if err != nil {
+		return Graph{}, err
+	}
+	b := &builder{config: config}
+	changes := plan.ResourceChanges.Managed().Where(planjson.ResourceChange.Exists)
+
+	for _, rc := range changes.WithType(resourceRouteTable) {
+		table := RouteTable{Address: rc.Address}
+		if r, ok := b.resource(rc); ok {
+			for _, block := range r.Blocks("route") {
+				if _, ok := block.Expressions["cidr_block"]; !ok {
+					// IPv6 and prefix list routes are not followed
+					continue
+				}
+				route := Route{Target: b.target(rc, block)}
+				if cidr, ok := block.Constant("cidr_block"); ok {
+					route.Destination, _ = cidr.(string)
+				} else {
+					b.unresolved(rc, "route cidr_block is not a constant")
+				}
+				table.Routes = append(table.Routes, route)
+			}
+		}
+		b.graph.RouteTables = append(b.graph.RouteTables, table)
+	}
+	for _, rc := range changes.WithType(resourceRoute) {
+		r, ok := b.resource(rc)
+		if !ok {
+			continue
+		}
+		destination, ok := rc.AfterString("destination_cidr_block")
+		if !ok || destination == "" {
+			continue
+		}
+		address, ok := b.reference(rc, r, "route_table_id")
+		if !ok {
+			continue
+		}
+		for i := range b.graph.RouteTables {
+			if t := &b.graph.RouteTables[i]; t.Address == address {
+				t.Routes = append(t.Routes, Route{Destination: destination, Target: b.target(rc, r)})
+			}
+		}
+	}
+
+	tables := map[string]string{}
+	for _, rc := range changes.WithType(resourceAssociation) {
+		r, ok := b.resource(rc)
+		if !ok {
+			continue
+		}
+		if _, ok := r.Expressions["subnet_id"]; !ok {
+			// an edge association of a gateway
+			continue
+		}
+		subnet, ok := b.reference(rc, r, "subnet_id")
+		if !ok {
+			continue
+		}
+		tables[subnet], _ = b.reference(rc, r, "route_table_id")
+	}
+
+	for _, rc := range changes.WithType(resourceSubnet) {
+		s := Subnet{Address: rc.Address}
+		s.CIDR, _ = rc.AfterString("cidr_block")
+		s.AZ, _ = rc.AfterString("availability_zone")
+		switch role, _ := rc.AfterString("tags.Type"); strings.ToLower(role) {
+		case "public":
+			s.Public = true
+		case "private":
+		default:
+			public, _ := rc.After("map_public_ip_on_launch")
+			s.Public = public == true
+		}
+		s.RouteTable, s.Associated = tables[rc.Address]
+		b.graph.Subnets = append(b.graph.Subnets, s)
+	}
+
+	for _, rc := range changes.WithType(resourceNATGateway) {
+		nat := NATGateway{Address: rc.Address}
+		if connectivity, _ := rc.AfterString("connectivity_type"); connectivity == "private" {
+			nat.Private = true
+		}
+		if r, ok := b.resource(rc); ok {
+			nat.Subnet, _ = b.reference(rc, r, "subnet_id")
+		}
+		if s, ok := b.graph.Subnet(nat.Subnet); ok {
+			nat.AZ = s.AZ
+		}
+		b.graph.NATGateways = append(b.graph.NATGateways, nat)
+	}
+	return b.graph, nil
+}
+
+// builder accumulates a graph while resolving references through the plan's
+// configuration.
+type builder struct {
+	config planjson.Config
+	graph  Graph
+}
+
+// resource returns the configuration of the resource block rc is an
+// instance of.
+func (b *builder) resource(rc planjson.ResourceChange) (planjson.ConfigResource, bool) {
+	module, ok := b.config.Module(rc.ModuleAddress)
+	if !ok {
+		b.unresolved(rc, "the plan has no configuration for its module")
+		return planjson.ConfigResource{}, false
+	}
+	r, ok := module.Resource(rc.ConfigAddress())
+	if !ok {
+		b.unresolved(rc, "the plan has no configuration for it")
+	}
+	return r, ok
+}
+
+// references returns the resource instances argument of r refers to from
+// rc, with rc's module address. r is the block rc is an instance of, or one
+// of its nested blocks.
+func (b *builder) references(rc planjson.ResourceChange, r planjson.ConfigResource, argument string) []string {
+	refs := r.InstanceReferences(argument, rc.Index)
+	if rc.ModuleAddress != "" {
+		for i := range refs {
+			refs[i] = rc.ModuleAddress + "." + refs[i]
+		}
+	}
+	return refs
+}
+
+// reference returns the one resource instance argument of r refers to from
+// rc, recording an unresolved reference when there is not exactly one.
+func (b *builder) reference(rc planjson.ResourceChange, r planjson.ConfigResource, argument string) (string, bool) {
+	refs := b.references(rc, r, argument)
+	if len(refs) != 1 {
+		b.unresolved(rc, fmt.Sprintf("%s refers to %d resources in its module, want 1", argument, len(refs)))
+		return "", false
+	}
+	return refs[0], true
+}
+
+// target returns the target of the route r, an inline route block of rc or
+// the aws_route rc is an instance of.
+func (b *builder) target(rc planjson.ResourceChange, r planjson.ConfigResource) Target {
+	for _, a := range targetArguments {
+		if _, ok := r.Expressions[a.argument]; !ok {
+			continue
+		}
+		t := Target{Kind: a.kind}
+		if value, ok := r.Constant(a.argument); ok {
+			if t.ID, _ = value.(string); t.ID == "" {
+				continue
+			}
+			if a.argument == "gateway_id" && strings.HasPrefix(t.ID, "vgw-") {
+				t.Kind = TargetVPNGateway
+			}
+			return t
+		}
+		if address, ok := b.reference(rc, r, a.argument); ok {
+			t.Address = address
+			if a.argument == "gateway_id" && strings.Contains(address, resourceVPNGateway+".") {
+				t.Kind = TargetVPNGateway
+			}
+		}
+		return t
+	}
+	return Target{Kind: TargetOther}
+}
+
+// unresolved records a reference of rc the graph cannot follow.
+func (b *builder) unresolved(rc planjson.ResourceChange, reason string) {
+	b.graph.Unresolved = append(b.graph.Unresolved, rc.Address+": "+reason)
+}
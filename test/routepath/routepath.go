@@ -0,0 +1,347 @@
+// Package routepath builds the route graph of the VPCs in a plan, from each
+// subnet through its route table to the internet or NAT gateway its default
+// route leads to and the availability zone that gateway is in, and checks
+// that public subnets reach the internet directly and private subnets through
+// a NAT gateway in their own zone.
+//
+// modules/vpc pairs private route tables with NAT gateways by index, and NAT
+// gateways with public subnets the same way, so the pairing only keeps a
+// private subnet's egress in its zone while both subnet lists are laid out
+// zone by zone and equally long. The graph is what the plan will actually
+// create, so it catches layouts where the pairing quietly crosses zones.
+package routepath
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// ToolRoutePath is the tool name of route path findings, as recorded on
+// analysis.Finding.
+const ToolRoutePath = "routepath"
+
+// Rule names reported on problems.
+const (
+	// RulePublicEgress is a public subnet whose default route does not go to
+	// an internet gateway.
+	RulePublicEgress = "route-public-egress"
+	// RulePrivateEgress is a private subnet whose default route is missing,
+	// goes straight to an internet gateway, or goes to a NAT gateway the plan
+	// does not create.
+	RulePrivateEgress = "route-private-egress"
+	// RuleNATCrossAZ is a private subnet that egresses through a NAT gateway
+	// in another availability zone.
+	RuleNATCrossAZ = "route-nat-cross-az"
+	// RuleNATPlacement is a NAT gateway in a subnet without a default route
+	// to an internet gateway.
+	RuleNATPlacement = "route-nat-placement"
+)
+
+// DefaultDestination is the destination of a default route.
+const DefaultDestination = "0.0.0.0/0"
+
+// TargetKind is the kind of gateway a route sends traffic to.
+type TargetKind string
+
+// Target kinds, after the route argument naming the target.
+const (
+	TargetInternetGateway TargetKind = "internet gateway"
+	TargetNATGateway      TargetKind = "NAT gateway"
+	TargetTransitGateway  TargetKind = "transit gateway"
+	TargetVPNGateway      TargetKind = "VPN gateway"
+	TargetOther           TargetKind = "other"
+)
+
+// Target is where a route sends traffic. Address is the planned gateway
+// instance, e.g. "module.vpc.aws_nat_gateway.this[0]", and ID the gateway ID
+// when the route names one literally; both are empty when the target comes
+// from a variable or another module.
+type Target struct {
+	Kind    TargetKind
+	Address string
+	ID      string
+}
+
+func (t Target) String() string {
+	switch {
+	case t.Address != "":
+		return string(t.Kind) + " " + t.Address
+	case t.ID != "":
+		return string(t.Kind) + " " + t.ID
+	}
+	return string(t.Kind)
+}
+
+// Route is one IPv4 route of a route table. Destination is empty when the
+// CIDR is not a constant in the configuration.
+type Route struct {
+	Destination string
+	Target      Target
+}
+
+// RouteTable is a planned route table with its inline routes and the
+// aws_route resources added to it.
+type RouteTable struct {
+	Address string
+	Routes  []Route
+}
+
+// DefaultRoute returns the route of t to 0.0.0.0/0.
+func (t RouteTable) DefaultRoute() (Route, bool) {
+	for _, r := range t.Routes {
+		if r.Destination == DefaultDestination {
+			return r, true
+		}
+	}
+	return Route{}, false
+}
+
+// Subnet is a planned subnet. Public comes from the Type tag modules/vpc
+// sets, or failing that from map_public_ip_on_launch.
+type Subnet struct {
+	Address string
+	CIDR    string
+	AZ      string
+	Public  bool
+	// Associated is set when a route table association names the subnet;
+	// without one the subnet uses the VPC's main route table.
+	Associated bool
+	// RouteTable is the associated route table, empty when the association
+	// refers to it in a way the graph cannot follow.
+	RouteTable string
+}
+
+// NATGateway is a planned NAT gateway, in the zone of its subnet.
+type NATGateway struct {
+	Address string
+	Subnet  string
+	AZ      string
+	// Private is set for NAT gateways with private connectivity, which
+	// cannot reach the internet.
+	Private bool
+}
+
+// Graph is the route graph of a plan: subnets, the route tables they are
+// associated with and the NAT gateways those route to.
+type Graph struct {
+	Subnets     []Subnet
+	RouteTables []RouteTable
+	NATGateways []NATGateway
+	// Unresolved lists the references the graph could not follow, such as a
+	// route table ID passed in as a variable.
+	Unresolved []string
+}
+
+// Subnet returns the subnet at address.
+func (g Graph) Subnet(address string) (Subnet, bool) {
+	for _, s := range g.Subnets {
+		if s.Address == address {
+			return s, true
+		}
+	}
+	return Subnet{}, false
+}
+
+// RouteTable returns the route table at address.
+func (g Graph) RouteTable(address string) (RouteTable, bool) {
+	for _, t := range g.RouteTables {
+		if t.Address == address {
+			return t, true
+		}
+	}
+	return RouteTable{}, false
+}
+
+// NATGateway returns the NAT gateway at address.
+func (g Graph) NATGateway(address string) (NATGateway, bool) {
+	for _, n := range g.NATGateways {
+		if n.Address == address {
+			return n, true
+		}
+	}
+	return NATGateway{}, false
+}
+
+// Egress is the path a subnet's internet-bound traffic takes.
+type Egress struct {
+	Subnet     Subnet
+	RouteTable string
+	// Route is the default route, unset when there is none.
+	Route Route
+	// AZ is the zone of the NAT gateway the default route goes to, when it
+	// goes to one the plan creates.
+	AZ string
+}
+
+// Routed reports whether e has a default route.
+func (e Egress) Routed() bool {
+	return e.Route.Destination != ""
+}
+
+// Egress returns the egress path of the subnet at address.
+func (g Graph) Egress(address string) (Egress, bool) {
+	s, ok := g.Subnet(address)
+	if !ok {
+		return Egress{}, false
+	}
+	e := Egress{Subnet: s, RouteTable: s.RouteTable}
+	table, ok := g.RouteTable(s.RouteTable)
+	if !ok {
+		return e, true
+	}
+	if e.Route, ok = table.DefaultRoute(); ok && e.Route.Target.Kind == TargetNATGateway {
+		if nat, ok := g.NATGateway(e.Route.Target.Address); ok {
+			e.AZ = nat.AZ
+		}
+	}
+	return e, true
+}
+
+// Problem is a subnet or NAT gateway whose routing does not give it the
+// internet access its role needs, or gives it through another zone.
+type Problem struct {
+	Rule     string
+	Severity analysis.Severity
+	Address  string
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: [%s] %s (%s)", p.Address, p.Severity, p.Message, p.Rule)
+}
+
+// Finding converts p to a finding located in the configuration at dir.
+func (p Problem) Finding(root, dir string) analysis.Finding {
+	return analysis.Locate(analysis.Finding{
+		Tool:     ToolRoutePath,
+		Rule:     p.Rule,
+		Severity: p.Severity,
+		Resource: p.Address,
+		Message:  p.Message,
+	}, root, dir)
+}
+
+// Check returns the problems of g. A private subnet may also egress through
+// a transit gateway, as with a central egress VPC; other targets, such as a
+// NAT instance's network interface, are not checked further.
+func (g Graph) Check() []Problem {
+	var problems []Problem
+	report := func(rule string, severity analysis.Severity, address, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Rule:     rule,
+			Severity: severity,
+			Address:  address,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, s := range g.Subnets {
+		e, _ := g.Egress(s.Address)
+		rule, role := RulePrivateEgress, "private"
+		if s.Public {
+			rule, role = RulePublicEgress, "public"
+		}
+		switch _, ok := g.RouteTable(s.RouteTable); {
+		case !s.Associated:
+			report(rule, analysis.SeverityHigh, s.Address,
+				"%s subnet has no route table association, so it uses the VPC's main route table", role)
+			continue
+		case s.RouteTable == "":
+			continue
+		case !ok:
+			report(rule, analysis.SeverityHigh, s.Address,
+				"%s subnet is associated with %s, which the plan does not create", role, s.RouteTable)
+			continue
+		}
+		if !e.Routed() {
+			report(rule, analysis.SeverityHigh, s.Address,
+				"%s subnet has no %s route in %s, so it cannot reach the internet", role, DefaultDestination, s.RouteTable)
+			continue
+		}
+		target := e.Route.Target
+		if s.Public {
+			if target.Kind != TargetInternetGateway {
+				report(rule, analysis.SeverityHigh, s.Address,
+					"public subnet routes %s to %s in %s; public subnets route it to an internet gateway", DefaultDestination, target, s.RouteTable)
+			}
+			continue
+		}
+		switch target.Kind {
+		case TargetInternetGateway:
+			report(rule, analysis.SeverityHigh, s.Address,
+				"private subnet routes %s to %s in %s, so its instances have no public IP to use it; private subnets route it to a NAT gateway",
+				DefaultDestination, target, s.RouteTable)
+		case TargetNATGateway:
+			if target.Address == "" {
+				continue
+			}
+			nat, ok := g.NATGateway(target.Address)
+			if !ok {
+				report(rule, analysis.SeverityHigh, s.Address,
+					"private subnet routes %s to %s in %s, which the plan does not create", DefaultDestination, target.Address, s.RouteTable)
+				continue
+			}
+			if nat.AZ != "" && s.AZ != "" && nat.AZ != s.AZ {
+				report(RuleNATCrossAZ, analysis.SeverityHigh, s.Address,
+					"private subnet in %s egresses through %s in %s; the traffic is billed for crossing zones and the subnet loses internet access when %s fails",
+					s.AZ, nat.Address, nat.AZ, nat.AZ)
+			}
+		}
+	}
+
+	for _, nat := range g.NATGateways {
+		if nat.Private {
+			continue
+		}
+		s, ok := g.Subnet(nat.Subnet)
+		if !ok {
+			continue
+		}
+		if e, _ := g.Egress(s.Address); !e.Routed() || e.Route.Target.Kind != TargetInternetGateway {
+			report(RuleNATPlacement, analysis.SeverityHigh, nat.Address,
+				"NAT gateway is in %s, which has no %s route to an internet gateway, so it cannot reach the internet", s.Address, DefaultDestination)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Address < problems[j].Address })
+	return problems
+}
+
+// String renders g as a table of each subnet's egress path.
+func (g Graph) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "subnet\ttype\tzone\troute table\tdefault route\ttarget zone")
+	for _, s := range g.Subnets {
+		e, _ := g.Egress(s.Address)
+		role := "private"
+		if s.Public {
+			role = "public"
+		}
+		table, target, zone := orDash(s.RouteTable), "-", "-"
+		if !s.Associated {
+			table = "main"
+		}
+		if e.Routed() {
+			target = e.Route.Target.String()
+		}
+		if e.AZ != "" {
+			zone = e.AZ
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Address, role, orDash(s.AZ), table, target, zone)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// orDash returns s, or "-" when s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
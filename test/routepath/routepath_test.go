@@ -0,0 +1,115 @@
+package routepath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// graph is the route graph of testdata/plan.json: modules/vpc with a third
+// private subnet in another zone, plus a lab subnet routed straight to the
+// internet gateway and a database subnet with no route table.
+func graph(t *testing.T) Graph {
+	t.Helper()
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	g, err := FromPlan(plan)
+	require.NoError(t, err)
+	return g
+}
+
+func TestFromPlan(t *testing.T) {
+	g := graph(t)
+	assert.Len(t, g.Subnets, 7)
+	assert.Len(t, g.RouteTables, 5)
+
+	s, ok := g.Subnet("module.vpc.aws_subnet.private[1]")
+	require.True(t, ok)
+	assert.Equal(t, Subnet{
+		Address:    "module.vpc.aws_subnet.private[1]",
+		CIDR:       "10.0.20.0/24",
+		AZ:         "us-east-1c",
+		Associated: true,
+		RouteTable: "module.vpc.aws_route_table.private[1]",
+	}, s)
+	db, _ := g.Subnet("module.vpc.aws_subnet.db")
+	assert.False(t, db.Public, "no Type tag and no public IPs")
+	assert.False(t, db.Associated, "the association being deleted does not count")
+
+	table, ok := g.RouteTable("module.vpc.aws_route_table.private[1]")
+	require.True(t, ok)
+	assert.Equal(t, []Route{
+		{Destination: "0.0.0.0/0", Target: Target{Kind: TargetNATGateway, Address: "module.vpc.aws_nat_gateway.this[1]"}},
+		{Target: Target{Kind: TargetOther}},
+	}, table.Routes, "IPv6 routes are skipped")
+	lab, _ := g.RouteTable("module.vpc.aws_route_table.lab")
+	assert.Equal(t, []Route{
+		{Destination: "0.0.0.0/0", Target: Target{Kind: TargetInternetGateway, Address: "module.vpc.aws_internet_gateway.this"}},
+	}, lab.Routes, "aws_route resources join their table")
+
+	nat, ok := g.NATGateway("module.vpc.aws_nat_gateway.this[1]")
+	require.True(t, ok)
+	assert.Equal(t, NATGateway{Address: "module.vpc.aws_nat_gateway.this[1]", Subnet: "module.vpc.aws_subnet.public[1]", AZ: "us-east-1b"}, nat)
+	assert.Contains(t, g.Unresolved, "module.vpc.aws_route_table.private[0]: vpc_peering_connection_id refers to 0 resources in its module, want 1")
+
+	e, ok := g.Egress("module.vpc.aws_subnet.private[0]")
+	require.True(t, ok)
+	assert.True(t, e.Routed())
+	assert.Equal(t, "us-east-1a", e.AZ)
+	_, ok = g.Egress("module.vpc.aws_subnet.missing")
+	assert.False(t, ok)
+}
+
+func TestCheck(t *testing.T) {
+	var got []string
+	for _, p := range graph(t).Check() {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		"module.vpc.aws_nat_gateway.db: [HIGH] NAT gateway is in module.vpc.aws_subnet.db, which has no 0.0.0.0/0 route to an internet gateway, so it cannot reach the internet (route-nat-placement)",
+		"module.vpc.aws_subnet.db: [HIGH] private subnet has no route table association, so it uses the VPC's main route table (route-private-egress)",
+		"module.vpc.aws_subnet.lab: [HIGH] private subnet routes 0.0.0.0/0 to internet gateway module.vpc.aws_internet_gateway.this in module.vpc.aws_route_table.lab, so its instances have no public IP to use it; private subnets route it to a NAT gateway (route-private-egress)",
+		"module.vpc.aws_subnet.private[1]: [HIGH] private subnet in us-east-1c egresses through module.vpc.aws_nat_gateway.this[1] in us-east-1b; the traffic is billed for crossing zones and the subnet loses internet access when us-east-1b fails (route-nat-cross-az)",
+		"module.vpc.aws_subnet.private[2]: [HIGH] private subnet routes 0.0.0.0/0 to module.vpc.aws_nat_gateway.this[2] in module.vpc.aws_route_table.private[2], which the plan does not create (route-private-egress)",
+	}, got)
+
+	g := Graph{
+		Subnets: []Subnet{
+			{Address: "aws_subnet.public", AZ: "us-east-1a", Public: true, Associated: true, RouteTable: "aws_route_table.private"},
+			{Address: "aws_subnet.private", AZ: "us-east-1a", Associated: true, RouteTable: "aws_route_table.private"},
+			{Address: "aws_subnet.shared", Associated: true},
+		},
+		RouteTables: []RouteTable{{Address: "aws_route_table.private", Routes: []Route{
+			{Destination: "0.0.0.0/0", Target: Target{Kind: TargetTransitGateway, ID: "tgw-0a1b2c3d4e5f60001"}},
+		}}},
+	}
+	got = nil
+	for _, p := range g.Check() {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		"aws_subnet.public: [HIGH] public subnet routes 0.0.0.0/0 to transit gateway tgw-0a1b2c3d4e5f60001 in aws_route_table.private; public subnets route it to an internet gateway (route-public-egress)",
+	}, got, "central egress through a transit gateway is allowed; unresolved tables are skipped")
+}
+
+func TestString(t *testing.T) {
+	g := Graph{
+		Subnets: []Subnet{
+			{Address: "aws_subnet.public", AZ: "us-east-1a", Public: true, Associated: true, RouteTable: "aws_route_table.public"},
+			{Address: "aws_subnet.private", AZ: "us-east-1a", Associated: true, RouteTable: "aws_route_table.private"},
+			{Address: "aws_subnet.db"},
+		},
+		RouteTables: []RouteTable{
+			{Address: "aws_route_table.public", Routes: []Route{{Destination: "0.0.0.0/0", Target: Target{Kind: TargetInternetGateway, Address: "aws_internet_gateway.this"}}}},
+			{Address: "aws_route_table.private", Routes: []Route{{Destination: "0.0.0.0/0", Target: Target{Kind: TargetNATGateway, Address: "aws_nat_gateway.this"}}}},
+		},
+		NATGateways: []NATGateway{{Address: "aws_nat_gateway.this", Subnet: "aws_subnet.public", AZ: "us-east-1a"}},
+	}
+	assert.Equal(t, "subnet              type     zone        route table              default route                               target zone\n"+
+		"aws_subnet.public   public   us-east-1a  aws_route_table.public   internet gateway aws_internet_gateway.this  -\n"+
+		"aws_subnet.private  private  us-east-1a  aws_route_table.private  NAT gateway aws_nat_gateway.this            us-east-1a\n"+
+		"aws_subnet.db       private  -           main                     -                                           -\n", g.String())
+}
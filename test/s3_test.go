@@ -0,0 +1,60 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
+)
+
+// TestS3Module deploys a versioned bucket and checks it is private
+func TestS3Module(t *testing.T) {
+	harness.RequireDeployTier(t)
+	t.Parallel()
+
+	awsRegion := harness.Region(t)
+	bucketName := "terratest-" + strings.ToLower(random.UniqueId())
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "modules/s3", awsRegion, map[string]interface{}{
+		"bucket_name":        bucketName,
+		"environment":        "test",
+		"force_destroy":      true,
+		"versioning_enabled": true,
+	}))
+
+	defer terraform.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, bucketName, terraform.Output(t, terraformOptions, "bucket_id"))
+
+	aws.AssertS3BucketExists(t, awsRegion, bucketName)
+	assert.Equal(t, "Enabled", aws.GetS3BucketVersioning(t, awsRegion, bucketName))
+
+	// Verify all public access is blocked
+	client := aws.NewS3Client(t, awsRegion)
+	block, err := client.GetPublicAccessBlock(&s3.GetPublicAccessBlockInput{Bucket: awssdk.String(bucketName)})
+	require.NoError(t, err)
+	assert.True(t, awssdk.BoolValue(block.PublicAccessBlockConfiguration.BlockPublicAcls))
+	assert.True(t, awssdk.BoolValue(block.PublicAccessBlockConfiguration.BlockPublicPolicy))
+	assert.True(t, awssdk.BoolValue(block.PublicAccessBlockConfiguration.IgnorePublicAcls))
+	assert.True(t, awssdk.BoolValue(block.PublicAccessBlockConfiguration.RestrictPublicBuckets))
+
+	t.Run("AnonymousAccessDenied", func(t *testing.T) {
+		harness.SkipOnEmulator(t, "bucket access control is not enforced")
+
+		resp, err := http.Get(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", bucketName, awsRegion))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -0,0 +1,274 @@
+// Package sarif writes findings in SARIF 2.1.0, the Static Analysis Results
+// Interchange Format code hosts read to annotate pull requests. Locations are
+// relative to the repository root, so results land on the lines of the .tf
+// files they are about.
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// Version and Schema identify the SARIF version written.
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Log is a SARIF log file.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is the results of one tool.
+type Run struct {
+	Tool              Tool               `json:"tool"`
+	AutomationDetails *AutomationDetails `json:"automationDetails,omitempty"`
+	Results           []Result           `json:"results"`
+}
+
+// AutomationDetails identifies the analysis a run belongs to. Code scanning
+// takes its ID as the run's category, and keeps only the latest results of
+// each tool and category, so runs of the same tool from different analyses
+// need different IDs.
+type AutomationDetails struct {
+	ID string `json:"id"`
+}
+
+// Tool describes the tool that produced a run.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the tool's main component and the rules it checks.
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule is a check a tool reported results for.
+type Rule struct {
+	ID         string         `json:"id"`
+	Properties RuleProperties `json:"properties"`
+}
+
+// RuleProperties carries the severity code hosts rank security results by.
+type RuleProperties struct {
+	// SecuritySeverity is a CVSS-like score from 0.0 to 10.0, as a string.
+	SecuritySeverity string `json:"security-severity,omitempty"`
+}
+
+// Message is a plain text message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is one finding.
+type Result struct {
+	RuleID       string        `json:"ruleId"`
+	RuleIndex    int           `json:"ruleIndex"`
+	Level        string        `json:"level"`
+	Message      Message       `json:"message"`
+	Locations    []Location    `json:"locations,omitempty"`
+	Suppressions []Suppression `json:"suppressions,omitempty"`
+}
+
+// Location is where a result was found.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a region of a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file, relative to the repository root.
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a range of lines, starting at 1.
+type Region struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// Suppression records why a result does not need fixing.
+type Suppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// informationURIs are the home pages of the tools runs are written for.
+var informationURIs = map[string]string{
+	analysis.ToolTFSec:   "https://github.com/aquasecurity/tfsec",
+	analysis.ToolTFLint:  "https://github.com/terraform-linters/tflint",
+	analysis.ToolCheckov: "https://www.checkov.io",
+}
+
+// Level returns the SARIF level of a severity: error from HIGH, warning for
+// MEDIUM and note below.
+func Level(s analysis.Severity) string {
+	switch {
+	case s >= analysis.SeverityHigh:
+		return "error"
+	case s == analysis.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// securitySeverities are the scores code hosts map back onto critical, high,
+// medium and low.
+var securitySeverities = map[analysis.Severity]string{
+	analysis.SeverityCritical: "9.5",
+	analysis.SeverityHigh:     "8.0",
+	analysis.SeverityMedium:   "5.5",
+	analysis.SeverityLow:      "2.0",
+}
+
+// Builder collects findings into a Log, one run per tool. It is safe for
+// use by parallel subtests.
+type Builder struct {
+	mu       sync.Mutex
+	category string
+	runs     map[string]*Run
+	// rules maps a tool and rule ID to the rule's index in its run
+	rules map[string]map[string]int
+}
+
+// NewBuilder returns an empty Builder whose runs belong to category, such as
+// the name of the test producing them. An empty category leaves it to the
+// uploader.
+func NewBuilder(category string) *Builder {
+	return &Builder{category: category, runs: map[string]*Run{}, rules: map[string]map[string]int{}}
+}
+
+// Add records f as a result.
+func (b *Builder) Add(findings ...analysis.Finding) {
+	for _, f := range findings {
+		b.add(f, nil)
+	}
+}
+
+// AddSuppressed records f as a result accepted for the given reason, such
+// as a baseline suppression, so it shows as dismissed rather than new.
+func (b *Builder) AddSuppressed(f analysis.Finding, justification string) {
+	b.add(f, []Suppression{{Kind: "external", Justification: justification}})
+}
+
+func (b *Builder) add(f analysis.Finding, suppressions []Suppression) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	run, ok := b.runs[f.Tool]
+	if !ok {
+		run = &Run{
+			Tool: Tool{Driver: Driver{
+				Name:           f.Tool,
+				InformationURI: informationURIs[f.Tool],
+				Rules:          []Rule{},
+			}},
+			Results: []Result{},
+		}
+		if b.category != "" {
+			// a trailing slash makes the ID a category rather than the ID of
+			// one particular run
+			run.AutomationDetails = &AutomationDetails{ID: b.category + "/"}
+		}
+		b.runs[f.Tool] = run
+		b.rules[f.Tool] = map[string]int{}
+	}
+
+	index, ok := b.rules[f.Tool][f.Rule]
+	if !ok {
+		index = len(run.Tool.Driver.Rules)
+		b.rules[f.Tool][f.Rule] = index
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, Rule{
+			ID:         f.Rule,
+			Properties: RuleProperties{SecuritySeverity: securitySeverities[f.Severity]},
+		})
+	}
+
+	result := Result{
+		RuleID:       f.Rule,
+		RuleIndex:    index,
+		Level:        Level(f.Severity),
+		Message:      Message{Text: message(f)},
+		Suppressions: suppressions,
+	}
+	if f.File != "" {
+		location := PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: f.File}}
+		if f.Line > 0 {
+			location.Region = &Region{StartLine: f.Line}
+			if f.EndLine >= f.Line {
+				location.Region.EndLine = f.EndLine
+			}
+		}
+		result.Locations = []Location{{PhysicalLocation: location}}
+	}
+	run.Results = append(run.Results, result)
+}
+
+// message is the text of a result: the finding's message, naming the
+// resource when the location alone may not.
+func message(f analysis.Finding) string {
+	if f.Resource != "" {
+		return f.Resource + ": " + f.Message
+	}
+	return f.Message
+}
+
+// Len returns the number of results recorded.
+func (b *Builder) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for _, run := range b.runs {
+		n += len(run.Results)
+	}
+	return n
+}
+
+// Log returns the results recorded so far, with runs ordered by tool name.
+func (b *Builder) Log() Log {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	log := Log{Schema: Schema, Version: Version, Runs: []Run{}}
+	for _, run := range b.runs {
+		log.Runs = append(log.Runs, *run)
+	}
+	sort.Slice(log.Runs, func(i, j int) bool {
+		return log.Runs[i].Tool.Driver.Name < log.Runs[j].Tool.Driver.Name
+	})
+	return log
+}
+
+// WriteFile writes the log to path as indented JSON, creating its directory.
+func (b *Builder) WriteFile(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b.Log()); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
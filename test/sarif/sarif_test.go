@@ -0,0 +1,83 @@
+package sarif
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder("TestTFSec")
+	b.Add(
+		analysis.Finding{Tool: analysis.ToolTFSec, Rule: "aws-s3-enable-versioning", Severity: analysis.SeverityMedium,
+			File: "modules/s3/main.tf", Line: 10, EndLine: 14, Resource: "aws_s3_bucket.this", Message: "Bucket does not have versioning enabled"},
+		analysis.Finding{Tool: analysis.ToolTFSec, Rule: "aws-s3-enable-versioning", Severity: analysis.SeverityMedium,
+			File: "modules/s3/main.tf", Line: 30, Message: "Bucket does not have versioning enabled"},
+		analysis.Finding{Tool: analysis.ToolTFLint, Rule: "terraform_unused_declarations", Severity: analysis.SeverityLow,
+			File: "modules/vpc/variables.tf", Line: 3, EndLine: 3, Message: "variable \"x\" is declared but not used"},
+	)
+	b.AddSuppressed(analysis.Finding{Tool: analysis.ToolTFSec, Rule: "aws-ec2-no-public-egress-sgr", Severity: analysis.SeverityCritical,
+		File: "modules/elb/main.tf", Line: 5}, "load balancers need outbound access")
+	assert.Equal(t, 4, b.Len())
+
+	log := b.Log()
+	assert.Equal(t, Version, log.Version)
+	require.Len(t, log.Runs, 2)
+	assert.Equal(t, "tflint", log.Runs[0].Tool.Driver.Name)
+
+	tfsec := log.Runs[1]
+	assert.Equal(t, "tfsec", tfsec.Tool.Driver.Name)
+	assert.Equal(t, &AutomationDetails{ID: "TestTFSec/"}, tfsec.AutomationDetails)
+	require.Len(t, tfsec.Tool.Driver.Rules, 2)
+	assert.Equal(t, "5.5", tfsec.Tool.Driver.Rules[0].Properties.SecuritySeverity)
+	assert.Equal(t, "9.5", tfsec.Tool.Driver.Rules[1].Properties.SecuritySeverity)
+
+	require.Len(t, tfsec.Results, 3)
+	first := tfsec.Results[0]
+	assert.Equal(t, "warning", first.Level)
+	assert.Equal(t, "aws_s3_bucket.this: Bucket does not have versioning enabled", first.Message.Text)
+	require.Len(t, first.Locations, 1)
+	assert.Equal(t, ArtifactLocation{URI: "modules/s3/main.tf"}, first.Locations[0].PhysicalLocation.ArtifactLocation)
+	assert.Equal(t, &Region{StartLine: 10, EndLine: 14}, first.Locations[0].PhysicalLocation.Region)
+
+	assert.Equal(t, 0, tfsec.Results[1].RuleIndex)
+	assert.Equal(t, &Region{StartLine: 30}, tfsec.Results[1].Locations[0].PhysicalLocation.Region)
+
+	suppressed := tfsec.Results[2]
+	assert.Equal(t, 1, suppressed.RuleIndex)
+	assert.Equal(t, "error", suppressed.Level)
+	assert.Equal(t, []Suppression{{Kind: "external", Justification: "load balancers need outbound access"}}, suppressed.Suppressions)
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, "error", Level(analysis.SeverityCritical))
+	assert.Equal(t, "error", Level(analysis.SeverityHigh))
+	assert.Equal(t, "warning", Level(analysis.SeverityMedium))
+	assert.Equal(t, "note", Level(analysis.SeverityLow))
+	assert.Equal(t, "note", Level(analysis.SeverityUnknown))
+}
+
+func TestWriteFile(t *testing.T) {
+	b := NewBuilder("")
+	b.Add(analysis.Finding{Tool: "tagcheck", Rule: "required-tags", Severity: analysis.SeverityMedium,
+		File: "modules/sqs/main.tf", Line: 1, Message: "missing Project"})
+
+	path := filepath.Join(t.TempDir(), "out", "TestTagCompliance.sarif")
+	require.NoError(t, b.WriteFile(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, Schema, raw["$schema"])
+	assert.Equal(t, "2.1.0", raw["version"])
+	run := raw["runs"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, run, "automationDetails")
+}
@@ -0,0 +1,267 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+	"github.com/your-org/terraform-aws-modules/test/sggraph"
+)
+
+// Names of the security groups in the graph: the plan's name and the group's address in it
+const (
+	albSecurityGroup = "alb/aws_security_group.alb[0]"
+	appSecurityGroup = "app/aws_security_group.ecs_service[0]"
+	efsSecurityGroup = "efs/aws_security_group.efs[0]"
+	vpcSecurityGroup = "vpc/aws_security_group.default"
+)
+
+// securityGroupStack is the configuration whose wiring of the modules the security group graph checks
+const securityGroupStack = "examples/complete-stack"
+
+// securityGroupPlans maps each plan of the security group graph to the configuration planned
+var securityGroupPlans = map[string]string{
+	"alb": "modules/elb",
+	"app": "modules/ecs",
+	"efs": "modules/efs",
+	"vpc": "modules/vpc",
+}
+
+// securityGroupStackInputs maps each plan of the security group graph to its module call in the stack and
+// the arguments of that call the plan takes over: those that decide what the module's groups admit
+var securityGroupStackInputs = map[string]struct {
+	call string
+	args []string
+}{
+	"app": {"ecs", []string{"container_port", "allowed_security_group_ids", "allowed_cidr_blocks"}},
+	"efs": {"efs", []string{"allowed_security_group_ids", "allowed_cidr_blocks"}},
+	"vpc": {"vpc", []string{"vpc_cidr_block", "public_subnet_cidrs", "private_subnet_cidrs", "allowed_ips"}},
+}
+
+// securityGroupID returns the ID the group named name is bound to in the graph. The ID is made up, since
+// it is only known after apply, and derived from the name so that no module is handed it by accident
+func securityGroupID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return "sg-" + hex.EncodeToString(sum[:])[:17]
+}
+
+// stackInputs returns the arguments the stack passes to the module call of each plan of the security group
+// graph, evaluated with the stack's references to other modules' security_group_id outputs resolved to the
+// IDs of their groups and module.vpc.vpc_cidr_block to the VPC's CIDR block. Arguments the stack leaves
+// out are left out, so the module's defaults apply as they do in the stack
+func stackInputs(t *testing.T) map[string]map[string]interface{} {
+	t.Helper()
+
+	stack, ok, err := discovery.Load(discovery.KindExample, filepath.Join(repoRoot, securityGroupStack))
+	require.NoError(t, err)
+	require.True(t, ok, "no Terraform files found in %s", securityGroupStack)
+	calls := map[string]discovery.ModuleCall{}
+	for _, call := range stack.Modules {
+		calls[call.Name] = call
+	}
+
+	vpcCall, ok := calls[securityGroupStackInputs["vpc"].call]
+	require.True(t, ok, "%s has no module %q", securityGroupStack, securityGroupStackInputs["vpc"].call)
+	vpcCIDR, diags := vpcCall.Arguments["vpc_cidr_block"].Expr.Value(nil)
+	require.False(t, diags.HasErrors(), "%s: module.%s.vpc_cidr_block: %s", securityGroupStack, vpcCall.Name, diags)
+	ctx := &hcl.EvalContext{Variables: map[string]cty.Value{"module": cty.ObjectVal(map[string]cty.Value{
+		"vpc": cty.ObjectVal(map[string]cty.Value{"vpc_cidr_block": vpcCIDR}),
+		"alb": cty.ObjectVal(map[string]cty.Value{"security_group_id": cty.StringVal(securityGroupID(albSecurityGroup))}),
+		"ecs": cty.ObjectVal(map[string]cty.Value{"security_group_id": cty.StringVal(securityGroupID(appSecurityGroup))}),
+		"efs": cty.ObjectVal(map[string]cty.Value{"security_group_id": cty.StringVal(securityGroupID(efsSecurityGroup))}),
+	})}}
+
+	inputs := map[string]map[string]interface{}{}
+	for plan, in := range securityGroupStackInputs {
+		call, ok := calls[in.call]
+		require.True(t, ok, "%s has no module %q", securityGroupStack, in.call)
+		inputs[plan] = map[string]interface{}{}
+		for _, arg := range in.args {
+			attr, ok := call.Arguments[arg]
+			if !ok {
+				continue
+			}
+			value, diags := attr.Expr.Value(ctx)
+			require.False(t, diags.HasErrors(), "%s: module.%s.%s: %s", securityGroupStack, call.Name, arg, diags)
+			inputs[plan][arg] = goValue(t, value)
+		}
+	}
+	return inputs
+}
+
+// goValue converts value, a string, a number or a list of strings as the security group inputs are, to the
+// Go value terratest passes as a variable
+func goValue(t *testing.T, value cty.Value) interface{} {
+	t.Helper()
+
+	switch value.Type() {
+	case cty.String:
+		return value.AsString()
+	case cty.Number:
+		n, _ := value.AsBigFloat().Int64()
+		return n
+	}
+	list, err := convert.Convert(value, cty.List(cty.String))
+	require.NoError(t, err)
+	var out []string
+	require.NoError(t, gocty.FromCtyValue(list, &out))
+	return out
+}
+
+// planSecurityGroups plans the configuration of the named plan offline, with vars over its offline plan
+// variables, and returns its security groups
+func planSecurityGroups(t *testing.T, stub *awsstub.Server, name string, vars map[string]interface{}) sggraph.Graph {
+	t.Helper()
+	dir := securityGroupPlans[name]
+	merged := map[string]interface{}{}
+	for k, v := range offlinePlanVars[dir] {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	terraformOptions := harness.OfflineOptions(t, stub, repoRoot, dir, merged)
+	harness.InitAndPlan(t, terraformOptions)
+	g, err := sggraph.FromPlan(planjson.Show(t, terraformOptions), name)
+	require.NoError(t, err)
+	return g
+}
+
+// TestSecurityGroupReachability plans the VPC, load balancer, ECS and EFS modules offline, each with the
+// security group inputs examples/complete-stack passes it, joins their security groups into one graph and
+// checks the intended flows: the internet reaches the load balancer on 80 and 443, the load balancer the
+// app on its container port, the app the file system on NFS, and the VPC's own range the instances in the
+// VPC's default group on 80, 443 and 22. Any other flow the ingress rules admit fails the test when its
+// source includes public addresses, as when the app admits more than the load balancer, or the file
+// system's allowed_cidr_blocks or the VPC's allowed_ips include a public range
+func TestSecurityGroupReachability(t *testing.T) {
+	stub := awsstub.Start(t)
+	stack := stackInputs(t)
+
+	vpcCIDR := stack["vpc"]["vpc_cidr_block"].(string)
+	publicSubnets := stack["vpc"]["public_subnet_cidrs"].([]string)
+	privateSubnets := stack["vpc"]["private_subnet_cidrs"].([]string)
+	containerPort := int(stack["app"]["container_port"].(int64))
+
+	vpcVars := map[string]interface{}{"name_prefix": "unit"}
+	for k, v := range stack["vpc"] {
+		vpcVars[k] = v
+	}
+
+	var g sggraph.Graph
+	g.Merge(planSecurityGroups(t, stub, "vpc", vpcVars))
+	g.Merge(planSecurityGroups(t, stub, "alb", map[string]interface{}{
+		"listener_rules": map[string]interface{}{
+			"http": map[string]interface{}{
+				"port":           80,
+				"protocol":       "HTTP",
+				"default_action": map[string]interface{}{"type": "redirect", "redirect": map[string]interface{}{}},
+			},
+			"https": map[string]interface{}{
+				"port":            443,
+				"protocol":        "HTTPS",
+				"certificate_arn": "arn:aws:acm:" + awsstub.Region + ":" + awsstub.AccountID + ":certificate/0a1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9",
+				"default_action": map[string]interface{}{
+					"type":           "fixed-response",
+					"fixed_response": map[string]interface{}{"content_type": "text/plain", "status_code": "200"},
+				},
+			},
+		},
+	}))
+	appVars := map[string]interface{}{
+		"create_service": true,
+		"service_name":   "app",
+		"vpc_id":         "vpc-0a1b2c3d4e5f60001",
+		"subnet_ids":     []string{"subnet-0a1b2c3d4e5f60003", "subnet-0a1b2c3d4e5f60004"},
+	}
+	for k, v := range stack["app"] {
+		appVars[k] = v
+	}
+	g.Merge(planSecurityGroups(t, stub, "app", appVars))
+	g.Merge(planSecurityGroups(t, stub, "efs", stack["efs"]))
+	require.NoError(t, g.Bind(albSecurityGroup, securityGroupID(albSecurityGroup), publicSubnets...))
+	require.NoError(t, g.Bind(appSecurityGroup, securityGroupID(appSecurityGroup), privateSubnets...))
+	require.NoError(t, g.Bind(efsSecurityGroup, securityGroupID(efsSecurityGroup), privateSubnets...))
+	require.NoError(t, g.Bind(vpcSecurityGroup, "", privateSubnets...))
+
+	intended := []sggraph.Flow{
+		sggraph.Port(sggraph.Internet, albSecurityGroup, sggraph.ProtocolTCP, 80),
+		sggraph.Port(sggraph.Internet, albSecurityGroup, sggraph.ProtocolTCP, 443),
+		sggraph.Port(sggraph.GroupNode(albSecurityGroup), appSecurityGroup, sggraph.ProtocolTCP, containerPort),
+		sggraph.Port(sggraph.GroupNode(appSecurityGroup), efsSecurityGroup, sggraph.ProtocolTCP, 2049),
+		sggraph.Port(sggraph.CIDRNode(vpcCIDR), vpcSecurityGroup, sggraph.ProtocolTCP, 80),
+		sggraph.Port(sggraph.CIDRNode(vpcCIDR), vpcSecurityGroup, sggraph.ProtocolTCP, 443),
+		sggraph.Port(sggraph.CIDRNode(vpcCIDR), vpcSecurityGroup, sggraph.ProtocolTCP, 22),
+	}
+
+	t.Logf("Security group flows:\n%s", g)
+	for _, u := range g.Unresolved {
+		t.Errorf("security group rules not analysed: %s", u)
+	}
+	report := sarifReport(t)
+	for _, p := range sggraph.Check(g, intended) {
+		if p.Severity >= analysis.DefaultThreshold {
+			t.Error(p)
+		} else {
+			t.Log(p)
+		}
+		report.Add(p.Finding(repoRoot, securityGroupPlans[p.Plan]))
+	}
+	assert.False(t, g.Reachable(sggraph.Internet, appSecurityGroup, sggraph.ProtocolTCP, containerPort), "the app must only be reachable through the load balancer")
+	assert.False(t, g.Reachable(sggraph.CIDRNode(vpcCIDR), appSecurityGroup, sggraph.ProtocolTCP, containerPort), "the app must only be reachable through the load balancer")
+	assert.False(t, g.Reachable(sggraph.Internet, vpcSecurityGroup, sggraph.ProtocolTCP, 22), "SSH must not be reachable from the internet")
+	assert.False(t, g.Reachable(sggraph.Internet, efsSecurityGroup, sggraph.ProtocolTCP, 2049), "the file system must not be reachable from the internet")
+	assert.False(t, g.Reachable(sggraph.GroupNode(albSecurityGroup), efsSecurityGroup, sggraph.ProtocolTCP, 2049))
+
+	t.Run("efs-public-cidrs", func(t *testing.T) {
+		var public sggraph.Graph
+		public.Merge(planSecurityGroups(t, stub, "efs", map[string]interface{}{
+			"allowed_cidr_blocks": []string{vpcCIDR, "198.51.100.0/24"},
+		}))
+		require.NoError(t, public.Bind(efsSecurityGroup, securityGroupID(efsSecurityGroup), privateSubnets...))
+		assert.True(t, public.Reachable(sggraph.Internet, efsSecurityGroup, sggraph.ProtocolTCP, 2049))
+
+		var high []sggraph.Problem
+		for _, p := range sggraph.Check(public, intended) {
+			if p.Rule == sggraph.RuleUnintendedFlow && p.Severity == analysis.SeverityHigh {
+				high = append(high, p)
+			}
+		}
+		if assert.Len(t, high, 1, "the public range must be reported") {
+			assert.Contains(t, high[0].Message, "198.51.100.0/24")
+		}
+	})
+	t.Run("vpc-default-allowed-ips", func(t *testing.T) {
+		defaults := map[string]interface{}{}
+		for k, v := range vpcVars {
+			if k != "allowed_ips" {
+				defaults[k] = v
+			}
+		}
+		var public sggraph.Graph
+		public.Merge(planSecurityGroups(t, stub, "vpc", defaults))
+		require.NoError(t, public.Bind(vpcSecurityGroup, "", privateSubnets...))
+		assert.True(t, public.Reachable(sggraph.Internet, vpcSecurityGroup, sggraph.ProtocolTCP, 22))
+
+		var high []sggraph.Problem
+		for _, p := range sggraph.Check(public, intended) {
+			if p.Rule == sggraph.RuleUnintendedFlow && p.Severity == analysis.SeverityHigh {
+				high = append(high, p)
+			}
+		}
+		assert.Len(t, high, 3, "HTTP, HTTPS and SSH from 0.0.0.0/0 must be reported")
+	})
+}
@@ -0,0 +1,85 @@
+package sggraph
+
+import (
+	"fmt"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// ToolSGGraph is the tool name of security group graph findings, as
+// recorded on analysis.Finding.
+const ToolSGGraph = "sggraph"
+
+// Rule names reported on problems.
+const (
+	// RuleUnintendedFlow is an ingress rule admitting traffic no intended
+	// flow covers. It is HIGH when the source includes public addresses.
+	RuleUnintendedFlow = "sg-unintended-flow"
+	// RuleBlockedFlow is an intended flow the groups' rules do not allow.
+	RuleBlockedFlow = "sg-blocked-flow"
+)
+
+// Problem is a flow the graph allows but should not, or should allow but
+// does not.
+type Problem struct {
+	Rule     string
+	Severity analysis.Severity
+	// Plan and Address locate the resource the problem is about: the rule
+	// resource or group, in the plan of that name.
+	Plan    string
+	Address string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: [%s] %s (%s)", p.Address, p.Severity, p.Message, p.Rule)
+}
+
+// Finding converts p to a finding located in the configuration at dir.
+func (p Problem) Finding(root, dir string) analysis.Finding {
+	return analysis.Locate(analysis.Finding{
+		Tool:     ToolSGGraph,
+		Rule:     p.Rule,
+		Severity: p.Severity,
+		Resource: p.Address,
+		Message:  p.Message,
+	}, root, dir)
+}
+
+// Check compares g with the intended flows: every flow g's ingress rules
+// admit must be covered by one, and every intended flow must be reachable
+// on its first and last port.
+func Check(g Graph, intended []Flow) []Problem {
+	var problems []Problem
+	for _, f := range g.Flows() {
+		if Intended(f, intended) {
+			continue
+		}
+		group, _ := g.Group(f.To)
+		severity, source := analysis.SeverityMedium, f.From.String()
+		if f.From.Internet || f.From.Group == "" && Public(f.From.CIDR) {
+			severity, source = analysis.SeverityHigh, source+", which includes public addresses,"
+		}
+		problems = append(problems, Problem{
+			Rule:     RuleUnintendedFlow,
+			Severity: severity,
+			Plan:     group.Plan,
+			Address:  f.Rule,
+			Message:  fmt.Sprintf("%s admits %s from %s and no intended flow covers it", f.To, portRange(f.Protocol, f.FromPort, f.ToPort), source),
+		})
+	}
+	for _, f := range intended {
+		if g.Reachable(f.From, f.To, f.Protocol, f.FromPort) && g.Reachable(f.From, f.To, f.Protocol, f.ToPort) {
+			continue
+		}
+		group, _ := g.Group(f.To)
+		problems = append(problems, Problem{
+			Rule:     RuleBlockedFlow,
+			Severity: analysis.SeverityHigh,
+			Plan:     group.Plan,
+			Address:  group.Address,
+			Message:  fmt.Sprintf("intended flow %s is not allowed by the security groups' rules", f),
+		})
+	}
+	return problems
+}
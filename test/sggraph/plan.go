@@ -0,0 +1,233 @@
+package sggraph
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// Resource types the graph is built from.
+const (
+	resourceGroup       = "aws_security_group"
+	resourceRule        = "aws_security_group_rule"
+	resourceIngressRule = "aws_vpc_security_group_ingress_rule"
+	resourceEgressRule  = "aws_vpc_security_group_egress_rule"
+)
+
+// FromPlan returns the security groups plan creates or keeps, with their
+// inline rules and the standalone rules added to them, named after name.
+// Group IDs are unknown until apply, so standalone rules are tied to the
+// groups they refer to through the plan's configuration; inline rules that
+// refer to a group planned alongside are unknown as a whole and are listed
+// as unresolved.
+func FromPlan(plan *planjson.Plan, name string) (Graph, error) {
+	config, err := plan.Config()
+	if err != nil {
+		return Graph{}, err
+	}
+	b := &builder{name: name, config: config}
+	changes := plan.ResourceChanges.Managed().Where(planjson.ResourceChange.Exists)
+
+	for _, rc := range changes.WithType(resourceGroup) {
+		group := Group{Name: b.groupName(rc.Address), Plan: name, Address: rc.Address}
+		group.ID, _ = rc.AfterString("id")
+		for _, direction := range []string{"ingress", "egress"} {
+			if rc.AfterUnknown(direction) {
+				b.unresolved(rc, direction+" rules are only known after apply")
+				continue
+			}
+			value, _ := rc.After(direction)
+			blocks, _ := value.([]interface{})
+			for _, block := range blocks {
+				block, ok := block.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				r, err := inlineRule(rc.Address, block)
+				if err != nil {
+					return Graph{}, err
+				}
+				if direction == "ingress" {
+					group.Ingress = append(group.Ingress, r)
+				} else {
+					group.Egress = append(group.Egress, r)
+				}
+			}
+		}
+		b.graph.Groups = append(b.graph.Groups, group)
+	}
+
+	for _, rc := range changes.WithType(resourceRule) {
+		direction, _ := rc.AfterString("type")
+		r := Rule{Address: rc.Address, FromPort: afterPort(rc, "from_port"), ToPort: afterPort(rc, "to_port")}
+		r.Protocol, _ = rc.AfterString("protocol")
+		if self, _ := rc.After("self"); self == true {
+			r.Self = true
+		}
+		for _, argument := range []string{"cidr_blocks", "ipv6_cidr_blocks"} {
+			value, _ := rc.After(argument)
+			cidrs, err := prefixes(rc.Address, value)
+			if err != nil {
+				return Graph{}, err
+			}
+			r.CIDRs = append(r.CIDRs, cidrs...)
+		}
+		r.Groups = append(r.Groups, b.peers(rc, "source_security_group_id")...)
+		b.add(rc, direction, r)
+	}
+
+	for _, rc := range append(changes.WithType(resourceIngressRule), changes.WithType(resourceEgressRule)...) {
+		r := Rule{Address: rc.Address, FromPort: afterPort(rc, "from_port"), ToPort: afterPort(rc, "to_port")}
+		r.Protocol, _ = rc.AfterString("ip_protocol")
+		for _, argument := range []string{"cidr_ipv4", "cidr_ipv6"} {
+			if cidr, ok := rc.AfterString(argument); ok && cidr != "" {
+				cidrs, err := prefixes(rc.Address, []interface{}{cidr})
+				if err != nil {
+					return Graph{}, err
+				}
+				r.CIDRs = append(r.CIDRs, cidrs...)
+			}
+		}
+		r.Groups = append(r.Groups, b.peers(rc, "referenced_security_group_id")...)
+		direction := "ingress"
+		if rc.Type == resourceEgressRule {
+			direction = "egress"
+		}
+		b.add(rc, direction, r)
+	}
+	return b.graph, nil
+}
+
+// builder accumulates a graph while resolving references through the plan's
+// configuration.
+type builder struct {
+	name   string
+	config planjson.Config
+	graph  Graph
+}
+
+// groupName returns the name of the group planned at address.
+func (b *builder) groupName(address string) string {
+	if b.name == "" {
+		return address
+	}
+	return b.name + "/" + address
+}
+
+// add adds r, declared by the standalone rule rc, to the group rc's
+// security_group_id refers to.
+func (b *builder) add(rc planjson.ResourceChange, direction string, r Rule) {
+	groups := b.peers(rc, "security_group_id")
+	if len(groups) != 1 {
+		b.unresolved(rc, fmt.Sprintf("security_group_id refers to %d security groups, want 1", len(groups)))
+		return
+	}
+	for i := range b.graph.Groups {
+		group := &b.graph.Groups[i]
+		if !group.is(groups[0]) {
+			continue
+		}
+		if direction == "egress" {
+			group.Egress = append(group.Egress, r)
+		} else {
+			group.Ingress = append(group.Ingress, r)
+		}
+		return
+	}
+	b.unresolved(rc, "security group "+groups[0]+" is not in the plan")
+}
+
+// peers returns the security groups argument of rc names: its planned ID
+// when known, otherwise the names of the planned groups its expression
+// refers to.
+func (b *builder) peers(rc planjson.ResourceChange, argument string) []string {
+	if id, ok := rc.AfterString(argument); ok {
+		if id == "" {
+			return nil
+		}
+		return []string{id}
+	}
+	if !rc.AfterUnknown(argument) {
+		return nil
+	}
+	module, _ := b.config.Module(rc.ModuleAddress)
+	r, ok := module.Resource(rc.ConfigAddress())
+	if !ok {
+		b.unresolved(rc, "the plan has no configuration for it")
+		return nil
+	}
+	var out []string
+	for _, ref := range r.InstanceReferences(argument, rc.Index) {
+		if !strings.HasPrefix(ref, resourceGroup+".") {
+			continue
+		}
+		if rc.ModuleAddress != "" {
+			ref = rc.ModuleAddress + "." + ref
+		}
+		out = append(out, b.groupName(ref))
+	}
+	return out
+}
+
+// unresolved records a rule of rc the graph cannot read.
+func (b *builder) unresolved(rc planjson.ResourceChange, reason string) {
+	b.graph.Unresolved = append(b.graph.Unresolved, b.groupName(rc.Address)+": "+reason)
+}
+
+// inlineRule converts a planned ingress or egress block of the group at
+// address.
+func inlineRule(address string, block map[string]interface{}) (Rule, error) {
+	r := Rule{Address: address, FromPort: port(block["from_port"]), ToPort: port(block["to_port"])}
+	r.Protocol, _ = block["protocol"].(string)
+	r.Self = block["self"] == true
+	for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks"} {
+		cidrs, err := prefixes(address, block[key])
+		if err != nil {
+			return Rule{}, err
+		}
+		r.CIDRs = append(r.CIDRs, cidrs...)
+	}
+	groups, _ := block["security_groups"].([]interface{})
+	for _, id := range groups {
+		if s, ok := id.(string); ok {
+			r.Groups = append(r.Groups, s)
+		}
+	}
+	return r, nil
+}
+
+// prefixes parses a planned list of CIDR blocks.
+func prefixes(address string, value interface{}) ([]netip.Prefix, error) {
+	list, _ := value.([]interface{})
+	var out []netip.Prefix
+	for _, v := range list {
+		s, _ := v.(string)
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", address, err)
+		}
+		out = append(out, prefix.Masked())
+	}
+	return out, nil
+}
+
+// port converts a planned port number, decoded from JSON, to an int.
+func port(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+// afterPort returns the planned port at path, 0 when unset.
+func afterPort(rc planjson.ResourceChange, path string) int {
+	value, _ := rc.After(path)
+	return port(value)
+}
@@ -0,0 +1,386 @@
+// Package sggraph builds the graph of which security groups, and which CIDR
+// ranges, a security group admits traffic from, on which protocols and
+// ports, and answers whether one node of the graph can reach another.
+//
+// Every module creates its own security group and only knows the others by
+// the IDs passed to it, so a graph is usually assembled from the plans of
+// several modules: each plan contributes its groups under a name, and Bind
+// gives a group the ID the other plans were given for it, along with the
+// CIDR blocks its members live in.
+package sggraph
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Protocol names. Rules written with protocol numbers are normalised to
+// these; any other protocol keeps its number.
+const (
+	ProtocolAll  = "all"
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolICMP = "icmp"
+)
+
+// Rule is one ingress or egress rule of a security group, inline or from a
+// standalone rule resource.
+type Rule struct {
+	// Address is the resource declaring the rule: the group for inline
+	// rules, the rule resource otherwise.
+	Address  string
+	Protocol string
+	FromPort int
+	ToPort   int
+	CIDRs    []netip.Prefix
+	// Groups are the peer security groups, as IDs, or as group names when a
+	// standalone rule refers to a group planned alongside it.
+	Groups []string
+	Self   bool
+}
+
+// Allows reports whether r covers traffic on protocol to port. Rules for all
+// protocols cover every port.
+func (r Rule) Allows(protocol string, port int) bool {
+	switch NormalizeProtocol(r.Protocol) {
+	case ProtocolAll:
+		return true
+	case NormalizeProtocol(protocol):
+		return port >= r.FromPort && port <= r.ToPort
+	}
+	return false
+}
+
+// covers reports whether r covers all of the ports of f.
+func (r Rule) covers(f Flow) bool {
+	if NormalizeProtocol(r.Protocol) == ProtocolAll {
+		return true
+	}
+	return NormalizeProtocol(r.Protocol) == NormalizeProtocol(f.Protocol) && f.FromPort >= r.FromPort && f.ToPort <= r.ToPort
+}
+
+// Group is a planned security group.
+type Group struct {
+	// Name is the plan's name and the group's address in it, e.g.
+	// "efs/aws_security_group.efs[0]".
+	Name    string
+	Plan    string
+	Address string
+	// ID is the group's ID: planned when known, or given by Bind.
+	ID string
+	// CIDRs are the ranges the group's members have addresses in, given by
+	// Bind. A group without them is only reached through rules naming it.
+	CIDRs   []netip.Prefix
+	Ingress []Rule
+	Egress  []Rule
+}
+
+// is reports whether peer, a group ID or name from a rule, names g.
+func (g Group) is(peer string) bool {
+	return peer == g.Name || (g.ID != "" && peer == g.ID)
+}
+
+// Graph is a set of security groups and their rules.
+type Graph struct {
+	Groups []Group
+	// Unresolved lists the rules the graph could not read, such as inline
+	// rules only known after apply.
+	Unresolved []string
+}
+
+// Group returns the group named name, or with the ID name.
+func (g Graph) Group(name string) (Group, bool) {
+	for _, group := range g.Groups {
+		if group.is(name) {
+			return group, true
+		}
+	}
+	return Group{}, false
+}
+
+// Merge adds the groups of other to g.
+func (g *Graph) Merge(other Graph) {
+	g.Groups = append(g.Groups, other.Groups...)
+	g.Unresolved = append(g.Unresolved, other.Unresolved...)
+}
+
+// Bind sets the ID of the group named name, as passed to the plans that
+// refer to it, and the CIDR blocks its members have addresses in.
+func (g *Graph) Bind(name, id string, cidrs ...string) error {
+	for i := range g.Groups {
+		group := &g.Groups[i]
+		if group.Name != name {
+			continue
+		}
+		group.ID = id
+		for _, cidr := range cidrs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return fmt.Errorf("binding %s: %w", name, err)
+			}
+			group.CIDRs = append(group.CIDRs, prefix.Masked())
+		}
+		return nil
+	}
+	return fmt.Errorf("binding %s: no such security group", name)
+}
+
+// Node is a source of traffic: a security group, a CIDR range outside the
+// graph's groups, or the internet.
+type Node struct {
+	Group    string
+	CIDR     netip.Prefix
+	Internet bool
+}
+
+// Internet is every public address. A rule admits it when the rule's ranges
+// include any address outside the private and special purpose ranges.
+var Internet = Node{Internet: true}
+
+// GroupNode returns the node of the group named name.
+func GroupNode(name string) Node {
+	return Node{Group: name}
+}
+
+// CIDRNode returns the node of the range cidr. It panics if cidr is not a
+// valid prefix.
+func CIDRNode(cidr string) Node {
+	return Node{CIDR: netip.MustParsePrefix(cidr).Masked()}
+}
+
+func (n Node) String() string {
+	switch {
+	case n.Internet:
+		return "internet"
+	case n.Group != "":
+		return n.Group
+	}
+	return n.CIDR.String()
+}
+
+// covers reports whether n, as the source of an intended flow, includes all
+// of other, the source of a rule.
+func (n Node) covers(other Node) bool {
+	switch {
+	case n.Group != "" || other.Group != "":
+		return n.Group == other.Group
+	case n.Internet:
+		return other.Internet || Public(other.CIDR)
+	case other.Internet:
+		return false
+	}
+	return n.CIDR.Bits() <= other.CIDR.Bits() && n.CIDR.Contains(other.CIDR.Addr())
+}
+
+// Flow is traffic from a node to a group on a protocol and port range.
+type Flow struct {
+	From     Node
+	To       string
+	Protocol string
+	FromPort int
+	ToPort   int
+	// Rule is the resource declaring the ingress rule of a flow found in the
+	// graph; intended flows leave it empty.
+	Rule string
+}
+
+// Port returns the flow of traffic from from to the group to on a single
+// port.
+func Port(from Node, to, protocol string, port int) Flow {
+	return Flow{From: from, To: to, Protocol: protocol, FromPort: port, ToPort: port}
+}
+
+func (f Flow) String() string {
+	return fmt.Sprintf("%s -> %s %s", f.From, f.To, portRange(f.Protocol, f.FromPort, f.ToPort))
+}
+
+// portRange describes the traffic on protocol between two ports.
+func portRange(protocol string, from, to int) string {
+	p := NormalizeProtocol(protocol)
+	switch {
+	case p == ProtocolAll:
+		return "all traffic"
+	case from == to:
+		return fmt.Sprintf("%s port %d", p, from)
+	}
+	return fmt.Sprintf("%s ports %d-%d", p, from, to)
+}
+
+// Flows returns the traffic each group's ingress rules admit, one flow per
+// rule and source, with groups named by the graph where it has them. Flows
+// say nothing of egress; Reachable checks both ends.
+func (g Graph) Flows() []Flow {
+	var flows []Flow
+	for _, to := range g.Groups {
+		for _, r := range to.Ingress {
+			flow := Flow{To: to.Name, Protocol: NormalizeProtocol(r.Protocol), FromPort: r.FromPort, ToPort: r.ToPort, Rule: r.Address}
+			if flow.Protocol == ProtocolAll {
+				flow.FromPort, flow.ToPort = 0, 65535
+			}
+			for _, cidr := range r.CIDRs {
+				flow.From = Node{CIDR: cidr}
+				flows = append(flows, flow)
+			}
+			for _, peer := range r.Groups {
+				flow.From = GroupNode(peer)
+				if from, ok := g.Group(peer); ok {
+					flow.From = GroupNode(from.Name)
+				}
+				flows = append(flows, flow)
+			}
+			if r.Self {
+				flow.From = GroupNode(to.Name)
+				flows = append(flows, flow)
+			}
+		}
+	}
+	return flows
+}
+
+// Reachable reports whether from can open a connection to the group named
+// to on protocol and port: an ingress rule of the target must admit the
+// source, by naming its group or by a CIDR range holding its addresses, and
+// when the source is a group one of its egress rules must admit the target
+// the same way.
+func (g Graph) Reachable(from Node, to, protocol string, port int) bool {
+	target, ok := g.Group(to)
+	if !ok {
+		return false
+	}
+	if from.Group == "" {
+		for _, r := range target.Ingress {
+			if r.Allows(protocol, port) && admitsNode(r, from) {
+				return true
+			}
+		}
+		return false
+	}
+	source, ok := g.Group(from.Group)
+	if !ok {
+		return false
+	}
+	in, out := false, false
+	for _, r := range target.Ingress {
+		if r.Allows(protocol, port) && admits(r, source, target) {
+			in = true
+			break
+		}
+	}
+	for _, r := range source.Egress {
+		if r.Allows(protocol, port) && admits(r, target, source) {
+			out = true
+			break
+		}
+	}
+	return in && out
+}
+
+// admits reports whether the rule r of the group owner admits the peer
+// group.
+func admits(r Rule, peer, owner Group) bool {
+	if r.Self && peer.Name == owner.Name {
+		return true
+	}
+	for _, name := range r.Groups {
+		if peer.is(name) {
+			return true
+		}
+	}
+	for _, cidr := range r.CIDRs {
+		if cidr.Bits() == 0 {
+			// anywhere, whether or not the peer's ranges are known
+			return true
+		}
+		for _, member := range peer.CIDRs {
+			if cidr.Overlaps(member) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// admitsNode reports whether r admits traffic from a range or the internet.
+func admitsNode(r Rule, n Node) bool {
+	for _, cidr := range r.CIDRs {
+		if n.Internet && Public(cidr) || !n.Internet && cidr.Overlaps(n.CIDR) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateRanges are the ranges that are not routed on the internet: RFC
+// 1918, shared address space, loopback, link local and IPv6 unique local.
+var privateRanges = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// Public reports whether cidr includes any public address.
+func Public(cidr netip.Prefix) bool {
+	for _, private := range privateRanges {
+		if private.Bits() <= cidr.Bits() && private.Contains(cidr.Addr()) {
+			return false
+		}
+	}
+	return cidr.IsValid()
+}
+
+// NormalizeProtocol returns the name of protocol as a security group rule
+// writes it, by name or number.
+func NormalizeProtocol(protocol string) string {
+	switch p := strings.ToLower(protocol); p {
+	case "-1", ProtocolAll:
+		return ProtocolAll
+	case "6":
+		return ProtocolTCP
+	case "17":
+		return ProtocolUDP
+	case "1":
+		return ProtocolICMP
+	default:
+		return p
+	}
+}
+
+// Intended reports whether f is covered by one of the intended flows: the
+// same target, a source that includes f's, and the same protocol with a port
+// range that includes f's.
+func Intended(f Flow, intended []Flow) bool {
+	for _, i := range intended {
+		rule := Rule{Protocol: i.Protocol, FromPort: i.FromPort, ToPort: i.ToPort}
+		if i.To == f.To && i.From.covers(f.From) && rule.covers(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the flows of g, one line per source and target.
+func (g Graph) String() string {
+	lines := map[string][]string{}
+	var keys []string
+	for _, f := range g.Flows() {
+		key := fmt.Sprintf("%s -> %s", f.From, f.To)
+		if _, ok := lines[key]; !ok {
+			keys = append(keys, key)
+		}
+		lines[key] = append(lines[key], portRange(f.Protocol, f.FromPort, f.ToPort))
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, strings.Join(lines[key], ", "))
+	}
+	return b.String()
+}
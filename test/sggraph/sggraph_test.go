@@ -0,0 +1,122 @@
+package sggraph
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+const (
+	alb = "stack/module.stack.aws_security_group.alb"
+	app = "stack/module.stack.aws_security_group.app"
+	efs = "stack/module.stack.aws_security_group.efs"
+)
+
+// graph is the graph of testdata/plan.json: a load balancer open on 443, an
+// app admitting it on 8080 and a file system admitting the app on 2049, as
+// well as a private and a public range.
+func graph(t *testing.T) Graph {
+	t.Helper()
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	g, err := FromPlan(plan, "stack")
+	require.NoError(t, err)
+	require.NoError(t, g.Bind(alb, "sg-0a1b2c3d4e5f60008", "10.0.1.0/24", "10.0.2.0/24"))
+	require.NoError(t, g.Bind(app, "sg-0a1b2c3d4e5f60009", "10.0.10.0/24"))
+	return g
+}
+
+func TestFromPlan(t *testing.T) {
+	g := graph(t)
+	require.Len(t, g.Groups, 4, "the deleted group is left out")
+
+	group, ok := g.Group("sg-0a1b2c3d4e5f60009")
+	require.True(t, ok)
+	assert.Equal(t, app, group.Name)
+	assert.Equal(t, "module.stack.aws_security_group.app", group.Address)
+	assert.Equal(t, []Rule{{
+		Address:  "module.stack.aws_vpc_security_group_ingress_rule.app_from_alb",
+		Protocol: "tcp", FromPort: 8080, ToPort: 8080,
+		Groups: []string{alb},
+	}}, group.Ingress, "standalone rules are tied to their group through the configuration")
+
+	group, _ = g.Group(efs)
+	require.Len(t, group.Ingress, 3)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("203.0.113.0/24")}, group.Ingress[0].CIDRs)
+	assert.Empty(t, group.Egress)
+
+	assert.Equal(t, []string{
+		"stack/module.stack.aws_security_group.db: ingress rules are only known after apply",
+		"stack/module.stack.aws_security_group.db: egress rules are only known after apply",
+		"stack/module.stack.aws_vpc_security_group_egress_rule.db_out: security_group_id refers to 0 security groups, want 1",
+	}, g.Unresolved)
+
+	assert.Error(t, g.Bind("stack/aws_security_group.missing", "sg-1"))
+	assert.Error(t, g.Bind(alb, "sg-1", "10.0.0.0"))
+}
+
+func TestReachable(t *testing.T) {
+	g := graph(t)
+
+	assert.True(t, g.Reachable(Internet, alb, "tcp", 443))
+	assert.False(t, g.Reachable(Internet, alb, "tcp", 80))
+	assert.False(t, g.Reachable(Internet, app, "tcp", 8080))
+	assert.True(t, g.Reachable(Internet, efs, "tcp", 2049), "203.0.113.0/24 is public")
+	assert.True(t, g.Reachable(CIDRNode("10.0.200.0/24"), efs, "tcp", 2049))
+	assert.False(t, g.Reachable(CIDRNode("192.168.0.0/16"), efs, "tcp", 2049))
+
+	assert.True(t, g.Reachable(GroupNode(alb), app, "tcp", 8080))
+	assert.False(t, g.Reachable(GroupNode(alb), app, "udp", 8080))
+	assert.True(t, g.Reachable(GroupNode(app), efs, "6", 2049), "protocol numbers match names")
+	assert.True(t, g.Reachable(GroupNode(alb), efs, "tcp", 2049), "through 10.0.0.0/16")
+	assert.False(t, g.Reachable(GroupNode(efs), app, "tcp", 8080), "efs has no egress rules")
+	assert.False(t, g.Reachable(GroupNode(app), "stack/aws_security_group.missing", "tcp", 80))
+}
+
+func TestCheck(t *testing.T) {
+	g := graph(t)
+	intended := []Flow{
+		Port(Internet, alb, "tcp", 443),
+		Port(GroupNode(alb), app, "tcp", 8080),
+		Port(GroupNode(app), efs, "tcp", 2049),
+		Port(GroupNode(efs), app, "tcp", 8080),
+	}
+	var got []string
+	for _, p := range Check(g, intended) {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		"module.stack.aws_security_group.efs: [MEDIUM] " + efs + " admits tcp port 2049 from 10.0.0.0/16 and no intended flow covers it (sg-unintended-flow)",
+		"module.stack.aws_security_group.efs: [HIGH] " + efs + " admits tcp port 2049 from 203.0.113.0/24, which includes public addresses, and no intended flow covers it (sg-unintended-flow)",
+		"module.stack.aws_security_group.app: [HIGH] intended flow " + efs + " -> " + app + " tcp port 8080 is not allowed by the security groups' rules (sg-blocked-flow)",
+	}, got)
+
+	intended = append(intended[:3], Port(CIDRNode("10.0.0.0/8"), efs, "tcp", 2049))
+	for _, p := range Check(g, intended) {
+		assert.Equal(t, RuleUnintendedFlow, p.Rule)
+		assert.Contains(t, p.Message, "203.0.113.0/24")
+	}
+}
+
+func TestIntended(t *testing.T) {
+	f := Flow{From: CIDRNode("0.0.0.0/0"), To: alb, Protocol: "tcp", FromPort: 443, ToPort: 443}
+	assert.True(t, Intended(f, []Flow{Port(Internet, alb, "tcp", 443)}))
+	assert.True(t, Intended(f, []Flow{{From: Internet, To: alb, Protocol: "all"}}))
+	assert.False(t, Intended(f, []Flow{Port(Internet, alb, "udp", 443)}))
+	assert.False(t, Intended(f, []Flow{Port(CIDRNode("10.0.0.0/8"), alb, "tcp", 443)}))
+	assert.False(t, Intended(Port(CIDRNode("10.0.0.0/16"), alb, "tcp", 443), []Flow{Port(Internet, alb, "tcp", 443)}),
+		"private ranges are not the internet")
+	assert.False(t, Intended(Port(GroupNode(app), alb, "tcp", 443), []Flow{Port(Internet, alb, "tcp", 443)}))
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "0.0.0.0/0 -> "+alb+": tcp port 443\n"+
+		"10.0.0.0/16 -> "+efs+": tcp port 2049\n"+
+		"203.0.113.0/24 -> "+efs+": tcp port 2049\n"+
+		alb+" -> "+app+": tcp port 8080\n"+
+		app+" -> "+efs+": tcp port 2049, tcp port 2049\n", graph(t).String())
+}
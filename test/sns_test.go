@@ -0,0 +1,42 @@
+package test
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
+)
+
+// TestSNSModule deploys a standard topic and checks its attributes
+func TestSNSModule(t *testing.T) {
+	harness.RequireDeployTier(t)
+	t.Parallel()
+
+	awsRegion := harness.Region(t)
+	topicName := "terratest-" + random.UniqueId()
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "modules/sns", awsRegion, map[string]interface{}{
+		"topic_name":   topicName,
+		"environment":  "test",
+		"display_name": "Terratest",
+	}))
+
+	defer terraform.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	topicArn := terraform.Output(t, terraformOptions, "topic_arn")
+	assert.Equal(t, topicName, terraform.Output(t, terraformOptions, "topic_name"))
+
+	client := aws.NewSnsClient(t, awsRegion)
+	out, err := client.GetTopicAttributes(&sns.GetTopicAttributesInput{TopicArn: awssdk.String(topicArn)})
+	require.NoError(t, err)
+	assert.Equal(t, topicArn, awssdk.StringValue(out.Attributes["TopicArn"]))
+	assert.Equal(t, "Terratest", awssdk.StringValue(out.Attributes["DisplayName"]))
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/harness"
+)
+
+// TestSQSModule deploys a queue with a dead letter queue and sends a message through it
+func TestSQSModule(t *testing.T) {
+	harness.RequireDeployTier(t)
+	t.Parallel()
+
+	awsRegion := harness.Region(t)
+	queueName := "terratest-" + random.UniqueId()
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, harness.Options(t, repoRoot, "modules/sqs", awsRegion, map[string]interface{}{
+		"queue_name":        queueName,
+		"environment":       "test",
+		"create_dlq":        true,
+		"max_receive_count": 3,
+	}))
+
+	defer terraform.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	queueURL := terraform.Output(t, terraformOptions, "queue_url")
+	dlqArn := terraform.Output(t, terraformOptions, "dlq_arn")
+	assert.Equal(t, queueName, terraform.Output(t, terraformOptions, "queue_name"))
+
+	// Verify the queue redrives to the dead letter queue
+	client := aws.NewSqsClient(t, awsRegion)
+	attrs, err := client.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       awssdk.String(queueURL),
+		AttributeNames: []*string{awssdk.String(sqs.QueueAttributeNameRedrivePolicy)},
+	})
+	require.NoError(t, err)
+
+	var redrivePolicy struct {
+		DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(awssdk.StringValue(attrs.Attributes[sqs.QueueAttributeNameRedrivePolicy])), &redrivePolicy))
+	assert.Equal(t, dlqArn, redrivePolicy.DeadLetterTargetArn)
+	assert.Equal(t, "3", redrivePolicy.MaxReceiveCount.String())
+
+	// Verify messages can be sent and received
+	aws.SendMessageToQueue(t, awsRegion, queueURL, "hello from terratest")
+	message := aws.WaitForQueueMessage(t, awsRegion, queueURL, 20)
+	require.NoError(t, message.Error)
+	assert.Equal(t, "hello from terratest", message.MessageBody)
+	aws.DeleteMessageFromQueue(t, awsRegion, queueURL, message.ReceiptHandle)
+}
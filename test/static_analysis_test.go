@@ -1,233 +1,301 @@
 package test
 
 import (
+	"fmt"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"testing"
+	"time"
 
-	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/iampolicy"
+	"github.com/your-org/terraform-aws-modules/test/sarif"
+	"github.com/your-org/terraform-aws-modules/test/tfcheck"
 )
 
 // TestTerraformFormat checks if all Terraform files are properly formatted
 func TestTerraformFormat(t *testing.T) {
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2",
-		"../modules/ecs",
-		"../modules/eks",
-		"../modules/elb",
-		"../modules/storage",
-	}
-
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
+	for _, cfg := range discoverConfigs(t) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
 			cmd := exec.Command("terraform", "fmt", "-check", "-diff", "-recursive")
-			cmd.Dir = module
-			
+			cmd.Dir = cfg.Path
+
 			output, err := cmd.CombinedOutput()
-			
+
 			if err != nil {
-				t.Errorf("Terraform files in %s are not properly formatted:\n%s", module, string(output))
+				t.Errorf("Terraform files in %s are not properly formatted:\n%s", cfg.ID(), string(output))
 			}
 		})
 	}
 }
 
-// TestTFLint runs tflint on all modules to check for common issues
+// staticAnalysisBaseline is the committed baseline of accepted tfsec, tflint and checkov findings and
+// of the severity from which findings fail each module
+const staticAnalysisBaseline = "static_analysis_baseline.json"
+
+// TestTFLint runs tflint on all modules, examples and environments to check for common issues
 func TestTFLint(t *testing.T) {
 	// Check if tflint is installed
 	if _, err := exec.LookPath("tflint"); err != nil {
 		t.Skip("tflint not installed, skipping linting tests")
 	}
 
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2", 
-		"../modules/ecs",
-		"../modules/eks",
-		"../modules/elb",
-		"../modules/storage",
-	}
+	runScanner(t, analysis.ToolTFLint, func(t *testing.T, cfg discovery.Config) ([]analysis.Finding, error) {
+		// Install the plugins .tflint.hcl asks for; without them tflint checks nothing
+		initCmd := exec.Command("tflint", "--init")
+		initCmd.Dir = cfg.Path
+		if output, err := initCmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("tflint --init: %v\n%s", err, output)
+		}
 
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			// Initialize tflint
-			initCmd := exec.Command("tflint", "--init")
-			initCmd.Dir = module
-			initCmd.Run()
+		// tflint exits non-zero when it finds issues; the JSON report says whether it ran
+		cmd := exec.Command("tflint", "--format", "json")
+		cmd.Dir = cfg.Path
+		output, _ := cmd.Output()
 
-			// Run tflint
-			cmd := exec.Command("tflint", "--format", "compact")
-			cmd.Dir = module
-			
-			output, err := cmd.CombinedOutput()
-			
-			if err != nil {
-				t.Errorf("TFLint found issues in %s:\n%s", module, string(output))
-			}
-		})
-	}
+		return analysis.ParseTFLint(output, repoRoot, cfg.Path)
+	})
 }
 
-// TestTFSec runs tfsec security analysis on all modules
+// TestTFSec runs tfsec security analysis on all modules, examples and environments
 func TestTFSec(t *testing.T) {
 	// Check if tfsec is installed
 	if _, err := exec.LookPath("tfsec"); err != nil {
 		t.Skip("tfsec not installed, skipping security tests")
 	}
 
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2",
-		"../modules/ecs", 
-		"../modules/eks",
-		"../modules/elb",
-		"../modules/storage",
+	runScanner(t, analysis.ToolTFSec, func(t *testing.T, cfg discovery.Config) ([]analysis.Finding, error) {
+		cmd := exec.Command("tfsec", ".", "--format", "json", "--soft-fail")
+		cmd.Dir = cfg.Path
+
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("tfsec: %v%s", err, stderr(err))
+		}
+		return analysis.ParseTFSec(output, repoRoot, cfg.Path)
+	})
+}
+
+// TestCheckov runs checkov policy checks on all modules, examples and environments
+func TestCheckov(t *testing.T) {
+	// Check if checkov is installed
+	if _, err := exec.LookPath("checkov"); err != nil {
+		t.Skip("checkov not installed, skipping policy tests")
 	}
 
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			cmd := exec.Command("tfsec", ".", "--format", "json", "--soft-fail")
-			cmd.Dir = module
-			
-			output, err := cmd.CombinedOutput()
-			
-			// tfsec returns non-zero exit code when issues are found
-			// but we use --soft-fail to get the output
-			if err != nil && !strings.Contains(string(output), "results") {
-				t.Errorf("TFSec failed to run on %s: %v", module, err)
+	runScanner(t, analysis.ToolCheckov, func(t *testing.T, cfg discovery.Config) ([]analysis.Finding, error) {
+		cmd := exec.Command("checkov", "--directory", ".", "--framework", "terraform", "--output", "json", "--quiet", "--soft-fail")
+		cmd.Dir = cfg.Path
+
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("checkov: %v%s", err, stderr(err))
+		}
+		return analysis.ParseCheckov(output, repoRoot, cfg.Path)
+	})
+}
+
+// runScanner runs scan on every configuration and fails each on the findings the baseline neither
+// suppresses nor puts below the module's threshold. Findings below the threshold are logged, and
+// every finding goes to the test's SARIF report, suppressed ones with their justification
+func runScanner(t *testing.T, tool string, scan func(t *testing.T, cfg discovery.Config) ([]analysis.Finding, error)) {
+	baseline, err := analysis.LoadBaseline(staticAnalysisBaseline)
+	require.NoError(t, err)
+	now := time.Now()
+
+	var all []analysis.Finding
+	for _, cfg := range discoverConfigs(t) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			findings, err := scan(t, cfg)
+			require.NoError(t, err)
+			all = append(all, findings...)
+
+			result := baseline.Evaluate(findings, now)
+			for _, s := range result.Expired {
+				t.Logf("Baseline suppression expired, its findings count as new: %s", s)
+			}
+			for _, f := range result.BelowThreshold {
+				t.Logf("Below the %s threshold of %s: %s", baseline.Threshold(f.Module), f.Module, f)
 			}
-			
-			// Parse output and check for high/critical severity issues
-			outputStr := string(output)
-			if strings.Contains(outputStr, `"severity":"HIGH"`) || 
-			   strings.Contains(outputStr, `"severity":"CRITICAL"`) {
-				t.Errorf("TFSec found high/critical security issues in %s:\n%s", module, outputStr)
+			if len(result.Suppressed) > 0 {
+				t.Logf("%d findings suppressed by %s", len(result.Suppressed), staticAnalysisBaseline)
+			}
+			for _, f := range result.Failing {
+				t.Error(f)
 			}
 		})
 	}
+
+	all = analysis.Dedupe(all)
+	report := sarifReport(t)
+	for _, f := range all {
+		if s, ok := baseline.Suppression(f, now); ok {
+			report.AddSuppressed(f, s.Justification)
+		} else {
+			report.Add(f)
+		}
+	}
+
+	for _, s := range baseline.Evaluate(all, now).Unused {
+		if s.Tool == tool {
+			t.Logf("Baseline suppression matched no finding and can be removed: %s", s)
+		}
+	}
+}
+
+// reportFindings fails the test on each finding of the tfcheck source checks the baseline does not
+// suppress and adds them all to report, suppressed ones with their justification
+func reportFindings(t *testing.T, report *sarif.Builder, findings []tfcheck.Finding) {
+	t.Helper()
+
+	baseline, err := analysis.LoadBaseline(staticAnalysisBaseline)
+	require.NoError(t, err)
+	now := time.Now()
+
+	for i, f := range analysis.FromTFCheck(findings, analysis.SeverityHigh, repoRoot) {
+		if s, ok := baseline.Suppression(f, now); ok {
+			t.Logf("Suppressed by %s: %s", staticAnalysisBaseline, findings[i])
+			report.AddSuppressed(f, s.Justification)
+			continue
+		}
+		t.Error(findings[i])
+		report.Add(f)
+	}
+}
+
+// stderr returns the standard error of a failed command, for error messages
+func stderr(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return "\n" + string(exitErr.Stderr)
+	}
+	return ""
 }
 
-// TestModuleStructure verifies that all modules have required files
+// TestModuleStructure verifies every module against the module contract: required files exist and every
+// provider it uses is declared in the root versions.tf with a bounded constraint
 func TestModuleStructure(t *testing.T) {
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2",
-		"../modules/ecs",
-		"../modules/eks", 
-		"../modules/elb",
-		"../modules/storage",
-	}
-
-	requiredFiles := []string{
-		"main.tf",
-		"variables.tf",
-		"outputs.tf",
-		"versions.tf",
-		"README.md",
-		"examples.tf",
-	}
-
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			for _, file := range requiredFiles {
-				filePath := filepath.Join(module, file)
-				
-				if _, err := filepath.Glob(filePath); err != nil {
-					t.Errorf("Required file %s not found in module %s", file, module)
-				}
-			}
+	report := sarifReport(t)
+	root := loadRootConfig(t)
+	contracts, rootFindings := tfcheck.CheckContracts(root, discoverConfigs(t, discovery.KindModule))
+
+	t.Logf("Module contract status:\n%s", tfcheck.FormatContracts(contracts))
+
+	for _, contract := range contracts {
+		contract := contract
+		t.Run(contract.Module, func(t *testing.T) {
+			reportFindings(t, report, contract.Findings)
 		})
 	}
+
+	t.Run("versions.tf", func(t *testing.T) {
+		reportDiagnostics(t, root)
+
+		reportFindings(t, report, rootFindings)
+	})
 }
 
-// TestVariableDescriptions ensures all variables have descriptions
+// TestVariableDescriptions ensures all variables have a type and a non-empty description
 func TestVariableDescriptions(t *testing.T) {
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2",
-		"../modules/ecs",
-		"../modules/eks",
-		"../modules/elb", 
-		"../modules/storage",
-	}
-
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			cmd := exec.Command("grep", "-n", "variable", filepath.Join(module, "variables.tf"))
-			output, err := cmd.CombinedOutput()
-			
-			if err != nil {
-				// No variables file or no variables - skip
-				return
-			}
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindModule) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			reportFileDiagnostics(t, cfg, "variables.tf")
 
-			// Check that each variable block has a description
-			lines := strings.Split(string(output), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "variable") && strings.Contains(line, "{") {
-					// This is a variable declaration - check for description
-					variableName := extractVariableName(line)
-					if variableName != "" {
-						descCmd := exec.Command("grep", "-A", "5", "variable \""+variableName+"\"", filepath.Join(module, "variables.tf"))
-						descOutput, _ := descCmd.CombinedOutput()
-						
-						if !strings.Contains(string(descOutput), "description") {
-							t.Errorf("Variable %s in module %s is missing a description", variableName, module)
-						}
-					}
-				}
-			}
+			reportFindings(t, report, tfcheck.CheckVariables(cfg))
 		})
 	}
 }
 
-// Helper function to extract variable name from grep output
-func extractVariableName(line string) string {
-	parts := strings.Split(line, "\"")
-	if len(parts) >= 2 {
-		return parts[1]
+// TestOutputDescriptions ensures all outputs have a non-empty description and secret-looking outputs are sensitive
+func TestOutputDescriptions(t *testing.T) {
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindModule) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			reportFileDiagnostics(t, cfg, "outputs.tf")
+
+			reportFindings(t, report, tfcheck.CheckOutputs(cfg))
+		})
 	}
-	return ""
 }
 
-// TestOutputDescriptions ensures all outputs have descriptions
-func TestOutputDescriptions(t *testing.T) {
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2",
-		"../modules/ecs",
-		"../modules/eks",
-		"../modules/elb",
-		"../modules/storage",
-	}
-
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			cmd := exec.Command("grep", "-n", "output", filepath.Join(module, "outputs.tf"))
-			output, err := cmd.CombinedOutput()
-			
-			if err != nil {
-				// No outputs file or no outputs - skip
-				return
+// TestModuleCalls checks the arguments of every local module call in the environments and examples
+// against the variables the called module declares
+func TestModuleCalls(t *testing.T) {
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindEnv, discovery.KindExample) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			reportDiagnostics(t, cfg)
+
+			findings, err := tfcheck.CheckModuleCalls(cfg)
+			require.NoError(t, err)
+
+			reportFindings(t, report, findings)
+		})
+	}
+}
+
+// TestEnvTFVars type-checks each environment's tfvars files against its variables and evaluates their
+// validation conditions offline
+func TestEnvTFVars(t *testing.T) {
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindEnv) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			reportDiagnostics(t, cfg)
+
+			files, err := filepath.Glob(filepath.Join(cfg.Path, "*.tfvars"))
+			require.NoError(t, err)
+			if len(files) == 0 {
+				t.Skip("no tfvars files")
 			}
 
-			// Check that each output block has a description
-			lines := strings.Split(string(output), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "output") && strings.Contains(line, "{") {
-					outputName := extractVariableName(line)
-					if outputName != "" {
-						descCmd := exec.Command("grep", "-A", "3", "output \""+outputName+"\"", filepath.Join(module, "outputs.tf"))
-						descOutput, _ := descCmd.CombinedOutput()
-						
-						if !strings.Contains(string(descOutput), "description") {
-							t.Errorf("Output %s in module %s is missing a description", outputName, module)
-						}
-					}
-				}
+			for _, file := range files {
+				findings, err := tfcheck.CheckTFVars(cfg, file)
+				require.NoError(t, err)
+
+				reportFindings(t, report, findings)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// TestIAMPolicyDocuments lints every IAM policy document passed to the modules as a JSON string: those in
+// module calls and tfvars files of the examples and environments and those in the variables the Go tests
+// pass. Findings at or above the default threshold fail; the rest, such as Resource "*", are logged
+func TestIAMPolicyDocuments(t *testing.T) {
+	report := sarifReport(t)
+	configs := discoverConfigs(t, discovery.KindModule, discovery.KindExample, discovery.KindEnv)
+
+	var docs []iampolicy.Document
+	for _, cfg := range configs {
+		tfvars, err := filepath.Glob(filepath.Join(cfg.Path, "*.tfvars"))
+		require.NoError(t, err)
+		found, err := iampolicy.FromConfig(cfg, tfvars)
+		require.NoError(t, err)
+		docs = append(docs, found...)
+	}
+
+	files, err := filepath.Glob("*_test.go")
+	require.NoError(t, err)
+	found, err := iampolicy.FromGoFiles(files, configs)
+	require.NoError(t, err)
+	docs = append(docs, found...)
+	t.Logf("checked %d policy documents", len(docs))
+
+	for _, finding := range iampolicy.Lint(docs) {
+		if finding.Severity >= analysis.DefaultThreshold {
+			t.Error(finding)
+		} else {
+			t.Log(finding)
+		}
+		report.Add(finding.Finding(repoRoot))
+	}
+}
@@ -0,0 +1,222 @@
+// Package tagcheck checks that the resources in a Terraform plan carry a
+// required set of tags. It works on plan JSON rather than the configuration
+// because modules build their tags in different ways, with merge, locals and
+// dynamic blocks, and only the plan shows what each resource ends up with.
+package tagcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// Policy is the set of tags every taggable resource must carry.
+type Policy struct {
+	// Required lists the tag keys every taggable resource must have.
+	Required []string
+	// Exempt lists resource types that are not checked.
+	Exempt []string
+}
+
+// Violation is a resource missing required tags.
+type Violation struct {
+	// Module is the address of the module containing the resource, empty
+	// for the root module.
+	Module  string
+	Type    string
+	Address string
+	Missing []string
+	// Where names the part of the resource the tags are missing from, when
+	// it is not the resource's own tags, e.g. "instances launched by the
+	// group".
+	Where string
+}
+
+func (v Violation) String() string {
+	module := v.Module
+	if module == "" {
+		module = "(root)"
+	}
+	msg := fmt.Sprintf("%s: %s %s is missing %s", module, v.Type, v.Address, strings.Join(v.Missing, ", "))
+	if v.Where != "" {
+		msg += " on " + v.Where
+	}
+	return msg
+}
+
+// Check returns the violations of policy in plan, ordered by module address,
+// resource type and address. A resource is taggable if its planned value has
+// a tags attribute; tags_all is checked in preference to tags when known, so
+// provider default_tags count. Auto Scaling groups are checked through their
+// tag blocks, and the tags propagated to the instances they launch must
+// include the required ones too. Launch template tag specifications are
+// checked as well, since they tag instances and volumes directly.
+//
+// Resources being deleted or read are skipped, as are tags whose value is
+// only known after apply.
+func Check(plan *planjson.Plan, policy Policy) []Violation {
+	exempt := map[string]bool{}
+	for _, t := range policy.Exempt {
+		exempt[t] = true
+	}
+
+	var violations []Violation
+	for _, rc := range plan.ResourceChanges.Managed() {
+		switch rc.Change.Actions.Kind() {
+		case planjson.ActionDelete, planjson.ActionRead:
+			continue
+		}
+		if exempt[rc.Type] {
+			continue
+		}
+
+		values, ok := rc.Planned().(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		add := func(missing []string, where string) {
+			if len(missing) > 0 {
+				violations = append(violations, Violation{
+					Module:  rc.ModuleAddress,
+					Type:    rc.Type,
+					Address: rc.Address,
+					Missing: missing,
+					Where:   where,
+				})
+			}
+		}
+
+		if rc.Type == "aws_autoscaling_group" {
+			own, launched, known := groupTags(values)
+			if known {
+				add(missing(policy.Required, own), "")
+				add(missing(policy.Required, launched), "instances launched by the group (propagate_at_launch)")
+			}
+			continue
+		}
+
+		if tags, known := resourceTags(values); known {
+			add(missing(policy.Required, tags), "")
+		}
+
+		if specs, ok := values["tag_specifications"].([]interface{}); ok {
+			for _, spec := range specs {
+				spec, ok := spec.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				tags, known := tagMap(spec["tags"])
+				if known {
+					add(missing(policy.Required, tags), fmt.Sprintf("tag_specifications for %v", spec["resource_type"]))
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Address < b.Address
+	})
+	return violations
+}
+
+// resourceTags returns the keys a resource will be tagged with, and whether
+// they are known. ok is false for resources without tags.
+func resourceTags(values map[string]interface{}) (map[string]bool, bool) {
+	if all, known := tagMap(values["tags_all"]); known && len(all) > 0 {
+		return all, true
+	}
+	if _, ok := values["tags"]; !ok {
+		return nil, false
+	}
+	return tagMap(values["tags"])
+}
+
+// tagMap returns the keys of a planned tags map. A null map has no keys; an
+// unknown map is not known.
+func tagMap(value interface{}) (map[string]bool, bool) {
+	switch v := value.(type) {
+	case nil:
+		return map[string]bool{}, true
+	case map[string]interface{}:
+		keys := make(map[string]bool, len(v))
+		for key := range v {
+			keys[key] = true
+		}
+		return keys, true
+	default:
+		return nil, false
+	}
+}
+
+// groupTags returns the tag keys of an Auto Scaling group and those it
+// propagates to the instances it launches, from its tag blocks.
+func groupTags(values map[string]interface{}) (own, launched map[string]bool, known bool) {
+	own, launched = map[string]bool{}, map[string]bool{}
+
+	blocks, ok := values["tag"].([]interface{})
+	if !ok {
+		return own, launched, values["tag"] == nil
+	}
+	for _, block := range blocks {
+		block, ok := block.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		key, ok := block["key"].(string)
+		if !ok || key == planjson.UnknownValue {
+			return nil, nil, false
+		}
+		own[key] = true
+		if propagate, ok := block["propagate_at_launch"].(bool); ok && propagate {
+			launched[key] = true
+		}
+	}
+	return own, launched, true
+}
+
+func missing(required []string, have map[string]bool) []string {
+	var out []string
+	for _, key := range required {
+		if !have[key] {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// Report formats violations grouped by module and resource type, for a test
+// failure message.
+func Report(violations []Violation) string {
+	var b strings.Builder
+	var module, resourceType string
+	for i, v := range violations {
+		if i == 0 || v.Module != module {
+			module, resourceType = v.Module, ""
+			name := module
+			if name == "" {
+				name = "(root)"
+			}
+			fmt.Fprintf(&b, "%s\n", name)
+		}
+		if v.Type != resourceType {
+			resourceType = v.Type
+			fmt.Fprintf(&b, "  %s\n", resourceType)
+		}
+		fmt.Fprintf(&b, "    %s: missing %s", v.Address, strings.Join(v.Missing, ", "))
+		if v.Where != "" {
+			fmt.Fprintf(&b, " on %s", v.Where)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
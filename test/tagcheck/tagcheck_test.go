@@ -0,0 +1,50 @@
+package tagcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+func loadPlan(t *testing.T) *planjson.Plan {
+	t.Helper()
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	return plan
+}
+
+func TestCheck(t *testing.T) {
+	violations := Check(loadPlan(t), Policy{Required: []string{"Environment", "Project"}})
+
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	assert.Equal(t, []string{
+		"module.ec2: aws_autoscaling_group module.ec2.aws_autoscaling_group.this is missing Environment on instances launched by the group (propagate_at_launch)",
+		"module.ec2: aws_launch_template module.ec2.aws_launch_template.this is missing Environment, Project on tag_specifications for volume",
+		"module.vpc: aws_subnet module.vpc.aws_subnet.public[0] is missing Environment, Project",
+	}, got)
+}
+
+func TestCheckExempt(t *testing.T) {
+	violations := Check(loadPlan(t), Policy{
+		Required: []string{"Environment", "Project"},
+		Exempt:   []string{"aws_subnet", "aws_launch_template", "aws_autoscaling_group"},
+	})
+	assert.Empty(t, violations)
+}
+
+func TestReport(t *testing.T) {
+	report := Report(Check(loadPlan(t), Policy{Required: []string{"Project"}}))
+	assert.Equal(t, `module.ec2
+  aws_launch_template
+    module.ec2.aws_launch_template.this: missing Project on tag_specifications for volume
+module.vpc
+  aws_subnet
+    module.vpc.aws_subnet.public[0]: missing Project
+`, report)
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+	"github.com/your-org/terraform-aws-modules/test/tagcheck"
+)
+
+// requiredTags are the tags every taggable resource must carry, the ones envs/*/main.tf pass to
+// the modules. Set REQUIRED_TAGS to a comma-separated list of keys to check a different set
+var requiredTags = []string{"Name", "Environment", "Project"}
+
+// TestTagCompliance plans every module offline with the required tags passed in its tags variable
+// and checks each taggable resource ends up with all of them, including the instances Auto Scaling
+// groups launch
+func TestTagCompliance(t *testing.T) {
+	stub := awsstub.Start(t)
+
+	policy := tagcheck.Policy{Required: requiredTags}
+	if keys := os.Getenv("REQUIRED_TAGS"); keys != "" {
+		policy.Required = strings.Split(keys, ",")
+	}
+	tags := map[string]interface{}{}
+	for _, key := range policy.Required {
+		tags[key] = "unit"
+	}
+
+	report := sarifReport(t)
+	for _, cfg := range discoverConfigs(t, discovery.KindModule) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			if _, ok := cfg.Variable("tags"); !ok {
+				t.Skip("module has no tags variable")
+			}
+
+			vars := map[string]interface{}{"tags": tags}
+			for name, value := range offlinePlanVars[cfg.ID()] {
+				vars[name] = value
+			}
+			terraformOptions := harness.OfflineOptions(t, stub, repoRoot, cfg.ID(), vars)
+
+			harness.InitAndPlan(t, terraformOptions)
+			plan := planjson.Show(t, terraformOptions)
+
+			violations := tagcheck.Check(plan, policy)
+			report.Add(analysis.FromTagCheck(violations, analysis.SeverityMedium, repoRoot, cfg.Path)...)
+			if len(violations) > 0 {
+				t.Errorf("%d resources are missing required tags:\n%s", len(violations), tagcheck.Report(violations))
+			}
+		})
+	}
+}
@@ -4,26 +4,21 @@ import (
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+	"github.com/your-org/terraform-aws-modules/test/harness"
 )
 
-// TestTerraformValidateAllModules validates the syntax of all Terraform modules
+// TestTerraformValidateAllModules validates the syntax of all Terraform modules, examples and environments
 func TestTerraformValidateAllModules(t *testing.T) {
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2",
-		"../modules/ecs",
-		"../modules/eks",
-		"../modules/elb",
-		"../modules/storage",
-	}
-
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			terraformOptions := &terraform.Options{
-				TerraformDir: module,
-			}
-
-			// Run terraform validate
+	for _, cfg := range discoverConfigs(t) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			terraformOptions := workspaceOptions(t, cfg)
+
+			// Run terraform init and validate. Validate never reads state, so the
+			// envs' S3 backends are skipped and need no AWS credentials
+			harness.InitWithoutBackend(t, terraformOptions)
 			terraform.Validate(t, terraformOptions)
 		})
 	}
@@ -31,25 +26,25 @@ func TestTerraformValidateAllModules(t *testing.T) {
 
 // TestTerraformPlanAllModules runs terraform plan on all modules to check for syntax errors
 func TestTerraformPlanAllModules(t *testing.T) {
-	modules := []string{
-		"../modules/vpc",
-		"../modules/ec2", 
-		"../modules/ecs",
-		"../modules/eks",
-		"../modules/elb",
-		"../modules/storage",
-	}
+	harness.RequireDeployTier(t)
 
-	for _, module := range modules {
-		t.Run(module, func(t *testing.T) {
-			terraformOptions := &terraform.Options{
-				TerraformDir: module,
-				PlanFilePath: "tfplan",
-			}
+	for _, cfg := range discoverConfigs(t, discovery.KindModule) {
+		cfg := cfg
+		t.Run(cfg.ID(), func(t *testing.T) {
+			terraformOptions := workspaceOptions(t, cfg)
+			terraformOptions.PlanFilePath = "tfplan"
 
 			// Run terraform init and plan
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			harness.InitAndPlan(t, terraformOptions)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// workspaceOptions returns options for cfg in a fresh workspace copy, so init
+// never writes .terraform or a plan into the checked-in tree
+func workspaceOptions(t *testing.T, cfg discovery.Config) *terraform.Options {
+	return &terraform.Options{
+		TerraformDir: harness.CopyWorkspace(t, repoRoot, cfg.ID()),
+		EnvVars:      harness.PluginCacheEnv(),
+	}
+}
@@ -0,0 +1,209 @@
+package tfcheck
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+// Rule names reported by the module contract checker.
+const (
+	RuleModuleFile          = "module-file"
+	RuleProviderUndeclared  = "provider-undeclared"
+	RuleProviderUnbounded   = "provider-unbounded"
+	RuleProviderUnused      = "provider-unused"
+	RuleProviderUnversioned = "provider-unversioned"
+)
+
+// RequiredModuleFiles lists the files every module must ship. Provider and
+// Terraform version constraints live in the root versions.tf, so modules do
+// not carry their own (see scripts/cleanup-versions.sh).
+var RequiredModuleFiles = []string{
+	"main.tf",
+	"variables.tf",
+	"outputs.tf",
+	"README.md",
+	"examples.tf",
+}
+
+// Contract is the result of checking one module against the module contract.
+type Contract struct {
+	Module string
+	// MissingFiles lists the entries of RequiredModuleFiles not present.
+	MissingFiles []string
+	// Providers lists the providers the module's resources and data sources use.
+	Providers []string
+	Findings  []Finding
+}
+
+// OK reports whether the module satisfies the contract.
+func (c Contract) OK() bool {
+	return len(c.Findings) == 0
+}
+
+// CheckContracts checks each module against the contract: the required files
+// must exist and every provider a module uses must be declared in the root
+// configuration's required_providers with a bounded version constraint.
+// Findings about the root configuration itself, such as providers that no
+// module uses, are returned separately.
+func CheckContracts(root discovery.Config, modules []discovery.Config) ([]Contract, []Finding) {
+	declared := map[string]discovery.ProviderRequirement{}
+	for _, req := range root.RequiredProviders {
+		declared[req.Name] = req
+	}
+
+	used := map[string]bool{}
+	var contracts []Contract
+
+	for _, cfg := range modules {
+		c := Contract{Module: cfg.ID()}
+
+		for _, file := range RequiredModuleFiles {
+			path := filepath.Join(cfg.Path, file)
+			if _, err := os.Stat(path); err != nil {
+				c.MissingFiles = append(c.MissingFiles, file)
+				c.Findings = append(c.Findings, Finding{
+					Rule:    RuleModuleFile,
+					Message: fmt.Sprintf("Required file %s not found in module %s", file, cfg.ID()),
+					Range:   hcl.Range{Filename: path},
+				})
+			}
+		}
+
+		for provider, rng := range providersUsed(cfg) {
+			c.Providers = append(c.Providers, provider)
+			used[provider] = true
+
+			if _, ok := declared[provider]; !ok {
+				c.Findings = append(c.Findings, Finding{
+					Rule:     RuleProviderUndeclared,
+					Message:  fmt.Sprintf("Provider %q is used by %s but not declared in the root required_providers", provider, cfg.ID()),
+					Range:    rng,
+					Resource: provider,
+				})
+			}
+		}
+		sort.Strings(c.Providers)
+		sortFindings(c.Findings)
+
+		contracts = append(contracts, c)
+	}
+
+	var rootFindings []Finding
+	for _, req := range root.RequiredProviders {
+		switch {
+		case req.Version == "":
+			rootFindings = append(rootFindings, Finding{
+				Rule:     RuleProviderUnversioned,
+				Message:  fmt.Sprintf("Provider %q has no version constraint", req.Name),
+				Range:    req.DeclRange,
+				Resource: req.Name,
+			})
+		case !isBounded(req.Version):
+			rootFindings = append(rootFindings, Finding{
+				Rule:     RuleProviderUnbounded,
+				Message:  fmt.Sprintf("Provider %q constraint %q has no upper bound", req.Name, req.Version),
+				Range:    req.DeclRange,
+				Resource: req.Name,
+			})
+		}
+
+		if !used[req.Name] {
+			rootFindings = append(rootFindings, Finding{
+				Rule:     RuleProviderUnused,
+				Message:  fmt.Sprintf("Provider %q is declared but no module uses it", req.Name),
+				Range:    req.DeclRange,
+				Resource: req.Name,
+			})
+		}
+	}
+	sortFindings(rootFindings)
+
+	return contracts, rootFindings
+}
+
+// providersUsed maps each provider referenced by the module's resources and
+// data sources to the first block that uses it.
+func providersUsed(cfg discovery.Config) map[string]hcl.Range {
+	providers := map[string]hcl.Range{}
+	for _, r := range cfg.Resources {
+		// Usage snippets in examples.tf are documentation, not module code.
+		if filepath.Base(r.DeclRange.Filename) == "examples.tf" {
+			continue
+		}
+		// The built-in terraform provider needs no declaration.
+		if r.Provider == "terraform" {
+			continue
+		}
+		if _, ok := providers[r.Provider]; !ok {
+			providers[r.Provider] = r.DeclRange
+		}
+	}
+	return providers
+}
+
+// isBounded reports whether a version constraint string caps the versions it
+// accepts, either with an explicit upper bound, a pessimistic constraint or
+// an exact version.
+func isBounded(constraint string) bool {
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, "~>"),
+			strings.HasPrefix(part, "<"),
+			strings.HasPrefix(part, "="):
+			return true
+		case strings.HasPrefix(part, ">"), strings.HasPrefix(part, "!="):
+			continue
+		case part != "":
+			// A bare version is an exact match.
+			return true
+		}
+	}
+	return false
+}
+
+// FormatContracts renders contracts as a plain-text table with one row per
+// module.
+func FormatContracts(contracts []Contract) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "MODULE\tMISSING FILES\tPROVIDERS\tSTATUS")
+	for _, c := range contracts {
+		missing := "-"
+		if len(c.MissingFiles) > 0 {
+			missing = strings.Join(c.MissingFiles, ",")
+		}
+		providers := "-"
+		if len(c.Providers) > 0 {
+			providers = strings.Join(c.Providers, ",")
+		}
+		status := "ok"
+		if !c.OK() {
+			status = fmt.Sprintf("FAIL (%d)", len(c.Findings))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Module, missing, providers, status)
+	}
+	w.Flush()
+
+	return buf.String()
+}
+
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Range, findings[j].Range
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Start.Line < b.Start.Line
+	})
+}
@@ -0,0 +1,91 @@
+package tfcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+func TestCheckContracts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+	}
+
+	write("versions.tf", `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    helm = {
+      source  = "hashicorp/helm"
+      version = ">= 2.10"
+    }
+  }
+}
+`)
+	for _, file := range RequiredModuleFiles {
+		write(filepath.Join("modules", "efs", file), "")
+		write(filepath.Join("modules", "vpc", file), "")
+	}
+	write("modules/efs/main.tf", `resource "random_id" "token" {}
+resource "aws_efs_file_system" "this" {}
+`)
+	write("modules/efs/examples.tf", `resource "tls_private_key" "doc" {}
+`)
+	require.NoError(t, os.Remove(filepath.Join(dir, "modules", "vpc", "README.md")))
+	write("modules/vpc/main.tf", `data "aws_availability_zones" "available" {}
+`)
+
+	root, _, err := discovery.Load(discovery.KindRoot, dir)
+	require.NoError(t, err)
+	modules, err := discovery.Discover(dir, discovery.KindModule)
+	require.NoError(t, err)
+
+	contracts, rootFindings := CheckContracts(root, modules)
+	require.Len(t, contracts, 2)
+
+	efs := contracts[0]
+	assert.Equal(t, "modules/efs", efs.Module)
+	assert.Equal(t, []string{"aws", "random"}, efs.Providers)
+	require.Len(t, efs.Findings, 1)
+	assert.Equal(t, RuleProviderUndeclared, efs.Findings[0].Rule)
+	assert.Equal(t, "random", efs.Findings[0].Resource)
+	assert.Equal(t, 1, efs.Findings[0].Range.Start.Line)
+
+	vpc := contracts[1]
+	assert.Equal(t, []string{"README.md"}, vpc.MissingFiles)
+	assert.False(t, vpc.OK())
+
+	assert.Equal(t, map[string][]int{
+		RuleProviderUnbounded: {7},
+		RuleProviderUnused:    {7},
+	}, rules(rootFindings))
+
+	table := FormatContracts(contracts)
+	assert.Contains(t, table, "MODULE")
+	assert.Regexp(t, `modules/efs\s+-\s+aws,random\s+FAIL \(1\)`, table)
+	assert.Regexp(t, `modules/vpc\s+README.md\s+aws\s+FAIL \(1\)`, table)
+}
+
+func TestIsBounded(t *testing.T) {
+	for constraint, want := range map[string]bool{
+		"~> 5.0":         true,
+		">= 1.0, < 2.0":  true,
+		"= 3.1.0":        true,
+		"3.1.0":          true,
+		">= 2.10":        false,
+		">= 1.0, != 1.5": false,
+		"":               false,
+	} {
+		assert.Equal(t, want, isBounded(constraint), constraint)
+	}
+}
@@ -0,0 +1,240 @@
+package tfcheck
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// Functions returns the Terraform built-in functions that can be evaluated
+// offline, keyed by name. It covers what variable validation conditions in
+// this repository use; an expression calling anything else cannot be
+// evaluated and is skipped by the callers.
+func Functions() map[string]function.Function {
+	return map[string]function.Function{
+		"abs":             stdlib.AbsoluteFunc,
+		"alltrue":         allTrueFunc,
+		"anytrue":         anyTrueFunc,
+		"can":             tryfunc.CanFunc,
+		"ceil":            stdlib.CeilFunc,
+		"chomp":           stdlib.ChompFunc,
+		"cidrhost":        CidrHostFunc,
+		"cidrnetmask":     CidrNetmaskFunc,
+		"cidrsubnet":      CidrSubnetFunc,
+		"coalesce":        stdlib.CoalesceFunc,
+		"compact":         stdlib.CompactFunc,
+		"concat":          stdlib.ConcatFunc,
+		"contains":        stdlib.ContainsFunc,
+		"distinct":        stdlib.DistinctFunc,
+		"element":         stdlib.ElementFunc,
+		"endswith":        endsWithFunc,
+		"flatten":         stdlib.FlattenFunc,
+		"floor":           stdlib.FloorFunc,
+		"format":          stdlib.FormatFunc,
+		"index":           stdlib.IndexFunc,
+		"join":            stdlib.JoinFunc,
+		"jsondecode":      stdlib.JSONDecodeFunc,
+		"jsonencode":      stdlib.JSONEncodeFunc,
+		"keys":            stdlib.KeysFunc,
+		"length":          lengthFunc,
+		"lookup":          stdlib.LookupFunc,
+		"lower":           stdlib.LowerFunc,
+		"max":             stdlib.MaxFunc,
+		"merge":           stdlib.MergeFunc,
+		"min":             stdlib.MinFunc,
+		"regex":           stdlib.RegexFunc,
+		"regexall":        stdlib.RegexAllFunc,
+		"setintersection": stdlib.SetIntersectionFunc,
+		"setsubtract":     stdlib.SetSubtractFunc,
+		"setunion":        stdlib.SetUnionFunc,
+		"split":           stdlib.SplitFunc,
+		"startswith":      startsWithFunc,
+		"strcontains":     strContainsFunc,
+		"substr":          stdlib.SubstrFunc,
+		"trimspace":       stdlib.TrimSpaceFunc,
+		"try":             tryfunc.TryFunc,
+		"upper":           stdlib.UpperFunc,
+		"values":          stdlib.ValuesFunc,
+	}
+}
+
+var allTrueFunc = boolFold(true)
+var anyTrueFunc = boolFold(false)
+
+// boolFold implements alltrue (all is true) and anytrue (all is false). As in
+// Terraform, a null element makes alltrue false and is skipped by anytrue.
+func boolFold(all bool) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "list", Type: cty.List(cty.Bool)},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			list := args[0]
+			if !list.IsWhollyKnown() {
+				return cty.UnknownVal(cty.Bool), nil
+			}
+			for it := list.ElementIterator(); it.Next(); {
+				_, v := it.Element()
+				if v.IsNull() {
+					if all {
+						return cty.False, nil
+					}
+					continue
+				}
+				if v.True() != all {
+					return cty.BoolVal(!all), nil
+				}
+			}
+			return cty.BoolVal(all), nil
+		},
+	})
+}
+
+// lengthFunc is Terraform's length, which unlike the cty one also counts the
+// characters of a string.
+var lengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "value", Type: cty.DynamicPseudoType, AllowDynamicType: true, AllowUnknown: true},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if args[0].Type() == cty.String {
+			return stdlib.Strlen(args[0])
+		}
+		return stdlib.Length(args[0])
+	},
+})
+
+func stringPredicate(name string, test func(s, arg string) bool) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "str", Type: cty.String},
+			{Name: name, Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.BoolVal(test(args[0].AsString(), args[1].AsString())), nil
+		},
+	})
+}
+
+var startsWithFunc = stringPredicate("prefix", strings.HasPrefix)
+var endsWithFunc = stringPredicate("suffix", strings.HasSuffix)
+var strContainsFunc = stringPredicate("substr", strings.Contains)
+
+// CidrHostFunc is Terraform's cidrhost: the address of host number hostnum
+// in prefix, counting back from the end of the range when negative.
+var CidrHostFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+		{Name: "hostnum", Type: cty.Number},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		prefix, err := parseCIDR(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(0, err)
+		}
+		var hostnum int64
+		if err := gocty.FromCtyValue(args[1], &hostnum); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(1, err)
+		}
+
+		size := new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits()))
+		host := big.NewInt(hostnum)
+		if host.Sign() < 0 {
+			host.Add(host, size)
+		}
+		if host.Sign() < 0 || host.Cmp(size) >= 0 {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(1, "prefix %s has no host number %d", prefix, hostnum)
+		}
+
+		return cty.StringVal(addAddr(prefix.Addr(), host).String()), nil
+	},
+})
+
+// CidrNetmaskFunc is Terraform's cidrnetmask: the dotted netmask of an IPv4
+// prefix.
+var CidrNetmaskFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		prefix, err := parseCIDR(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(0, err)
+		}
+		if !prefix.Addr().Is4() {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, "only IPv4 prefixes have a netmask")
+		}
+
+		mask := new(big.Int).Lsh(big.NewInt(1), 32)
+		mask.Sub(mask, new(big.Int).Lsh(big.NewInt(1), uint(32-prefix.Bits())))
+		return cty.StringVal(addAddr(netip.IPv4Unspecified(), mask).String()), nil
+	},
+})
+
+// CidrSubnetFunc is Terraform's cidrsubnet: subnet number netnum of prefix,
+// newbits longer than it.
+var CidrSubnetFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "prefix", Type: cty.String},
+		{Name: "newbits", Type: cty.Number},
+		{Name: "netnum", Type: cty.Number},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		prefix, err := parseCIDR(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(0, err)
+		}
+		var newbits, netnum int64
+		if err := gocty.FromCtyValue(args[1], &newbits); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(1, err)
+		}
+		if err := gocty.FromCtyValue(args[2], &netnum); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgError(2, err)
+		}
+
+		bits := prefix.Bits() + int(newbits)
+		if newbits < 0 || bits > prefix.Addr().BitLen() {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(1, "cannot extend prefix %s by %d bits", prefix, newbits)
+		}
+		if netnum < 0 || big.NewInt(netnum).Cmp(new(big.Int).Lsh(big.NewInt(1), uint(newbits))) >= 0 {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(2, "prefix %s has no %d-bit subnet number %d", prefix, newbits, netnum)
+		}
+
+		offset := new(big.Int).Lsh(big.NewInt(netnum), uint(prefix.Addr().BitLen()-bits))
+		return cty.StringVal(netip.PrefixFrom(addAddr(prefix.Addr(), offset), bits).String()), nil
+	},
+})
+
+// parseCIDR parses a prefix the way Terraform does, accepting host bits and
+// discarding them.
+func parseCIDR(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR expression: %s", err)
+	}
+	return prefix.Masked(), nil
+}
+
+// addAddr returns addr plus n.
+func addAddr(addr netip.Addr, n *big.Int) netip.Addr {
+	sum := new(big.Int).SetBytes(addr.AsSlice())
+	sum.Add(sum, n)
+
+	buf := make([]byte, addr.BitLen()/8)
+	sum.FillBytes(buf)
+	out, _ := netip.AddrFromSlice(buf)
+	return out
+}
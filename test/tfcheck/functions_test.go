@@ -0,0 +1,48 @@
+package tfcheck
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFunctions(t *testing.T) {
+	ctx := &hcl.EvalContext{Functions: Functions()}
+
+	for src, want := range map[string]cty.Value{
+		`cidrhost("10.0.1.0/24", 5)`:            cty.StringVal("10.0.1.5"),
+		`cidrhost("10.0.1.7/24", -1)`:           cty.StringVal("10.0.1.255"),
+		`cidrhost("fd00::/64", 16)`:             cty.StringVal("fd00::10"),
+		`cidrnetmask("172.16.0.0/12")`:          cty.StringVal("255.240.0.0"),
+		`cidrsubnet("10.0.0.0/16", 8, 2)`:       cty.StringVal("10.0.2.0/24"),
+		`cidrsubnet("10.0.0.0/16", 4, 15)`:      cty.StringVal("10.0.240.0/20"),
+		`can(cidrhost("10.0.0.0/33", 0))`:       cty.False,
+		`can(cidrhost("10.0.0.0/30", 4))`:       cty.False,
+		`alltrue([true, true])`:                 cty.True,
+		`alltrue([true, false])`:                cty.False,
+		`alltrue([])`:                           cty.True,
+		`anytrue([false, true])`:                cty.True,
+		`anytrue([])`:                           cty.False,
+		`anytrue([null, true])`:                 cty.True,
+		`anytrue([null, false])`:                cty.False,
+		`alltrue([true, null])`:                 cty.False,
+		`length("abc")`:                         cty.NumberIntVal(3),
+		`length(["a", "b"])`:                    cty.NumberIntVal(2),
+		`startswith("ami-123", "ami-")`:         cty.True,
+		`contains(["EC2", "ELB"], "ELB")`:       cty.True,
+		`can(regex("^[a-z]+$", "Upper"))`:       cty.False,
+		`try(regex("^([a-z]+)", "abc")[0], "")`: cty.StringVal("abc"),
+	} {
+		expr, diags := hclsyntax.ParseExpression([]byte(src), "test.tf", hcl.InitialPos)
+		require.False(t, diags.HasErrors(), diags.Error())
+
+		got, diags := expr.Value(ctx)
+		if assert.False(t, diags.HasErrors(), "%s: %s", src, diags.Error()) {
+			assert.True(t, got.RawEquals(want), "%s = %#v, want %#v", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package tfcheck
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+// Rule names reported by the module call checker.
+const (
+	RuleModuleSource   = "module-source"
+	RuleModuleArgument = "module-argument"
+	RuleModuleRequired = "module-required"
+	RuleModuleType     = "module-type"
+)
+
+// CheckModuleCalls checks every module block in cfg whose source is a local
+// path against the variables the called module declares. It reports sources
+// that hold no configuration, arguments the module does not declare,
+// required variables that are not passed and arguments whose statically
+// known value cannot convert to the variable's type. Registry and remote
+// sources are skipped.
+func CheckModuleCalls(cfg discovery.Config) ([]Finding, error) {
+	callerTypes := VariableTypes(cfg)
+
+	var findings []Finding
+	for _, call := range cfg.Modules {
+		if !call.IsLocal() {
+			continue
+		}
+
+		module, ok, err := discovery.Load(discovery.KindModule, filepath.Join(cfg.Path, filepath.FromSlash(call.Source)))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			findings = append(findings, Finding{
+				Rule:    RuleModuleSource,
+				Message: fmt.Sprintf("Module %q source %s holds no Terraform configuration", call.Name, call.Source),
+				Range:   call.DeclRange,
+			})
+			continue
+		}
+
+		names := make([]string, 0, len(call.Arguments))
+		for name := range call.Arguments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			arg := call.Arguments[name]
+			decl, ok := module.Variable(name)
+			if !ok {
+				findings = append(findings, Finding{
+					Rule:    RuleModuleArgument,
+					Message: fmt.Sprintf("Module %q passes %q, which %s does not declare", call.Name, name, call.Source),
+					Range:   arg.NameRange,
+				})
+				continue
+			}
+
+			if _, err := ConvertVariable(decl, StaticValue(arg.Expr, callerTypes)); err != nil {
+				findings = append(findings, Finding{
+					Rule:    RuleModuleType,
+					Message: fmt.Sprintf("Module %q passes %q: %s", call.Name, name, err),
+					Range:   arg.Expr.Range(),
+				})
+			}
+		}
+
+		for _, decl := range module.Variables {
+			if _, ok := call.Arguments[decl.Name]; decl.Required() && !ok {
+				findings = append(findings, Finding{
+					Rule:    RuleModuleRequired,
+					Message: fmt.Sprintf("Module %q does not pass %q, which %s requires", call.Name, decl.Name, call.Source),
+					Range:   call.DeclRange,
+				})
+			}
+		}
+	}
+
+	sortFindings(findings)
+	return findings, nil
+}
@@ -0,0 +1,102 @@
+package tfcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+func TestCheckModuleCalls(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"modules/ec2/variables.tf": `variable "name_prefix" {
+  type = string
+}
+
+variable "subnet_ids" {
+  type = list(string)
+}
+
+variable "desired_capacity" {
+  type    = number
+  default = 1
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+`,
+		"envs/dev/variables.tf": `variable "subnet_id" {
+  type = string
+}
+
+variable "subnet_ids" {
+  type = list(string)
+}
+`,
+		"envs/dev/main.tf": `module "ec2" {
+  source = "../../modules/ec2"
+
+  subnet_ids       = var.subnet_id
+  desired_capacity = "three"
+  vpc_id           = "vpc-123"
+  tags = {
+    Name = "${var.subnet_id}-asg"
+  }
+}
+
+module "ok" {
+  source = "../../modules/ec2"
+
+  name_prefix      = "dev"
+  subnet_ids       = var.subnet_ids
+  desired_capacity = "3"
+}
+
+module "missing" {
+  source = "../../modules/storage"
+}
+
+module "registry" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0"
+}
+`,
+	})
+
+	cfg, ok, err := discovery.Load(discovery.KindEnv, filepath.Join(root, "envs", "dev"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+
+	findings, err := CheckModuleCalls(cfg)
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		f.Range.Filename = filepath.Base(f.Range.Filename)
+		got = append(got, f.String())
+	}
+	assert.Equal(t, []string{
+		`main.tf:1: Module "ec2" does not pass "name_prefix", which ../../modules/ec2 requires (module-required)`,
+		`main.tf:4: Module "ec2" passes "subnet_ids": string is not a valid list(string): list of string required (module-type)`,
+		`main.tf:5: Module "ec2" passes "desired_capacity": string is not a valid number: a number is required (module-type)`,
+		`main.tf:6: Module "ec2" passes "vpc_id", which ../../modules/ec2 does not declare (module-argument)`,
+		`main.tf:20: Module "missing" source ../../modules/storage holds no Terraform configuration (module-source)`,
+	}, got)
+}
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+	}
+}
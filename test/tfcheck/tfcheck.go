@@ -0,0 +1,168 @@
+// Package tfcheck implements source-level checks over the Terraform
+// configurations found by the discovery package. Checks work on the parsed
+// HCL rather than on text, and every finding carries the file and line it
+// refers to.
+package tfcheck
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+// Rule names reported on findings.
+const (
+	RuleVariableDescription = "variable-description"
+	RuleVariableType        = "variable-type"
+	RuleOutputDescription   = "output-description"
+	RuleOutputSensitive     = "output-sensitive"
+)
+
+// Finding is a single problem found in a configuration.
+type Finding struct {
+	Rule    string
+	Message string
+	Range   hcl.Range
+	// Resource names what the finding is about when the range alone does not,
+	// e.g. the provider of a provider finding, so a baseline can match it.
+	Resource string
+}
+
+// String formats the finding as "file:line: message (rule)".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", f.Range.Filename, f.Range.Start.Line, f.Message, f.Rule)
+}
+
+// CheckVariables reports variables that have no type constraint or whose
+// description is missing or blank.
+func CheckVariables(cfg discovery.Config) []Finding {
+	var findings []Finding
+
+	for _, v := range cfg.Variables {
+		if f, ok := checkDescription(RuleVariableDescription, "Variable", v.Name, v.Attributes, v.DeclRange); ok {
+			findings = append(findings, f)
+		}
+
+		if _, ok := v.Attributes["type"]; !ok {
+			findings = append(findings, Finding{
+				Rule:    RuleVariableType,
+				Message: fmt.Sprintf("Variable %q has no type constraint", v.Name),
+				Range:   v.DeclRange,
+			})
+		}
+	}
+
+	return findings
+}
+
+// CheckOutputs reports outputs whose description is missing or blank, and
+// outputs that look like they expose a secret but are not marked sensitive.
+func CheckOutputs(cfg discovery.Config) []Finding {
+	var findings []Finding
+
+	for _, o := range cfg.Outputs {
+		if f, ok := checkDescription(RuleOutputDescription, "Output", o.Name, o.Attributes, o.DeclRange); ok {
+			findings = append(findings, f)
+		}
+
+		if o.Sensitive {
+			continue
+		}
+		if reason, ok := secretReason(o); ok {
+			findings = append(findings, Finding{
+				Rule:    RuleOutputSensitive,
+				Message: fmt.Sprintf("Output %q %s but is not marked sensitive", o.Name, reason),
+				Range:   o.DeclRange,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkDescription(rule, noun, name string, attrs hcl.Attributes, declRange hcl.Range) (Finding, bool) {
+	attr, ok := attrs["description"]
+	if !ok {
+		return Finding{
+			Rule:    rule,
+			Message: fmt.Sprintf("%s %q is missing a description", noun, name),
+			Range:   declRange,
+		}, true
+	}
+
+	// Descriptions built from templates or references can't be judged
+	// statically; only flag literals that are blank.
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() || !val.Type().Equals(cty.String) {
+		return Finding{}, false
+	}
+	if strings.TrimSpace(val.AsString()) == "" {
+		return Finding{
+			Rule:    rule,
+			Message: fmt.Sprintf("%s %q has an empty description", noun, name),
+			Range:   attr.Range,
+		}, true
+	}
+
+	return Finding{}, false
+}
+
+// secretName matches identifiers that conventionally hold credentials. It
+// works on whole underscore-separated words so names such as
+// private_subnet_ids or creation_token do not trip it.
+var secretName = regexp.MustCompile(`(^|_)(password|passwd|secret|secrets|credentials?|private_key|private_key_pem|secret_key|api_key|auth_token|access_token|client_secret)(_|$)`)
+
+// secretReason reports why an output looks secret: either its own name, or an
+// attribute its value reads, matches secretName.
+func secretReason(o discovery.Output) (string, bool) {
+	if secretName.MatchString(o.Name) {
+		return "has a secret-looking name", true
+	}
+
+	value, ok := o.Attributes["value"]
+	if !ok {
+		return "", false
+	}
+	expr, ok := value.Expr.(hclsyntax.Expression)
+	if !ok {
+		return "", false
+	}
+
+	var attr string
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if attr != "" {
+			return nil
+		}
+		var traversal hcl.Traversal
+		switch n := node.(type) {
+		case *hclsyntax.ScopeTraversalExpr:
+			traversal = n.Traversal
+		case *hclsyntax.RelativeTraversalExpr:
+			traversal = n.Traversal
+		}
+		for _, step := range traversal {
+			switch s := step.(type) {
+			case hcl.TraverseRoot:
+				if secretName.MatchString(s.Name) {
+					attr = s.Name
+				}
+			case hcl.TraverseAttr:
+				if secretName.MatchString(s.Name) {
+					attr = s.Name
+				}
+			}
+		}
+		return nil
+	})
+	if attr != "" {
+		return fmt.Sprintf("reads %q", attr), true
+	}
+
+	return "", false
+}
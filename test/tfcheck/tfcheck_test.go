@@ -0,0 +1,99 @@
+package tfcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+func loadModule(t *testing.T, files map[string]string) discovery.Config {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "mod")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	cfg, ok, err := discovery.Load(discovery.KindModule, dir)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+	return cfg
+}
+
+func rules(findings []Finding) map[string][]int {
+	out := map[string][]int{}
+	for _, f := range findings {
+		out[f.Rule] = append(out[f.Rule], f.Range.Start.Line)
+	}
+	return out
+}
+
+func TestCheckVariables(t *testing.T) {
+	cfg := loadModule(t, map[string]string{"variables.tf": `variable "ok" {
+  type = string
+
+
+
+  # A description further down than grep -A 5 would look.
+  description = "Fine"
+}
+
+variable "untyped" {
+  description = "No type"
+}
+
+variable "blank" {
+  description = "   "
+  type        = number
+}
+
+variable "missing" {
+  type = bool
+}
+`})
+
+	findings := CheckVariables(cfg)
+	assert.Equal(t, map[string][]int{
+		RuleVariableType:        {10},
+		RuleVariableDescription: {15, 19},
+	}, rules(findings))
+	assert.Contains(t, findings[0].String(), "variables.tf:10: ")
+}
+
+func TestCheckOutputs(t *testing.T) {
+	cfg := loadModule(t, map[string]string{"outputs.tf": `output "id" {
+  description = "ID"
+  value       = aws_db_instance.this.id
+}
+
+output "db_password" {
+  description = "Password"
+  value       = random_password.this.result
+}
+
+output "master" {
+  description = "Reads a secret attribute"
+  value       = aws_iam_access_key.this.secret
+}
+
+output "hidden" {
+  description = "Already sensitive"
+  value       = var.password
+  sensitive   = true
+}
+
+output "private_subnet_ids" {
+  value = aws_subnet.private[*].id
+}
+`})
+
+	assert.Equal(t, map[string][]int{
+		RuleOutputSensitive:   {6, 11},
+		RuleOutputDescription: {22},
+	}, rules(CheckOutputs(cfg)))
+}
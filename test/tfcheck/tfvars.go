@@ -0,0 +1,182 @@
+package tfcheck
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+// Rule names reported by the tfvars checker.
+const (
+	RuleTFVarsSyntax       = "tfvars-syntax"
+	RuleTFVarsUndeclared   = "tfvars-undeclared"
+	RuleTFVarsType         = "tfvars-type"
+	RuleTFVarsRequired     = "tfvars-required"
+	RuleVariableValidation = "variable-validation"
+)
+
+// CheckTFVars checks the variable definitions file at path against the
+// variables cfg declares, as `terraform plan -var-file` would before
+// touching any provider: every value must be a literal, belong to a declared
+// variable and convert to its type, every required variable must be set and
+// every validation condition that can be evaluated offline must hold.
+func CheckTFVars(cfg discovery.Config, path string) ([]Finding, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if file == nil {
+		return nil, diags
+	}
+	findings := diagnosticFindings(RuleTFVarsSyntax, diags)
+
+	attrs, diags := file.Body.JustAttributes()
+	findings = append(findings, diagnosticFindings(RuleTFVarsSyntax, diags)...)
+
+	for _, attr := range attrs {
+		decl, ok := cfg.Variable(attr.Name)
+		if !ok {
+			findings = append(findings, Finding{
+				Rule:    RuleTFVarsUndeclared,
+				Message: fmt.Sprintf("Value for undeclared variable %q", attr.Name),
+				Range:   attr.NameRange,
+			})
+			continue
+		}
+
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			findings = append(findings, diagnosticFindings(RuleTFVarsSyntax, diags)...)
+			continue
+		}
+
+		value, err := ConvertVariable(decl, value)
+		if err != nil {
+			findings = append(findings, Finding{
+				Rule:    RuleTFVarsType,
+				Message: fmt.Sprintf("Invalid value for variable %q: %s", attr.Name, err),
+				Range:   attr.Expr.Range(),
+			})
+			continue
+		}
+
+		findings = append(findings, CheckValidations(decl, value, attr.Expr.Range())...)
+	}
+
+	for _, decl := range cfg.Variables {
+		if _, ok := attrs[decl.Name]; decl.Required() && !ok {
+			findings = append(findings, Finding{
+				Rule:    RuleTFVarsRequired,
+				Message: fmt.Sprintf("No value for required variable %q", decl.Name),
+				Range:   hcl.Range{Filename: path, Start: hcl.InitialPos, End: hcl.InitialPos},
+			})
+		}
+	}
+
+	sortFindings(findings)
+	return findings, nil
+}
+
+// CheckValidations evaluates the validation blocks of v against value and
+// reports each condition that does not hold at rng, the location the value
+// came from. Conditions that refer to anything but the variable itself, or
+// call functions that cannot be evaluated offline, are skipped, as are
+// conditions whose result depends on unknown parts of value.
+func CheckValidations(v discovery.Variable, value cty.Value, rng hcl.Range) []Finding {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{v.Name: value}),
+		},
+		Functions: Functions(),
+	}
+
+	var findings []Finding
+	for _, validation := range v.Validations {
+		if !evaluable(validation.Condition, v.Name, ctx) {
+			continue
+		}
+
+		result, diags := validation.Condition.Value(ctx)
+		switch {
+		case diags.HasErrors():
+			findings = append(findings, Finding{
+				Rule:    RuleVariableValidation,
+				Message: fmt.Sprintf("Invalid value for variable %q: condition at %s cannot be evaluated: %s", v.Name, formatPos(validation.DeclRange), diags.Error()),
+				Range:   rng,
+			})
+		case !result.IsKnown() || result.IsNull() || result.Type() != cty.Bool:
+			continue
+		case result.False():
+			findings = append(findings, Finding{
+				Rule:    RuleVariableValidation,
+				Message: fmt.Sprintf("Invalid value for variable %q: %s (validation at %s)", v.Name, errorMessage(validation, ctx), formatPos(validation.DeclRange)),
+				Range:   rng,
+			})
+		}
+	}
+	return findings
+}
+
+// evaluable reports whether expr only refers to var.<name> and only calls
+// functions available in ctx.
+func evaluable(expr hcl.Expression, name string, ctx *hcl.EvalContext) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "var" || len(traversal) < 2 {
+			return false
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); !ok || attr.Name != name {
+			return false
+		}
+	}
+
+	node, ok := expr.(hclsyntax.Node)
+	if !ok {
+		return false
+	}
+	known := true
+	hclsyntax.VisitAll(node, func(n hclsyntax.Node) hcl.Diagnostics {
+		if call, ok := n.(*hclsyntax.FunctionCallExpr); ok {
+			if _, ok := ctx.Functions[call.Name]; !ok {
+				known = false
+			}
+		}
+		return nil
+	})
+	return known
+}
+
+func errorMessage(validation discovery.Validation, ctx *hcl.EvalContext) string {
+	msg, diags := validation.ErrorMessage.Value(ctx)
+	if diags.HasErrors() || !msg.IsKnown() || msg.IsNull() || msg.Type() != cty.String {
+		return "validation condition failed"
+	}
+	return msg.AsString()
+}
+
+func formatPos(rng hcl.Range) string {
+	return fmt.Sprintf("%s:%d", rng.Filename, rng.Start.Line)
+}
+
+func diagnosticFindings(rule string, diags hcl.Diagnostics) []Finding {
+	var findings []Finding
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+		f := Finding{Rule: rule, Message: diag.Summary}
+		if diag.Detail != "" {
+			f.Message += ": " + diag.Detail
+		}
+		if diag.Subject != nil {
+			f.Range = *diag.Subject
+		}
+		findings = append(findings, f)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Range.Start.Byte < findings[j].Range.Start.Byte
+	})
+	return findings
+}
@@ -0,0 +1,84 @@
+package tfcheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+func TestCheckTFVars(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"dev/variables.tf": `variable "vpc_cidr_block" {
+  type = string
+
+  validation {
+    condition     = can(cidrhost(var.vpc_cidr_block, 0))
+    error_message = "VPC CIDR block must be a valid IPv4 CIDR, got ${var.vpc_cidr_block}."
+  }
+}
+
+variable "instance_type" {
+  type = string
+
+  validation {
+    condition     = contains(local.allowed_types, var.instance_type)
+    error_message = "Skipped: refers to a local."
+  }
+
+  validation {
+    condition     = startswith(var.instance_type, "t3.")
+    error_message = "Only t3 instances are allowed."
+  }
+}
+
+variable "subnet_ids" {
+  type = list(string)
+
+  validation {
+    condition     = alltrue([for id in var.subnet_ids : can(regex("^subnet-", id))])
+    error_message = "Subnet IDs must start with subnet-."
+  }
+}
+
+variable "desired_capacity" {
+  type = number
+}
+
+variable "key_name" {}
+`,
+		"dev/dev.tfvars": `vpc_cidr_block   = "10.0.0.0/33"
+instance_type    = "m5.large"
+subnet_ids       = ["subnet-aaa", "sn-bbb"]
+desired_capacity = "three"
+unknown          = true
+`,
+	})
+
+	cfg, ok, err := discovery.Load(discovery.KindEnv, filepath.Join(root, "dev"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, cfg.Diagnostics)
+
+	findings, err := CheckTFVars(cfg, filepath.Join(root, "dev", "dev.tfvars"))
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		f.Range.Filename = filepath.Base(f.Range.Filename)
+		got = append(got, f.String())
+	}
+	variables := filepath.Join(root, "dev", "variables.tf")
+	assert.Equal(t, []string{
+		`dev.tfvars:1: Invalid value for variable "vpc_cidr_block": VPC CIDR block must be a valid IPv4 CIDR, got 10.0.0.0/33. (validation at ` + variables + `:4) (variable-validation)`,
+		`dev.tfvars:1: No value for required variable "key_name" (tfvars-required)`,
+		`dev.tfvars:2: Invalid value for variable "instance_type": Only t3 instances are allowed. (validation at ` + variables + `:18) (variable-validation)`,
+		`dev.tfvars:3: Invalid value for variable "subnet_ids": Subnet IDs must start with subnet-. (validation at ` + variables + `:27) (variable-validation)`,
+		`dev.tfvars:4: Invalid value for variable "desired_capacity": string is not a valid number: a number is required (tfvars-type)`,
+		`dev.tfvars:5: Value for undeclared variable "unknown" (tfvars-undeclared)`,
+	}, got)
+}
@@ -0,0 +1,145 @@
+package tfcheck
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"github.com/your-org/terraform-aws-modules/test/discovery"
+)
+
+// VariableType returns the type constraint of a variable. Variables without
+// a type constraint accept any value.
+func VariableType(v discovery.Variable) (cty.Type, hcl.Diagnostics) {
+	attr, ok := v.Attributes["type"]
+	if !ok {
+		return cty.DynamicPseudoType, nil
+	}
+
+	ty, _, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+	return ty, diags
+}
+
+// ConvertVariable converts value to the type of v the way Terraform converts
+// input variable values, so a string "3" is a valid number but a list is not
+// a valid string. Unknown values, and unknown parts of values, always
+// convert; callers pass cty.DynamicVal for anything they cannot evaluate.
+func ConvertVariable(v discovery.Variable, value cty.Value) (cty.Value, error) {
+	ty, diags := VariableType(v)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("variable %q has an invalid type constraint: %s", v.Name, diags.Error())
+	}
+
+	out, err := convert.Convert(value, ty)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("%s is not a valid %s: %s", describeValue(value), typeexpr.TypeString(ty), err)
+	}
+	return out, nil
+}
+
+func describeValue(value cty.Value) string {
+	ty := value.Type()
+	switch {
+	case ty == cty.DynamicPseudoType:
+		return "value"
+	case ty.IsPrimitiveType():
+		return ty.FriendlyName()
+	case ty.IsTupleType() || ty.IsListType() || ty.IsSetType():
+		return "list"
+	default:
+		return "map"
+	}
+}
+
+// stringFunctions lists built-in functions that always return a string, so a
+// call to one can be checked against a variable type without evaluating it.
+var stringFunctions = map[string]bool{
+	"format":       true,
+	"join":         true,
+	"jsonencode":   true,
+	"lower":        true,
+	"upper":        true,
+	"replace":      true,
+	"trimspace":    true,
+	"file":         true,
+	"templatefile": true,
+	"base64encode": true,
+	"cidrsubnet":   true,
+	"cidrhost":     true,
+}
+
+// StaticValue returns the part of expr that is known without evaluating any
+// references: literals, lists and maps of literals and string templates. A
+// reference to an input variable, var.<name>, becomes an unknown value of
+// that variable's type when vars declares it. Everything else is unknown.
+func StaticValue(expr hcl.Expression, vars map[string]cty.Type) cty.Value {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		return e.Val
+
+	case *hclsyntax.TemplateExpr:
+		if e.IsStringLiteral() {
+			if val, diags := e.Value(nil); !diags.HasErrors() {
+				return val
+			}
+		}
+		return cty.UnknownVal(cty.String)
+
+	case *hclsyntax.TemplateWrapExpr:
+		return StaticValue(e.Wrapped, vars)
+
+	case *hclsyntax.TupleConsExpr:
+		elems := make([]cty.Value, 0, len(e.Exprs))
+		for _, elem := range e.Exprs {
+			elems = append(elems, StaticValue(elem, vars))
+		}
+		return cty.TupleVal(elems)
+
+	case *hclsyntax.ObjectConsExpr:
+		attrs := make(map[string]cty.Value, len(e.Items))
+		for _, item := range e.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || !key.IsKnown() || key.IsNull() || key.Type() != cty.String {
+				// A computed key can only be assigned to a map, so any
+				// placeholder name will do
+				key = cty.StringVal(fmt.Sprintf("<key %d>", len(attrs)))
+			}
+			attrs[key.AsString()] = StaticValue(item.ValueExpr, vars)
+		}
+		return cty.ObjectVal(attrs)
+
+	case *hclsyntax.ScopeTraversalExpr:
+		if len(e.Traversal) == 2 && e.Traversal.RootName() == "var" {
+			if attr, ok := e.Traversal[1].(hcl.TraverseAttr); ok {
+				if ty, ok := vars[attr.Name]; ok {
+					return cty.UnknownVal(ty.WithoutOptionalAttributesDeep())
+				}
+			}
+		}
+
+	case *hclsyntax.FunctionCallExpr:
+		if stringFunctions[e.Name] {
+			return cty.UnknownVal(cty.String)
+		}
+	}
+
+	return cty.DynamicVal
+}
+
+// VariableTypes returns the type constraint of every variable cfg declares,
+// for use with StaticValue.
+func VariableTypes(cfg discovery.Config) map[string]cty.Type {
+	types := make(map[string]cty.Type, len(cfg.Variables))
+	for _, v := range cfg.Variables {
+		ty, diags := VariableType(v)
+		if diags.HasErrors() {
+			ty = cty.DynamicPseudoType
+		}
+		types[v.Name] = ty
+	}
+	return types
+}
@@ -0,0 +1,48 @@
+package tfcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestConvertVariable(t *testing.T) {
+	cfg := loadModule(t, map[string]string{"variables.tf": `variable "count" {
+  type = number
+}
+
+variable "subnets" {
+  type = list(object({
+    cidr = string
+    az   = optional(string)
+  }))
+}
+
+variable "anything" {}
+`})
+
+	count, _ := cfg.Variable("count")
+	subnets, _ := cfg.Variable("subnets")
+	anything, _ := cfg.Variable("anything")
+
+	out, err := ConvertVariable(count, cty.StringVal("3"))
+	require.NoError(t, err)
+	assert.True(t, out.Equals(cty.NumberIntVal(3)).True())
+
+	_, err = ConvertVariable(count, cty.TupleVal([]cty.Value{cty.NumberIntVal(3)}))
+	assert.EqualError(t, err, "list is not a valid number: number required")
+
+	_, err = ConvertVariable(subnets, cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{"cidr": cty.StringVal("10.0.1.0/24")}),
+		cty.ObjectVal(map[string]cty.Value{"az": cty.StringVal("us-east-1a")}),
+	}))
+	assert.EqualError(t, err, `list is not a valid list(object({az=string,cidr=string})): element 1: attribute "cidr" is required`)
+
+	_, err = ConvertVariable(subnets, cty.TupleVal([]cty.Value{cty.DynamicVal}))
+	assert.NoError(t, err)
+
+	_, err = ConvertVariable(anything, cty.ObjectVal(map[string]cty.Value{"a": cty.True}))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,137 @@
+package tgwsim
+
+import (
+	"fmt"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+)
+
+// ToolTGWSim is the tool name of transit gateway findings, as recorded on
+// analysis.Finding.
+const ToolTGWSim = "tgwsim"
+
+// Rule names reported on problems.
+const (
+	// RuleAssociationConflict is an association of an attachment that AWS
+	// has already associated with the default route table.
+	RuleAssociationConflict = "tgw-association-conflict"
+	// RuleDuplicateAssociation is an attachment associated with more than
+	// one route table.
+	RuleDuplicateAssociation = "tgw-duplicate-association"
+	// RuleUnknownReference is a name that is not in route_tables or
+	// vpc_attachments.
+	RuleUnknownReference = "tgw-unknown-reference"
+	// RulePeeringPropagation is a propagation of a peering attachment, which
+	// AWS does not support.
+	RulePeeringPropagation = "tgw-peering-propagation"
+	// RuleUnassociated is an attachment with no route table, whose traffic
+	// the gateway drops.
+	RuleUnassociated = "tgw-unassociated"
+)
+
+// Problem is a mistake in how a gateway is wired.
+type Problem struct {
+	Rule     string
+	Severity analysis.Severity
+	Gateway  string
+	// Address is the resource in modules/vpc-transit-gw the problem is
+	// about.
+	Address string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: [%s] gateway %s: %s (%s)", p.Address, p.Severity, p.Gateway, p.Message, p.Rule)
+}
+
+// Finding converts p to a finding located in the configuration at dir.
+func (p Problem) Finding(root, dir string) analysis.Finding {
+	return analysis.Locate(analysis.Finding{
+		Tool:     ToolTGWSim,
+		Rule:     p.Rule,
+		Severity: p.Severity,
+		Resource: p.Address,
+		Message:  "gateway " + p.Gateway + ": " + p.Message,
+	}, root, dir)
+}
+
+// Check returns the problems of g that make its plan or apply fail, or
+// leave an attachment without a route table.
+func Check(g Gateway) []Problem {
+	var problems []Problem
+	report := func(rule string, severity analysis.Severity, address, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Rule:     rule,
+			Severity: severity,
+			Gateway:  g.Name,
+			Address:  address,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	tables := map[string]bool{}
+	for _, t := range g.RouteTables {
+		tables[t] = true
+	}
+	reference := func(address, table, attachment string) {
+		if !tables[table] {
+			report(RuleUnknownReference, analysis.SeverityHigh, address, "route table %q is not in route_tables", table)
+		}
+		if _, ok := g.Attachment(attachment); !ok {
+			report(RuleUnknownReference, analysis.SeverityHigh, address, "VPC attachment %q is not in vpc_attachments", attachment)
+		}
+	}
+
+	associated := map[string]string{}
+	for _, b := range g.Associations {
+		address := fmt.Sprintf("%s.this[%q]", resourceAssociation, b.Name)
+		reference(address, b.RouteTable, b.Attachment)
+		a, ok := g.Attachment(b.Attachment)
+		if !ok {
+			continue
+		}
+		if previous, ok := associated[a.Name]; ok {
+			report(RuleDuplicateAssociation, analysis.SeverityHigh, address,
+				"attachment %s is already associated with route table %s; an attachment has one route table", a.Name, previous)
+			continue
+		}
+		associated[a.Name] = b.RouteTable
+		if g.DefaultAssociation && a.Type != TypePeering {
+			report(RuleAssociationConflict, analysis.SeverityHigh, address,
+				"attachment %s is associated with route table %s, but default_route_table_association is enabled, so AWS associates it with the default route table when it is created and this association fails; disable the default association",
+				a.Name, b.RouteTable)
+		}
+	}
+	for _, b := range g.Propagations {
+		address := fmt.Sprintf("%s.this[%q]", resourcePropagation, b.Name)
+		reference(address, b.RouteTable, b.Attachment)
+		if a, ok := g.Attachment(b.Attachment); ok && a.Type == TypePeering {
+			report(RulePeeringPropagation, analysis.SeverityHigh, address,
+				"peering attachment %s cannot propagate routes; add static routes to its peer's networks instead", a.Name)
+		}
+	}
+	for _, r := range g.Routes {
+		address := fmt.Sprintf("%s.this[%q]", resourceRoute, r.Name)
+		if r.Blackhole {
+			if !tables[r.RouteTable] {
+				report(RuleUnknownReference, analysis.SeverityHigh, address, "route table %q is not in route_tables", r.RouteTable)
+			}
+			continue
+		}
+		reference(address, r.RouteTable, r.Attachment)
+	}
+
+	for _, a := range g.Attachments {
+		if _, ok := g.routeTable(a); ok {
+			continue
+		}
+		address := fmt.Sprintf("%s.this[%q]", resourceVPC, a.Name)
+		if a.Type == TypePeering {
+			address = fmt.Sprintf("%s.this[%q]", resourcePeering, a.Name)
+		} else if a.Type != TypeVPC {
+			address = fmt.Sprintf("%s.this", resourceGateway)
+		}
+		report(RuleUnassociated, analysis.SeverityMedium, address,
+			"attachment %s is associated with no route table, so the gateway drops the traffic it sends", a.Name)
+	}
+	return problems
+}
@@ -0,0 +1,224 @@
+package tgwsim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// Resource types of modules/vpc-transit-gw, keyed by their input map.
+const (
+	resourceGateway     = "aws_ec2_transit_gateway"
+	resourceVPC         = "aws_ec2_transit_gateway_vpc_attachment"
+	resourcePeering     = "aws_ec2_transit_gateway_peering_attachment"
+	resourceAssociation = "aws_ec2_transit_gateway_route_table_association"
+	resourcePropagation = "aws_ec2_transit_gateway_route_table_propagation"
+	resourceRoute       = "aws_ec2_transit_gateway_route"
+)
+
+// inputs are the variables of modules/vpc-transit-gw the simulation reads.
+type inputs struct {
+	CreateTransitGateway         *bool                      `json:"create_transit_gateway"`
+	DefaultRouteTableAssociation string                     `json:"default_route_table_association"`
+	DefaultRouteTablePropagation string                     `json:"default_route_table_propagation"`
+	VPCAttachments               map[string]json.RawMessage `json:"vpc_attachments"`
+	PeeringAttachments           map[string]json.RawMessage `json:"peering_attachments"`
+	RouteTables                  map[string]json.RawMessage `json:"route_tables"`
+	Associations                 map[string]json.RawMessage `json:"route_table_associations"`
+	Propagations                 map[string]json.RawMessage `json:"route_table_propagations"`
+	StaticRoutes                 map[string]json.RawMessage `json:"static_routes"`
+}
+
+type binding struct {
+	AttachmentName string `json:"attachment_name"`
+	AttachmentType string `json:"attachment_type"`
+	RouteTableName string `json:"route_table_name"`
+}
+
+type staticRoute struct {
+	binding
+	DestinationCIDRBlock string `json:"destination_cidr_block"`
+	Blackhole            bool   `json:"blackhole"`
+}
+
+// FromVars builds the gateway a call of modules/vpc-transit-gw with vars
+// creates. networks maps attachment names to the CIDR blocks behind them;
+// the module only knows VPCs by ID. VPN, Direct Connect and peering
+// attachments made outside the module are added as the associations,
+// propagations and routes name them.
+func FromVars(name string, vars map[string]interface{}, networks map[string][]string) (Gateway, error) {
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return Gateway{}, err
+	}
+	in := inputs{DefaultRouteTableAssociation: "enable", DefaultRouteTablePropagation: "enable"}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return Gateway{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if in.CreateTransitGateway != nil && !*in.CreateTransitGateway {
+		return Gateway{}, fmt.Errorf("%s: create_transit_gateway is false", name)
+	}
+
+	g := Gateway{
+		Name:               name,
+		DefaultAssociation: in.DefaultRouteTableAssociation == "enable",
+		DefaultPropagation: in.DefaultRouteTablePropagation == "enable",
+		RouteTables:        sortedKeys(in.RouteTables),
+	}
+	cidrs := func(attachment string) ([]netip.Prefix, error) {
+		var out []netip.Prefix
+		for _, s := range networks[attachment] {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: network of %s: %w", name, attachment, err)
+			}
+			out = append(out, p.Masked())
+		}
+		return out, nil
+	}
+	add := func(attachment, typ string) error {
+		if _, ok := g.Attachment(attachment); ok {
+			return nil
+		}
+		c, err := cidrs(attachment)
+		if err != nil {
+			return err
+		}
+		g.Attachments = append(g.Attachments, Attachment{Name: attachment, Type: typ, CIDRs: c})
+		return nil
+	}
+
+	for _, key := range sortedKeys(in.VPCAttachments) {
+		if err := add(key, TypeVPC); err != nil {
+			return Gateway{}, err
+		}
+	}
+	for _, key := range sortedKeys(in.PeeringAttachments) {
+		if err := add(key, TypePeering); err != nil {
+			return Gateway{}, err
+		}
+	}
+	// Attachments of other types are made outside the module and passed by
+	// ID; VPC attachments must be in vpc_attachments, which Check reports.
+	external := func(b binding) error {
+		if b.AttachmentType == TypeVPC {
+			return nil
+		}
+		return add(b.AttachmentName, b.AttachmentType)
+	}
+
+	for _, key := range sortedKeys(in.Associations) {
+		var b binding
+		if err := json.Unmarshal(in.Associations[key], &b); err != nil {
+			return Gateway{}, fmt.Errorf("%s: route_table_associations[%q]: %w", name, key, err)
+		}
+		if err := external(b); err != nil {
+			return Gateway{}, err
+		}
+		g.Associations = append(g.Associations, Binding{Name: key, Attachment: b.AttachmentName, RouteTable: b.RouteTableName})
+	}
+	for _, key := range sortedKeys(in.Propagations) {
+		var b binding
+		if err := json.Unmarshal(in.Propagations[key], &b); err != nil {
+			return Gateway{}, fmt.Errorf("%s: route_table_propagations[%q]: %w", name, key, err)
+		}
+		if err := external(b); err != nil {
+			return Gateway{}, err
+		}
+		g.Propagations = append(g.Propagations, Binding{Name: key, Attachment: b.AttachmentName, RouteTable: b.RouteTableName})
+	}
+	for _, key := range sortedKeys(in.StaticRoutes) {
+		var r staticRoute
+		if err := json.Unmarshal(in.StaticRoutes[key], &r); err != nil {
+			return Gateway{}, fmt.Errorf("%s: static_routes[%q]: %w", name, key, err)
+		}
+		dst, err := netip.ParsePrefix(r.DestinationCIDRBlock)
+		if err != nil {
+			return Gateway{}, fmt.Errorf("%s: static_routes[%q]: %w", name, key, err)
+		}
+		if !r.Blackhole {
+			if err := external(r.binding); err != nil {
+				return Gateway{}, err
+			}
+		}
+		g.Routes = append(g.Routes, Route{
+			Name:        key,
+			RouteTable:  r.RouteTableName,
+			Destination: dst.Masked(),
+			Attachment:  r.AttachmentName,
+			Blackhole:   r.Blackhole,
+		})
+	}
+	return g, nil
+}
+
+// FromPlan builds the gateway planned by a plan of modules/vpc-transit-gw
+// itself. The resources say what is planned, but the IDs that join them
+// are known only after apply, so how they are wired is read from the
+// variables the plan was made with, by for_each key.
+func FromPlan(plan *planjson.Plan, name string, networks map[string][]string) (Gateway, error) {
+	root := plan.ResourceChanges.Managed().Where(func(rc planjson.ResourceChange) bool {
+		return rc.ModuleAddress == ""
+	})
+	gateways := root.WithType(resourceGateway)
+	if len(gateways) != 1 {
+		return Gateway{}, fmt.Errorf("%s: plan has %d %s resources, want 1", name, len(gateways), resourceGateway)
+	}
+
+	vars := map[string]interface{}{}
+	for key, v := range plan.Variables {
+		vars[key] = v.Value
+	}
+	for _, setting := range []string{"default_route_table_association", "default_route_table_propagation"} {
+		if v, ok := gateways[0].AfterString(setting); ok {
+			vars[setting] = v
+		}
+	}
+	g, err := FromVars(name, vars, networks)
+	if err != nil {
+		return Gateway{}, err
+	}
+
+	keys := func(resourceType string) map[string]bool {
+		out := map[string]bool{}
+		for _, rc := range root.WithType(resourceType) {
+			if key, ok := rc.Index.(string); ok {
+				out[key] = true
+			}
+		}
+		return out
+	}
+	g.Associations = filterBindings(g.Associations, keys(resourceAssociation))
+	g.Propagations = filterBindings(g.Propagations, keys(resourcePropagation))
+	planned := keys(resourceRoute)
+	var routes []Route
+	for _, r := range g.Routes {
+		if planned[r.Name] {
+			routes = append(routes, r)
+		}
+	}
+	g.Routes = routes
+	return g, nil
+}
+
+func filterBindings(bindings []Binding, planned map[string]bool) []Binding {
+	var out []Binding
+	for _, b := range bindings {
+		if planned[b.Name] {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,557 @@
+// Package tgwsim simulates how transit gateways forward traffic between
+// their attachments, so tests can assert which networks can reach which
+// without deploying anything.
+//
+// A Gateway is built from the inputs of modules/vpc-transit-gw, or from a
+// plan of it: its attachments, route tables, associations, propagations and
+// static routes. Gateways joined by peering attachments form a Topology.
+// Trace follows one packet from an attachment to a destination address the
+// way AWS does: the route table associated with the attachment it arrives on
+// picks the longest matching route, static routes win over propagated ones,
+// blackhole routes drop the packet and peering attachments hand it to the
+// peer gateway. Reachability traces every pair of attachments into a Matrix.
+package tgwsim
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Attachment types, as in the attachment_type inputs of modules/vpc-transit-gw.
+const (
+	TypeVPC     = "vpc"
+	TypeVPN     = "vpn"
+	TypeDX      = "dx"
+	TypePeering = "peering"
+)
+
+// DefaultRouteTable names the default route table of a gateway, which
+// attachments are associated with and propagate to when the gateway's
+// default association and propagation are enabled.
+const DefaultRouteTable = "default"
+
+// maxHops bounds a trace through peered gateways.
+const maxHops = 16
+
+// Attachment is a network attached to a gateway.
+type Attachment struct {
+	Name string
+	Type string
+	// CIDRs are the networks behind the attachment: the VPC's blocks or the
+	// on-premises prefixes of a VPN or Direct Connect. Peering attachments
+	// have none; what lies behind them is up to the peer's routes.
+	CIDRs []netip.Prefix
+	// Peer and PeerAttachment name the other end of a peering attachment,
+	// as set by Topology.Peer.
+	Peer           string
+	PeerAttachment string
+}
+
+// Binding is a route table association or propagation.
+type Binding struct {
+	// Name is the key of the binding in its input map.
+	Name       string
+	Attachment string
+	RouteTable string
+}
+
+// Route is a static route.
+type Route struct {
+	// Name is the key of the route in static_routes.
+	Name        string
+	RouteTable  string
+	Destination netip.Prefix
+	// Attachment is where the route sends traffic; it is ignored for
+	// blackhole routes.
+	Attachment string
+	Blackhole  bool
+}
+
+// Gateway is one transit gateway.
+type Gateway struct {
+	Name string
+	// DefaultAssociation and DefaultPropagation are whether VPC, VPN and
+	// Direct Connect attachments are associated with, and propagate to,
+	// DefaultRouteTable when they are created. Peering attachments never
+	// are.
+	DefaultAssociation bool
+	DefaultPropagation bool
+	Attachments        []Attachment
+	// RouteTables are the custom route tables; DefaultRouteTable exists
+	// whenever either default is enabled.
+	RouteTables  []string
+	Associations []Binding
+	Propagations []Binding
+	Routes       []Route
+}
+
+// Attachment returns the attachment with the given name.
+func (g *Gateway) Attachment(name string) (*Attachment, bool) {
+	for i := range g.Attachments {
+		if g.Attachments[i].Name == name {
+			return &g.Attachments[i], true
+		}
+	}
+	return nil, false
+}
+
+// routeTable returns the route table traffic arriving on attachment a is
+// looked up in.
+func (g *Gateway) routeTable(a Attachment) (string, bool) {
+	for _, b := range g.Associations {
+		if b.Attachment == a.Name {
+			return b.RouteTable, true
+		}
+	}
+	if g.DefaultAssociation && a.Type != TypePeering {
+		return DefaultRouteTable, true
+	}
+	return "", false
+}
+
+// candidate is a route in a route table, static or propagated.
+type candidate struct {
+	destination netip.Prefix
+	attachment  string
+	blackhole   bool
+	// priority orders routes to the same destination the way AWS does:
+	// static, then propagated from VPC, Direct Connect and VPN attachments.
+	priority int
+	static   string
+}
+
+// routes returns the routes of a route table.
+func (g *Gateway) routes(table string) []candidate {
+	var out []candidate
+	for _, r := range g.Routes {
+		if r.RouteTable == table {
+			out = append(out, candidate{destination: r.Destination, attachment: r.Attachment, blackhole: r.Blackhole, static: r.Name})
+		}
+	}
+	propagating := map[string]bool{}
+	for _, b := range g.Propagations {
+		if b.RouteTable == table {
+			propagating[b.Attachment] = true
+		}
+	}
+	for _, a := range g.Attachments {
+		if a.Type == TypePeering {
+			continue
+		}
+		if !propagating[a.Name] && !(table == DefaultRouteTable && g.DefaultPropagation) {
+			continue
+		}
+		for _, cidr := range a.CIDRs {
+			out = append(out, candidate{destination: cidr, attachment: a.Name, priority: propagationPriority[a.Type]})
+		}
+	}
+	return out
+}
+
+var propagationPriority = map[string]int{TypeVPC: 1, TypeDX: 2, TypeVPN: 3}
+
+// lookup returns the route of table that dst matches.
+func (g *Gateway) lookup(table string, dst netip.Addr) (candidate, bool) {
+	var best candidate
+	found := false
+	for _, c := range g.routes(table) {
+		if !c.destination.Contains(dst) {
+			continue
+		}
+		if !found || c.destination.Bits() > best.destination.Bits() ||
+			(c.destination.Bits() == best.destination.Bits() && (c.priority < best.priority ||
+				c.priority == best.priority && c.attachment < best.attachment)) {
+			best, found = c, true
+		}
+	}
+	return best, found
+}
+
+// Endpoint is an attachment of a gateway.
+type Endpoint struct {
+	Gateway    string
+	Attachment string
+}
+
+func (e Endpoint) String() string {
+	return e.Gateway + "/" + e.Attachment
+}
+
+// Status is how a trace ends.
+type Status string
+
+const (
+	// StatusDelivered is a packet handed to an attachment whose network
+	// holds the destination.
+	StatusDelivered Status = "delivered"
+	// StatusBlackhole is a packet dropped by a blackhole route.
+	StatusBlackhole Status = "blackhole"
+	// StatusNoRoute is a packet whose route table has no matching route.
+	StatusNoRoute Status = "no route"
+	// StatusNoRouteTable is a packet arriving on an attachment associated
+	// with no route table.
+	StatusNoRouteTable Status = "no route table"
+	// StatusMisrouted is a packet handed to an attachment whose network
+	// does not hold the destination, or to one that does not exist.
+	StatusMisrouted Status = "misrouted"
+	// StatusLoop is a packet that keeps crossing peering attachments.
+	StatusLoop Status = "loop"
+)
+
+// Hop is a route lookup on one gateway.
+type Hop struct {
+	Gateway    string
+	From       string
+	RouteTable string
+	// Route is the destination of the matched route, invalid if none
+	// matched, and Static the name of the static route it is.
+	Route  netip.Prefix
+	Static string
+}
+
+func (h Hop) String() string {
+	s := fmt.Sprintf("%s/%s -> %s", h.Gateway, h.From, h.RouteTable)
+	if h.Route.IsValid() {
+		s += " " + h.Route.String()
+		if h.Static != "" {
+			s += fmt.Sprintf(" (static_routes[%q])", h.Static)
+		}
+	}
+	return s
+}
+
+// Path is the trace of one packet.
+type Path struct {
+	From        Endpoint
+	Destination netip.Addr
+	Hops        []Hop
+	Status      Status
+	// To is the attachment the packet was handed to, for delivered and
+	// misrouted packets.
+	To Endpoint
+}
+
+func (p Path) String() string {
+	hops := make([]string, len(p.Hops))
+	for i, h := range p.Hops {
+		hops[i] = h.String()
+	}
+	s := fmt.Sprintf("%s to %s: %s", p.From, p.Destination, p.Status)
+	if p.To != (Endpoint{}) {
+		s += " at " + p.To.String()
+	}
+	if len(hops) > 0 {
+		s += " via " + strings.Join(hops, ", ")
+	}
+	return s
+}
+
+// Topology is a set of gateways, joined by peering attachments.
+type Topology struct {
+	Gateways []Gateway
+}
+
+// Gateway returns the gateway with the given name.
+func (t *Topology) Gateway(name string) (*Gateway, bool) {
+	for i := range t.Gateways {
+		if t.Gateways[i].Name == name {
+			return &t.Gateways[i], true
+		}
+	}
+	return nil, false
+}
+
+// Peer joins two peering attachments, so traffic routed to one arrives on
+// the other.
+func (t *Topology) Peer(a, b Endpoint) error {
+	ends := [2]*Attachment{}
+	for i, e := range []Endpoint{a, b} {
+		g, ok := t.Gateway(e.Gateway)
+		if !ok {
+			return fmt.Errorf("no gateway %s", e.Gateway)
+		}
+		att, ok := g.Attachment(e.Attachment)
+		if !ok || att.Type != TypePeering {
+			return fmt.Errorf("%s is not a peering attachment", e)
+		}
+		ends[i] = att
+	}
+	ends[0].Peer, ends[0].PeerAttachment = b.Gateway, b.Attachment
+	ends[1].Peer, ends[1].PeerAttachment = a.Gateway, a.Attachment
+	return nil
+}
+
+// Trace follows a packet to dst arriving on the attachment from.
+func (t *Topology) Trace(from Endpoint, dst netip.Addr) Path {
+	p := Path{From: from, Destination: dst}
+	at := from
+	visited := map[Endpoint]bool{}
+	for len(p.Hops) < maxHops {
+		if visited[at] {
+			p.Status = StatusLoop
+			return p
+		}
+		visited[at] = true
+
+		g, ok := t.Gateway(at.Gateway)
+		if !ok {
+			p.Status = StatusMisrouted
+			return p
+		}
+		ingress, ok := g.Attachment(at.Attachment)
+		if !ok {
+			p.Status = StatusMisrouted
+			return p
+		}
+		table, ok := g.routeTable(*ingress)
+		if !ok {
+			p.Status = StatusNoRouteTable
+			return p
+		}
+		hop := Hop{Gateway: g.Name, From: ingress.Name, RouteTable: table}
+		route, ok := g.lookup(table, dst)
+		if !ok {
+			p.Hops = append(p.Hops, hop)
+			p.Status = StatusNoRoute
+			return p
+		}
+		hop.Route, hop.Static = route.destination, route.static
+		p.Hops = append(p.Hops, hop)
+		if route.blackhole {
+			p.Status = StatusBlackhole
+			return p
+		}
+
+		p.To = Endpoint{Gateway: g.Name, Attachment: route.attachment}
+		egress, ok := g.Attachment(route.attachment)
+		if !ok {
+			p.Status = StatusMisrouted
+			return p
+		}
+		if egress.Type == TypePeering {
+			if egress.Peer == "" {
+				p.Status = StatusMisrouted
+				return p
+			}
+			at = Endpoint{Gateway: egress.Peer, Attachment: egress.PeerAttachment}
+			p.To = Endpoint{}
+			continue
+		}
+		p.Status = StatusMisrouted
+		for _, cidr := range egress.CIDRs {
+			if cidr.Contains(dst) {
+				p.Status = StatusDelivered
+			}
+		}
+		return p
+	}
+	p.Status = StatusLoop
+	return p
+}
+
+// Endpoints returns the attachments traffic can start and end at: all but
+// peering attachments.
+func (t *Topology) Endpoints() []Endpoint {
+	var out []Endpoint
+	for _, g := range t.Gateways {
+		for _, a := range g.Attachments {
+			if a.Type != TypePeering {
+				out = append(out, Endpoint{Gateway: g.Name, Attachment: a.Name})
+			}
+		}
+	}
+	return out
+}
+
+// probes returns the addresses a trace to the networks behind e starts
+// with: the first address of each network, and of every route destination
+// inside it, so a more specific route to part of a network is seen.
+func (t *Topology) probes(e Endpoint) []netip.Addr {
+	g, _ := t.Gateway(e.Gateway)
+	a, _ := g.Attachment(e.Attachment)
+	var destinations []netip.Prefix
+	for _, gw := range t.Gateways {
+		for _, r := range gw.Routes {
+			destinations = append(destinations, r.Destination)
+		}
+		for _, att := range gw.Attachments {
+			destinations = append(destinations, att.CIDRs...)
+		}
+	}
+	seen := map[netip.Addr]bool{}
+	var out []netip.Addr
+	for _, cidr := range a.CIDRs {
+		for _, d := range append([]netip.Prefix{cidr}, destinations...) {
+			if d.Bits() >= cidr.Bits() && cidr.Contains(d.Addr()) && !seen[d.Addr()] {
+				seen[d.Addr()] = true
+				out = append(out, d.Addr())
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Less(out[j]) })
+	return out
+}
+
+// Result is whether traffic from one attachment reaches the networks behind
+// another.
+type Result struct {
+	From, To Endpoint
+	// Paths trace a packet to the first address of each network behind To,
+	// and of each route destination inside them.
+	Paths []Path
+}
+
+// Reachable reports whether every traced packet is delivered to To.
+func (r Result) Reachable() bool {
+	for _, p := range r.Paths {
+		if !r.delivered(p) {
+			return false
+		}
+	}
+	return len(r.Paths) > 0
+}
+
+// Partial reports whether some, but not all, traced packets are delivered
+// to To.
+func (r Result) Partial() bool {
+	n := 0
+	for _, p := range r.Paths {
+		if r.delivered(p) {
+			n++
+		}
+	}
+	return n > 0 && n < len(r.Paths)
+}
+
+func (r Result) delivered(p Path) bool {
+	return p.Status == StatusDelivered && p.To == r.To
+}
+
+func (r Result) summary() string {
+	switch {
+	case r.Reachable():
+		return "yes"
+	case r.Partial():
+		return "partial"
+	}
+	for _, p := range r.Paths {
+		if !r.delivered(p) {
+			return "no (" + string(p.Status) + ")"
+		}
+	}
+	return "no"
+}
+
+// Matrix is the reachability of every ordered pair of endpoints.
+type Matrix struct {
+	Endpoints []Endpoint
+	Results   []Result
+}
+
+// Reachability traces traffic between every ordered pair of endpoints.
+func (t *Topology) Reachability() Matrix {
+	m := Matrix{Endpoints: t.Endpoints()}
+	for _, from := range m.Endpoints {
+		for _, to := range m.Endpoints {
+			if from == to {
+				continue
+			}
+			r := Result{From: from, To: to}
+			for _, addr := range t.probes(to) {
+				r.Paths = append(r.Paths, t.Trace(from, addr))
+			}
+			m.Results = append(m.Results, r)
+		}
+	}
+	return m
+}
+
+// endpoint resolves "gateway/attachment", or just the attachment name when
+// only one gateway has it.
+func (m Matrix) endpoint(name string) (Endpoint, bool) {
+	var found []Endpoint
+	for _, e := range m.Endpoints {
+		if e.String() == name {
+			return e, true
+		}
+		if e.Attachment == name {
+			found = append(found, e)
+		}
+	}
+	if len(found) != 1 {
+		return Endpoint{}, false
+	}
+	return found[0], true
+}
+
+// Result returns the result from one endpoint to another, each named as
+// "gateway/attachment" or by the attachment alone when that is unique.
+func (m Matrix) Result(from, to string) (Result, bool) {
+	f, ok := m.endpoint(from)
+	if !ok {
+		return Result{}, false
+	}
+	t, ok := m.endpoint(to)
+	if !ok {
+		return Result{}, false
+	}
+	for _, r := range m.Results {
+		if r.From == f && r.To == t {
+			return r, true
+		}
+	}
+	return Result{}, false
+}
+
+// Reachable reports whether traffic from one endpoint reaches all of the
+// networks behind the other.
+func (m Matrix) Reachable(from, to string) bool {
+	r, ok := m.Result(from, to)
+	return ok && r.Reachable()
+}
+
+// Connected reports whether two endpoints reach each other both ways, as a
+// connection needs its replies to come back.
+func (m Matrix) Connected(a, b string) bool {
+	return m.Reachable(a, b) && m.Reachable(b, a)
+}
+
+// Isolated reports whether no traffic from either endpoint reaches the
+// other.
+func (m Matrix) Isolated(a, b string) bool {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		r, ok := m.Result(pair[0], pair[1])
+		if !ok || r.Reachable() || r.Partial() {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the matrix as a table, sources down and destinations
+// across.
+func (m Matrix) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "from \\ to")
+	for _, e := range m.Endpoints {
+		fmt.Fprintf(w, "\t%s", e)
+	}
+	fmt.Fprintln(w)
+	for _, from := range m.Endpoints {
+		fmt.Fprint(w, from)
+		for _, to := range m.Endpoints {
+			cell := "-"
+			if r, ok := m.Result(from.String(), to.String()); ok {
+				cell = r.summary()
+			}
+			fmt.Fprintf(w, "\t%s", cell)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	return b.String()
+}
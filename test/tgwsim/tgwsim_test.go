@@ -0,0 +1,227 @@
+package tgwsim
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+func bind(attachment, typ, table string) map[string]interface{} {
+	return map[string]interface{}{"attachment_name": attachment, "attachment_type": typ, "route_table_name": table}
+}
+
+// topology is a hub gateway segmenting prod, lab and shared VPCs, peered
+// with a gateway in another region.
+func topology(t *testing.T) Topology {
+	t.Helper()
+	hub, err := FromVars("hub", map[string]interface{}{
+		"default_route_table_association": "disable",
+		"default_route_table_propagation": "disable",
+		"vpc_attachments": map[string]interface{}{
+			"prod":   map[string]interface{}{"vpc_id": "vpc-prod"},
+			"lab":    map[string]interface{}{"vpc_id": "vpc-lab"},
+			"shared": map[string]interface{}{"vpc_id": "vpc-shared"},
+		},
+		"peering_attachments": map[string]interface{}{
+			"dr": map[string]interface{}{"peer_region": "eu-central-1"},
+		},
+		"route_tables": map[string]interface{}{"prod": map[string]interface{}{}, "lab": map[string]interface{}{}, "shared": map[string]interface{}{}},
+		"route_table_associations": map[string]interface{}{
+			"prod":   bind("prod", "vpc", "prod"),
+			"lab":    bind("lab", "vpc", "lab"),
+			"shared": bind("shared", "vpc", "shared"),
+			"dr":     bind("dr", "peering", "shared"),
+		},
+		"route_table_propagations": map[string]interface{}{
+			"shared-to-prod": bind("shared", "vpc", "prod"),
+			"shared-to-lab":  bind("shared", "vpc", "lab"),
+			"prod-to-shared": bind("prod", "vpc", "shared"),
+			"lab-to-shared":  bind("lab", "vpc", "shared"),
+		},
+		"static_routes": map[string]interface{}{
+			"prod-to-dr": map[string]interface{}{
+				"destination_cidr_block": "10.100.0.0/16", "route_table_name": "prod",
+				"attachment_name": "dr", "attachment_type": "peering",
+			},
+			"drop-prod-data": map[string]interface{}{
+				"destination_cidr_block": "10.0.128.0/17", "route_table_name": "shared",
+				"attachment_name": "prod", "attachment_type": "vpc", "blackhole": true,
+			},
+		},
+	}, map[string][]string{"prod": {"10.0.0.0/16"}, "lab": {"10.1.0.0/16"}, "shared": {"10.2.0.0/16"}})
+	require.NoError(t, err)
+
+	dr, err := FromVars("dr", map[string]interface{}{
+		"vpc_attachments":                 map[string]interface{}{"dr": map[string]interface{}{"vpc_id": "vpc-dr"}},
+		"route_tables":                    map[string]interface{}{"main": map[string]interface{}{}},
+		"default_route_table_association": "disable",
+		"route_table_associations": map[string]interface{}{
+			"dr":  bind("dr", "vpc", "main"),
+			"hub": bind("hub", "peering", "main"),
+		},
+		"route_table_propagations": map[string]interface{}{"dr": bind("dr", "vpc", "main")},
+		"static_routes": map[string]interface{}{
+			"to-prod": map[string]interface{}{
+				"destination_cidr_block": "10.0.0.0/16", "route_table_name": "main",
+				"attachment_name": "hub", "attachment_type": "peering",
+			},
+		},
+	}, map[string][]string{"dr": {"10.100.0.0/16"}})
+	require.NoError(t, err)
+
+	topo := Topology{Gateways: []Gateway{hub, dr}}
+	require.NoError(t, topo.Peer(Endpoint{"hub", "dr"}, Endpoint{"dr", "hub"}))
+	return topo
+}
+
+func TestFromVars(t *testing.T) {
+	topo := topology(t)
+	hub, ok := topo.Gateway("hub")
+	require.True(t, ok)
+	assert.False(t, hub.DefaultAssociation)
+	assert.Equal(t, []string{"lab", "prod", "shared"}, hub.RouteTables)
+	assert.Equal(t, []Attachment{
+		{Name: "lab", Type: TypeVPC, CIDRs: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}},
+		{Name: "prod", Type: TypeVPC, CIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")}},
+		{Name: "shared", Type: TypeVPC, CIDRs: []netip.Prefix{netip.MustParsePrefix("10.2.0.0/16")}},
+		{Name: "dr", Type: TypePeering, Peer: "dr", PeerAttachment: "hub"},
+	}, hub.Attachments)
+
+	dr, _ := topo.Gateway("dr")
+	assert.True(t, dr.DefaultPropagation, "the module default")
+	peer, ok := dr.Attachment("hub")
+	require.True(t, ok, "the accepter side is added from the associations")
+	assert.Equal(t, TypePeering, peer.Type)
+
+	_, err := FromVars("off", map[string]interface{}{"create_transit_gateway": false}, nil)
+	assert.EqualError(t, err, "off: create_transit_gateway is false")
+	_, err = FromVars("bad", map[string]interface{}{"static_routes": map[string]interface{}{
+		"r": map[string]interface{}{"destination_cidr_block": "10.0.0.0", "blackhole": true},
+	}}, nil)
+	assert.Error(t, err)
+}
+
+func TestTrace(t *testing.T) {
+	topo := topology(t)
+
+	p := topo.Trace(Endpoint{"hub", "prod"}, netip.MustParseAddr("10.100.1.10"))
+	assert.Equal(t, StatusDelivered, p.Status)
+	assert.Equal(t, Endpoint{"dr", "dr"}, p.To)
+	assert.Equal(t, `hub/prod to 10.100.1.10: delivered at dr/dr via hub/prod -> prod 10.100.0.0/16 (static_routes["prod-to-dr"]), dr/hub -> main 10.100.0.0/16`, p.String())
+
+	p = topo.Trace(Endpoint{"hub", "shared"}, netip.MustParseAddr("10.0.200.1"))
+	assert.Equal(t, StatusBlackhole, p.Status)
+
+	p = topo.Trace(Endpoint{"hub", "prod"}, netip.MustParseAddr("10.1.0.1"))
+	assert.Equal(t, StatusNoRoute, p.Status)
+	assert.Equal(t, "hub/prod to 10.1.0.1: no route via hub/prod -> prod", p.String())
+
+	p = topo.Trace(Endpoint{"hub", "missing"}, netip.MustParseAddr("10.1.0.1"))
+	assert.Equal(t, StatusMisrouted, p.Status)
+}
+
+func TestTraceLoop(t *testing.T) {
+	route := func(table, attachment string) map[string]interface{} {
+		return map[string]interface{}{"to-x": map[string]interface{}{
+			"destination_cidr_block": "192.168.0.0/24", "route_table_name": table,
+			"attachment_name": attachment, "attachment_type": "peering",
+		}}
+	}
+	a, err := FromVars("a", map[string]interface{}{
+		"vpc_attachments":          map[string]interface{}{"vpc": map[string]interface{}{}},
+		"route_tables":             map[string]interface{}{"rt": map[string]interface{}{}},
+		"route_table_associations": map[string]interface{}{"vpc": bind("vpc", "vpc", "rt"), "peer": bind("to-b", "peering", "rt")},
+		"static_routes":            route("rt", "to-b"),
+	}, nil)
+	require.NoError(t, err)
+	b, err := FromVars("b", map[string]interface{}{
+		"route_tables":             map[string]interface{}{"rt": map[string]interface{}{}},
+		"route_table_associations": map[string]interface{}{"peer": bind("to-a", "peering", "rt")},
+		"static_routes":            route("rt", "to-a"),
+	}, nil)
+	require.NoError(t, err)
+	topo := Topology{Gateways: []Gateway{a, b}}
+	require.NoError(t, topo.Peer(Endpoint{"a", "to-b"}, Endpoint{"b", "to-a"}))
+	assert.Error(t, topo.Peer(Endpoint{"a", "vpc"}, Endpoint{"b", "to-a"}))
+
+	p := topo.Trace(Endpoint{"a", "vpc"}, netip.MustParseAddr("192.168.0.1"))
+	assert.Equal(t, StatusLoop, p.Status)
+	assert.Len(t, p.Hops, 3)
+}
+
+func TestReachability(t *testing.T) {
+	topo := topology(t)
+	m := topo.Reachability()
+	assert.Len(t, m.Endpoints, 4, "peering attachments are not endpoints")
+
+	assert.True(t, m.Isolated("prod", "lab"))
+	assert.True(t, m.Reachable("prod", "shared"))
+	assert.False(t, m.Connected("prod", "shared"), "shared reaches only half of prod")
+	r, ok := m.Result("shared", "prod")
+	require.True(t, ok)
+	assert.True(t, r.Partial())
+	assert.True(t, m.Reachable("hub/prod", "dr/dr"))
+	assert.False(t, m.Reachable("lab", "dr"))
+	assert.False(t, m.Reachable("nowhere", "dr"))
+
+	assert.Equal(t, "from \\ to   hub/lab        hub/prod       hub/shared     dr/dr\n"+
+		"hub/lab     -              no (no route)  yes            no (no route)\n"+
+		"hub/prod    no (no route)  -              yes            yes\n"+
+		"hub/shared  yes            partial        -              no (no route)\n"+
+		"dr/dr       no (no route)  partial        no (no route)  -\n", m.String())
+}
+
+func TestCheck(t *testing.T) {
+	g, err := FromVars("unit", map[string]interface{}{
+		"vpc_attachments":     map[string]interface{}{"prod": map[string]interface{}{}, "lab": map[string]interface{}{}},
+		"peering_attachments": map[string]interface{}{"dr": map[string]interface{}{}},
+		"route_tables":        map[string]interface{}{"prod": map[string]interface{}{}},
+		"route_table_associations": map[string]interface{}{
+			"a": bind("prod", "vpc", "prod"),
+			"b": bind("prod", "vpc", "lab"),
+			"c": bind("stage", "vpc", "prod"),
+		},
+		"route_table_propagations": map[string]interface{}{"dr": bind("dr", "peering", "prod")},
+	}, nil)
+	require.NoError(t, err)
+
+	var got []string
+	for _, p := range Check(g) {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		`aws_ec2_transit_gateway_route_table_association.this["a"]: [HIGH] gateway unit: attachment prod is associated with route table prod, but default_route_table_association is enabled, so AWS associates it with the default route table when it is created and this association fails; disable the default association (tgw-association-conflict)`,
+		`aws_ec2_transit_gateway_route_table_association.this["b"]: [HIGH] gateway unit: route table "lab" is not in route_tables (tgw-unknown-reference)`,
+		`aws_ec2_transit_gateway_route_table_association.this["b"]: [HIGH] gateway unit: attachment prod is already associated with route table prod; an attachment has one route table (tgw-duplicate-association)`,
+		`aws_ec2_transit_gateway_route_table_association.this["c"]: [HIGH] gateway unit: VPC attachment "stage" is not in vpc_attachments (tgw-unknown-reference)`,
+		`aws_ec2_transit_gateway_route_table_propagation.this["dr"]: [HIGH] gateway unit: peering attachment dr cannot propagate routes; add static routes to its peer's networks instead (tgw-peering-propagation)`,
+		`aws_ec2_transit_gateway_peering_attachment.this["dr"]: [MEDIUM] gateway unit: attachment dr is associated with no route table, so the gateway drops the traffic it sends (tgw-unassociated)`,
+	}, got)
+
+	topo := topology(t)
+	for _, g := range topo.Gateways {
+		assert.Empty(t, Check(g), g.Name)
+	}
+}
+
+func TestFromPlan(t *testing.T) {
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	g, err := FromPlan(plan, "unit", map[string][]string{"prod": {"10.0.0.0/16"}, "lab": {"10.1.0.0/16"}})
+	require.NoError(t, err)
+	assert.False(t, g.DefaultAssociation)
+	assert.Len(t, g.Associations, 2)
+	assert.Empty(t, g.Routes, "static routes not in the plan are dropped")
+
+	m := (&Topology{Gateways: []Gateway{g}}).Reachability()
+	assert.True(t, m.Reachable("prod", "lab"))
+	assert.False(t, m.Reachable("lab", "prod"))
+
+	plan.ResourceChanges = nil
+	_, err = FromPlan(plan, "unit", nil)
+	assert.EqualError(t, err, "unit: plan has 0 aws_ec2_transit_gateway resources, want 1")
+}
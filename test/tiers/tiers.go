@@ -0,0 +1,336 @@
+// Package tiers is the registry of test tiers: which tests run together, what
+// they need installed or configured, and how long they may take. The test
+// runner in cmd/testrunner reads it, so developers and CI select tests by
+// tier name rather than by hand-written -run patterns.
+//
+// Every top-level test in the suite must belong to at least one tier; the
+// package tests fail when a test is added without registering it.
+package tiers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Tier names, from cheapest to most expensive.
+const (
+	Static   = "static"
+	Unit     = "unit"
+	Emulator = "emulator"
+	Live     = "live"
+)
+
+// Tier is a named group of suites run together.
+type Tier struct {
+	Name        string
+	Description string
+	Suites      []Suite
+	Timeout     time.Duration
+	// Env is set for every suite in the tier; an empty value unsets the
+	// variable.
+	Env map[string]string
+}
+
+// Suite is a single `go test` invocation: the named top-level tests of
+// Package, or all of its tests when Tests is empty.
+type Suite struct {
+	Package  string
+	Tests    []string
+	Requires []Prerequisite
+}
+
+// Prerequisite is something a suite needs from the machine it runs on.
+type Prerequisite struct {
+	Name  string
+	Check func() error
+}
+
+// Run returns the -run pattern selecting the suite's tests, or "" for all.
+func (s Suite) Run() string {
+	if len(s.Tests) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(s.Tests))
+	for i, name := range s.Tests {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// Missing returns the prerequisites of the suite that are not met, with the
+// reason each failed.
+func (s Suite) Missing() []error {
+	var missing []error
+	for _, p := range s.Requires {
+		if err := p.Check(); err != nil {
+			missing = append(missing, fmt.Errorf("%s: %w", p.Name, err))
+		}
+	}
+	return missing
+}
+
+// Tool requires an executable on PATH.
+func Tool(name string) Prerequisite {
+	return Prerequisite{
+		Name: name,
+		Check: func() error {
+			_, err := exec.LookPath(name)
+			return err
+		},
+	}
+}
+
+// AWSCredentials requires credentials the AWS SDK can pick up from the
+// environment or the shared config files. It does not call AWS to check them.
+var AWSCredentials = Prerequisite{
+	Name: "AWS credentials",
+	Check: func() error {
+		for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"} {
+			if os.Getenv(name) != "" {
+				return nil
+			}
+		}
+		home, err := os.UserHomeDir()
+		if err == nil {
+			for _, name := range []string{"credentials", "config"} {
+				if _, err := os.Stat(filepath.Join(home, ".aws", name)); err == nil {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("set AWS_ACCESS_KEY_ID or AWS_PROFILE, or configure ~/.aws")
+	},
+}
+
+// TierEnvVar names the environment variable the runner sets to the name of
+// the tier it runs. It matches harness.TierEnvVar: the deployment tests skip
+// unless it names the emulator or live tier, so a plain go test ./... never
+// creates AWS resources.
+const TierEnvVar = "TEST_TIER"
+
+// Environment returns the variables set for every suite of the tier: Env,
+// and TierEnvVar set to the tier's name.
+func (t Tier) Environment() map[string]string {
+	env := map[string]string{TierEnvVar: t.Name}
+	for name, value := range t.Env {
+		env[name] = value
+	}
+	return env
+}
+
+// EmulatorEndpointEnvVar matches harness.EmulatorEndpointEnvVar; the
+// registry does not import the harness to keep the runner free of test
+// dependencies.
+const EmulatorEndpointEnvVar = "LOCAL_AWS_ENDPOINT"
+
+// EmulatorRunning requires LOCAL_AWS_ENDPOINT to be set and accepting
+// connections.
+var EmulatorRunning = Prerequisite{
+	Name: "AWS emulator",
+	Check: func() error {
+		endpoint := os.Getenv(EmulatorEndpointEnvVar)
+		if endpoint == "" {
+			return fmt.Errorf("%s is not set", EmulatorEndpointEnvVar)
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("%s=%q is not a URL", EmulatorEndpointEnvVar, endpoint)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), 2*time.Second)
+		if err != nil {
+			return fmt.Errorf("nothing is listening at %s: %w", endpoint, err)
+		}
+		return conn.Close()
+	},
+}
+
+// Deployment tests that also pass against the emulator. The rest need
+// services or APIs the emulator does not provide.
+var emulatorTests = []string{
+	"TestVPCModule",
+	"TestIAMModuleBasic",
+	"TestS3Module",
+	"TestSQSModule",
+	"TestSNSModule",
+}
+
+// All is the registry, in the order the runner runs tiers.
+var All = []Tier{
+	{
+		Name:        Static,
+		Description: "formatting, linting, security scans and offline checks of the Terraform source",
+		Timeout:     10 * time.Minute,
+		Suites: []Suite{
+			{
+				Package: ".",
+				Tests: []string{
+					"TestModuleStructure",
+					"TestVariableDescriptions",
+					"TestOutputDescriptions",
+					"TestModuleCalls",
+					"TestEnvTFVars",
+					"TestIAMPolicyDocuments",
+					"TestFixtures",
+					"TestNetworkPlan",
+				},
+			},
+			{Package: ".", Tests: []string{"TestTerraformFormat"}, Requires: []Prerequisite{Tool("terraform")}},
+			{Package: ".", Tests: []string{"TestTFLint"}, Requires: []Prerequisite{Tool("tflint")}},
+			{Package: ".", Tests: []string{"TestTFSec"}, Requires: []Prerequisite{Tool("tfsec")}},
+			{Package: ".", Tests: []string{"TestRegoPolicyTests"}},
+			{Package: ".", Tests: []string{"TestCheckov"}, Requires: []Prerequisite{Tool("checkov")}},
+		},
+	},
+	{
+		Name:        Unit,
+		Description: "the test libraries, terraform validate and plans against the offline AWS stub",
+		Timeout:     20 * time.Minute,
+		Suites: []Suite{
+			{Package: "./analysis"},
+			{Package: "./awsstub"},
+			{Package: "./discovery"},
+			{Package: "./fixtures"},
+			{Package: "./golden"},
+			{Package: "./harness"},
+			{Package: "./iameval"},
+			{Package: "./iampolicy"},
+			{Package: "./netplan"},
+			{Package: "./planjson"},
+			{Package: "./policy"},
+			{Package: "./routepath"},
+			{Package: "./sarif"},
+			{Package: "./sggraph"},
+			{Package: "./tagcheck"},
+			{Package: "./tfcheck"},
+			{Package: "./tgwsim"},
+			{Package: "./tiers"},
+			{Package: "./trustpolicy"},
+			{Package: "./regopolicy"},
+			{
+				Package: ".",
+				Tests: []string{
+					"TestTerraformValidateAllModules",
+					"TestOfflinePlan",
+					"TestVPCModuleOfflinePlan",
+					"TestSQSModuleOfflinePlan",
+					"TestTagCompliance",
+					"TestPolicyRules",
+					"TestIAMRolePermissions",
+					"TestIAMTrustPolicies",
+					"TestTransitGatewayReachability",
+					"TestVPCRoutePaths",
+					"TestSecurityGroupReachability",
+				},
+				Requires: []Prerequisite{Tool("terraform")},
+			},
+			{Package: ".", Tests: []string{"TestRegoPolicies"}, Requires: []Prerequisite{Tool("terraform")}},
+		},
+	},
+	{
+		Name:        Emulator,
+		Description: "deployment tests against a local AWS emulator",
+		Timeout:     30 * time.Minute,
+		Suites: []Suite{
+			{Package: ".", Tests: emulatorTests, Requires: []Prerequisite{Tool("terraform"), EmulatorRunning}},
+		},
+	},
+	{
+		Name:        Live,
+		Description: "deployment tests against a real AWS account; creates billable resources",
+		Timeout:     90 * time.Minute,
+		// Never let a stray emulator setting turn the live tier into a
+		// second emulator run.
+		Env: map[string]string{EmulatorEndpointEnvVar: ""},
+		Suites: []Suite{
+			{
+				Package: ".",
+				Tests: append(append([]string{}, emulatorTests...),
+					"TestTerraformPlanAllModules",
+					"TestVPCModuleWithCustomCIDR",
+					"TestEC2Module",
+					"TestEC2ModuleWithScalingPolicies",
+					"TestELBModule",
+					"TestELBModuleWithTargetGroups",
+					"TestIAMModuleRoles",
+					"TestIAMModuleCustomPolicies",
+					"TestIAMModuleOIDCProvider",
+					"TestIAMModulePasswordPolicy",
+					"TestIAMModuleValidation",
+					"TestIAMModuleComplexScenario",
+					"TestCompleteInfrastructureStack",
+					"TestModuleUpgrade",
+					"TestDisasterRecovery",
+					"TestCompleteStackIntegration",
+					"TestModuleIAMIntegrationPatterns",
+				),
+				Requires: []Prerequisite{Tool("terraform"), AWSCredentials},
+			},
+		},
+	},
+}
+
+// Default is the tiers run when none are selected: everything that needs
+// neither an emulator nor an AWS account.
+var Default = []string{Static, Unit}
+
+// Lookup returns the registered tier called name.
+func Lookup(name string) (Tier, bool) {
+	for _, tier := range All {
+		if tier.Name == name {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// Select returns the tiers named in a comma-separated list, in registry
+// order. "all" selects every tier and an empty list selects Default.
+func Select(list string) ([]Tier, error) {
+	names := Default
+	if strings.TrimSpace(list) != "" {
+		names = strings.Split(list, ",")
+	}
+
+	want := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			return All, nil
+		}
+		if _, ok := Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown tier %q; known tiers are %s", name, strings.Join(Names(), ", "))
+		}
+		want[name] = true
+	}
+
+	var selected []Tier
+	for _, tier := range All {
+		if want[tier.Name] {
+			selected = append(selected, tier)
+		}
+	}
+	return selected, nil
+}
+
+// Names returns the names of all registered tiers.
+func Names() []string {
+	names := make([]string, len(All))
+	for i, tier := range All {
+		names[i] = tier.Name
+	}
+	return names
+}
@@ -0,0 +1,215 @@
+package tiers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDir is the root of the test module, relative to this package.
+const testDir = ".."
+
+// topLevelTests returns the Test functions declared in the _test.go files of
+// dir, excluding TestMain.
+func topLevelTests(t *testing.T, dir string) map[string]bool {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	require.NoError(t, err)
+
+	tests := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		require.NoError(t, err)
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") || fn.Name.Name == "TestMain" {
+				continue
+			}
+			tests[fn.Name.Name] = true
+		}
+	}
+	return tests
+}
+
+func TestEveryTestHasATier(t *testing.T) {
+	declared := topLevelTests(t, testDir)
+	require.NotEmpty(t, declared)
+
+	registered := map[string]bool{}
+	for _, tier := range All {
+		for _, suite := range tier.Suites {
+			if suite.Package != "." {
+				continue
+			}
+			for _, name := range suite.Tests {
+				assert.True(t, declared[name], "tier %s runs %s, which is not declared in %s", tier.Name, name, testDir)
+				registered[name] = true
+			}
+		}
+	}
+
+	for name := range declared {
+		assert.True(t, registered[name], "%s is not registered in any tier", name)
+	}
+}
+
+// TestDeploymentTestsRequireTier checks every test of the emulator and live
+// tiers starts with harness.RequireDeployTier, so go test ./... skips it.
+func TestDeploymentTestsRequireTier(t *testing.T) {
+	deploys := map[string]bool{}
+	for _, tier := range All {
+		if tier.Name != Emulator && tier.Name != Live {
+			continue
+		}
+		for _, suite := range tier.Suites {
+			for _, name := range suite.Tests {
+				deploys[name] = true
+			}
+		}
+	}
+	require.NotEmpty(t, deploys)
+
+	files, err := filepath.Glob(filepath.Join(testDir, "*_test.go"))
+	require.NoError(t, err)
+	fset := token.NewFileSet()
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		require.NoError(t, err)
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !deploys[fn.Name.Name] {
+				continue
+			}
+			assert.True(t, callsRequireDeployTier(fn), "%s must call harness.RequireDeployTier(t) first", fn.Name.Name)
+		}
+	}
+}
+
+// callsRequireDeployTier reports whether the first statement of fn is a call
+// of harness.RequireDeployTier.
+func callsRequireDeployTier(fn *ast.FuncDecl) bool {
+	if fn.Body == nil || len(fn.Body.List) == 0 {
+		return false
+	}
+	stmt, ok := fn.Body.List[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := stmt.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "harness" && sel.Sel.Name == "RequireDeployTier"
+}
+
+func TestEveryPackageHasATier(t *testing.T) {
+	registered := map[string]bool{}
+	for _, tier := range All {
+		for _, suite := range tier.Suites {
+			registered[suite.Package] = true
+		}
+	}
+
+	err := filepath.WalkDir(testDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		// The go tool ignores testdata, and so do the tiers
+		if (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata") && path != testDir {
+			return filepath.SkipDir
+		}
+		if len(topLevelTests(t, path)) == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(testDir, path)
+		require.NoError(t, err)
+		pkg := "."
+		if rel != "." {
+			pkg = "./" + filepath.ToSlash(rel)
+		}
+		assert.True(t, registered[pkg], "package %s has tests but no tier runs it", pkg)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSuiteRun(t *testing.T) {
+	assert.Equal(t, "", Suite{Package: "./harness"}.Run())
+
+	run := Suite{Tests: []string{"TestVPCModule", "TestS3Module"}}.Run()
+	assert.Equal(t, "^(TestVPCModule|TestS3Module)$", run)
+
+	re := regexp.MustCompile(run)
+	assert.True(t, re.MatchString("TestVPCModule"))
+	assert.False(t, re.MatchString("TestVPCModuleWithCustomCIDR"), "tests sharing a prefix must not be selected")
+}
+
+func TestSelect(t *testing.T) {
+	tiers, err := Select("")
+	require.NoError(t, err)
+	assert.Equal(t, Default, tierNames(tiers))
+
+	tiers, err = Select("live, static")
+	require.NoError(t, err)
+	assert.Equal(t, []string{Static, Live}, tierNames(tiers), "tiers run in registry order")
+
+	tiers, err = Select("all")
+	require.NoError(t, err)
+	assert.Equal(t, Names(), tierNames(tiers))
+
+	_, err = Select("static,integration")
+	assert.ErrorContains(t, err, `unknown tier "integration"`)
+}
+
+func TestEnvironment(t *testing.T) {
+	live, ok := Lookup(Live)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{TierEnvVar: Live, EmulatorEndpointEnvVar: ""}, live.Environment())
+
+	static, ok := Lookup(Static)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{TierEnvVar: Static}, static.Environment(), "a tier always names itself")
+}
+
+func TestPrerequisites(t *testing.T) {
+	suite := Suite{Requires: []Prerequisite{Tool("go"), Tool("no-such-tool-for-tiers-test")}}
+	missing := suite.Missing()
+	require.Len(t, missing, 1)
+	assert.Contains(t, missing[0].Error(), "no-such-tool-for-tiers-test")
+
+	t.Setenv(EmulatorEndpointEnvVar, "")
+	assert.ErrorContains(t, EmulatorRunning.Check(), "is not set")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	t.Setenv(EmulatorEndpointEnvVar, "http://"+listener.Addr().String())
+	assert.NoError(t, EmulatorRunning.Check())
+
+	listener.Close()
+	assert.ErrorContains(t, EmulatorRunning.Check(), "nothing is listening")
+}
+
+func tierNames(tiers []Tier) []string {
+	names := make([]string, len(tiers))
+	for i, tier := range tiers {
+		names[i] = tier.Name
+	}
+	return names
+}
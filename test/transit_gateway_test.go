@@ -0,0 +1,149 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+	"github.com/your-org/terraform-aws-modules/test/tgwsim"
+)
+
+// transitGatewayModule is the configuration the transit gateway tests plan
+const transitGatewayModule = "modules/vpc-transit-gw"
+
+// tgwBinding is a route_table_associations or route_table_propagations entry of the transit gateway module
+func tgwBinding(attachment, attachmentType, routeTable string) map[string]interface{} {
+	return map[string]interface{}{
+		"attachment_name":  attachment,
+		"attachment_type":  attachmentType,
+		"attachment_id":    "tgw-attach-" + attachment,
+		"route_table_name": routeTable,
+	}
+}
+
+// TestTransitGatewayReachability plans the transit gateway module offline with the environments segmented
+// into their own route tables, peers it with a disaster recovery gateway and simulates which VPCs can reach
+// which: prod and lab must be isolated from each other, both must reach the shared services VPC, and only
+// prod may reach the disaster recovery region
+func TestTransitGatewayReachability(t *testing.T) {
+	stub := awsstub.Start(t)
+
+	attachment := func(vpc string) map[string]interface{} {
+		return map[string]interface{}{"vpc_id": vpc, "subnet_ids": []string{"subnet-0a1b2c3d4e5f60001"}}
+	}
+	vars := map[string]interface{}{
+		"name":                            "unit",
+		"default_route_table_association": "disable",
+		"default_route_table_propagation": "disable",
+		"vpc_attachments": map[string]interface{}{
+			"prod":   attachment("vpc-0a1b2c3d4e5f60001"),
+			"lab":    attachment("vpc-0a1b2c3d4e5f60002"),
+			"shared": attachment("vpc-0a1b2c3d4e5f60003"),
+		},
+		"peering_attachments": map[string]interface{}{
+			"dr": map[string]interface{}{
+				"peer_account_id":         awsstub.AccountID,
+				"peer_region":             "eu-central-1",
+				"peer_transit_gateway_id": "tgw-0a1b2c3d4e5f60002",
+			},
+		},
+		"route_tables": map[string]interface{}{
+			"prod":   map[string]interface{}{},
+			"lab":    map[string]interface{}{},
+			"shared": map[string]interface{}{},
+		},
+		"route_table_associations": map[string]interface{}{
+			"prod":   tgwBinding("prod", "vpc", "prod"),
+			"lab":    tgwBinding("lab", "vpc", "lab"),
+			"shared": tgwBinding("shared", "vpc", "shared"),
+			"dr":     tgwBinding("dr", "peering", "shared"),
+		},
+		"route_table_propagations": map[string]interface{}{
+			"shared-to-prod": tgwBinding("shared", "vpc", "prod"),
+			"shared-to-lab":  tgwBinding("shared", "vpc", "lab"),
+			"prod-to-shared": tgwBinding("prod", "vpc", "shared"),
+			"lab-to-shared":  tgwBinding("lab", "vpc", "shared"),
+		},
+		"static_routes": map[string]interface{}{
+			"prod-to-dr": map[string]interface{}{
+				"destination_cidr_block": "10.100.0.0/16",
+				"route_table_name":       "prod",
+				"attachment_name":        "dr",
+				"attachment_type":        "peering",
+				"attachment_id":          "tgw-attach-dr",
+			},
+			"lab-no-dr": map[string]interface{}{
+				"destination_cidr_block": "10.100.0.0/16",
+				"route_table_name":       "lab",
+				"attachment_name":        "dr",
+				"attachment_type":        "peering",
+				"attachment_id":          "tgw-attach-dr",
+				"blackhole":              true,
+			},
+		},
+	}
+	terraformOptions := harness.OfflineOptions(t, stub, repoRoot, transitGatewayModule, vars)
+	harness.InitAndPlan(t, terraformOptions)
+	plan := planjson.Show(t, terraformOptions)
+
+	hub, err := tgwsim.FromPlan(plan, "hub", map[string][]string{
+		"prod":   {"10.0.0.0/16"},
+		"lab":    {"10.1.0.0/16"},
+		"shared": {"10.2.0.0/16"},
+	})
+	require.NoError(t, err)
+	dr, err := tgwsim.FromVars("dr", map[string]interface{}{
+		"default_route_table_association": "disable",
+		"vpc_attachments":                 map[string]interface{}{"dr": attachment("vpc-0a1b2c3d4e5f60004")},
+		"route_tables":                    map[string]interface{}{"main": map[string]interface{}{}},
+		"route_table_associations": map[string]interface{}{
+			"dr":  tgwBinding("dr", "vpc", "main"),
+			"hub": tgwBinding("hub", "peering", "main"),
+		},
+		"route_table_propagations": map[string]interface{}{"dr": tgwBinding("dr", "vpc", "main")},
+		"static_routes": map[string]interface{}{
+			"to-prod": map[string]interface{}{
+				"destination_cidr_block": "10.0.0.0/16",
+				"route_table_name":       "main",
+				"attachment_name":        "hub",
+				"attachment_type":        "peering",
+			},
+		},
+	}, map[string][]string{"dr": {"10.100.0.0/16"}})
+	require.NoError(t, err)
+
+	topology := tgwsim.Topology{Gateways: []tgwsim.Gateway{hub, dr}}
+	require.NoError(t, topology.Peer(tgwsim.Endpoint{Gateway: "hub", Attachment: "dr"}, tgwsim.Endpoint{Gateway: "dr", Attachment: "hub"}))
+
+	report := sarifReport(t)
+	for _, g := range topology.Gateways {
+		for _, p := range tgwsim.Check(g) {
+			if p.Severity >= analysis.DefaultThreshold {
+				t.Error(p)
+			} else {
+				t.Log(p)
+			}
+			if g.Name == hub.Name {
+				report.Add(p.Finding(repoRoot, transitGatewayModule))
+			}
+		}
+	}
+
+	m := topology.Reachability()
+	t.Logf("Reachability:\n%s", m)
+	assert.True(t, m.Isolated("prod", "lab"), "prod and lab must not reach each other")
+	assert.True(t, m.Connected("prod", "shared"))
+	assert.True(t, m.Connected("lab", "shared"))
+	assert.True(t, m.Connected("hub/prod", "dr/dr"))
+	assert.True(t, m.Isolated("lab", "dr/dr"), "lab must not reach the disaster recovery region")
+	if r, ok := m.Result("lab", "dr/dr"); assert.True(t, ok) {
+		for _, p := range r.Paths {
+			assert.Equal(t, tgwsim.StatusBlackhole, p.Status, p.String())
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package trustpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/iameval"
+)
+
+// guardKeys are the condition keys that narrow a wildcard principal down to
+// known callers.
+var guardKeys = []string{
+	"aws:PrincipalAccount", "aws:PrincipalArn", "aws:PrincipalOrgID", "aws:PrincipalOrgPaths",
+	"aws:SourceAccount", "aws:SourceArn", "aws:SourceOrgID",
+}
+
+// analyzer adds the trusts of one role to a matrix.
+type analyzer struct {
+	matrix  *Matrix
+	account string
+	role    string
+	address string
+}
+
+func (a *analyzer) report(rule string, severity analysis.Severity, format string, args ...interface{}) {
+	a.matrix.Findings = append(a.matrix.Findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		Role:     a.role,
+		Address:  a.address,
+		Message:  fmt.Sprintf("role %s: ", a.role) + fmt.Sprintf(format, args...),
+	})
+}
+
+// document adds the trusts of a decoded trust policy.
+func (a *analyzer) document(doc interface{}) error {
+	policy, err := iameval.Parse(a.role, doc)
+	if err != nil {
+		return err
+	}
+	raw := rawStatements(doc)
+	for _, s := range policy.Statements {
+		if s.Effect != iameval.Allow {
+			continue
+		}
+		statement, _ := raw[s.Index].(map[string]interface{})
+		if _, ok := statement["NotPrincipal"]; ok {
+			a.report(RuleNotPrincipal, analysis.SeverityHigh,
+				"Statement[%d] allows every principal but those in NotPrincipal; name the trusted principals instead", s.Index)
+			continue
+		}
+		principals, err := parsePrincipals(statement["Principal"])
+		if err != nil {
+			return fmt.Errorf("Statement[%d].Principal: %w", s.Index, err)
+		}
+		for _, p := range principals {
+			t := Trust{
+				Role:       a.role,
+				Address:    a.address,
+				Principal:  p,
+				Actions:    s.Action,
+				Statement:  s.Index,
+				Sid:        s.Sid,
+				Conditions: s.Condition,
+			}
+			a.matrix.Trusts = append(a.matrix.Trusts, t)
+			a.check(t)
+		}
+	}
+	return nil
+}
+
+// check reports the rules t breaks.
+func (a *analyzer) check(t Trust) {
+	switch t.Principal.Type {
+	case PrincipalAWS:
+		if t.Principal.ID == "*" {
+			if guard, ok := guarded(t); ok {
+				a.report(RuleWildcardPrincipal, analysis.SeverityMedium,
+					"Statement[%d] trusts every AWS principal, limited only by a condition on %s", t.Statement, guard)
+			} else {
+				a.report(RuleWildcardPrincipal, analysis.SeverityHigh,
+					"Statement[%d] lets any AWS principal, in any account, assume the role", t.Statement)
+			}
+			return
+		}
+		account, ok := principalAccount(t.Principal.ID)
+		if !ok || a.account == "" || account == a.account {
+			return
+		}
+		message := fmt.Sprintf("Statement[%d] trusts %s in account %s", t.Statement, t.Principal.ID, account)
+		if len(t.Condition("sts:ExternalId")) == 0 {
+			message += ", without an sts:ExternalId condition"
+		}
+		a.report(RuleCrossAccount, analysis.SeverityMedium, "%s", message)
+	case PrincipalFederated:
+		if allows(t.Actions, "sts:AssumeRoleWithWebIdentity") {
+			a.webIdentity(t)
+		}
+	}
+}
+
+// webIdentity checks that a web identity trust pins the token's audience
+// and subject, using the condition keys of its provider.
+func (a *analyzer) webIdentity(t Trust) {
+	// The account of the provider's ARN may be a placeholder; its host is
+	// what the condition keys are named after.
+	host := ""
+	if i := strings.Index(t.Principal.ID, ":oidc-provider/"); i >= 0 {
+		host = t.Principal.ID[i+len(":oidc-provider/"):]
+	}
+	if strings.Contains(host, "${") {
+		host = ""
+	}
+	var provider *Provider
+	for i, p := range a.matrix.Providers {
+		if host != "" && p.Host == host {
+			provider = &a.matrix.Providers[i]
+		}
+	}
+	if host != "" && provider == nil {
+		a.report(RuleUnknownProvider, analysis.SeverityMedium,
+			"Statement[%d] trusts OIDC provider %s, which is not declared in oidc_providers", t.Statement, host)
+	}
+
+	aud, sub := t.conditionSuffix(host, "aud"), t.conditionSuffix(host, "sub")
+	if len(aud) == 0 {
+		a.report(RuleWebIdentity, analysis.SeverityHigh,
+			"Statement[%d] accepts web identity tokens for any audience; add a condition on %s", t.Statement, keyName(host, "aud"))
+	} else if provider != nil && len(provider.ClientIDs) > 0 {
+		for _, v := range aud {
+			if !contains(provider.ClientIDs, v) {
+				a.report(RuleWebIdentity, analysis.SeverityHigh,
+					"Statement[%d] accepts audience %q, which is not a client ID of %s", t.Statement, v, provider.Address)
+			}
+		}
+	}
+	switch {
+	case len(sub) == 0:
+		a.report(RuleWebIdentity, analysis.SeverityHigh,
+			"Statement[%d] accepts web identity tokens for any subject; add a condition on %s", t.Statement, keyName(host, "sub"))
+	case contains(sub, "*"):
+		a.report(RuleWebIdentity, analysis.SeverityHigh,
+			"Statement[%d] matches any subject with %s \"*\"", t.Statement, keyName(host, "sub"))
+	}
+}
+
+// conditionSuffix returns the values of the conditions on host:field, or on
+// any key ending in :field when the host is not known.
+func (t Trust) conditionSuffix(host, field string) []string {
+	if host != "" {
+		return t.Condition(host + ":" + field)
+	}
+	var values []string
+	for _, c := range t.Conditions {
+		if strings.HasSuffix(strings.ToLower(c.Key), ":"+field) {
+			values = append(values, c.Values...)
+		}
+	}
+	return values
+}
+
+func keyName(host, field string) string {
+	if host == "" {
+		return "<provider>:" + field
+	}
+	return host + ":" + field
+}
+
+// guarded returns the first guard key t has a condition on.
+func guarded(t Trust) (string, bool) {
+	for _, key := range guardKeys {
+		if len(t.Condition(key)) > 0 {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// allows reports whether actions, which may hold wildcards, include action.
+func allows(actions []string, action string) bool {
+	for _, a := range actions {
+		pattern := strings.ToLower(a)
+		if pattern == "*" || pattern == "sts:*" || pattern == strings.ToLower(action) {
+			return true
+		}
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(strings.ToLower(action), strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// rawStatements returns the statements of a decoded document, indexed as
+// iameval.Parse indexes them.
+func rawStatements(doc interface{}) []interface{} {
+	obj, _ := doc.(map[string]interface{})
+	switch s := obj["Statement"].(type) {
+	case map[string]interface{}:
+		return []interface{}{s}
+	case []interface{}:
+		return s
+	}
+	return nil
+}
+
+// parsePrincipals reads a Principal element: "*", or an object of principal
+// types to an ID or a list of them.
+func parsePrincipals(value interface{}) ([]Principal, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("missing")
+	case string:
+		if v != "*" {
+			return nil, fmt.Errorf("must be \"*\" or an object, not %q", v)
+		}
+		return []Principal{{Type: PrincipalAWS, ID: "*"}}, nil
+	case map[string]interface{}:
+		types := make([]string, 0, len(v))
+		for key := range v {
+			types = append(types, key)
+		}
+		sort.Strings(types)
+		var out []Principal
+		for _, typ := range types {
+			ids := stringValues(v[typ])
+			if s, ok := v[typ].(string); ok {
+				ids = []string{s}
+			}
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("%s: no principals", typ)
+			}
+			for _, id := range ids {
+				out = append(out, Principal{Type: typ, ID: id})
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unexpected %T", value)
+}
@@ -0,0 +1,310 @@
+// Package trustpolicy analyses the trust policies of planned IAM roles: who
+// may assume each role, by which action and under which conditions. The
+// result is a trust matrix tests can assert on, with findings for principals
+// trusted too widely: everyone, other accounts, and web identities whose
+// tokens are not pinned to an audience and a subject.
+package trustpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/iameval"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+// ToolTrustPolicy is the tool name of trust policy findings, as recorded on
+// analysis.Finding.
+const ToolTrustPolicy = "trustpolicy"
+
+// Rule names reported on findings.
+const (
+	// RuleWildcardPrincipal is a role anyone can assume.
+	RuleWildcardPrincipal = "trust-wildcard-principal"
+	// RuleNotPrincipal is a role trusting every principal but those listed.
+	RuleNotPrincipal = "trust-not-principal"
+	// RuleCrossAccount is a role another account can assume.
+	RuleCrossAccount = "trust-cross-account"
+	// RuleWebIdentity is a role assumable with a web identity token whose
+	// audience or subject is not pinned by a condition.
+	RuleWebIdentity = "trust-web-identity"
+	// RuleUnknownProvider is a role trusting an OIDC provider the plan does
+	// not declare.
+	RuleUnknownProvider = "trust-unknown-provider"
+)
+
+// Principal types, as keys of a statement's Principal element.
+const (
+	PrincipalAWS           = "AWS"
+	PrincipalService       = "Service"
+	PrincipalFederated     = "Federated"
+	PrincipalCanonicalUser = "CanonicalUser"
+)
+
+// Principal is one principal a role trusts. Principal: "*" is recorded as
+// an AWS principal with ID "*".
+type Principal struct {
+	Type string
+	ID   string
+}
+
+func (p Principal) String() string {
+	return p.Type + ":" + p.ID
+}
+
+// Service returns the principal for an AWS service, e.g.
+// Service("ec2.amazonaws.com").
+func Service(name string) Principal {
+	return Principal{Type: PrincipalService, ID: name}
+}
+
+// Trust is one principal allowed to assume a role by one statement of its
+// trust policy.
+type Trust struct {
+	Role string
+	// Address is the planned aws_iam_role.
+	Address   string
+	Principal Principal
+	// Actions are the sts actions the statement allows, e.g.
+	// "sts:AssumeRoleWithWebIdentity".
+	Actions []string
+	// Statement and Sid identify the statement.
+	Statement int
+	Sid       string
+	// Conditions are the statement's conditions.
+	Conditions []iameval.Condition
+}
+
+// Condition returns the values the trust's conditions test key against,
+// under any operator.
+func (t Trust) Condition(key string) []string {
+	var values []string
+	for _, c := range t.Conditions {
+		if strings.EqualFold(c.Key, key) {
+			values = append(values, c.Values...)
+		}
+	}
+	return values
+}
+
+// Provider is an OIDC identity provider declared by the plan.
+type Provider struct {
+	Address string
+	// Host is the provider's URL without its scheme, as used in its ARN and
+	// in condition keys, e.g. "token.actions.githubusercontent.com".
+	Host      string
+	ClientIDs []string
+}
+
+// Finding is a trust breaking a rule.
+type Finding struct {
+	Rule     string
+	Severity analysis.Severity
+	Role     string
+	Address  string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: [%s] %s (%s)", f.Address, f.Severity, f.Message, f.Rule)
+}
+
+// Finding converts f, found in a plan of the configuration in dir, to a
+// finding located at the block declaring the role, with its file relative
+// to root.
+func (f Finding) Finding(root, dir string) analysis.Finding {
+	return analysis.Locate(analysis.Finding{
+		Tool:     ToolTrustPolicy,
+		Rule:     f.Rule,
+		Severity: f.Severity,
+		Resource: f.Address,
+		Message:  f.Message,
+	}, root, dir)
+}
+
+// Matrix is who can assume the roles of a plan.
+type Matrix struct {
+	Trusts    []Trust
+	Providers []Provider
+	Findings  []Finding
+	// Unresolved lists the trust policies that could not be read.
+	Unresolved []string
+}
+
+// Options configures Analyze.
+type Options struct {
+	// Account is the account the roles are created in; AWS principals in
+	// any other account are cross-account.
+	Account string
+	// Resolve supplies trust policies the plan knows only after apply; see
+	// iameval.Symbolic. May be nil.
+	Resolve iameval.Resolver
+}
+
+// Analyze builds the trust matrix of the roles planned in plan.
+func Analyze(plan *planjson.Plan, opts Options) Matrix {
+	changes := plan.ResourceChanges.Managed()
+	var m Matrix
+	for _, rc := range changes.WithType("aws_iam_openid_connect_provider") {
+		p := Provider{Address: rc.Address}
+		if raw, ok := rc.AfterString("url"); ok {
+			p.Host = providerHost(raw)
+		}
+		if ids, ok := rc.After("client_id_list"); ok {
+			p.ClientIDs = stringValues(ids)
+		}
+		m.Providers = append(m.Providers, p)
+	}
+
+	for _, rc := range changes.WithType("aws_iam_role") {
+		role, ok := rc.AfterString("name")
+		if !ok {
+			m.Unresolved = append(m.Unresolved, rc.Address+": name known only after apply")
+			continue
+		}
+		doc, err := trustDocument(rc, role, opts.Resolve)
+		if err != nil {
+			m.Unresolved = append(m.Unresolved, fmt.Sprintf("%s: %v", rc.Address, err))
+			continue
+		}
+		a := analyzer{matrix: &m, account: opts.Account, role: role, address: rc.Address}
+		if err := a.document(doc); err != nil {
+			m.Unresolved = append(m.Unresolved, fmt.Sprintf("%s: %v", rc.Address, err))
+		}
+	}
+	sort.SliceStable(m.Findings, func(i, j int) bool {
+		if m.Findings[i].Severity != m.Findings[j].Severity {
+			return m.Findings[i].Severity > m.Findings[j].Severity
+		}
+		return m.Findings[i].Address < m.Findings[j].Address
+	})
+	return m
+}
+
+// trustDocument returns the decoded trust policy of the planned role rc,
+// from the plan or, when only known after apply, from resolve.
+func trustDocument(rc planjson.ResourceChange, role string, resolve iameval.Resolver) (interface{}, error) {
+	if text, ok := rc.AfterString("assume_role_policy"); ok {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, fmt.Errorf("assume_role_policy: %w", err)
+		}
+		return doc, nil
+	}
+	if resolve != nil {
+		name := fmt.Sprintf(`%s.roles[%q].assume_role_policy`, rc.ModuleAddress, role)
+		if doc, ok := resolve(name); ok {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("assume_role_policy known only after apply")
+}
+
+// Principals returns the principals that can assume role, in order.
+func (m Matrix) Principals(role string) []Principal {
+	seen := map[Principal]bool{}
+	var out []Principal
+	for _, t := range m.Trusts {
+		if t.Role == role && !seen[t.Principal] {
+			seen[t.Principal] = true
+			out = append(out, t.Principal)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out
+}
+
+// Roles returns the roles p can assume, in order.
+func (m Matrix) Roles(p Principal) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range m.Trusts {
+		if t.Principal == p && !seen[t.Role] {
+			seen[t.Role] = true
+			out = append(out, t.Role)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Lookup returns the trusts allowing p to assume role.
+func (m Matrix) Lookup(role string, p Principal) []Trust {
+	var out []Trust
+	for _, t := range m.Trusts {
+		if t.Role == role && t.Principal == p {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// String renders the matrix as a table of role, principal, actions and
+// conditions, one row per trust.
+func (m Matrix) String() string {
+	rows := append([]Trust(nil), m.Trusts...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Role != rows[j].Role {
+			return rows[i].Role < rows[j].Role
+		}
+		return rows[i].Principal.String() < rows[j].Principal.String()
+	})
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tPRINCIPAL\tACTIONS\tCONDITIONS")
+	for _, t := range rows {
+		var conditions []string
+		for _, c := range t.Conditions {
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", c.Operator, c.Key, strings.Join(c.Values, ",")))
+		}
+		if len(conditions) == 0 {
+			conditions = []string{"-"}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Role, t.Principal, strings.Join(t.Actions, ","), strings.Join(conditions, "; "))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// providerHost returns the host and path of an OIDC provider URL, as IAM
+// names the provider.
+func providerHost(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return strings.TrimSuffix(u.Host+u.Path, "/")
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(raw, "https://"), "/")
+}
+
+// accountID matches a bare account ID, as a principal or in an ARN.
+var accountID = regexp.MustCompile(`^[0-9]{12}$`)
+
+// principalAccount returns the account of an AWS principal, which is an
+// account ID or an ARN, reporting false when it cannot be told, e.g. for a
+// placeholder of an account known only after apply.
+func principalAccount(id string) (string, bool) {
+	if accountID.MatchString(id) {
+		return id, true
+	}
+	parts := strings.SplitN(id, ":", 6)
+	if len(parts) == 6 && parts[0] == "arn" && accountID.MatchString(parts[4]) {
+		return parts[4], true
+	}
+	return "", false
+}
+
+func stringValues(value interface{}) []string {
+	list, _ := value.([]interface{})
+	var out []string
+	for _, elem := range list {
+		if s, ok := elem.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package trustpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+)
+
+const account = "123456789012"
+
+func analyze(t *testing.T) Matrix {
+	t.Helper()
+	plan, err := planjson.Load("testdata/plan.json")
+	require.NoError(t, err)
+	return Analyze(plan, Options{
+		Account: account,
+		Resolve: func(name string) (interface{}, bool) {
+			if name != `module.iam.roles["pending"].assume_role_policy` {
+				return nil, false
+			}
+			return map[string]interface{}{"Statement": []interface{}{map[string]interface{}{
+				"Effect": "Allow", "Action": "sts:AssumeRole", "Principal": map[string]interface{}{"Service": "lambda.amazonaws.com"},
+			}}}, true
+		},
+	})
+}
+
+func findingStrings(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.String())
+	}
+	return out
+}
+
+func TestMatrix(t *testing.T) {
+	m := analyze(t)
+	assert.Empty(t, m.Unresolved)
+	require.Len(t, m.Providers, 1)
+	assert.Equal(t, Provider{
+		Address:   `module.iam.aws_iam_openid_connect_provider.this["github-actions"]`,
+		Host:      "token.actions.githubusercontent.com",
+		ClientIDs: []string{"sts.amazonaws.com"},
+	}, m.Providers[0])
+
+	assert.Equal(t, []Principal{Service("ec2.amazonaws.com")}, m.Principals("unit-ec2-role"))
+	assert.Equal(t, []Principal{Service("lambda.amazonaws.com")}, m.Principals("pending"), "resolved from the configuration")
+	assert.Equal(t, []Principal{
+		{Type: PrincipalAWS, ID: "arn:aws:iam::123456789012:role/ops"},
+		{Type: PrincipalAWS, ID: "arn:aws:iam::210987654321:root"},
+	}, m.Principals("partner"))
+	assert.Empty(t, m.Principals("excluded"))
+
+	github := Principal{Type: PrincipalFederated, ID: "arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"}
+	assert.Equal(t, []string{"github", "github-loose"}, m.Roles(github))
+	trusts := m.Lookup("github", github)
+	require.Len(t, trusts, 1)
+	assert.Equal(t, "GitHub", trusts[0].Sid)
+	assert.Equal(t, []string{"sts:AssumeRoleWithWebIdentity"}, trusts[0].Actions)
+	assert.Equal(t, []string{"repo:my-org/my-repo:*"}, trusts[0].Condition("token.actions.githubusercontent.com:sub"))
+
+	assert.Contains(t, m.String(), "unit-ec2-role  Service:ec2.amazonaws.com")
+}
+
+func TestFindings(t *testing.T) {
+	assert.Equal(t, []string{
+		`module.iam.aws_iam_role.this["excluded"]: [HIGH] role excluded: Statement[0] allows every principal but those in NotPrincipal; name the trusted principals instead (trust-not-principal)`,
+		`module.iam.aws_iam_role.this["github-loose"]: [HIGH] role github-loose: Statement[0] accepts audience "api://other", which is not a client ID of module.iam.aws_iam_openid_connect_provider.this["github-actions"] (trust-web-identity)`,
+		`module.iam.aws_iam_role.this["github-loose"]: [HIGH] role github-loose: Statement[0] accepts web identity tokens for any subject; add a condition on token.actions.githubusercontent.com:sub (trust-web-identity)`,
+		`module.iam.aws_iam_role.this["open"]: [HIGH] role open: Statement[0] lets any AWS principal, in any account, assume the role (trust-wildcard-principal)`,
+		`module.iam.aws_iam_role.this["irsa"]: [MEDIUM] role irsa: Statement[0] trusts OIDC provider oidc.eks.eu-west-1.amazonaws.com/id/ABC, which is not declared in oidc_providers (trust-unknown-provider)`,
+		`module.iam.aws_iam_role.this["org"]: [MEDIUM] role org: Statement[0] trusts every AWS principal, limited only by a condition on aws:PrincipalOrgID (trust-wildcard-principal)`,
+		`module.iam.aws_iam_role.this["partner"]: [MEDIUM] role partner: Statement[0] trusts arn:aws:iam::210987654321:root in account 210987654321, without an sts:ExternalId condition (trust-cross-account)`,
+	}, findingStrings(analyze(t).Findings))
+}
+
+func TestWebIdentityWithoutHost(t *testing.T) {
+	m := Matrix{}
+	a := analyzer{matrix: &m, role: "ci", address: "aws_iam_role.ci"}
+	require.NoError(t, a.document(map[string]interface{}{"Statement": map[string]interface{}{
+		"Effect":    "Allow",
+		"Action":    "sts:AssumeRoleWithWebIdentity",
+		"Principal": map[string]interface{}{"Federated": "${module.iam.oidc_provider_arns[\"github\"]}"},
+		"Condition": map[string]interface{}{"StringLike": map[string]interface{}{"token.actions.githubusercontent.com:sub": "*"}},
+	}}))
+	assert.Equal(t, []string{
+		`aws_iam_role.ci: [HIGH] role ci: Statement[0] accepts web identity tokens for any audience; add a condition on <provider>:aud (trust-web-identity)`,
+		`aws_iam_role.ci: [HIGH] role ci: Statement[0] matches any subject with <provider>:sub "*" (trust-web-identity)`,
+	}, findingStrings(m.Findings))
+}
+
+func TestPrincipalAccount(t *testing.T) {
+	for id, want := range map[string]string{
+		"210987654321":                                                     "210987654321",
+		"arn:aws:iam::210987654321:root":                                   "210987654321",
+		"arn:aws:sts::210987654321:assumed-role/a/b":                       "210987654321",
+		"arn:aws:iam::${data.aws_caller_identity.current.account_id}:root": "",
+	} {
+		got, ok := principalAccount(id)
+		assert.Equal(t, want != "", ok, id)
+		assert.Equal(t, want, got, id)
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/terraform-aws-modules/test/analysis"
+	"github.com/your-org/terraform-aws-modules/test/awsstub"
+	"github.com/your-org/terraform-aws-modules/test/harness"
+	"github.com/your-org/terraform-aws-modules/test/planjson"
+	"github.com/your-org/terraform-aws-modules/test/routepath"
+)
+
+// vpcModule is the configuration the route path tests plan
+const vpcModule = "modules/vpc"
+
+// TestVPCRoutePaths plans the VPC module offline with even and uneven subnet layouts and follows each
+// subnet's default route: public subnets must reach an internet gateway, and private subnets a NAT gateway
+// in their own availability zone. More private than public subnets is not a layout the module can plan,
+// since the private route tables index the NAT gateways by their own count, so that plan must fail
+func TestVPCRoutePaths(t *testing.T) {
+	cases := []struct {
+		name    string
+		public  []string
+		private []string
+	}{
+		{"even", []string{"10.0.1.0/24", "10.0.2.0/24"}, []string{"10.0.10.0/24", "10.0.20.0/24"}},
+		{"more-public", []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"}, []string{"10.0.10.0/24", "10.0.20.0/24"}},
+		{"three-zones", []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"}, []string{"10.0.10.0/24", "10.0.20.0/24", "10.0.30.0/24"}},
+	}
+
+	stub := awsstub.Start(t)
+
+	report := sarifReport(t)
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			terraformOptions := harness.OfflineOptions(t, stub, repoRoot, vpcModule, map[string]interface{}{
+				"name_prefix":          "unit",
+				"public_subnet_cidrs":  tc.public,
+				"private_subnet_cidrs": tc.private,
+			})
+			harness.InitAndPlan(t, terraformOptions)
+			plan := planjson.Show(t, terraformOptions)
+
+			g, err := routepath.FromPlan(plan)
+			require.NoError(t, err)
+			t.Logf("Route paths:\n%s", g)
+			for _, u := range g.Unresolved {
+				t.Logf("not followed: %s", u)
+			}
+			for _, p := range g.Check() {
+				if p.Severity >= analysis.DefaultThreshold {
+					t.Error(p)
+				} else {
+					t.Log(p)
+				}
+				report.Add(p.Finding(repoRoot, vpcModule))
+			}
+
+			require.Len(t, g.Subnets, len(tc.public)+len(tc.private))
+			for _, s := range g.Subnets {
+				e, _ := g.Egress(s.Address)
+				if !assert.True(t, e.Routed(), "%s has no default route", s.Address) {
+					continue
+				}
+				if s.Public {
+					assert.Equal(t, routepath.TargetInternetGateway, e.Route.Target.Kind, s.Address)
+					continue
+				}
+				assert.Equal(t, routepath.TargetNATGateway, e.Route.Target.Kind, s.Address)
+				assert.Equal(t, s.AZ, e.AZ, "%s egresses through a NAT gateway in another zone", s.Address)
+			}
+		})
+	}
+
+	t.Run("more-private", func(t *testing.T) {
+		terraformOptions := harness.OfflineOptions(t, stub, repoRoot, vpcModule, map[string]interface{}{
+			"name_prefix":          "unit",
+			"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+			"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.20.0/24", "10.0.30.0/24"},
+		})
+		_, err := harness.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, "a third private subnet has no NAT gateway in its zone to route through")
+		assert.Contains(t, err.Error(), "Invalid index")
+		assert.Contains(t, err.Error(), "aws_nat_gateway.this")
+	})
+}